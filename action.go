@@ -7,7 +7,6 @@ import (
 
 	"github.com/baidu-security/openrasp-golang/common"
 	"github.com/baidu-security/openrasp-golang/model"
-	v8 "github.com/baidu-security/openrasp-v8/go"
 )
 
 type BuildinAction struct {
@@ -46,7 +45,7 @@ func (ba *BuildinAction) Get(ct common.CheckType) model.InterceptCode {
 func (ba *BuildinAction) OnPluginUpdate() {
 	script := common.BuildinActionScript()
 	if len(script) > 0 {
-		actionResult := v8.ExecScript(script, "extract_buildin_action")
+		actionResult := ExecScript(script, "extract_buildin_action")
 		var ms [][]string
 		unquotedResult, err := strconv.Unquote(actionResult)
 		if err != nil {
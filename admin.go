@@ -0,0 +1,313 @@
+package openrasp
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// AdminServer serves a debugging API on admin.addr when admin.enable is on
+// and admin.token is set. It only listens on a loopback address and only
+// answers local clients which send the token in the X-OpenRASP-Token header,
+// address the server by a loopback host and carry no Origin header, which
+// keeps out browsers, rebound dns names and requests forged by the
+// application. POST takes a JSON body.
+//
+//	GET  /config      the effective general config, secrets masked
+//	GET  /whitelist   the check types exempted under every url prefix
+//	GET  /detections  the admin.recent_size latest attacks, newest first
+//	GET  /hooks       the hooks with their switch
+//	POST /hooks       turns a hook on or off, {"name":"sql","enable":false}
+//	GET  /health      the result of HealthCheck
+//	POST /selftest    runs SelfTest
+type AdminServer struct {
+	// recording comes first to be 64-bit aligned for atomic access
+	recording uint64
+	enable    bool
+	addr      string
+	token     string
+	server    *http.Server
+	mu        sync.Mutex
+	recent    recentDetections
+}
+
+func NewAdminServer() *AdminServer {
+	as := &AdminServer{}
+	as.OnConfigUpdate()
+	return as
+}
+
+func (as *AdminServer) OnConfigUpdate() {
+	enable := GetGeneral().GetBool("admin.enable")
+	addr := GetGeneral().GetString("admin.addr")
+	token := GetGeneral().GetString("admin.token")
+	as.recent.resize(GetGeneral().GetInt("admin.recent_size"))
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if enable == as.enable && addr == as.addr && token == as.token {
+		return
+	}
+	as.enable, as.addr, as.token = enable, addr, token
+	if as.server != nil {
+		as.server.Close()
+		as.server = nil
+	}
+	atomic.StoreUint64(&as.recording, 0)
+	if !enable {
+		return
+	}
+	if len(token) == 0 {
+		GetLog().RaspWarn("admin.enable is ignored, cuz of admin.token is empty", orlog.Config)
+		return
+	}
+	if err := checkLoopback(addr); err != nil {
+		GetLog().RaspWarn("admin.enable is ignored, cuz of "+err.Error(), orlog.Config)
+		return
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		GetLog().RaspWarn("Unable to start admin server, cuz of "+err.Error(), orlog.Config)
+		return
+	}
+	as.server = &http.Server{
+		Handler:      as.handler(),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	go as.server.Serve(listener)
+	atomic.StoreUint64(&as.recording, 1)
+	GetLog().RaspInfo("Admin server listening on "+listener.Addr().String(), orlog.Runtime)
+}
+
+// checkLoopback rejects an address which is reachable from other hosts
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("%s is not a loopback address", addr)
+	}
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (as *AdminServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", as.serveConfig)
+	mux.HandleFunc("/whitelist", as.serveWhitelist)
+	mux.HandleFunc("/detections", as.serveDetections)
+	mux.HandleFunc("/hooks", as.serveHooks)
+	mux.HandleFunc("/health", as.serveHealth)
+	mux.HandleFunc("/selftest", as.serveSelfTest)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := as.authorize(req); err != nil {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+			return
+		}
+		if req.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "content type must be application/json"})
+				return
+			}
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+// authorize rejects a request which is not made by a local client knowing
+// admin.token, forms posted by browsers and names rebound to the loopback
+// address do not get through either
+func (as *AdminServer) authorize(req *http.Request) error {
+	if host, _, _ := net.SplitHostPort(req.RemoteAddr); !isLoopbackHost(host) {
+		return errors.New("forbidden client")
+	}
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if !isLoopbackHost(host) {
+		return errors.New("forbidden host")
+	}
+	if len(req.Header.Get("Origin")) > 0 {
+		return errors.New("cross origin request")
+	}
+	as.mu.Lock()
+	token := as.token
+	as.mu.Unlock()
+	if len(token) == 0 || subtle.ConstantTimeCompare([]byte(req.Header.Get("X-OpenRASP-Token")), []byte(token)) != 1 {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func methodAllowed(w http.ResponseWriter, req *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if req.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func (as *AdminServer) serveConfig(w http.ResponseWriter, req *http.Request) {
+	settings := GetGeneral().AllSettings()
+	for key, value := range settings {
+		settings[key] = GetMasker().MaskSetting(key, value)
+	}
+	writeJSON(w, http.StatusOK, settings)
+}
+
+func (as *AdminServer) serveWhitelist(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, GetWhite().Entries())
+}
+
+func (as *AdminServer) serveDetections(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, as.recent.list())
+}
+
+func (as *AdminServer) serveHealth(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, HealthCheck())
+}
+
+func (as *AdminServer) serveSelfTest(w http.ResponseWriter, req *http.Request) {
+	if !methodAllowed(w, req, http.MethodPost) {
+		return
+	}
+	writeJSON(w, http.StatusOK, SelfTest())
+}
+
+func (as *AdminServer) serveHooks(w http.ResponseWriter, req *http.Request) {
+	if !methodAllowed(w, req, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if req.Method == http.MethodPost {
+		var body struct {
+			Name   string `json:"name"`
+			Enable *bool  `json:"enable"`
+		}
+		if err := json.NewDecoder(io.LimitReader(req.Body, 4096)).Decode(&body); err != nil || body.Enable == nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be {\"name\": hook, \"enable\": true or false}"})
+			return
+		}
+		if !isHookName(body.Name) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown hook " + body.Name})
+			return
+		}
+		if err := GetGeneral().Update(map[string]interface{}{"hook." + body.Name + ".enable": *body.Enable}); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, HealthCheck().Hooks)
+}
+
+func isHookName(name string) bool {
+	for _, hk := range hookKeys {
+		if hk.key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// record keeps attackLog for /detections while the server runs
+func (as *AdminServer) record(attackLog *model.AttackLog) {
+	if as == nil || atomic.LoadUint64(&as.recording) == 0 {
+		return
+	}
+	if s := attackLog.String(); len(s) > 0 {
+		as.recent.add(json.RawMessage(s))
+	}
+}
+
+// recentDetections is a ring buffer of the latest attack logs
+type recentDetections struct {
+	entries []json.RawMessage
+	next    int
+	full    bool
+	mu      sync.Mutex
+}
+
+// resize keeps the latest size entries
+func (rd *recentDetections) resize(size int) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if size == len(rd.entries) {
+		return
+	}
+	latest := rd.latest()
+	if len(latest) > size {
+		latest = latest[:size]
+	}
+	rd.entries = make([]json.RawMessage, size)
+	rd.next, rd.full = 0, false
+	for i := len(latest) - 1; i >= 0; i-- {
+		rd.push(latest[i])
+	}
+}
+
+func (rd *recentDetections) add(entry json.RawMessage) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	rd.push(entry)
+}
+
+func (rd *recentDetections) push(entry json.RawMessage) {
+	if len(rd.entries) == 0 {
+		return
+	}
+	rd.entries[rd.next] = entry
+	if rd.next++; rd.next == len(rd.entries) {
+		rd.next, rd.full = 0, true
+	}
+}
+
+func (rd *recentDetections) list() []json.RawMessage {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	return rd.latest()
+}
+
+// latest returns the entries from the newest to the oldest
+func (rd *recentDetections) latest() []json.RawMessage {
+	count := rd.next
+	if rd.full {
+		count = len(rd.entries)
+	}
+	latest := make([]json.RawMessage, 0, count)
+	for i := 1; i <= count; i++ {
+		latest = append(latest, rd.entries[(rd.next-i+len(rd.entries))%len(rd.entries)])
+	}
+	return latest
+}
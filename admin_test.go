@@ -0,0 +1,60 @@
+package openrasp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// adminRequest sends a request which passes the checks of the admin server
+// of token s3cret, unless setup changes it
+func adminRequest(as *AdminServer, method, target, body string, setup func(req *http.Request)) int {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.RemoteAddr = "127.0.0.1:50000"
+	req.Host = "127.0.0.1:8990"
+	req.Header.Set("X-OpenRASP-Token", "s3cret")
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if setup != nil {
+		setup(req)
+	}
+	rec := httptest.NewRecorder()
+	as.handler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAdminAuthorize(t *testing.T) {
+	as := &AdminServer{token: "s3cret"}
+	assert.Equal(t, http.StatusOK, adminRequest(as, "GET", "/whitelist", "", nil))
+	assert.Equal(t, http.StatusOK, adminRequest(as, "GET", "/whitelist", "", func(req *http.Request) { req.Host = "localhost" }))
+	assert.Equal(t, http.StatusForbidden, adminRequest(as, "GET", "/whitelist", "", func(req *http.Request) { req.Header.Del("X-OpenRASP-Token") }))
+	assert.Equal(t, http.StatusForbidden, adminRequest(as, "GET", "/whitelist", "", func(req *http.Request) { req.Header.Set("X-OpenRASP-Token", "s3cre") }))
+	assert.Equal(t, http.StatusForbidden, adminRequest(as, "GET", "/whitelist", "", func(req *http.Request) { req.RemoteAddr = "192.0.2.1:50000" }))
+	// a name rebound to 127.0.0.1 keeps its own Host
+	assert.Equal(t, http.StatusForbidden, adminRequest(as, "GET", "/whitelist", "", func(req *http.Request) { req.Host = "rebind.example:8990" }))
+	assert.Equal(t, http.StatusForbidden, adminRequest(as, "GET", "/whitelist", "", func(req *http.Request) { req.Header.Set("Origin", "http://127.0.0.1:8990") }))
+	assert.Equal(t, http.StatusForbidden, adminRequest(&AdminServer{}, "GET", "/whitelist", "", func(req *http.Request) { req.Header.Set("X-OpenRASP-Token", "") }))
+}
+
+func TestAdminHooks(t *testing.T) {
+	as := &AdminServer{token: "s3cret"}
+	contentType := func(contentType string) func(req *http.Request) {
+		return func(req *http.Request) { req.Header.Set("Content-Type", contentType) }
+	}
+	defer func() {
+		assert.Nil(t, GetGeneral().Update(map[string]interface{}{"hook.sql.enable": true}))
+	}()
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, adminRequest(as, "POST", "/hooks", "name=sql&enable=false", contentType("application/x-www-form-urlencoded")))
+	assert.Equal(t, http.StatusUnsupportedMediaType, adminRequest(as, "POST", "/hooks", `{"name":"sql","enable":false}`, contentType("text/plain")))
+	assert.True(t, GetGeneral().GetBool("hook.sql.enable"))
+	assert.Equal(t, http.StatusBadRequest, adminRequest(as, "POST", "/hooks", `{"name":"sql"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, adminRequest(as, "POST", "/hooks", `{"name":"nosuchhook","enable":false}`, nil))
+	assert.Equal(t, http.StatusOK, adminRequest(as, "POST", "/hooks", `{"name":"sql","enable":false}`, contentType("application/json; charset=utf-8")))
+	assert.False(t, GetGeneral().GetBool("hook.sql.enable"))
+	assert.Equal(t, http.StatusMethodNotAllowed, adminRequest(as, "GET", "/selftest", "", nil))
+}
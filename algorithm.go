@@ -0,0 +1,114 @@
+package openrasp
+
+import (
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/spf13/cast"
+)
+
+// AlgorithmConfig holds algorithm.config, the per algorithm settings shared
+// with the other OpenRASP agents:
+//
+//	algorithm.config:
+//	  sql_userinput:
+//	    action: log
+//	  dns_exfiltration:
+//	    action: block
+//	    max_label_length: 40
+//
+// An entry is keyed by the algorithm of a plugin result or by the check type
+// of a built-in check, its settings override the ones of the built-in check.
+type AlgorithmConfig struct {
+	config map[string]map[string]interface{}
+	mu     sync.RWMutex
+}
+
+func NewAlgorithmConfig() *AlgorithmConfig {
+	return &AlgorithmConfig{
+		config: make(map[string]map[string]interface{}),
+	}
+}
+
+func (ac *AlgorithmConfig) OnConfigUpdate() {
+	config := make(map[string]map[string]interface{})
+	for algorithm, settings := range GetGeneral().GetStringMap("algorithm.config") {
+		if m, err := cast.ToStringMapE(settings); err == nil {
+			config[algorithm] = m
+		}
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.config = config
+}
+
+// Get returns the setting key of algorithm, nil-safe before init
+func (ac *AlgorithmConfig) Get(algorithm, key string) (interface{}, bool) {
+	if ac == nil {
+		return nil, false
+	}
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	value, ok := ac.config[algorithm][key]
+	return value, ok
+}
+
+// Override applies the action and the severity configured for the algorithm
+// of result, or for checkType when the algorithm has none
+func (ac *AlgorithmConfig) Override(checkType string, result *model.AttackResult) {
+	for _, algorithm := range []string{result.PluginAlgorithm, checkType} {
+		if action, ok := ac.Get(algorithm, "action"); ok {
+			if s, err := cast.ToStringE(action); err == nil {
+				result.InterceptState = model.InterceptCodeToString(model.InterceptStringToCode(s))
+				break
+			}
+		}
+	}
+	for _, algorithm := range []string{result.PluginAlgorithm, checkType} {
+		if severity, ok := ac.Get(algorithm, "severity"); ok {
+			if s, err := cast.ToStringE(severity); err == nil && model.ValidSeverity(s) {
+				result.Severity = s
+				return
+			}
+		}
+	}
+}
+
+// AlgorithmInt returns algorithm.config.<check type>.<key>, generalKey when
+// it is not set or invalid
+func AlgorithmInt(ct common.CheckType, key, generalKey string) int {
+	if value, ok := GetAlgorithmConfig().Get(common.CheckTypeToString(ct), key); ok {
+		if i, err := cast.ToIntE(value); err == nil {
+			return i
+		}
+	}
+	return GetGeneral().GetInt(generalKey)
+}
+
+func AlgorithmBool(ct common.CheckType, key, generalKey string) bool {
+	if value, ok := GetAlgorithmConfig().Get(common.CheckTypeToString(ct), key); ok {
+		if b, err := cast.ToBoolE(value); err == nil {
+			return b
+		}
+	}
+	return GetGeneral().GetBool(generalKey)
+}
+
+func AlgorithmStringSlice(ct common.CheckType, key, generalKey string) []string {
+	if value, ok := GetAlgorithmConfig().Get(common.CheckTypeToString(ct), key); ok {
+		if s, err := cast.ToStringSliceE(value); err == nil {
+			return s
+		}
+	}
+	return GetGeneral().GetStringSlice(generalKey)
+}
+
+func AlgorithmIntSlice(ct common.CheckType, key, generalKey string) []int {
+	if value, ok := GetAlgorithmConfig().Get(common.CheckTypeToString(ct), key); ok {
+		if s, err := cast.ToIntSliceE(value); err == nil {
+			return s
+		}
+	}
+	return GetGeneral().GetIntSlice(generalKey)
+}
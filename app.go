@@ -0,0 +1,109 @@
+package openrasp
+
+import (
+	"net"
+	"strings"
+
+	"github.com/baidu-security/openrasp-golang/cloud"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+// AppBinding attributes the requests matching Hosts and Paths to another
+// application of the cloud console, an empty list matches everything
+type AppBinding struct {
+	AppId     string   `mapstructure:"app_id"`
+	AppSecret string   `mapstructure:"app_secret"`
+	Hosts     []string `mapstructure:"hosts"`
+	Paths     []string `mapstructure:"paths"`
+	client    *cloud.Client
+}
+
+func (ab *AppBinding) match(host, path string) bool {
+	return ab.matchHost(host) && ab.matchPath(path)
+}
+
+// matchHost compares without the port, *.example.com matches the subdomains
+func (ab *AppBinding) matchHost(host string) bool {
+	if len(ab.Hosts) == 0 {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	for _, pattern := range ab.Hosts {
+		pattern = strings.ToLower(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func (ab *AppBinding) matchPath(path string) bool {
+	if len(ab.Paths) == 0 {
+		return true
+	}
+	for _, prefix := range ab.Paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppRouter picks the application of a request from cloud.apps, the first
+// matching binding wins and cloud.app_id is used when none matches
+type AppRouter struct {
+	bindings []*AppBinding
+}
+
+func NewAppRouter(bindings []*AppBinding) *AppRouter {
+	ar := &AppRouter{}
+	for _, binding := range bindings {
+		if binding != nil && len(binding.AppId) > 0 && len(binding.AppSecret) > 0 {
+			ar.bindings = append(ar.bindings, binding)
+		}
+	}
+	return ar
+}
+
+// Match returns the binding of the request or nil for the default application
+func (ar *AppRouter) Match(host, path string) *AppBinding {
+	if ar == nil {
+		return nil
+	}
+	for _, binding := range ar.bindings {
+		if binding.match(host, path) {
+			return binding
+		}
+	}
+	return nil
+}
+
+func (ar *AppRouter) Bindings() []*AppBinding {
+	if ar == nil {
+		return nil
+	}
+	return ar.bindings
+}
+
+// BindApp attributes the current request to the application matching host
+// and path, it must be called after gls.Initialize
+func BindApp(host, path string) {
+	if binding := GetAppRouter().Match(host, path); binding != nil {
+		gls.Set("appId", binding.AppId)
+	}
+}
+
+// currentAppId returns the application of the current request
+func currentAppId() string {
+	if appId, ok := gls.Get("appId").(string); ok && len(appId) > 0 {
+		return appId
+	}
+	return GetBasic().GetString("cloud.app_id")
+}
@@ -0,0 +1,136 @@
+package openrasp
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+type asyncCheck struct {
+	ac     common.AttackChecker
+	opts   []common.AttackOption
+	values map[interface{}]interface{}
+	pcs    []uintptr
+}
+
+// AsyncDetector runs the checks of the types in detect.async.types on
+// detect.async.workers goroutines off the request path, with the inherited
+// gls values of the request. Such checks only log, a result asking to block
+// or to challenge is logged as the request went on. A check runs on the
+// request goroutine when detect.async.queue_size checks are already queued.
+// A check type downgraded for exceeding its latency budget runs off the
+// request path as well until the process restarts.
+type AsyncDetector struct {
+	// types and downgraded come first to be 64-bit aligned for atomic access
+	types      uint64
+	downgraded uint64
+	queue      chan asyncCheck
+	workers    int
+	queueSize  int
+	mu         sync.RWMutex
+}
+
+func NewAsyncDetector() *AsyncDetector {
+	ad := &AsyncDetector{}
+	ad.OnConfigUpdate()
+	return ad
+}
+
+func (ad *AsyncDetector) OnConfigUpdate() {
+	var types common.CheckType
+	for _, name := range GetGeneral().GetStringSlice("detect.async.types") {
+		ct := common.CheckStringToType(name)
+		if ct == common.InvalidType {
+			GetLog().RaspWarn("Unknown check type "+name+" in detect.async.types", orlog.Config)
+			continue
+		}
+		types |= ct
+	}
+	if types != 0 || atomic.LoadUint64(&ad.downgraded) != 0 {
+		ad.startWorkers()
+	}
+	atomic.StoreUint64(&ad.types, uint64(types))
+}
+
+// startWorkers starts detect.async.workers workers unless they run already
+// with the current settings
+func (ad *AsyncDetector) startWorkers() {
+	workers := GetGeneral().GetInt("detect.async.workers")
+	queueSize := GetGeneral().GetInt("detect.async.queue_size")
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+	if ad.queue != nil && workers == ad.workers && queueSize == ad.queueSize {
+		return
+	}
+	// the workers of the previous queue drain it and exit
+	if ad.queue != nil {
+		close(ad.queue)
+	}
+	ad.queue = make(chan asyncCheck, queueSize)
+	ad.workers, ad.queueSize = workers, queueSize
+	for i := 0; i < workers; i++ {
+		go ad.work(ad.queue)
+	}
+}
+
+// Async reports whether the checks of ct run off the request path
+func (ad *AsyncDetector) Async(ct common.CheckType) bool {
+	return ad != nil && (atomic.LoadUint64(&ad.types)|atomic.LoadUint64(&ad.downgraded))&uint64(ct) != 0
+}
+
+// Downgrade moves the checks of ct off the request path, it reports false
+// when they were downgraded already
+func (ad *AsyncDetector) Downgrade(ct common.CheckType) bool {
+	if ad == nil || ct == common.InvalidType {
+		return false
+	}
+	ad.startWorkers()
+	for {
+		downgraded := atomic.LoadUint64(&ad.downgraded)
+		if downgraded&uint64(ct) != 0 {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(&ad.downgraded, downgraded, downgraded|uint64(ct)) {
+			return true
+		}
+	}
+}
+
+// Downgraded reports whether ct was downgraded for exceeding its budget
+func (ad *AsyncDetector) Downgraded(ct common.CheckType) bool {
+	return ad != nil && atomic.LoadUint64(&ad.downgraded)&uint64(ct) != 0
+}
+
+// enqueue hands ac over to the workers, false when the queue is full
+func (ad *AsyncDetector) enqueue(ac common.AttackChecker, opts []common.AttackOption) bool {
+	values := gls.Capture()
+	if values == nil {
+		return false
+	}
+	// the hook may put a pooled param back once AttackCheck returns
+	if retainer, ok := ac.(common.Retainer); ok {
+		retainer.Retain()
+	}
+	// the stack of the request goroutine is the one to log
+	check := asyncCheck{ac: ac, opts: opts, values: values, pcs: collectStack()}
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+	select {
+	case ad.queue <- check:
+		return true
+	default:
+		return false
+	}
+}
+
+func (ad *AsyncDetector) work(queue chan asyncCheck) {
+	for check := range queue {
+		check := check
+		gls.RunWith(check.values, func() {
+			attackCheck(check.ac, check.opts, func() []uintptr { return check.pcs }, true)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package openrasp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/stacktrace"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+type Blocker interface {
+	BlockByOpenRASP()
+}
+
+// openraspPackages prefixes the functions of the agent and its hooks
+const openraspPackages = "github.com/baidu-security/openrasp-golang"
+
+func NewAttackLog(attackResult *model.AttackResult, requestInfo *model.RequestInfo, attackParams interface{}, attackType string) *model.AttackLog {
+	return newAttackLog(attackResult, requestInfo, attackParams, attackType, collectStack())
+}
+
+// newAttackLog returns the log of an attack raised at the stack of pcs
+func newAttackLog(attackResult *model.AttackResult, requestInfo *model.RequestInfo, attackParams interface{}, attackType string, pcs []uintptr) *model.AttackLog {
+	attackLog := model.AcquireAttackLog()
+	*attackLog = model.AttackLog{
+		AttackResult: attackResult,
+		Server:       GetGlobals().Server,
+		System:       GetGlobals().System,
+		RequestInfo:  GetMasker().MaskRequestInfo(requestInfo),
+		AttackParams: GetMasker().MaskParams(attackParams),
+		SourceCode:   []string{},
+		RaspId:       GetGlobals().RaspId,
+		AppId:        currentAppId(),
+		ServerIp:     GetGlobals().HttpAddr,
+		EventTime:    utils.CurrentISO8601Time(),
+		EventType:    "attack",
+		AttackType:   attackType,
+		CustomFields: currentCustomFields(requestInfo),
+	}
+	attackLog.TraceId, attackLog.SpanId = traceIds(requestInfo)
+	attackLog.SetStackResolver(stackResolver(pcs, GetGeneral().GetBool("log.source_code.enable")))
+	return attackLog
+}
+
+// appSourceCode returns the lines around the call of the application which
+// triggered the check, none when its source is not found
+func appSourceCode(frames []stacktrace.Frame) []string {
+	frame, ok := stacktrace.AppFrame(frames, openraspPackages)
+	if !ok {
+		return []string{}
+	}
+	lines := stacktrace.SourceLines(frame.File, frame.Line, GetGeneral().GetInt("log.source_code.lines"), GetGeneral().GetString("log.source_code.root"))
+	if lines == nil {
+		return []string{}
+	}
+	return lines
+}
+
+// AttackCheck runs the checker against the current request, writes an alarm
+// for every result that is not ignored and reports whether to block. A panic
+// of the checker is reported and decided by plugin.failure_action. When only
+// challenge results ask to interrupt the request, BlockRequest challenges the
+// client instead of blocking it. The checks AsyncDetector runs off the
+// request path never block, nor does any check in dry run.
+func AttackCheck(ac common.AttackChecker, opts ...common.AttackOption) bool {
+	if !GetHookSwitch().CheckEnabled(ac.GetType()) {
+		return false
+	}
+	if GetAsyncDetector().Async(ac.GetType()) && GetAsyncDetector().enqueue(ac, opts) {
+		return false
+	}
+	return attackCheck(ac, opts, collectStack, false)
+}
+
+// attackCheck runs ac, the logs take their stack from stack. An async check
+// or a check in dry run logs the results which would block or challenge the
+// request.
+func attackCheck(ac common.AttackChecker, opts []common.AttackOption, stack func() []uintptr, async bool) (shouldBlock bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ReportError(fmt.Errorf("Recovered from panic of %s check, %v", ac.GetTypeString(), r), orlog.Panic)
+			shouldBlock = model.InterceptStringToCode(GetGeneral().GetString("plugin.failure_action")) == model.Block &&
+				!GetHookSwitch().DryRun()
+		}
+	}()
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return false
+	}
+	start := time.Now()
+	attackResults := ac.AttackCheck(opts...)
+	if !skipByOptions(ac, opts) {
+		attackResults = append(attackResults, runCheckers(ac)...)
+	}
+	elapsed := time.Since(start)
+	attacks, challenge, dryRun := 0, false, GetHookSwitch().DryRun()
+	for _, attackResult := range attackResults {
+		GetAlgorithmConfig().Override(ac.GetTypeString(), attackResult)
+		attackResult.Score(ac.GetTypeString())
+		state := attackResult.GetInterceptState()
+		code := state
+		if code == model.Challenge && challengePassed() {
+			code = model.Log
+		}
+		if code == model.Block || code == model.Challenge {
+			if dryRun {
+				attackResult.WouldBlock = true
+				code = model.Log
+			} else if async {
+				code = model.Log
+			}
+		}
+		if code != state {
+			attackResult.InterceptState = model.InterceptCodeToString(code)
+		}
+		if interceptCode := attackResult.GetInterceptState(); interceptCode != model.Ignore {
+			attacks++
+			GetStatistics().AddAttack(ac.GetTypeString(), model.InterceptCodeToString(interceptCode))
+			if retainer, ok := ac.(common.Retainer); ok {
+				retainer.Retain()
+			}
+			attackLog := newAttackLog(attackResult, requestInfo, ac, ac.GetTypeString(), stack())
+			if GetAlarmFilter().Allow(attackLog) {
+				if attackLogString := attackLog.String(); len(attackLogString) > 0 {
+					GetLog().AlarmInfoForApp(attackLogString, attackLog.AppId)
+				}
+			}
+			traced := traceAttack(attackLog, interceptCode)
+			GetAdminServer().record(attackLog)
+			// the log goes back to the pool unless a tracer or callback may keep it
+			if notified := notifyAttack(attackLog, interceptCode); !notified && !traced {
+				attackLog.Release()
+			}
+			if interceptCode == model.Block {
+				shouldBlock = true
+			} else if interceptCode == model.Challenge {
+				challenge = true
+			}
+		}
+	}
+	if attacks > 0 {
+		GetSampler().Flag(requestInfo.Client())
+	}
+	// a block of this check must not turn into the challenge of an earlier one
+	challenge = challenge && !shouldBlock
+	gls.Set("challenge", challenge)
+	shouldBlock = shouldBlock || challenge
+	GetStatistics().AddCheck(ac.GetTypeString(), elapsed, attacks, shouldBlock)
+	return shouldBlock
+}
+
+// skipByOptions reports whether an option such as the whitelist exempts ac
+func skipByOptions(ac common.AttackChecker, opts []common.AttackOption) bool {
+	for _, opt := range opts {
+		if opt(ac) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockRequest interrupts the current request through the response writer
+// stored in gls, it does nothing outside of a request. The client is sent a
+// challenge page instead when the last check only asked for a challenge and
+// the response writer supports it.
+func BlockRequest() {
+	challenge, _ := gls.Get("challenge").(bool)
+	if challenge {
+		gls.Set("challenge", false)
+		if challenger, ok := gls.Get("responseWriter").(Challenger); ok {
+			challenger.ChallengeByOpenRASP()
+			return
+		}
+	}
+	blocker, ok := gls.Get("responseWriter").(Blocker)
+	if ok {
+		blocker.BlockByOpenRASP()
+	}
+}
@@ -0,0 +1,27 @@
+package openrasp
+
+import (
+	"github.com/baidu-security/openrasp-golang/config"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// auditConfigChange writes every applied config change to audit.log, so it
+// can be proven when a protection was loosened and by whom
+func auditConfigChange(source config.Source, changes []config.Change) {
+	for _, change := range changes {
+		auditLog := &model.AuditLog{
+			Source:    string(source),
+			Key:       change.Key,
+			OldValue:  GetMasker().MaskSetting(change.Key, change.Old),
+			NewValue:  GetMasker().MaskSetting(change.Key, change.New),
+			RaspId:    GetGlobals().RaspId,
+			AppId:     GetBasic().GetString("cloud.app_id"),
+			EventTime: utils.CurrentISO8601Time(),
+			EventType: "config_change",
+		}
+		if auditLogString := auditLog.String(); len(auditLogString) > 0 {
+			GetLog().AuditInfo(auditLogString)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package openrasp
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+var secretEnvRegex = regexp.MustCompile(`(?i)(secret|passw(or)?d|token|api_?key|private_?key|credential)`)
+
+type EnvParam struct {
+	Names []string          `json:"names"`
+	Env   map[string]string `json:"env,omitempty"`
+}
+
+type envBaselineCheck func(env map[string]string) (*EnvParam, *model.PolicyResult)
+
+var envBaselineChecks = []envBaselineCheck{
+	debugInProductionCheck,
+	tracebackCheck,
+}
+
+func isProduction(env map[string]string) bool {
+	for _, name := range []string{"APP_ENV", "GO_ENV", "ENV", "ENVIRONMENT"} {
+		switch strings.ToLower(env[name]) {
+		case "prod", "production":
+			return true
+		}
+	}
+	return false
+}
+
+func debugInProductionCheck(env map[string]string) (*EnvParam, *model.PolicyResult) {
+	if !isProduction(env) {
+		return nil, nil
+	}
+	ep := &EnvParam{Env: make(map[string]string)}
+	for _, name := range []string{"DEBUG", "APP_DEBUG"} {
+		switch strings.ToLower(env[name]) {
+		case "1", "true", "yes", "on":
+			ep.Names = append(ep.Names, name)
+			ep.Env[name] = env[name]
+		}
+	}
+	if env["GIN_MODE"] == "debug" {
+		ep.Names = append(ep.Names, "GIN_MODE")
+		ep.Env["GIN_MODE"] = env["GIN_MODE"]
+	}
+	if len(ep.Names) == 0 {
+		return nil, nil
+	}
+	return ep, model.NewPolicyResult("Environment security - Debug mode is enabled in production by "+strings.Join(ep.Names, ", "), 3012)
+}
+
+// secretEnvNames lists the variables which look like they hold a secret,
+// only the names are logged, values never reach the log
+func secretEnvNames(env map[string]string) []string {
+	var names []string
+	for name, value := range env {
+		if len(value) > 0 && secretEnvRegex.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func tracebackCheck(env map[string]string) (*EnvParam, *model.PolicyResult) {
+	if env["GOTRACEBACK"] != "crash" {
+		return nil, nil
+	}
+	ep := &EnvParam{
+		Names: []string{"GOTRACEBACK"},
+		Env:   map[string]string{"GOTRACEBACK": "crash"},
+	}
+	return ep, model.NewPolicyResult("Environment security - GOTRACEBACK=crash dumps the process memory including secrets on fatal errors", 3014)
+}
+
+func environMap() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	return env
+}
+
+// EnvBaselineCheck scans the process environment once and writes a policy
+// log for every dangerous setting. Secrets in the environment are common and
+// only leak through settings such as debug mode or crash dumps, which are
+// reported, so they are noted in rasp.log instead.
+func EnvBaselineCheck() {
+	env := environMap()
+	for _, check := range envBaselineChecks {
+		envParam, policyResult := check(env)
+		if policyResult == nil || !GetHookSwitch().PolicyEnabled(policyResult.PolicyId) {
+			continue
+		}
+		if policyLogString := NewPolicyLog(policyResult, envParam).String(); len(policyLogString) > 0 {
+			GetLog().PolicyInfo(policyLogString)
+		}
+	}
+	if names := secretEnvNames(env); len(names) > 0 {
+		GetLog().RaspInfo("Secrets are stored in environment variables "+strings.Join(names, ", ")+
+			", keep them out of debug output and crash dumps", orlog.Runtime)
+	}
+}
@@ -0,0 +1,22 @@
+package openrasp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvBaselineChecks(t *testing.T) {
+	env := map[string]string{"DB_PASSWORD": "hunter2", "GITHUB_TOKEN": "", "API_KEY": "k", "PATH": "/usr/bin"}
+	assert.Equal(t, []string{"API_KEY", "DB_PASSWORD"}, secretEnvNames(env))
+	// secrets alone raise no policy alarm
+	for _, check := range envBaselineChecks {
+		_, policyResult := check(env)
+		assert.Nil(t, policyResult)
+	}
+	env["GOTRACEBACK"] = "crash"
+	_, policyResult := tracebackCheck(env)
+	if assert.NotNil(t, policyResult) {
+		assert.Equal(t, uint64(3014), policyResult.PolicyId)
+	}
+}
@@ -0,0 +1,22 @@
+// +build go1.12
+
+package openrasp
+
+import "runtime/debug"
+
+// readModules returns the versions of the modules built into the binary by
+// path, a replaced module has the version of its replacement
+func readModules() (map[string]string, bool) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil, false
+	}
+	modules := make(map[string]string, len(info.Deps))
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		modules[dep.Path] = dep.Version
+	}
+	return modules, true
+}
@@ -0,0 +1,8 @@
+// +build !go1.12
+
+package openrasp
+
+// readModules has no build info to read before go1.12
+func readModules() (map[string]string, bool) {
+	return nil, false
+}
@@ -0,0 +1,62 @@
+package openrasp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// AttackFunc is called with the log of an attack, it must not modify it
+type AttackFunc func(attackLog *model.AttackLog)
+
+var (
+	attackFuncsMu sync.RWMutex
+	detectFuncs   []AttackFunc
+	blockFuncs    []AttackFunc
+)
+
+// OnDetect adds f to the callbacks run for every attack which is not
+// ignored, whether or not its log is filtered out
+func OnDetect(f AttackFunc) {
+	attackFuncsMu.Lock()
+	defer attackFuncsMu.Unlock()
+	detectFuncs = append(detectFuncs, f)
+}
+
+// OnBlock adds f to the callbacks run for every attack which blocks the
+// request, after the ones of OnDetect
+func OnBlock(f AttackFunc) {
+	attackFuncsMu.Lock()
+	defer attackFuncsMu.Unlock()
+	blockFuncs = append(blockFuncs, f)
+}
+
+// notifyAttack runs the callbacks on the goroutine of the check, before the
+// request is blocked, and reports whether any callback received attackLog. A
+// panic of a callback is reported and does not stop the others.
+func notifyAttack(attackLog *model.AttackLog, interceptCode model.InterceptCode) bool {
+	attackFuncsMu.RLock()
+	funcs := detectFuncs
+	if interceptCode == model.Block {
+		funcs = append(funcs[:len(funcs):len(funcs)], blockFuncs...)
+	}
+	attackFuncsMu.RUnlock()
+	if len(funcs) > 0 {
+		attackLog.ResolveStack()
+	}
+	for _, f := range funcs {
+		callAttackFunc(f, attackLog)
+	}
+	return len(funcs) > 0
+}
+
+func callAttackFunc(f AttackFunc, attackLog *model.AttackLog) {
+	defer func() {
+		if r := recover(); r != nil {
+			ReportError(fmt.Errorf("Recovered from panic of attack callback, %v", r), orlog.Panic)
+		}
+	}()
+	f(attackLog)
+}
@@ -0,0 +1,84 @@
+package openrasp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+type Challenger interface {
+	ChallengeByOpenRASP()
+}
+
+// challengeKey signs the tokens when challenge.secret is empty, tokens of
+// another process are then refused and the client is challenged again
+var challengeKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+func challengeSecret() []byte {
+	if secret := GetGeneral().GetString("challenge.secret"); len(secret) > 0 {
+		return []byte(secret)
+	}
+	return challengeKey
+}
+
+// challengeClient is the client a token is bound to
+func challengeClient(requestInfo *model.RequestInfo) string {
+	if len(requestInfo.ClientIp) > 0 {
+		return requestInfo.ClientIp
+	}
+	return requestInfo.AttackSource
+}
+
+func signChallenge(client, issued string) string {
+	mac := hmac.New(sha256.New, challengeSecret())
+	mac.Write([]byte(client + "|" + issued))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewChallengeToken returns the token a client solving the challenge page
+// presents in the challenge.cookie_name cookie
+func NewChallengeToken(requestInfo *model.RequestInfo, now time.Time) string {
+	issued := strconv.FormatInt(now.Unix(), 10)
+	return issued + "." + signChallenge(challengeClient(requestInfo), issued)
+}
+
+// VerifyChallengeToken reports whether token was issued to the client of
+// requestInfo no longer than challenge.ttl seconds ago
+func VerifyChallengeToken(requestInfo *model.RequestInfo, token string, now time.Time) bool {
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return false
+	}
+	issued, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Unix() - issued
+	if age < 0 || age > GetGeneral().GetInt64("challenge.ttl") {
+		return false
+	}
+	expected := signChallenge(challengeClient(requestInfo), token[:i])
+	return hmac.Equal([]byte(token[i+1:]), []byte(expected))
+}
+
+// PassChallenge marks the current request as sent by a client which solved
+// the challenge, challenge results are only logged for it
+func PassChallenge() {
+	gls.Set("challengePassed", true)
+}
+
+func challengePassed() bool {
+	passed, _ := gls.Get("challengePassed").(bool)
+	return passed
+}
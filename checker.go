@@ -0,0 +1,67 @@
+package openrasp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// Checker is a detection written in Go, it runs after the built-in check of
+// every hook point whose type is in CheckType, e.g. common.Sql|common.Ldap.
+// params is the parameter of the hook point such as *orsql.SqlParam, Check
+// returns nil when there is nothing to report.
+type Checker interface {
+	CheckType() common.CheckType
+	Check(params common.AttackChecker) *model.AttackResult
+}
+
+var checkers struct {
+	list []Checker
+	mu   sync.RWMutex
+}
+
+// RegisterChecker adds c to the checkers run by AttackCheck, it is meant to
+// be called from an init function
+func RegisterChecker(c Checker) {
+	checkers.mu.Lock()
+	defer checkers.mu.Unlock()
+	checkers.list = append(checkers.list, c)
+}
+
+// runCheckers collects the results of the registered checkers for ac, a
+// panicking checker is reported and skipped
+func runCheckers(ac common.AttackChecker) []*model.AttackResult {
+	checkers.mu.RLock()
+	defer checkers.mu.RUnlock()
+	var results []*model.AttackResult
+	for _, c := range checkers.list {
+		if c.CheckType()&ac.GetType() == 0 {
+			continue
+		}
+		if result := runChecker(c, ac); result != nil {
+			if len(result.PluginName) == 0 {
+				result.PluginName = "go_custom_plugin"
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func runChecker(c Checker, ac common.AttackChecker) (result *model.AttackResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("panic of %T checker, %v", c, r)
+			ReportError(errors.New("Recovered from "+err.Error()), orlog.Panic)
+			result = nil
+			for _, m := range failureResults(err) {
+				result = model.NewAttackResultFromMap(m)
+			}
+		}
+	}()
+	return c.Check(ac)
+}
@@ -2,10 +2,14 @@ package cloud
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"sync"
 	"time"
 )
@@ -23,6 +27,12 @@ type Client struct {
 	plugin      Plugin
 	config      map[string]interface{}
 	configTime  int64
+	// the outcome of the heartbeats, read by HeartBeatStatus
+	lastHeartBeat time.Time
+	heartBeatErr  error
+	statusMu      sync.Mutex
+
+	commandHandler func(*Command) error
 }
 
 // NewClient emmm
@@ -37,6 +47,42 @@ func NewClient(host, appid, appsecret string, timeout time.Duration) *Client {
 	return c
 }
 
+// SetTLSConfig emmm
+func (c *Client) SetTLSConfig(tlsConfig *tls.Config) {
+	c.transport().TLSClientConfig = tlsConfig
+}
+
+// SetProxy emmm
+func (c *Client) SetProxy(proxy string) error {
+	switch proxy {
+	case "":
+		c.transport().Proxy = http.ProxyFromEnvironment
+	case "direct":
+		c.transport().Proxy = nil
+	default:
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return err
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %s", proxyURL.Scheme)
+		}
+		c.transport().Proxy = http.ProxyURL(proxyURL)
+	}
+	return nil
+}
+
+func (c *Client) transport() *http.Transport {
+	transport, ok := c.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		c.Transport = transport
+	}
+	return transport
+}
+
 // Post emmm
 func (c *Client) Post(path string, request, response interface{}) error {
 	data, err := json.Marshal(request)
@@ -65,13 +111,44 @@ func (c *Client) Post(path string, request, response interface{}) error {
 
 // PostRaw emmm
 func (c *Client) PostRaw(path string, request []byte) (io.ReadCloser, error) {
+	return c.postRaw(path, request, "application/json", "")
+}
+
+// PostGzip emmm
+func (c *Client) PostGzip(path string, request []byte) (io.ReadCloser, error) {
+	compressed, err := gzipBody(request)
+	if err != nil {
+		return nil, err
+	}
+	return c.postRaw(path, compressed, "application/json", "gzip")
+}
+
+func gzipBody(request []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(request); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (c *Client) postRaw(path string, request []byte, contentType, contentEncoding string) (io.ReadCloser, error) {
 	req, err := http.NewRequest("POST", c.host+path, bytes.NewReader(request))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
+	if len(contentEncoding) > 0 {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
 	req.Header.Set("X-OpenRASP-AppID", c.appid)
 	req.Header.Set("X-OpenRASP-AppSecret", c.appsecret)
 	resp, err := c.Do(req)
-	return resp.Body, err
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
 }
@@ -1,6 +1,9 @@
 package cloud
 
 import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -19,3 +22,27 @@ func TestPost(t *testing.T) {
 	assert.Error(t, err)
 	assert.EqualError(t, err, "Unauthorized")
 }
+
+func TestSetTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	c := NewClient(server.URL, "", "", time.Second)
+	assert.Error(t, c.Log("attack", []byte("[]")))
+	c.SetTLSConfig(&tls.Config{InsecureSkipVerify: true})
+	assert.NoError(t, c.Log("attack", []byte("[]")))
+}
+
+func TestSetProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	c := NewClient("http://console.example.com", "", "", time.Second)
+	assert.Error(t, c.SetProxy("ftp://127.0.0.1"))
+	assert.NoError(t, c.SetProxy(proxy.URL))
+	assert.NoError(t, c.Log("attack", []byte("[]")))
+	assert.Equal(t, "http://console.example.com/v1/agent/log/attack", proxied)
+	assert.NoError(t, c.SetProxy("direct"))
+	assert.Nil(t, c.transport().Proxy)
+}
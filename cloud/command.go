@@ -0,0 +1,27 @@
+package cloud
+
+import "fmt"
+
+// Command emmm
+type Command struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// SetCommandHandler emmm
+func (c *Client) SetCommandHandler(handler func(*Command) error) {
+	c.commandHandler = handler
+}
+
+func (c *Client) handleCommands(commands []Command) error {
+	if c.commandHandler == nil {
+		return nil
+	}
+	var failed error
+	for i := range commands {
+		if err := c.commandHandler(&commands[i]); err != nil && failed == nil {
+			failed = fmt.Errorf("unable to run command %s, %v", commands[i].Name, err)
+		}
+	}
+	return failed
+}
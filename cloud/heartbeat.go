@@ -1,12 +1,19 @@
 package cloud
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"time"
 )
 
 // HeartBeatReq emmm
 type HeartBeatReq struct {
 	RaspId        string `json:"rasp_id"`
+	AppId         string `json:"app_id"`
+	HostName      string `json:"hostname"`
 	PluginVersion string `json:"plugin_version"`
 	PluginMd5     string `json:"plugin_md5"`
 	ConfigTime    int64  `json:"config_time"`
@@ -17,29 +24,80 @@ type HeartBeatResp struct {
 	Plugin     *Plugin                 `json:"plugin"`
 	Config     *map[string]interface{} `json:"config"`
 	ConfigTime int64                   `json:"config_time"`
+	Commands   []Command               `json:"commands"`
 }
 
 // HeartBeat emmm
 func (c *Client) HeartBeat(updatePlugin func(string, string), updateConfig func(*map[string]interface{})) error {
 	request := HeartBeatReq{
-		c.rasp.Id,
-		c.plugin.Version,
-		c.plugin.Md5,
-		c.configTime,
+		RaspId:        c.rasp.Id,
+		AppId:         c.appid,
+		HostName:      c.rasp.HostName,
+		PluginVersion: c.plugin.Version,
+		PluginMd5:     c.plugin.Md5,
+		ConfigTime:    c.configTime,
 	}
 	var response HeartBeatResp
-	if err := c.Post("/v1/agent/heartbeat", &request, &response); err != nil {
+	err := c.Post("/v1/agent/heartbeat", &request, &response)
+	c.recordHeartBeat(err)
+	if err != nil {
 		return err
 	}
+	// a plugin failing verification holds up neither the config nor the
+	// commands of the same response
+	var pluginErr error
 	if response.Plugin != nil && c.plugin.Md5 != response.Plugin.Md5 {
-		c.plugin = *response.Plugin
-		updatePlugin(c.plugin.Content, c.plugin.Name)
+		if pluginErr = verifyPlugin(response.Plugin); pluginErr == nil {
+			c.plugin = *response.Plugin
+			updatePlugin(c.plugin.Content, c.plugin.Name)
+		}
 	}
 	if response.Config != nil {
 		c.config = *response.Config
 		c.configTime = response.ConfigTime
 		updateConfig(&c.config)
 	}
+	err = c.handleCommands(response.Commands)
+	if pluginErr == nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%v, %v", pluginErr, err)
+	}
+	return pluginErr
+}
+
+func (c *Client) recordHeartBeat(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.heartBeatErr = err
+	if err == nil {
+		c.lastHeartBeat = time.Now()
+	}
+}
+
+// HeartBeatStatus returns when the console last answered a heartbeat, zero
+// before the first answer, and the error of the latest heartbeat
+func (c *Client) HeartBeatStatus() (time.Time, error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.lastHeartBeat, c.heartBeatErr
+}
+
+// verifyPlugin rejects a plugin whose content does not match its md5, or its
+// sha256 when the console sends one, the active plugin is kept and the
+// download is retried on the next heartbeat
+func verifyPlugin(plugin *Plugin) error {
+	sum := md5.Sum([]byte(plugin.Content))
+	if len(plugin.Content) == 0 || hex.EncodeToString(sum[:]) != plugin.Md5 {
+		return fmt.Errorf("checksum mismatch of plugin %s %s", plugin.Name, plugin.Version)
+	}
+	if len(plugin.Sha256) > 0 {
+		sum := sha256.Sum256([]byte(plugin.Content))
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), plugin.Sha256) {
+			return fmt.Errorf("sha256 mismatch of plugin %s %s", plugin.Name, plugin.Version)
+		}
+	}
 	return nil
 }
 
@@ -47,6 +105,9 @@ func (c *Client) HeartBeat(updatePlugin func(string, string), updateConfig func(
 func (c *Client) StartHeartBeat(interval time.Duration, updatePlugin func(string, string), updateConfig func(*map[string]interface{}), onError func(error)) {
 	c.wg.Add(1)
 	c.isHeartBeat = true
+	if err := c.HeartBeat(updatePlugin, updateConfig); err != nil {
+		onError(err)
+	}
 ABORT:
 	for {
 		select {
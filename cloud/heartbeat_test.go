@@ -1,6 +1,13 @@
 package cloud
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -29,3 +36,114 @@ func TestHeartBeat(t *testing.T) {
 	c.StopHeartBeat()
 	assert.False(t, c.isHeartBeat)
 }
+
+func TestHeartBeatCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":0,"data":{"commands":[{"name":"log_config","args":{"log.level.rasp":"debug"}},{"name":"unknown"}]}}`))
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "", "", time.Second)
+	assert.NoError(t, c.HeartBeat(func(string, string) {}, func(*map[string]interface{}) {}))
+	var names []string
+	c.SetCommandHandler(func(cmd *Command) error {
+		names = append(names, cmd.Name)
+		if cmd.Name != "log_config" {
+			return errors.New("unknown command")
+		}
+		assert.Equal(t, "debug", cmd.Args["log.level.rasp"])
+		return nil
+	})
+	err := c.HeartBeat(func(string, string) {}, func(*map[string]interface{}) {})
+	assert.EqualError(t, err, "unable to run command unknown, unknown command")
+	assert.Equal(t, []string{"log_config", "unknown"}, names)
+}
+
+func TestHeartBeatRequest(t *testing.T) {
+	requests := make(chan HeartBeatReq, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "appid", r.Header.Get("X-OpenRASP-AppID"))
+		assert.Equal(t, "secret", r.Header.Get("X-OpenRASP-AppSecret"))
+		if r.URL.Path == "/v1/agent/heartbeat" {
+			var request HeartBeatReq
+			json.NewDecoder(r.Body).Decode(&request)
+			requests <- request
+		}
+		w.Write([]byte(`{"status":0,"data":{}}`))
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "appid", "secret", time.Second)
+	assert.NoError(t, c.Register("raspid", "/tmp", "host", "golang", "1", 60))
+	go c.StartHeartBeat(time.Hour, func(string, string) {}, func(*map[string]interface{}) {}, func(err error) {
+		assert.NoError(t, err)
+	})
+	select {
+	case request := <-requests:
+		assert.Equal(t, "raspid", request.RaspId)
+		assert.Equal(t, "appid", request.AppId)
+		assert.Equal(t, "host", request.HostName)
+	case <-time.After(time.Second):
+		t.Error("no heartbeat sent on start")
+	}
+	c.StopHeartBeat()
+}
+
+func TestHeartBeatPlugin(t *testing.T) {
+	content := "const plugin = new RASP('official')"
+	checksum := "0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":0,"data":{"plugin":{"name":"official","version":"2020-0101-0000","md5":"` + checksum + `","plugin":"` + content + `"}}}`))
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "", "", time.Second)
+	var updated string
+	updatePlugin := func(source, filename string) {
+		updated = source
+	}
+	assert.Error(t, c.HeartBeat(updatePlugin, func(*map[string]interface{}) {}))
+	assert.Empty(t, updated)
+	assert.Empty(t, c.plugin.Md5)
+	sum := md5.Sum([]byte(content))
+	checksum = hex.EncodeToString(sum[:])
+	assert.NoError(t, c.HeartBeat(updatePlugin, func(*map[string]interface{}) {}))
+	assert.Equal(t, content, updated)
+	assert.Equal(t, "2020-0101-0000", c.plugin.Version)
+}
+
+func TestHeartBeatBadPlugin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":0,"data":{"plugin":{"name":"official","version":"2020-0101-0000","md5":"0","plugin":"const plugin = 1"},` +
+			`"config":{"log.level.rasp":"debug"},"config_time":42,"commands":[{"name":"log_config"}]}}`))
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "", "", time.Second)
+	var names []string
+	c.SetCommandHandler(func(cmd *Command) error {
+		names = append(names, cmd.Name)
+		return nil
+	})
+	var config map[string]interface{}
+	err := c.HeartBeat(func(string, string) {
+		t.Error("unverified plugin loaded")
+	}, func(update *map[string]interface{}) {
+		config = *update
+	})
+	assert.EqualError(t, err, "checksum mismatch of plugin official 2020-0101-0000")
+	assert.Equal(t, "debug", config["log.level.rasp"])
+	assert.Equal(t, int64(42), c.configTime)
+	assert.Equal(t, []string{"log_config"}, names)
+	assert.Empty(t, c.plugin.Md5)
+}
+
+func TestVerifyPlugin(t *testing.T) {
+	content := "const plugin = new RASP('official')"
+	md5Sum := md5.Sum([]byte(content))
+	sha256Sum := sha256.Sum256([]byte(content))
+	plugin := &Plugin{Name: "official", Md5: hex.EncodeToString(md5Sum[:]), Content: content}
+	assert.NoError(t, verifyPlugin(plugin))
+	plugin.Sha256 = hex.EncodeToString(sha256Sum[:])
+	assert.NoError(t, verifyPlugin(plugin))
+	plugin.Content = content + " "
+	assert.Error(t, verifyPlugin(plugin))
+	plugin.Content, plugin.Sha256 = content, hex.EncodeToString(md5Sum[:])
+	assert.Error(t, verifyPlugin(plugin))
+}
@@ -16,3 +16,30 @@ func (c *Client) Log(t string, request []byte) error {
 	}
 	return err
 }
+
+// LogGzip emmm
+func (c *Client) LogGzip(t string, request []byte) error {
+	body, err := c.PostGzip("/v1/agent/log/"+t, request)
+	if err == nil {
+		body.Close()
+	}
+	return err
+}
+
+// LogLines uploads newline-delimited entries such as CEF or LEEF lines,
+// which are not JSON, compressed when compress is set
+func (c *Client) LogLines(t string, request []byte, compress bool) error {
+	contentEncoding := ""
+	if compress {
+		compressed, err := gzipBody(request)
+		if err != nil {
+			return err
+		}
+		request, contentEncoding = compressed, "gzip"
+	}
+	body, err := c.postRaw("/v1/agent/log/"+t, request, "text/plain", contentEncoding)
+	if err == nil {
+		body.Close()
+	}
+	return err
+}
@@ -1,7 +1,11 @@
 package cloud
 
 import (
+	"compress/gzip"
 	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -19,3 +23,18 @@ func TestLog(t *testing.T) {
 	err := c.Log("attack", data)
 	assert.NoError(t, err)
 }
+
+func TestLogGzip(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		zr, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		body, _ = ioutil.ReadAll(zr)
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "", "", time.Second)
+	err := c.LogGzip("attack", []byte(`[{"attack_type":"sql"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"attack_type":"sql"}]`, string(body))
+}
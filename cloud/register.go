@@ -27,7 +27,8 @@ func (c *Client) Register(id, rasphome, hostname, language, languageversion stri
 	if err := c.Post("/v1/agent/rasp", &request, &response); err != nil {
 		return err
 	}
-	if response.Rasp != nil {
+	c.rasp = *request.Rasp
+	if response.Rasp != nil && len(response.Rasp.Id) > 0 {
 		c.rasp = *response.Rasp
 	}
 	return nil
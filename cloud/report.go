@@ -2,25 +2,40 @@ package cloud
 
 import (
 	"time"
+
+	"github.com/baidu-security/openrasp-golang/utils"
 )
 
+// CheckTime sums up the latency of one check type in milliseconds, the
+// percentiles are estimated from Histogram when the report is drained
+type CheckTime struct {
+	Count     int64            `json:"count"`
+	Total     float64          `json:"total"`
+	Max       float64          `json:"max"`
+	P50       float64          `json:"p50,omitempty"`
+	P90       float64          `json:"p90,omitempty"`
+	P99       float64          `json:"p99,omitempty"`
+	Histogram *utils.Histogram `json:"-"`
+}
+
 // ReportReq emmm
 type ReportReq struct {
-	RaspId     string `json:"rasp_id"`
-	Time       int64  `json:"time"`
-	RequestSum int64  `json:"request_sum"`
+	RaspId     string                `json:"rasp_id"`
+	Time       int64                 `json:"time"`
+	RequestSum int64                 `json:"request_sum"`
+	AttackSum  map[string]int64      `json:"attack_sum,omitempty"`
+	BlockSum   map[string]int64      `json:"block_sum,omitempty"`
+	CheckTime  map[string]*CheckTime `json:"check_time,omitempty"`
 }
 
 // ReportResp emmm
 type ReportResp struct{}
 
-// Report emmm
-func (c *Client) Report(requestSum int64) error {
-	request := ReportReq{
-		c.rasp.Id,
-		int64(time.Millisecond),
-		requestSum,
-	}
+// Report uploads the statistics aggregated since the last report, rasp_id
+// and time are filled in by the client
+func (c *Client) Report(request *ReportReq) error {
+	request.RaspId = c.rasp.Id
+	request.Time = time.Now().UnixNano() / int64(time.Millisecond)
 	var response ReportResp
-	return c.Post("/v1/agent/report", &request, &response)
+	return c.Post("/v1/agent/report", request, &response)
 }
@@ -1,6 +1,9 @@
 package cloud
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -10,6 +13,32 @@ import (
 func TestReport(t *testing.T) {
 	c := NewClient("http://scloud.baidu.com:8087", "043b6f2ad443a858ca1b3c593b9baa12e75b6041", "jVRYpHVfkBq6XF0Wb73hX1AvN3Xo9ZiSxKY6xdBQVee", 20*time.Second)
 	c.Register("569e8ea7a16123492b5878920fd36985", "/tmp", "tmp", "golang", "1", 60)
-	err := c.Report(1)
+	err := c.Report(&ReportReq{RequestSum: 1})
 	assert.NoError(t, err)
 }
+
+func TestReportRequest(t *testing.T) {
+	var request ReportReq
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/agent/report", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&request)
+		w.Write([]byte(`{"status":0,"data":{}}`))
+	}))
+	defer server.Close()
+	c := NewClient(server.URL, "", "", time.Second)
+	c.rasp.Id = "raspid"
+	before := time.Now().UnixNano() / int64(time.Millisecond)
+	err := c.Report(&ReportReq{
+		RequestSum: 10,
+		AttackSum:  map[string]int64{"sql": 2},
+		BlockSum:   map[string]int64{"sql": 1},
+		CheckTime:  map[string]*CheckTime{"sql": {Count: 2, Total: 1.5, Max: 1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "raspid", request.RaspId)
+	assert.True(t, request.Time >= before)
+	assert.Equal(t, int64(10), request.RequestSum)
+	assert.Equal(t, int64(2), request.AttackSum["sql"])
+	assert.Equal(t, int64(1), request.BlockSum["sql"])
+	assert.Equal(t, 1.5, request.CheckTime["sql"].Total)
+}
@@ -28,6 +28,7 @@ type Plugin struct {
 	UploadTime      int64                  `json:"upload_time"`
 	Version         string                 `json:"version"`
 	Md5             string                 `json:"md5"`
+	Sha256          string                 `json:"sha256,omitempty"`
 	Content         string                 `json:"plugin,omitempty"`
 	AlgorithmConfig map[string]interface{} `json:"algorithm_config"`
 }
@@ -0,0 +1,23 @@
+package openrasp
+
+import (
+	"fmt"
+
+	"github.com/baidu-security/openrasp-golang/cloud"
+	"github.com/baidu-security/openrasp-golang/config"
+)
+
+// handleCommand runs a command pushed by the cloud console in heartbeat
+func handleCommand(command *cloud.Command) error {
+	switch command.Name {
+	case "log_config":
+		return GetLog().reconfigure(config.SourceCloud, command.Args)
+	case "hook_config":
+		if err := validateHookSettings(command.Args); err != nil {
+			return err
+		}
+		return GetGeneral().UpdateFrom(config.SourceCloud, command.Args)
+	default:
+		return fmt.Errorf("unknown command %s", command.Name)
+	}
+}
@@ -11,3 +11,10 @@ type AttackChecker interface {
 	GetType() CheckType
 	GetTypeString() string
 }
+
+// Retainer is implemented by checkers taken from a pool, Retain is called
+// once a log which outlives the check references the checker so that it is
+// not put back into the pool
+type Retainer interface {
+	Retain()
+}
@@ -3,10 +3,29 @@ package common
 type CheckType int
 
 const (
-	InvalidType  CheckType = 0
-	SqlException           = 1 << 0
-	Sql                    = 1 << 1
-	AllType                = Sql | SqlException
+	InvalidType         CheckType = 0
+	SqlException                  = 1 << 0
+	Sql                           = 1 << 1
+	ReadFile                      = 1 << 2
+	WriteFile                     = 1 << 3
+	WebshellFile                  = 1 << 4
+	Xxe                           = 1 << 5
+	Ssti                          = 1 << 6
+	Deserialization               = 1 << 7
+	Ldap                          = 1 << 8
+	Ssrf                          = 1 << 9
+	DnsExfiltration               = 1 << 10
+	ZipSlip                       = 1 << 11
+	DecompressionBomb             = 1 << 12
+	MemcacheInjection             = 1 << 13
+	MailHeaderInjection           = 1 << 14
+	Request                       = 1 << 15
+	Graphql                       = 1 << 16
+	Authentication                = 1 << 17
+	BruteForce                    = 1 << 18
+	DataLeak                      = 1 << 19
+	AllType                       = Sql | SqlException | ReadFile | WriteFile | WebshellFile | Xxe | Ssti | Deserialization | Ldap | Ssrf | DnsExfiltration |
+		ZipSlip | DecompressionBomb | MemcacheInjection | MailHeaderInjection | Request | Graphql | Authentication | BruteForce | DataLeak
 )
 
 var buildinCheckTypes = []CheckType{SqlException}
@@ -17,6 +36,42 @@ func CheckTypeToString(ct CheckType) string {
 		return "sql_exception"
 	case Sql:
 		return "sql"
+	case ReadFile:
+		return "readFile"
+	case WriteFile:
+		return "writeFile"
+	case WebshellFile:
+		return "webshell_file"
+	case Xxe:
+		return "xxe"
+	case Ssti:
+		return "ssti"
+	case Deserialization:
+		return "deserialization"
+	case Ldap:
+		return "ldap"
+	case Ssrf:
+		return "ssrf"
+	case DnsExfiltration:
+		return "dns_exfiltration"
+	case ZipSlip:
+		return "zip_slip"
+	case DecompressionBomb:
+		return "decompression_bomb"
+	case MemcacheInjection:
+		return "memcache_injection"
+	case MailHeaderInjection:
+		return "mail_header_injection"
+	case Request:
+		return "request"
+	case Graphql:
+		return "graphql"
+	case Authentication:
+		return "authentication"
+	case BruteForce:
+		return "brute_force"
+	case DataLeak:
+		return "data_leak"
 	default:
 		return "unknown"
 	}
@@ -28,6 +83,42 @@ func CheckStringToType(key string) CheckType {
 		return SqlException
 	case "sql":
 		return Sql
+	case "readFile":
+		return ReadFile
+	case "writeFile":
+		return WriteFile
+	case "webshell_file":
+		return WebshellFile
+	case "xxe":
+		return Xxe
+	case "ssti":
+		return Ssti
+	case "deserialization":
+		return Deserialization
+	case "ldap":
+		return Ldap
+	case "ssrf":
+		return Ssrf
+	case "dns_exfiltration":
+		return DnsExfiltration
+	case "zip_slip":
+		return ZipSlip
+	case "decompression_bomb":
+		return DecompressionBomb
+	case "memcache_injection":
+		return MemcacheInjection
+	case "mail_header_injection":
+		return MailHeaderInjection
+	case "request":
+		return Request
+	case "graphql":
+		return Graphql
+	case "authentication":
+		return Authentication
+	case "brute_force":
+		return BruteForce
+	case "data_leak":
+		return DataLeak
 	case "all":
 		return AllType
 	default:
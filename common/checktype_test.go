@@ -9,12 +9,26 @@ import (
 func TestCheckTypeToString(t *testing.T) {
 	assert.Equal(t, CheckTypeToString(Sql), "sql", "they should be equal")
 	assert.Equal(t, CheckTypeToString(SqlException), "sql_exception", "they should be equal")
+	assert.Equal(t, CheckTypeToString(ReadFile), "readFile", "they should be equal")
+	assert.Equal(t, CheckTypeToString(WriteFile), "writeFile", "they should be equal")
+	assert.Equal(t, CheckTypeToString(Request), "request", "they should be equal")
+	assert.Equal(t, CheckTypeToString(Graphql), "graphql", "they should be equal")
+	assert.Equal(t, CheckTypeToString(Authentication), "authentication", "they should be equal")
+	assert.Equal(t, CheckTypeToString(BruteForce), "brute_force", "they should be equal")
+	assert.Equal(t, CheckTypeToString(DataLeak), "data_leak", "they should be equal")
 	assert.Equal(t, CheckTypeToString(InvalidType), "unknown", "they should be equal")
 }
 
 func TestCheckStringToType(t *testing.T) {
 	assert.EqualValues(t, CheckStringToType("sql"), Sql, "they should be equal")
 	assert.EqualValues(t, CheckStringToType("sql_exception"), SqlException, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("readFile"), ReadFile, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("writeFile"), WriteFile, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("request"), Request, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("graphql"), Graphql, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("authentication"), Authentication, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("brute_force"), BruteForce, "they should be equal")
+	assert.EqualValues(t, CheckStringToType("data_leak"), DataLeak, "they should be equal")
 	assert.EqualValues(t, CheckStringToType("all"), AllType, "they should be equal")
 	assert.EqualValues(t, CheckStringToType("doom"), InvalidType, "they should be equal")
 }
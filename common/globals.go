@@ -1,7 +1,12 @@
 package common
 
 import (
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/baidu-security/openrasp-golang/model"
 	"github.com/baidu-security/openrasp-golang/utils"
@@ -22,7 +27,7 @@ type Globals struct {
 func NewGlobals(rootDir string) *Globals {
 	hostname := utils.GetHostname()
 	nic, _ := getServerNic()
-	raspId := calculateRaspId(hostname, rootDir)
+	raspId := loadRaspId(hostname, rootDir)
 	g := Globals{
 		Hostname:      hostname,
 		RaspId:        raspId,
@@ -70,6 +75,46 @@ func getServerNic() ([]model.Nic, error) {
 	return nics, nil
 }
 
+const raspIdFilename = "rasp_id"
+
+var raspIdRegex = regexp.MustCompile(`^[0-9a-zA-Z]{16,512}$`)
+
+// loadRaspId keeps the rasp_id of the first run in the work directory, so a
+// changed network interface or a redeployment with the same work directory
+// registers as the same agent instead of a new one
+func loadRaspId(hostname, rootDir string) string {
+	filename := filepath.Join(rootDir, raspIdFilename)
+	if content, err := ioutil.ReadFile(filename); err == nil {
+		if raspId := strings.TrimSpace(string(content)); raspIdRegex.MatchString(raspId) {
+			return raspId
+		}
+	}
+	raspId := calculateRaspId(hostname, rootDir)
+	saveRaspId(filename, raspId)
+	return raspId
+}
+
+func saveRaspId(filename, raspId string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+raspIdFilename)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(raspId + "\n")
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
 func calculateRaspId(hostname, path string) string {
 	var raspString string
 	macAddrs := utils.GetMacAddrs()
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baidu-security/openrasp-golang/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRaspId(t *testing.T) {
+	testDir := test.TempMkdir(t)
+	defer os.RemoveAll(testDir)
+	raspId := loadRaspId("host", testDir)
+	assert.Regexp(t, raspIdRegex, raspId)
+	content, err := ioutil.ReadFile(filepath.Join(testDir, raspIdFilename))
+	assert.Nil(t, err)
+	assert.Equal(t, raspId+"\n", string(content))
+	assert.Equal(t, raspId, loadRaspId("renamed", testDir))
+
+	ioutil.WriteFile(filepath.Join(testDir, raspIdFilename), []byte("0123456789abcdef0123\n"), 0644)
+	assert.Equal(t, "0123456789abcdef0123", loadRaspId("host", testDir))
+	ioutil.WriteFile(filepath.Join(testDir, raspIdFilename), []byte("../../etc"), 0644)
+	assert.Equal(t, calculateRaspId("host", testDir), loadRaspId("host", testDir))
+}
@@ -2,6 +2,9 @@ package common
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/baidu-security/openrasp-golang/notify"
@@ -20,6 +23,7 @@ const (
 	LogPlugin
 	LogPolicy
 	LogRasp
+	LogAudit
 )
 
 type NotifyListener interface {
@@ -79,6 +83,10 @@ func NewWorkSpace(baseDir string) *WorkSpace {
 	workDirMap[LogRasp] = newCodeInfo(logRaspDir)
 	logsDir.appendSubDir(logRaspDir)
 
+	logAuditDir := NewWorkDirInfo(logsDir.absPath(), "audit", 0777)
+	workDirMap[LogAudit] = newCodeInfo(logAuditDir)
+	logsDir.appendSubDir(logAuditDir)
+
 	pluginsDir := NewWorkDirInfo(raspDir.absPath(), "plugins", 0777)
 	workDirMap[Plugins] = newCodeInfo(pluginsDir)
 	raspDir.appendSubDir(pluginsDir)
@@ -133,6 +141,14 @@ func (ws *WorkSpace) clear() error {
 	}
 }
 
+// Close stops watching the directories
+func (ws *WorkSpace) Close() error {
+	if ws.watcher == nil {
+		return nil
+	}
+	return ws.watcher.Close()
+}
+
 func (ws *WorkSpace) GetDir(code WorkDirCode) (string, error) {
 	if !ws.Active() {
 		return "", errors.New("work space is not active.")
@@ -176,6 +192,27 @@ func (ws *WorkSpace) StartWatch(codes ...WorkDirCode) bool {
 	return true
 }
 
+// Relocate points code to an existing directory outside of the work space,
+// such as a read-only policy bundle. It must be called before StartWatch.
+func (ws *WorkSpace) Relocate(code WorkDirCode, absPath string) error {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", absPath)
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ci, ok := ws.dirMap[code]
+	if !ok {
+		return errors.New("invalid code parameter.")
+	}
+	absPath = filepath.Clean(absPath)
+	ci.workDir = NewWorkDirInfo(filepath.Dir(absPath), filepath.Base(absPath), info.Mode())
+	return nil
+}
+
 func (ws *WorkSpace) RegisterListener(code WorkDirCode, listener NotifyListener) {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()
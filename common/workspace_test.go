@@ -140,3 +140,26 @@ func TestWorkSpaceInitWatch(t *testing.T) {
 		t.Errorf("GetDir should return error.")
 	}
 }
+
+func TestWorkSpaceRelocate(t *testing.T) {
+	testDir := test.TempMkdir(t)
+	defer os.RemoveAll(testDir)
+	workSpace := NewWorkSpace(testDir)
+	workSpace.Init()
+	bundleDir := filepath.Join(testDir, "bundle")
+	if err := workSpace.Relocate(Conf, bundleDir); err == nil {
+		t.Errorf("Relocate should fail on missing dir.")
+	}
+	os.Mkdir(bundleDir, 0755)
+	if err := workSpace.Relocate(Conf, bundleDir); err != nil {
+		t.Errorf("Fail to relocate, %v", err)
+	}
+	confDir, _ := workSpace.GetDir(Conf)
+	if confDir != bundleDir {
+		t.Errorf("Conf dir is %s, want %s", confDir, bundleDir)
+	}
+	logsDir, _ := workSpace.GetDir(Logs)
+	if logsDir != filepath.Join(testDir, "rasp", "logs") {
+		t.Errorf("Logs dir should not be relocated, got %s", logsDir)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"sync"
+)
+
+// Source tells where a config change comes from
+type Source string
+
+const (
+	SourceFile  Source = "file"
+	SourceCloud Source = "cloud"
+	SourceAPI   Source = "api"
+)
+
+// Change is an applied change of a key, Old or New is nil when the key has
+// no value before or after
+type Change struct {
+	Key string
+	Old interface{}
+	New interface{}
+}
+
+// Auditor receives the changes of every update once they are applied
+type Auditor func(source Source, changes []Change)
+
+type auditors struct {
+	auditor Auditor
+	mu      sync.RWMutex
+}
+
+// SetAuditor replaces the auditor, nil removes it
+func (a *auditors) SetAuditor(auditor Auditor) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.auditor = auditor
+}
+
+func (a *auditors) audit(source Source, changes []Change) {
+	a.mu.RLock()
+	auditor := a.auditor
+	a.mu.RUnlock()
+	if auditor != nil && len(changes) > 0 {
+		auditor(source, changes)
+	}
+}
+
+func keysOf(changes []Change) []string {
+	keys := make([]string, 0, len(changes))
+	for _, change := range changes {
+		keys = append(keys, change.Key)
+	}
+	return keys
+}
@@ -1,39 +1,78 @@
 package config
 
 import (
+	"log"
+	"strings"
+	"sync"
+
 	"github.com/spf13/viper"
 )
 
 type BasicConfig struct {
+	auditors
 	basic *viper.Viper
+	mu    sync.RWMutex
 }
 
 func NewBasicConfig() *BasicConfig {
+	basic := newBasicViper()
+	if err := applyEnv(newBasicViper(), basic); err != nil {
+		log.Printf("%v", err)
+	}
+	return &BasicConfig{
+		basic: basic,
+	}
+}
+
+func newBasicViper() *viper.Viper {
 	basicViper := viper.New()
 	basicViper.SetDefault("cloud.enable", false)
 	basicViper.SetDefault("cloud.backend_url", "")
 	basicViper.SetDefault("cloud.app_id", "")
 	basicViper.SetDefault("cloud.app_secret", "")
 	basicViper.SetDefault("cloud.heartbeat_interval", 180)
-	bc := &BasicConfig{
-		basic: basicViper,
-	}
-	return bc
+	basicViper.SetDefault("cloud.report_interval", 60)
+	basicViper.SetDefault("cloud.tls.ca_file", "")
+	basicViper.SetDefault("cloud.tls.cert_file", "")
+	basicViper.SetDefault("cloud.tls.key_file", "")
+	basicViper.SetDefault("cloud.tls.insecure_skip_verify", false)
+	basicViper.SetDefault("cloud.proxy", "")
+	basicViper.SetDefault("cloud.apps", []interface{}{})
+	basicViper.SetDefault("plugin.engine", "v8")
+	basicViper.SetDefault("plugin.wasm.enable", false)
+	basicViper.SetDefault("plugin.wasm.max_memory_pages", 256)
+	basicViper.SetDefault("offline.enable", false)
+	basicViper.SetDefault("offline.bundle_dir", "")
+	return basicViper
 }
 
 func (bc *BasicConfig) GetBool(key string) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	return bc.basic.GetBool(key)
 }
 
 func (bc *BasicConfig) GetString(key string) string {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	return bc.basic.GetString(key)
 }
 
 func (bc *BasicConfig) GetInt64(key string) int64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
 	return bc.basic.GetInt64(key)
 }
 
+func (bc *BasicConfig) UnmarshalKey(key string, rawVal interface{}) error {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.basic.UnmarshalKey(key, rawVal)
+}
+
 func (bc *BasicConfig) LoadYaml(path string) error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
 	bc.basic.SetConfigType("yaml")
 	bc.basic.SetConfigFile(path)
 	err := bc.basic.ReadInConfig()
@@ -43,3 +82,46 @@ func (bc *BasicConfig) LoadYaml(path string) error {
 		return nil
 	}
 }
+
+// LoadFiles swaps all values for the defaults overridden by the files and
+// returns the keys which changed, nothing changes when a file is malformed or
+// a value is invalid. Most basic settings only take effect on start. The
+// environment stays on top, basic settings cannot be set through the API.
+func (bc *BasicConfig) LoadFiles(paths ...string) ([]string, error) {
+	next := newBasicViper()
+	if err := readFiles(next, paths); err != nil {
+		return nil, err
+	}
+	if err := validateDefaults(newBasicViper(), next); err != nil {
+		return nil, err
+	}
+	applyEnv(newBasicViper(), next)
+	// openrasp.yml carries the general settings as well, they are reported
+	// by GeneralConfig
+	var changes []Change
+	defaults := newBasicViper()
+	bc.mu.Lock()
+	for _, change := range diff(snapshot(bc.basic), snapshot(next)) {
+		if hasDefault(defaults, change.Key) {
+			changes = append(changes, change)
+		}
+	}
+	bc.basic = next
+	bc.mu.Unlock()
+	bc.audit(SourceFile, changes)
+	return keysOf(changes), nil
+}
+
+// hasDefault reports whether key or a section containing it has a default
+func hasDefault(defaults *viper.Viper, key string) bool {
+	for {
+		if defaults.IsSet(key) {
+			return true
+		}
+		i := strings.LastIndex(key, ".")
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
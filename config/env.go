@@ -0,0 +1,115 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the environment variables which override config keys,
+// OPENRASP_BLOCK_STATUS_CODE overrides block.status_code
+const EnvPrefix = "OPENRASP_"
+
+// EnvKey returns the variable which overrides key
+func EnvKey(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// applyEnv sets every key with a default whose variable is set, they take
+// precedence over the config files and the cloud. Lists are separated by
+// commas and maps are written as JSON. Invalid values are skipped and
+// reported in the error.
+func applyEnv(defaults, v *viper.Viper) error {
+	var invalid []string
+	for _, kv := range os.Environ() {
+		i := strings.Index(kv, "=")
+		if i < 0 || !strings.HasPrefix(kv, EnvPrefix) {
+			continue
+		}
+		name, raw := kv[:i], kv[i+1:]
+		key, ok := lookupKey(defaults, strings.ToLower(strings.TrimPrefix(name, EnvPrefix)))
+		if !ok {
+			continue
+		}
+		value, err := envValue(key, defaults.Get(key), raw)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s of %s, %v", raw, name, err))
+			continue
+		}
+		v.Set(key, value)
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid value %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+// lookupKey finds the key with a default which is spelled as name, each
+// underscore of name stands for a dot or an underscore of the key. Sections
+// such as log are not keys, empty maps such as hook.white are.
+func lookupKey(defaults *viper.Viper, name string) (string, bool) {
+	parts := strings.Split(name, "_")
+	if len(parts) > 10 {
+		return "", false
+	}
+	for mask := 0; mask < 1<<uint(len(parts)-1); mask++ {
+		key := parts[0]
+		for i, part := range parts[1:] {
+			if mask&(1<<uint(i)) != 0 {
+				key += "_" + part
+			} else {
+				key += "." + part
+			}
+		}
+		if !defaults.IsSet(key) {
+			continue
+		}
+		if section, ok := defaults.Get(key).(map[string]interface{}); ok && len(section) > 0 {
+			continue
+		}
+		return key, true
+	}
+	return "", false
+}
+
+func envValue(key string, def interface{}, raw string) (interface{}, error) {
+	var value interface{} = raw
+	switch def.(type) {
+	case []string:
+		value = splitList(raw)
+	case []int:
+		var ints []int
+		for _, s := range splitList(raw) {
+			i, err := cast.ToIntE(s)
+			if err != nil {
+				return nil, err
+			}
+			ints = append(ints, i)
+		}
+		value = ints
+	case map[string]interface{}, []interface{}:
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			return nil, err
+		}
+	}
+	if err := validateValue(key, def, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func splitList(raw string) []string {
+	var list []string
+	for _, s := range strings.Split(raw, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			list = append(list, s)
+		}
+	}
+	return list
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// fileTypes maps the extensions of the supported config files to their
+// format, the keys are the same in every format
+var fileTypes = map[string]string{
+	".yml":        "yaml",
+	".yaml":       "yaml",
+	".toml":       "toml",
+	".properties": "properties",
+}
+
+// readFiles reads the files which exist into v in order, the keys of a file
+// override the ones read before
+func readFiles(v *viper.Viper, paths []string) error {
+	read := false
+	for _, path := range paths {
+		configType, ok := fileTypes[filepath.Ext(path)]
+		if !ok {
+			return fmt.Errorf("unsupported config file %s", filepath.Base(path))
+		}
+		raw, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if read || configType == "properties" {
+			// ReadConfig drops the keys read before, and merging fails
+			// when a file changes the type of a value
+			scratch := viper.New()
+			scratch.SetConfigType(configType)
+			err = scratch.ReadConfig(raw)
+			for _, key := range scratch.AllKeys() {
+				v.Set(key, scratch.Get(key))
+			}
+		} else {
+			v.SetConfigType(configType)
+			err = v.ReadConfig(raw)
+			read = true
+		}
+		raw.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read %s, %v", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// validateDefaults checks the value of every key with a default in v
+func validateDefaults(defaults, v *viper.Viper) error {
+	for _, key := range defaults.AllKeys() {
+		if value := v.Get(key); value != nil {
+			if err := validateValue(key, defaults.Get(key), value); err != nil {
+				return fmt.Errorf("invalid value %v of %s, %v", value, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// snapshot copies the values of all keys of v
+func snapshot(v *viper.Viper) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, key := range v.AllKeys() {
+		values[key] = v.Get(key)
+	}
+	return values
+}
+
+// diff lists the keys whose value differs between two snapshots
+func diff(previous, next map[string]interface{}) []Change {
+	var changes []Change
+	for key, old := range previous {
+		if new := next[key]; !reflect.DeepEqual(old, new) {
+			changes = append(changes, Change{Key: key, Old: old, New: new})
+		}
+	}
+	for key, new := range next {
+		if _, ok := previous[key]; !ok {
+			changes = append(changes, Change{Key: key, New: new})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
@@ -4,8 +4,10 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 	"sync"
 
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 )
 
@@ -14,19 +16,92 @@ type UpdateListener interface {
 }
 
 type GeneralConfig struct {
-	general   *viper.Viper
+	auditors
+	general *viper.Viper
+	// overrides are the values set through the API, they stay on top of the
+	// cloud config and the files when those are applied again
+	overrides map[string]interface{}
 	listeners []UpdateListener
 	mu        sync.RWMutex
+	// updateMu runs one update at a time, its listeners and auditor included
+	updateMu sync.Mutex
 }
 
 func NewGeneralConfig() *GeneralConfig {
+	general := newGeneralViper()
+	if err := applyEnv(newGeneralViper(), general); err != nil {
+		log.Printf("%v", err)
+	}
+	return &GeneralConfig{
+		general:   general,
+		overrides: make(map[string]interface{}),
+	}
+}
+
+func newGeneralViper() *viper.Viper {
 	generalViper := viper.New()
 	generalViper.SetDefault("plugin.timeout.millis", 100)
 	generalViper.SetDefault("plugin.maxstack", 100)
 	generalViper.SetDefault("plugin.filter", false)
+	generalViper.SetDefault("plugin.failure_action", "ignore")
+	generalViper.SetDefault("algorithm.config", map[string]interface{}{})
 	generalViper.SetDefault("log.maxburst", 100)
 	generalViper.SetDefault("log.maxstack", 10)
+	generalViper.SetDefault("log.stack.skip_prefixes", []string{"runtime.", "github.com/baidu-security/openrasp-golang"})
+	generalViper.SetDefault("log.source_code.enable", false)
+	generalViper.SetDefault("log.source_code.root", "")
+	generalViper.SetDefault("log.source_code.lines", 3)
 	generalViper.SetDefault("log.maxbackup", 30)
+	generalViper.SetDefault("log.drop.summary_interval", 60)
+	generalViper.SetDefault("log.alarm.maxburst", 0)
+	generalViper.SetDefault("log.alarm.rate", 0)
+	generalViper.SetDefault("log.policy.maxburst", 0)
+	generalViper.SetDefault("log.policy.rate", 0)
+	generalViper.SetDefault("log.plugin.maxburst", 0)
+	generalViper.SetDefault("log.plugin.rate", 0)
+	generalViper.SetDefault("log.rasp.maxburst", 0)
+	generalViper.SetDefault("log.rasp.rate", 0)
+	generalViper.SetDefault("log.audit.maxburst", 10000)
+	generalViper.SetDefault("log.audit.rate", 0)
+	generalViper.SetDefault("log.maxfiles", 0)
+	generalViper.SetDefault("log.maxsize", 0)
+	generalViper.SetDefault("log.daily", true)
+	generalViper.SetDefault("log.format", "json")
+	generalViper.SetDefault("log.level.alarm", "info")
+	generalViper.SetDefault("log.level.policy", "info")
+	generalViper.SetDefault("log.level.plugin", "info")
+	generalViper.SetDefault("log.level.rasp", "info")
+	generalViper.SetDefault("log.level.audit", "info")
+	generalViper.SetDefault("log.file.enable", true)
+	generalViper.SetDefault("log.output", "file")
+	generalViper.SetDefault("log.encryption.enable", false)
+	generalViper.SetDefault("log.encryption.key", "")
+	generalViper.SetDefault("log.encryption.key_file", "")
+	generalViper.SetDefault("log.cloud.enable", true)
+	generalViper.SetDefault("log.async.enable", false)
+	generalViper.SetDefault("log.async.queue_size", 1024)
+	generalViper.SetDefault("log.async.batch_size", 100)
+	generalViper.SetDefault("log.async.flush_interval", 1000)
+	generalViper.SetDefault("log.async.drop_policy", "drop_newest")
+	generalViper.SetDefault("log.async.batch_bytes", 512*1024)
+	generalViper.SetDefault("log.gzip", false)
+	generalViper.SetDefault("log.http.format", "json")
+	generalViper.SetDefault("log.mask.enable", true)
+	generalViper.SetDefault("log.mask.headers", []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token"})
+	generalViper.SetDefault("log.mask.fields", `(?i)(passw(or)?d|pwd|secret|token|api_?key|credential|private_?key)`)
+	generalViper.SetDefault("log.privacy.enable", false)
+	generalViper.SetDefault("log.privacy.body", "hash")
+	generalViper.SetDefault("log.privacy.query", "hash")
+	generalViper.SetDefault("log.privacy.client_ip", "truncate")
+	generalViper.SetDefault("log.privacy.truncate_length", 64)
+	generalViper.SetDefault("log.privacy.salt", "")
+	generalViper.SetDefault("alarm.dedup.window", 0)
+	generalViper.SetDefault("alarm.sample.threshold", 0)
+	generalViper.SetDefault("alarm.sample.rate", 0.1)
+	generalViper.SetDefault("log.retry.max_retries", 3)
+	generalViper.SetDefault("log.retry.initial_backoff", 500)
+	generalViper.SetDefault("log.retry.max_backoff", 30000)
+	generalViper.SetDefault("log.spool.max_size", 10)
 	generalViper.SetDefault("syslog.tag", "OpenRASP")
 	generalViper.SetDefault("syslog.url", "")
 	generalViper.SetDefault("syslog.facility", 1)
@@ -34,23 +109,152 @@ func NewGeneralConfig() *GeneralConfig {
 	generalViper.SetDefault("syslog.connection_timeout", 50)
 	generalViper.SetDefault("syslog.read_timeout", 10)
 	generalViper.SetDefault("syslog.reconnect_interval", 300)
+	generalViper.SetDefault("syslog.alarm_severity", 4)
+	generalViper.SetDefault("syslog.policy_severity", 5)
+	generalViper.SetDefault("syslog.format", "json")
+	generalViper.SetDefault("kafka.enable", false)
+	generalViper.SetDefault("kafka.brokers", []string{})
+	generalViper.SetDefault("kafka.topic", "openrasp")
+	generalViper.SetDefault("kafka.partition_key", "rasp_id")
+	generalViper.SetDefault("kafka.format", "json")
+	generalViper.SetDefault("kafka.sasl.mechanism", "")
+	generalViper.SetDefault("kafka.sasl.username", "")
+	generalViper.SetDefault("kafka.sasl.password", "")
+	generalViper.SetDefault("kafka.tls.enable", false)
+	generalViper.SetDefault("kafka.tls.insecure_skip_verify", false)
+	generalViper.SetDefault("kafka.tls.ca_file", "")
+	generalViper.SetDefault("kafka.tls.cert_file", "")
+	generalViper.SetDefault("kafka.tls.key_file", "")
+	generalViper.SetDefault("splunk.enable", false)
+	generalViper.SetDefault("splunk.url", "")
+	generalViper.SetDefault("splunk.token", "")
+	generalViper.SetDefault("splunk.index", "")
+	generalViper.SetDefault("splunk.source", "openrasp")
+	generalViper.SetDefault("splunk.sourcetype.attack", "openrasp:attack")
+	generalViper.SetDefault("splunk.sourcetype.policy", "openrasp:policy")
+	generalViper.SetDefault("splunk.format", "json")
+	generalViper.SetDefault("splunk.timeout", 5000)
+	generalViper.SetDefault("splunk.tls.ca_file", "")
+	generalViper.SetDefault("splunk.tls.cert_file", "")
+	generalViper.SetDefault("splunk.tls.key_file", "")
+	generalViper.SetDefault("splunk.tls.insecure_skip_verify", false)
+	generalViper.SetDefault("fluentd.enable", false)
+	generalViper.SetDefault("fluentd.url", "")
+	generalViper.SetDefault("fluentd.tag", "openrasp")
+	generalViper.SetDefault("fluentd.format", "json")
+	generalViper.SetDefault("fluentd.require_ack", false)
+	generalViper.SetDefault("fluentd.connection_timeout", 500)
+	generalViper.SetDefault("fluentd.write_timeout", 1000)
+	generalViper.SetDefault("fluentd.reconnect_interval", 30)
 	generalViper.SetDefault("block.status_code", 302)
 	generalViper.SetDefault("block.redirect_url", `https://rasp.baidu.com/blocked/?request_id=%request_id%`)
 	generalViper.SetDefault("block.content_json", `{"error":true, "reason": "Request blocked by OpenRASP", "request_id": "%request_id%"}`)
 	generalViper.SetDefault("block.content_xml", `<?xml version="1.0"?><doc><error>true</error><reason>Request blocked by OpenRASP</reason><request_id>%request_id%</request_id></doc>`)
 	generalViper.SetDefault("block.content_html", `</script><script>location.href="https://rasp.baidu.com/blocked2/?request_id=%request_id%"</script>`)
+	generalViper.SetDefault("challenge.status_code", 403)
+	generalViper.SetDefault("challenge.content_html", `<html><head><title>Checking your browser</title></head><body><noscript>Please enable JavaScript to continue.</noscript><script>document.cookie="%cookie_name%=%token%; path=/; max-age=%ttl%";location.reload()</script></body></html>`)
+	generalViper.SetDefault("challenge.cookie_name", "openrasp_challenge")
+	generalViper.SetDefault("challenge.ttl", 3600)
+	generalViper.SetDefault("challenge.secret", "")
+	generalViper.SetDefault("sampling.percent", 100)
+	generalViper.SetDefault("sampling.flagged_ttl", 24*3600)
+	generalViper.SetDefault("sampling.flagged_max_size", 10000)
+	generalViper.SetDefault("detect.dry_run", false)
+	generalViper.SetDefault("detect.async.types", []string{})
+	generalViper.SetDefault("detect.async.workers", 4)
+	generalViper.SetDefault("detect.async.queue_size", 1024)
+	generalViper.SetDefault("latency.budget_micros", 0)
+	generalViper.SetDefault("latency.budget_window", 1000)
+	generalViper.SetDefault("statsd.enable", false)
+	generalViper.SetDefault("statsd.host", "127.0.0.1")
+	generalViper.SetDefault("statsd.port", 8125)
+	generalViper.SetDefault("statsd.prefix", "openrasp.")
+	generalViper.SetDefault("statsd.sample_rate", 1.0)
+	generalViper.SetDefault("statsd.dogstatsd", true)
+	generalViper.SetDefault("statsd.interval", 10)
+	generalViper.SetDefault("admin.enable", false)
+	generalViper.SetDefault("admin.addr", "127.0.0.1:8990")
+	generalViper.SetDefault("admin.token", "")
+	generalViper.SetDefault("admin.recent_size", 100)
 	generalViper.SetDefault("inject.urlprefix", "")
 	generalViper.SetDefault("inject.custom_headers", []string{})
 	generalViper.SetDefault("body.maxbytes", 4096)
 	generalViper.SetDefault("clientip.header", "")
 	generalViper.SetDefault("security.enforce_policy", false)
+	generalViper.SetDefault("security.env_baseline", true)
+	generalViper.SetDefault("security.weak_passwords", []string{"", "root", "admin", "test", "mysql", "postgres", "password", "passw0rd", "p@ssw0rd", "qwerty", "abc123", "admin123", "root123", "123", "1234", "12345", "123456", "1234567", "12345678", "123456789", "111111", "000000", "666666", "888888"})
+	generalViper.SetDefault("security.weak_passwords_append", []string{})
+	generalViper.SetDefault("security.debug_endpoints.block_external", false)
+	generalViper.SetDefault("security.directory_listing.forbid", false)
+	generalViper.SetDefault("security.cookie.session_names", `(?i)(sess|sid|token|auth|jwt|remember)`)
+	generalViper.SetDefault("security.cookie.fix", false)
+	generalViper.SetDefault("security.dependency_check", true)
+	generalViper.SetDefault("security.dependency_advisories", "")
 	generalViper.SetDefault("lru.max_size", 1024)
 	generalViper.SetDefault("hook.white", map[string]interface{}{})
+	generalViper.SetDefault("file.roots", []string{})
+	generalViper.SetDefault("file.action", "log")
+	generalViper.SetDefault("file.sensitive.globs", []string{"/etc/shadow*", "id_rsa*", "id_dsa*", "id_ecdsa*", "id_ed25519*", "*.pem", "*.key", "*.p12", "*.pfx", "*.keystore", ".env", ".htpasswd"})
+	generalViper.SetDefault("file.sensitive.regexes", []string{})
+	generalViper.SetDefault("file.webroots", []string{})
+	generalViper.SetDefault("file.webshell.action", "block")
+	generalViper.SetDefault("xml.action", "log")
+	generalViper.SetDefault("xml.refuse_external_entity", false)
+	generalViper.SetDefault("template.action", "block")
+	generalViper.SetDefault("deserialization.action", "log")
+	generalViper.SetDefault("deserialization.max_bytes", 1024*1024)
+	generalViper.SetDefault("deserialization.max_depth", 64)
+	generalViper.SetDefault("ldap.action", "block")
+	generalViper.SetDefault("egress.action", "block")
+	generalViper.SetDefault("egress.deny_internal", false)
+	generalViper.SetDefault("egress.denied_cidrs", []string{})
+	generalViper.SetDefault("egress.allowed_ports", []int{})
+	generalViper.SetDefault("dns.action", "log")
+	generalViper.SetDefault("dns.max_label_length", 40)
+	generalViper.SetDefault("dns.max_name_length", 120)
+	generalViper.SetDefault("archive.action", "block")
+	generalViper.SetDefault("archive.max_entries", 10000)
+	generalViper.SetDefault("archive.max_size", 1024*1024*1024)
+	generalViper.SetDefault("plugin.allowed_dirs", []string{})
+	generalViper.SetDefault("memcache.action", "block")
+	generalViper.SetDefault("mail.action", "block")
+	generalViper.SetDefault("graphql.action", "block")
+	generalViper.SetDefault("graphql.max_depth", 15)
+	generalViper.SetDefault("graphql.max_complexity", 1000)
+	generalViper.SetDefault("graphql.max_bytes", 1024*1024)
+	generalViper.SetDefault("graphql.injection_patterns", []string{
+		`(?i)['"]\s*(or|and)\s+\S+\s*(=|<|>|\blike\b)`,
+		`(?i)\bunion\b.+\bselect\b`,
+		`(?i)\b(sleep|benchmark|pg_sleep)\s*\(|\bwaitfor\s+delay\b`,
+		`(?i);\s*(drop|delete|insert|update|alter|truncate|exec)\b`,
+		`\$(where|ne|gt|gte|lt|lte|regex|expr|function)\b`,
+		"[;&|`]\\s*(cat|id|whoami|uname|curl|wget|nc|bash|sh|ping)\\b|\\$\\([^)]*\\)",
+		`(^|[\\/])\.\.[\\/]`,
+	})
+	generalViper.SetDefault("jwt.action", "log")
+	generalViper.SetDefault("jwt.weak_secrets", []string{"", "secret", "secretkey", "secret123", "your-256-bit-secret", "your-secret-key", "jwt", "jwtsecret", "jwt_secret", "jwt-secret", "changeme", "change_this_secret", "key", "default", "password", "123456", "admin", "test"})
+	generalViper.SetDefault("jwt.retry_threshold", 20)
+	generalViper.SetDefault("jwt.retry_window", 60)
+	generalViper.SetDefault("brute_force.action", "log")
+	generalViper.SetDefault("brute_force.window", 300)
+	generalViper.SetDefault("brute_force.max_failures_per_ip", 20)
+	generalViper.SetDefault("brute_force.max_failures_per_user", 10)
+	generalViper.SetDefault("brute_force.deny_ttl", 0)
+	generalViper.SetDefault("data_leak.enable", false)
+	generalViper.SetDefault("data_leak.max_bytes", 256*1024)
+	generalViper.SetDefault("data_leak.dump_items", 20)
 	generalViper.SetDefault("decompile.enable", false)
 	generalViper.SetDefault("debug.level", 0)
-	return &GeneralConfig{
-		general: generalViper,
+	generalViper.SetDefault("gls.cleanup_interval", 300)
+	generalViper.SetDefault("gls.tracking", false)
+	generalViper.SetDefault("hook.disabled", []string{})
+	for _, hook := range []string{"http", "http.body", "sql", "file", "dial", "dns", "xml", "template", "deserialization", "ldap", "memcache", "mail", "archive", "plugin", "graphql", "grpc"} {
+		generalViper.SetDefault("hook."+hook+".enable", true)
 	}
+	generalViper.SetDefault("policy.disabled", []int{})
+	generalViper.SetDefault("error.maxburst", 10)
+	generalViper.SetDefault("error.rate", 1.0/60)
+	return generalViper
 }
 
 func (gc *GeneralConfig) AttachListener(listener UpdateListener) {
@@ -83,28 +287,53 @@ func (gc *GeneralConfig) GetInt64(key string) int64 {
 	return gc.general.GetInt64(key)
 }
 
+func (gc *GeneralConfig) GetFloat64(key string) float64 {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+	return gc.general.GetFloat64(key)
+}
+
+func (gc *GeneralConfig) GetStringSlice(key string) []string {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+	return gc.general.GetStringSlice(key)
+}
+
+func (gc *GeneralConfig) GetIntSlice(key string) []int {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+	return cast.ToIntSlice(gc.general.Get(key))
+}
+
 func (gc *GeneralConfig) GetStringMap(key string) map[string]interface{} {
 	gc.mu.RLock()
 	defer gc.mu.RUnlock()
 	return gc.general.GetStringMap(key)
 }
 
-func (gc *GeneralConfig) ReadConfig(in io.Reader) (err error) {
+// AllSettings returns the current value of every key
+func (gc *GeneralConfig) AllSettings() map[string]interface{} {
+	gc.mu.RLock()
+	defer gc.mu.RUnlock()
+	return snapshot(gc.general)
+}
+
+func (gc *GeneralConfig) ReadConfig(in io.Reader) error {
+	gc.updateMu.Lock()
+	defer gc.updateMu.Unlock()
 	gc.mu.Lock()
-	defer func() {
-		gc.mu.Unlock()
-		if err == nil {
-			for _, l := range gc.listeners {
-				l.OnConfigUpdate()
-			}
-		}
-	}()
+	previous := snapshot(gc.general)
 	gc.general.SetConfigType("yaml")
-	err = gc.general.ReadConfig(in)
-	if err != nil {
+	if err := gc.general.ReadConfig(in); err != nil {
+		gc.mu.Unlock()
 		log.Printf("%v", err)
+		return err
 	}
-	return err
+	changes := diff(previous, snapshot(gc.general))
+	gc.mu.Unlock()
+	gc.notify()
+	gc.audit(SourceFile, changes)
+	return nil
 }
 
 func (gc *GeneralConfig) OnUpdate(absPath string) {
@@ -115,15 +344,103 @@ func (gc *GeneralConfig) OnUpdate(absPath string) {
 }
 
 func (gc *GeneralConfig) OnUpdateCloud(config *map[string]interface{}) {
+	if err := gc.UpdateFrom(SourceCloud, *config); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// Update merges config into the current values once all of them are valid,
+// the change is audited as made through the API
+func (gc *GeneralConfig) Update(config map[string]interface{}) error {
+	return gc.UpdateFrom(SourceAPI, config)
+}
+
+// UpdateFrom merges config from source into the current values once all of
+// them are valid. The values set through the API outlast Replace and
+// LoadFiles, until another source sets the same keys.
+func (gc *GeneralConfig) UpdateFrom(source Source, config map[string]interface{}) error {
+	if err := validate(newGeneralViper(), config); err != nil {
+		return err
+	}
+	gc.updateMu.Lock()
+	defer gc.updateMu.Unlock()
 	gc.mu.Lock()
-	defer func() {
-		gc.mu.Unlock()
-		for _, l := range gc.listeners {
-			l.OnConfigUpdate()
-		}
-	}()
-	for k, v := range *config {
+	previous := snapshot(gc.general)
+	for k, v := range config {
 		gc.general.Set(k, v)
+		if source == SourceAPI {
+			gc.overrides[strings.ToLower(k)] = v
+		} else {
+			delete(gc.overrides, strings.ToLower(k))
+		}
+	}
+	applyEnv(newGeneralViper(), gc.general)
+	changes := diff(previous, snapshot(gc.general))
+	gc.mu.Unlock()
+	gc.notify()
+	gc.audit(source, changes)
+	return nil
+}
+
+// Replace swaps all values for the defaults overridden by the cloud config in
+// one step, keys dropped from config return to their defaults, nothing
+// changes when a value is invalid. The values set through the API and the
+// environment stay on top.
+func (gc *GeneralConfig) Replace(config map[string]interface{}) error {
+	next := newGeneralViper()
+	if err := validate(next, config); err != nil {
+		return err
+	}
+	for k, v := range config {
+		next.Set(k, v)
+	}
+	gc.updateMu.Lock()
+	defer gc.updateMu.Unlock()
+	gc.swap(SourceCloud, next)
+	return nil
+}
+
+// LoadFiles swaps all values for the defaults overridden by the files in one
+// step and returns the keys which changed, nothing changes when a file is
+// malformed or a value is invalid. The values set through the API and the
+// environment stay on top.
+func (gc *GeneralConfig) LoadFiles(paths ...string) ([]string, error) {
+	next := newGeneralViper()
+	if err := readFiles(next, paths); err != nil {
+		return nil, err
+	}
+	if err := validateDefaults(newGeneralViper(), next); err != nil {
+		return nil, err
+	}
+	gc.updateMu.Lock()
+	defer gc.updateMu.Unlock()
+	return keysOf(gc.swap(SourceFile, next)), nil
+}
+
+// swap makes next, with the overrides and the environment applied, the
+// current config. The caller holds updateMu.
+func (gc *GeneralConfig) swap(source Source, next *viper.Viper) []Change {
+	for k, v := range gc.overrides {
+		next.Set(k, v)
+	}
+	applyEnv(newGeneralViper(), next)
+	gc.mu.Lock()
+	changes := diff(snapshot(gc.general), snapshot(next))
+	gc.general = next
+	gc.mu.Unlock()
+	gc.notify()
+	gc.audit(source, changes)
+	return changes
+}
+
+// notify runs the listeners in the order they are attached, the caller holds
+// updateMu so the listeners of two updates never run at once
+func (gc *GeneralConfig) notify() {
+	gc.mu.RLock()
+	listeners := gc.listeners
+	gc.mu.RUnlock()
+	for _, l := range listeners {
+		l.OnConfigUpdate()
 	}
 }
 
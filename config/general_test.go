@@ -0,0 +1,248 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countListener struct {
+	count int
+}
+
+func (cl *countListener) OnConfigUpdate() {
+	cl.count++
+}
+
+func TestGeneralUpdate(t *testing.T) {
+	gc := NewGeneralConfig()
+	cl := &countListener{}
+	gc.AttachListener(cl)
+	assert.Nil(t, gc.Update(map[string]interface{}{"log.maxstack": float64(20)}))
+	assert.Equal(t, 20, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 1, cl.count)
+
+	assert.NotNil(t, gc.Update(map[string]interface{}{"log.maxstack": "deep", "log.maxburst": 10}))
+	assert.NotNil(t, gc.Update(map[string]interface{}{"block.status_code": 42}))
+	assert.Equal(t, 20, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 100, gc.GetInt("log.maxburst"))
+	assert.Equal(t, 1, cl.count)
+
+	assert.Nil(t, gc.Update(map[string]interface{}{"statsd.sample_rate": 0.5}))
+	assert.NotNil(t, gc.Update(map[string]interface{}{"statsd.sample_rate": 2}))
+	assert.Equal(t, 0.5, gc.GetFloat64("statsd.sample_rate"))
+}
+
+func TestEnumValidation(t *testing.T) {
+	gc := NewGeneralConfig()
+	assert.Nil(t, gc.Update(map[string]interface{}{"log.privacy.body": "drop"}))
+	assert.NotNil(t, gc.Update(map[string]interface{}{"log.privacy.client_ip": "encrypt"}))
+	assert.Equal(t, "drop", gc.GetString("log.privacy.body"))
+	assert.Equal(t, "truncate", gc.GetString("log.privacy.client_ip"))
+}
+
+func TestGeneralReplace(t *testing.T) {
+	gc := NewGeneralConfig()
+	cl := &countListener{}
+	gc.AttachListener(cl)
+	assert.Nil(t, gc.UpdateFrom(SourceCloud, map[string]interface{}{"log.maxstack": 20}))
+	assert.Nil(t, gc.Replace(map[string]interface{}{"block.status_code": 403, "custom.key": "value"}))
+	assert.Equal(t, 10, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 403, gc.GetInt("block.status_code"))
+	assert.Equal(t, "value", gc.GetString("custom.key"))
+	assert.Equal(t, 2, cl.count)
+
+	assert.NotNil(t, gc.Replace(map[string]interface{}{"plugin.filter": "maybe"}))
+	assert.Equal(t, 403, gc.GetInt("block.status_code"))
+	assert.Equal(t, 2, cl.count)
+}
+
+func TestGeneralOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "openrasp.yml")
+	assert.NoError(t, ioutil.WriteFile(yamlPath, []byte("log:\n  maxstack: 30\n  maxburst: 50\n"), 0644))
+
+	gc := NewGeneralConfig()
+	assert.Nil(t, gc.Update(map[string]interface{}{"log.maxstack": 20, "hook.sql.enable": false}))
+	assert.Nil(t, gc.Replace(map[string]interface{}{"block.status_code": 403}))
+	assert.Equal(t, 20, gc.GetInt("log.maxstack"))
+	assert.False(t, gc.GetBool("hook.sql.enable"))
+	assert.Equal(t, 403, gc.GetInt("block.status_code"))
+
+	_, err = gc.LoadFiles(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 50, gc.GetInt("log.maxburst"))
+	assert.False(t, gc.GetBool("hook.sql.enable"))
+	assert.Equal(t, 302, gc.GetInt("block.status_code"))
+
+	// the cloud takes a key back by setting it
+	assert.Nil(t, gc.UpdateFrom(SourceCloud, map[string]interface{}{"log.maxstack": 40}))
+	assert.Nil(t, gc.Replace(map[string]interface{}{}))
+	assert.Equal(t, 10, gc.GetInt("log.maxstack"))
+	assert.False(t, gc.GetBool("hook.sql.enable"))
+}
+
+type serialListener struct {
+	running int32
+	overlap bool
+}
+
+func (sl *serialListener) OnConfigUpdate() {
+	if atomic.AddInt32(&sl.running, 1) > 1 {
+		sl.overlap = true
+	}
+	time.Sleep(time.Millisecond)
+	atomic.AddInt32(&sl.running, -1)
+}
+
+func TestGeneralSerialListeners(t *testing.T) {
+	gc := NewGeneralConfig()
+	sl := &serialListener{}
+	gc.AttachListener(sl)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				gc.Replace(map[string]interface{}{"log.maxburst": 10 + i})
+			} else {
+				gc.Update(map[string]interface{}{"log.maxstack": 10 + i})
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.False(t, sl.overlap)
+}
+
+func TestGeneralAudit(t *testing.T) {
+	gc := NewGeneralConfig()
+	var sources []Source
+	var audited [][]Change
+	gc.SetAuditor(func(source Source, changes []Change) {
+		sources = append(sources, source)
+		audited = append(audited, changes)
+	})
+	assert.Nil(t, gc.Update(map[string]interface{}{"log.maxstack": 20, "plugin.filter": false}))
+	assert.Nil(t, gc.UpdateFrom(SourceCloud, map[string]interface{}{"plugin.filter": true}))
+	assert.Nil(t, gc.Replace(map[string]interface{}{"plugin.filter": true, "log.maxburst": 50}))
+	assert.NotNil(t, gc.Update(map[string]interface{}{"log.maxstack": "deep"}))
+	assert.Nil(t, gc.Update(map[string]interface{}{"log.maxburst": 50}))
+
+	assert.Equal(t, []Source{SourceAPI, SourceCloud, SourceCloud}, sources)
+	assert.Equal(t, []Change{{Key: "log.maxstack", Old: 10, New: 20}}, audited[0])
+	assert.Equal(t, []Change{{Key: "plugin.filter", Old: false, New: true}}, audited[1])
+	assert.Equal(t, []Change{{Key: "log.maxburst", Old: 100, New: 50}}, audited[2])
+
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "openrasp.yml")
+	assert.NoError(t, ioutil.WriteFile(yamlPath, []byte("plugin:\n  filter: false\n"), 0644))
+	_, err = gc.LoadFiles(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, SourceFile, sources[3])
+	assert.Equal(t, []Change{{Key: "plugin.filter", Old: true, New: false}}, audited[3])
+}
+
+func TestGeneralLoadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "openrasp.yml")
+	propertiesPath := filepath.Join(dir, "rasp.properties")
+	assert.NoError(t, ioutil.WriteFile(yamlPath, []byte("log:\n  maxstack: 20\nblock:\n  status_code: 403\ncloud:\n  enable: true\n"), 0644))
+
+	gc := NewGeneralConfig()
+	cl := &countListener{}
+	gc.AttachListener(cl)
+	changed, err := gc.LoadFiles(yamlPath, propertiesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"block.status_code", "cloud.enable", "log.maxstack"}, changed)
+	assert.Equal(t, 20, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 1, cl.count)
+
+	assert.NoError(t, ioutil.WriteFile(propertiesPath, []byte("log.maxstack=30\n"), 0644))
+	changed, err = gc.LoadFiles(yamlPath, propertiesPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"log.maxstack"}, changed)
+	assert.Equal(t, 30, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 403, gc.GetInt("block.status_code"))
+
+	assert.NoError(t, ioutil.WriteFile(propertiesPath, []byte("log.maxstack=deep\n"), 0644))
+	_, err = gc.LoadFiles(yamlPath, propertiesPath)
+	assert.Error(t, err)
+	assert.NoError(t, ioutil.WriteFile(yamlPath, []byte("log: [\n"), 0644))
+	_, err = gc.LoadFiles(yamlPath)
+	assert.Error(t, err)
+	assert.Equal(t, 30, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 2, cl.count)
+
+	bc := NewBasicConfig()
+	assert.NoError(t, ioutil.WriteFile(yamlPath, []byte("log:\n  maxstack: 20\ncloud:\n  enable: true\n"), 0644))
+	changed, err = bc.LoadFiles(yamlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cloud.enable"}, changed)
+	assert.True(t, bc.GetBool("cloud.enable"))
+}
+
+func TestLoadFilesFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "openrasp.yaml")
+	tomlPath := filepath.Join(dir, "openrasp.toml")
+	assert.NoError(t, ioutil.WriteFile(yamlPath, []byte("log:\n  maxstack: 20\n  maxburst: 50\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(tomlPath, []byte("[log]\nmaxstack = 30\n\n[block]\nstatus_code = 403\n"), 0644))
+
+	gc := NewGeneralConfig()
+	changed, err := gc.LoadFiles(yamlPath, tomlPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"block.status_code", "log.maxburst", "log.maxstack"}, changed)
+	assert.Equal(t, 30, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 50, gc.GetInt("log.maxburst"))
+	assert.Equal(t, 403, gc.GetInt("block.status_code"))
+
+	_, err = gc.LoadFiles(filepath.Join(dir, "openrasp.json"))
+	assert.Error(t, err)
+}
+
+func TestEnvOverrides(t *testing.T) {
+	assert.Equal(t, "OPENRASP_CLOUD_BACKEND_URL", EnvKey("cloud.backend_url"))
+	env := map[string]string{
+		"OPENRASP_CLOUD_BACKEND_URL": "https://rasp.example.com",
+		"OPENRASP_LOG_MAXSTACK":      "30",
+		"OPENRASP_BLOCK_STATUS_CODE": "42",
+		"OPENRASP_HOOK_DISABLED":     "sql, ssrf",
+		"OPENRASP_POLICY_DISABLED":   "3006,3011",
+		"OPENRASP_HOOK_WHITE":        `{"example.com/health":["all"]}`,
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	bc := NewBasicConfig()
+	assert.Equal(t, "https://rasp.example.com", bc.GetString("cloud.backend_url"))
+
+	gc := NewGeneralConfig()
+	assert.Equal(t, 30, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 302, gc.GetInt("block.status_code"), "an invalid value is skipped")
+	assert.Equal(t, []string{"sql", "ssrf"}, gc.GetStringSlice("hook.disabled"))
+	assert.Equal(t, []int{3006, 3011}, gc.GetIntSlice("policy.disabled"))
+	assert.Contains(t, gc.GetStringMap("hook.white"), "example.com/health")
+
+	assert.Nil(t, gc.Update(map[string]interface{}{"log.maxstack": 20, "log.maxburst": 10}))
+	assert.Equal(t, 30, gc.GetInt("log.maxstack"))
+	assert.Equal(t, 10, gc.GetInt("log.maxburst"))
+	assert.Nil(t, gc.Replace(map[string]interface{}{"log.maxstack": 20}))
+	assert.Equal(t, 30, gc.GetInt("log.maxstack"))
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/common"
+)
+
+// Config is the typed form of the general settings accepted by
+// openrasp.Configure. A zero value keeps the current setting, so the
+// switches and the numbers which may be set to zero are pointers, see Bool
+// and Int. The basic settings such as cloud.* are read on start and can't be
+// changed here.
+type Config struct {
+	Plugin         PluginConfig
+	Log            LogConfig
+	Block          BlockConfig
+	Syslog         SyslogConfig
+	Hook           HookConfig
+	Security       SecurityConfig
+	ClientIPHeader string
+	BodyMaxBytes   *int
+	// DryRun logs every decision to block or challenge instead, for a
+	// rollout in observe mode
+	DryRun *bool
+}
+
+type PluginConfig struct {
+	// Timeout of a check, a whole number of milliseconds up to a minute
+	Timeout  time.Duration
+	MaxStack *int
+	// FailureAction is block, log or ignore
+	FailureAction string
+}
+
+type LogConfig struct {
+	MaxStack *int
+	MaxBurst *int
+	// MaxBackup is the number of days the rotated files are kept
+	MaxBackup *int
+	// Format is json, cef or leef
+	Format string
+	// Output is file or stdout
+	Output string
+}
+
+type BlockConfig struct {
+	// StatusCode of the blocked response, a redirect between 300 and 399
+	// is sent to RedirectURL
+	StatusCode int
+	// RedirectURL is an absolute http(s) url, %request_id% is replaced
+	RedirectURL string
+	ContentJSON string
+	ContentXML  string
+	ContentHTML string
+}
+
+type SyslogConfig struct {
+	Enable *bool
+	// URL is udp://host:port, tcp://host:port or unix:///path
+	URL      string
+	Tag      string
+	Facility *int
+}
+
+type HookConfig struct {
+	// Disabled check types such as sql or ssrf
+	Disabled []string
+	// White maps a url prefix without scheme to the check types it skips,
+	// all skips every check
+	White map[string][]string
+}
+
+type SecurityConfig struct {
+	EnforcePolicy *bool
+	EnvBaseline   *bool
+}
+
+// Bool returns a pointer to b for the switches of Config
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Int returns a pointer to i for the numbers of Config
+func Int(i int) *int {
+	return &i
+}
+
+// Settings validates cfg and returns the keys it sets, all invalid fields
+// are described in the error
+func (cfg *Config) Settings() (map[string]interface{}, error) {
+	s := &settings{values: make(map[string]interface{})}
+
+	if cfg.Plugin.Timeout != 0 {
+		if cfg.Plugin.Timeout < time.Millisecond || cfg.Plugin.Timeout > time.Minute || cfg.Plugin.Timeout%time.Millisecond != 0 {
+			s.invalid("Plugin.Timeout", cfg.Plugin.Timeout, "must be a whole number of milliseconds between 1ms and 1m")
+		} else {
+			s.set("plugin.timeout.millis", int64(cfg.Plugin.Timeout/time.Millisecond))
+		}
+	}
+	s.setInt("Plugin.MaxStack", "plugin.maxstack", cfg.Plugin.MaxStack, 0, 1000)
+	s.setOneOf("Plugin.FailureAction", "plugin.failure_action", cfg.Plugin.FailureAction, "block", "log", "ignore")
+
+	s.setInt("Log.MaxStack", "log.maxstack", cfg.Log.MaxStack, 0, 1000)
+	s.setInt("Log.MaxBurst", "log.maxburst", cfg.Log.MaxBurst, 0, 1<<20)
+	s.setInt("Log.MaxBackup", "log.maxbackup", cfg.Log.MaxBackup, 0, 1<<20)
+	s.setOneOf("Log.Format", "log.format", cfg.Log.Format, "json", "cef", "leef")
+	s.setOneOf("Log.Output", "log.output", cfg.Log.Output, "file", "stdout")
+
+	if cfg.Block.StatusCode != 0 {
+		s.setInt("Block.StatusCode", "block.status_code", &cfg.Block.StatusCode, 100, 599)
+	}
+	if len(cfg.Block.RedirectURL) > 0 {
+		u, err := url.Parse(strings.Replace(cfg.Block.RedirectURL, "%request_id%", "0", -1))
+		if err != nil {
+			s.invalid("Block.RedirectURL", cfg.Block.RedirectURL, err.Error())
+		} else if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			s.invalid("Block.RedirectURL", cfg.Block.RedirectURL, "must be an absolute http or https url")
+		} else {
+			s.set("block.redirect_url", cfg.Block.RedirectURL)
+		}
+	}
+	s.setString("block.content_json", cfg.Block.ContentJSON)
+	s.setString("block.content_xml", cfg.Block.ContentXML)
+	s.setString("block.content_html", cfg.Block.ContentHTML)
+
+	s.setBool("syslog.enable", cfg.Syslog.Enable)
+	if len(cfg.Syslog.URL) > 0 {
+		u, err := url.Parse(cfg.Syslog.URL)
+		if err != nil {
+			s.invalid("Syslog.URL", cfg.Syslog.URL, err.Error())
+		} else if u.Scheme != "udp" && u.Scheme != "tcp" && u.Scheme != "unix" {
+			s.invalid("Syslog.URL", cfg.Syslog.URL, "scheme must be udp, tcp or unix")
+		} else {
+			s.set("syslog.url", cfg.Syslog.URL)
+		}
+	}
+	s.setString("syslog.tag", cfg.Syslog.Tag)
+	s.setInt("Syslog.Facility", "syslog.facility", cfg.Syslog.Facility, 0, 23)
+
+	if cfg.Hook.Disabled != nil {
+		if s.checkTypes("Hook.Disabled", cfg.Hook.Disabled) {
+			s.set("hook.disabled", cfg.Hook.Disabled)
+		}
+	}
+	if cfg.Hook.White != nil {
+		white := make(map[string]interface{}, len(cfg.Hook.White))
+		valid := true
+		for prefix, types := range cfg.Hook.White {
+			valid = s.checkTypes("Hook.White["+prefix+"]", types) && valid
+			list := make([]interface{}, 0, len(types))
+			for _, t := range types {
+				list = append(list, t)
+			}
+			white[prefix] = list
+		}
+		if valid {
+			s.set("hook.white", white)
+		}
+	}
+
+	s.setBool("security.enforce_policy", cfg.Security.EnforcePolicy)
+	s.setBool("security.env_baseline", cfg.Security.EnvBaseline)
+	s.setString("clientip.header", cfg.ClientIPHeader)
+	s.setInt("BodyMaxBytes", "body.maxbytes", cfg.BodyMaxBytes, 0, 1<<30)
+	s.setBool("detect.dry_run", cfg.DryRun)
+
+	if len(s.errors) > 0 {
+		return nil, fmt.Errorf("invalid config, %s", strings.Join(s.errors, "; "))
+	}
+	return s.values, nil
+}
+
+type settings struct {
+	values map[string]interface{}
+	errors []string
+}
+
+func (s *settings) invalid(field string, value interface{}, reason string) {
+	s.errors = append(s.errors, fmt.Sprintf("%s %v %s", field, value, reason))
+}
+
+func (s *settings) set(key string, value interface{}) {
+	s.values[key] = value
+}
+
+func (s *settings) setString(key, value string) {
+	if len(value) > 0 {
+		s.set(key, value)
+	}
+}
+
+func (s *settings) setBool(key string, value *bool) {
+	if value != nil {
+		s.set(key, *value)
+	}
+}
+
+func (s *settings) setInt(field, key string, value *int, min, max int) {
+	if value == nil {
+		return
+	}
+	if *value < min || *value > max {
+		s.invalid(field, *value, fmt.Sprintf("is out of range [%d, %d]", min, max))
+		return
+	}
+	s.set(key, *value)
+}
+
+func (s *settings) setOneOf(field, key, value string, allowed ...string) {
+	if len(value) == 0 {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			s.set(key, value)
+			return
+		}
+	}
+	s.invalid(field, value, "must be one of "+strings.Join(allowed, ", "))
+}
+
+func (s *settings) checkTypes(field string, types []string) bool {
+	valid := true
+	for _, t := range types {
+		if common.CheckStringToType(t) == common.InvalidType {
+			s.invalid(field, t, "is not a check type")
+			valid = false
+		}
+	}
+	return valid
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigSettings(t *testing.T) {
+	cfg := &Config{
+		Plugin: PluginConfig{Timeout: 200 * time.Millisecond, MaxStack: Int(0), FailureAction: "block"},
+		Block:  BlockConfig{StatusCode: 403, RedirectURL: "https://example.com/blocked?id=%request_id%"},
+		Syslog: SyslogConfig{Enable: Bool(false), URL: "udp://127.0.0.1:514", Facility: Int(0)},
+		Hook: HookConfig{
+			Disabled: []string{"sql_exception"},
+			White:    map[string][]string{"example.com/health": {"all"}},
+		},
+		DryRun: Bool(true),
+	}
+	settings, err := cfg.Settings()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"plugin.timeout.millis": int64(200),
+		"plugin.maxstack":       0,
+		"plugin.failure_action": "block",
+		"block.status_code":     403,
+		"block.redirect_url":    "https://example.com/blocked?id=%request_id%",
+		"syslog.enable":         false,
+		"syslog.url":            "udp://127.0.0.1:514",
+		"syslog.facility":       0,
+		"hook.disabled":         []string{"sql_exception"},
+		"hook.white":            map[string]interface{}{"example.com/health": []interface{}{"all"}},
+		"detect.dry_run":        true,
+	}, settings)
+
+	gc := NewGeneralConfig()
+	assert.NoError(t, gc.Update(settings))
+	assert.Equal(t, 200, gc.GetInt("plugin.timeout.millis"))
+	assert.Contains(t, gc.GetStringMap("hook.white"), "example.com/health")
+
+	cfg = &Config{
+		Plugin: PluginConfig{Timeout: time.Hour, FailureAction: "drop"},
+		Log:    LogConfig{MaxStack: Int(-1), Format: "xml"},
+		Block:  BlockConfig{StatusCode: 42, RedirectURL: "/blocked"},
+		Syslog: SyslogConfig{URL: "http://127.0.0.1"},
+		Hook:   HookConfig{Disabled: []string{"sqli"}},
+	}
+	_, err = cfg.Settings()
+	if assert.Error(t, err) {
+		for _, field := range []string{"Plugin.Timeout", "Plugin.FailureAction", "Log.MaxStack", "Log.Format", "Block.StatusCode", "Block.RedirectURL", "Syslog.URL", "Hook.Disabled sqli"} {
+			assert.Contains(t, err.Error(), field)
+		}
+	}
+
+	settings, err = (&Config{}).Settings()
+	assert.NoError(t, err)
+	assert.Empty(t, settings)
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// rangeChecks bound the values which would break the agent when out of range
+var rangeChecks = map[string][2]int64{
+	"block.status_code":                 {100, 599},
+	"challenge.status_code":             {100, 599},
+	"challenge.ttl":                     {1, 30 * 24 * 3600},
+	"plugin.timeout.millis":             {1, 60 * 1000},
+	"plugin.maxstack":                   {0, 1000},
+	"log.maxstack":                      {0, 1000},
+	"log.source_code.lines":             {0, 50},
+	"log.maxburst":                      {0, 1 << 20},
+	"detect.async.workers":              {1, 256},
+	"detect.async.queue_size":           {0, 1 << 16},
+	"latency.budget_micros":             {0, 60 * 1000 * 1000},
+	"latency.budget_window":             {1, 1 << 20},
+	"sampling.percent":                  {0, 100},
+	"sampling.flagged_ttl":              {1, 30 * 24 * 3600},
+	"sampling.flagged_max_size":         {0, 1 << 20},
+	"statsd.port":                       {1, 65535},
+	"statsd.interval":                   {1, 3600},
+	"admin.recent_size":                 {1, 10000},
+	"graphql.max_depth":                 {0, 256},
+	"graphql.max_complexity":            {0, 1 << 28},
+	"graphql.max_bytes":                 {1, 1 << 30},
+	"jwt.retry_threshold":               {0, 1 << 20},
+	"jwt.retry_window":                  {1, 24 * 3600},
+	"brute_force.window":                {1, 24 * 3600},
+	"brute_force.max_failures_per_ip":   {0, 1 << 20},
+	"brute_force.max_failures_per_user": {0, 1 << 20},
+	"brute_force.deny_ttl":              {0, 30 * 24 * 3600},
+	"data_leak.max_bytes":               {1, 1 << 30},
+	"data_leak.dump_items":              {0, 1 << 20},
+}
+
+// floatRangeChecks bound the float values as rangeChecks do the integers
+var floatRangeChecks = map[string][2]float64{
+	"statsd.sample_rate": {0.001, 1},
+}
+
+// enumChecks list the values a setting accepts
+var enumChecks = map[string][]string{
+	"log.privacy.body":      {"keep", "truncate", "hash", "drop"},
+	"log.privacy.query":     {"keep", "truncate", "hash", "drop"},
+	"log.privacy.client_ip": {"keep", "truncate", "hash", "drop"},
+}
+
+// validate checks that every value of config can be read as the type of
+// its default, keys without a default are accepted as is
+func validate(defaults *viper.Viper, config map[string]interface{}) error {
+	for key, value := range config {
+		if err := validateValue(key, defaults.Get(key), value); err != nil {
+			return fmt.Errorf("invalid value %v of %s, %v", value, key, err)
+		}
+	}
+	return nil
+}
+
+func validateValue(key string, def, value interface{}) error {
+	var err error
+	switch def.(type) {
+	case bool:
+		_, err = cast.ToBoolE(value)
+	case int, int64:
+		var i int64
+		i, err = cast.ToInt64E(value)
+		if bounds, ok := rangeChecks[key]; ok && err == nil && (i < bounds[0] || i > bounds[1]) {
+			err = fmt.Errorf("out of range [%d, %d]", bounds[0], bounds[1])
+		}
+	case float64:
+		var f float64
+		f, err = cast.ToFloat64E(value)
+		if bounds, ok := floatRangeChecks[key]; ok && err == nil && (f < bounds[0] || f > bounds[1]) {
+			err = fmt.Errorf("out of range [%g, %g]", bounds[0], bounds[1])
+		}
+	case string:
+		var s string
+		s, err = cast.ToStringE(value)
+		if values, ok := enumChecks[key]; ok && err == nil && !contains(values, s) {
+			err = fmt.Errorf("not one of %v", values)
+		}
+	case []string:
+		_, err = cast.ToStringSliceE(value)
+	}
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package openrasp
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// configFiles are read from the conf directory in order, the keys of a file
+// override the ones of the files before
+var configFiles = []string{"openrasp.yml", "openrasp.yaml", "openrasp.toml", "rasp.properties"}
+
+// configPaths lists the config files of dir
+func configPaths(dir string) []string {
+	paths := make([]string, 0, len(configFiles))
+	for _, name := range configFiles {
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths
+}
+
+type ConfigChangeParam struct {
+	Files   []string `json:"files"`
+	Changed []string `json:"changed"`
+	Restart []string `json:"restart"`
+}
+
+// ConfigReloader re-applies the config files of the conf directory to the
+// basic and general config when they are written, each reload which changes
+// something is recorded by a policy log listing the changed keys
+type ConfigReloader struct {
+	confDir     string
+	reloadTimer *time.Timer
+	mu          sync.Mutex
+}
+
+func NewConfigReloader(confDir string) *ConfigReloader {
+	return &ConfigReloader{
+		confDir: confDir,
+	}
+}
+
+func (cr *ConfigReloader) paths() []string {
+	return configPaths(cr.confDir)
+}
+
+// Load reads the general config on start
+func (cr *ConfigReloader) Load() {
+	if _, err := GetGeneral().LoadFiles(cr.paths()...); err != nil {
+		GetLog().RaspWarn("Unable to load config, cuz of "+err.Error(), orlog.Config)
+	}
+}
+
+func (cr *ConfigReloader) OnUpdate(absPath string) {
+	name := filepath.Base(absPath)
+	for _, configFile := range configFiles {
+		if name == configFile {
+			cr.mu.Lock()
+			defer cr.mu.Unlock()
+			if cr.reloadTimer != nil {
+				cr.reloadTimer.Stop()
+			}
+			cr.reloadTimer = time.AfterFunc(reloadDelay, cr.Reload)
+			return
+		}
+	}
+}
+
+// Reload applies the config files, the running config is kept when a file
+// is malformed or holds an invalid value
+func (cr *ConfigReloader) Reload() {
+	defer Recover()
+	paths := cr.paths()
+	restart, err := GetBasic().LoadFiles(paths...)
+	if err != nil {
+		GetLog().RaspWarn("Unable to reload config, cuz of "+err.Error(), orlog.Config)
+		return
+	}
+	changed, err := GetGeneral().LoadFiles(paths...)
+	if err != nil {
+		GetLog().RaspWarn("Unable to reload config, cuz of "+err.Error(), orlog.Config)
+		return
+	}
+	changed = subtract(changed, restart)
+	if len(changed) == 0 && len(restart) == 0 {
+		return
+	}
+	message := "Configuration reloaded"
+	if len(changed) > 0 {
+		message += " - changed " + strings.Join(changed, ", ")
+	}
+	if len(restart) > 0 {
+		message += " - " + strings.Join(restart, ", ") + " take effect after a restart"
+	}
+	GetLog().RaspInfo(message, orlog.Config)
+	policyResult := model.NewPolicyResult(message, 3015)
+	if !GetHookSwitch().PolicyEnabled(policyResult.PolicyId) {
+		return
+	}
+	configChangeParam := &ConfigChangeParam{
+		Files:   configFiles,
+		Changed: changed,
+		Restart: restart,
+	}
+	if policyLogString := NewPolicyLog(policyResult, configChangeParam).String(); len(policyLogString) > 0 {
+		GetLog().PolicyInfo(policyLogString)
+	}
+}
+
+func subtract(keys, removed []string) []string {
+	var result []string
+	for _, key := range keys {
+		found := false
+		for _, r := range removed {
+			if key == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, key)
+		}
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package openrasp
+
+import (
+	"errors"
+
+	"github.com/baidu-security/openrasp-golang/config"
+)
+
+// Config is the typed form of the general settings, the nested types are
+// declared in package config
+type Config config.Config
+
+// Bool returns a pointer to b for the switches of Config
+func Bool(b bool) *bool {
+	return config.Bool(b)
+}
+
+// Int returns a pointer to i for the numbers of Config
+func Int(i int) *int {
+	return config.Int(i)
+}
+
+// Configure validates cfg and applies the fields it sets on top of the
+// current settings, nothing changes when a field is invalid. The settings
+// set here stay over the config the cloud pushes later and over the config
+// files when they are reloaded, unless a cloud command sets the same keys,
+// and environment variables win over them.
+func Configure(cfg Config) error {
+	if GetGeneral() == nil {
+		return errors.New("openrasp is not initialized")
+	}
+	settings, err := (*config.Config)(&cfg).Settings()
+	if err != nil {
+		return err
+	}
+	return GetGeneral().Update(settings)
+}
@@ -0,0 +1,41 @@
+package openrasp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baidu-security/openrasp-golang/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigure(t *testing.T) {
+	defer func() {
+		assert.Nil(t, Configure(Config{Plugin: config.PluginConfig{MaxStack: Int(100)}, Log: config.LogConfig{MaxStack: Int(10)}}))
+	}()
+
+	// zero is a valid stack depth
+	assert.Nil(t, Configure(Config{Plugin: config.PluginConfig{MaxStack: Int(0)}, Log: config.LogConfig{MaxStack: Int(0)}}))
+	assert.Equal(t, 0, GetGeneral().GetInt("plugin.maxstack"))
+	assert.Equal(t, 0, GetGeneral().GetInt("log.maxstack"))
+
+	// nothing changes when a field is invalid
+	err := Configure(Config{Plugin: config.PluginConfig{MaxStack: Int(50)}, Log: config.LogConfig{MaxStack: Int(1001)}})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Log.MaxStack")
+	}
+	assert.Equal(t, 0, GetGeneral().GetInt("plugin.maxstack"))
+
+	// a reloaded config file does not undo Configure
+	dir, err := ioutil.TempDir("", "openrasp")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "openrasp.yml")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("plugin.maxstack: 20\nlog.maxburst: 200\n"), 0644))
+	_, err = GetGeneral().LoadFiles(file)
+	assert.Nil(t, err)
+	defer GetGeneral().LoadFiles()
+	assert.Equal(t, 0, GetGeneral().GetInt("plugin.maxstack"))
+	assert.Equal(t, 200, GetGeneral().GetInt("log.maxburst"))
+}
@@ -0,0 +1,41 @@
+package openrasp
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+var errorTokenBucket *orlog.TokenBucket
+
+func initErrorReport() {
+	errorTokenBucket = orlog.NewRateTokenBucket(uint64(GetGeneral().GetInt64("error.maxburst")), GetGeneral().GetFloat64("error.rate"))
+	orlog.SetPanicHandler(func(where string, value interface{}) {
+		ReportError(fmt.Errorf("Recovered from panic in %s, %v", where, value), orlog.Panic)
+	})
+}
+
+// ReportError writes an internal error of the agent to rasp.log, whose
+// warnings are uploaded to the error endpoint of the cloud console, errors
+// beyond error.maxburst are limited to error.rate per second
+func ReportError(err error, moduleCode orlog.ModuleCode) {
+	if GetLog() == nil {
+		log.Printf("%v", err)
+		return
+	}
+	if errorTokenBucket != nil && errorTokenBucket.Consume() {
+		return
+	}
+	GetLog().RaspWarn(err.Error(), moduleCode)
+}
+
+// Recover turns a panic inside the agent into a reported error instead of
+// crashing the application, it must be deferred directly:
+//
+//	defer openrasp.Recover()
+func Recover() {
+	if r := recover(); r != nil {
+		ReportError(fmt.Errorf("Recovered from panic, %v", r), orlog.Panic)
+	}
+}
@@ -0,0 +1,90 @@
+package openrasp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// CustomFieldsFunc returns fields such as a tenant id, a region or a build
+// sha to add to an attack or policy log, requestInfo is nil outside of a
+// request
+type CustomFieldsFunc func(requestInfo *model.RequestInfo) map[string]interface{}
+
+var (
+	customFieldsMu    sync.RWMutex
+	customFieldsFuncs []CustomFieldsFunc
+)
+
+// RegisterCustomFields adds f to the callbacks run for every attack and
+// policy log, the fields of later callbacks win
+func RegisterCustomFields(f CustomFieldsFunc) {
+	customFieldsMu.Lock()
+	defer customFieldsMu.Unlock()
+	customFieldsFuncs = append(customFieldsFuncs, f)
+}
+
+// SetCustomField adds a field to the attack and policy logs of the current
+// request, over the ones of the callbacks, it does nothing outside of a
+// request
+func SetCustomField(key string, value interface{}) {
+	if !gls.Activated() {
+		return
+	}
+	previous, _ := gls.Get("customFields").(map[string]interface{})
+	gls.Set("customFields", withField(previous, key, value))
+}
+
+// WithCustomField returns a copy of ctx whose request carries the field, ctx
+// is returned as is when it carries no request
+func WithCustomField(ctx context.Context, key string, value interface{}) context.Context {
+	rc, ok := FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	withValue := *rc
+	withValue.customFields = withField(rc.customFields, key, value)
+	return context.WithValue(ctx, requestContextKey{}, &withValue)
+}
+
+// withField copies fields, they may be shared with other goroutines
+func withField(fields map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+func currentRequestInfo() *model.RequestInfo {
+	requestInfo, _ := gls.Get("requestInfo").(*model.RequestInfo)
+	return requestInfo
+}
+
+// currentCustomFields merges the fields of the callbacks and of the current
+// request, nil is returned when there are none
+func currentCustomFields(requestInfo *model.RequestInfo) map[string]interface{} {
+	var fields map[string]interface{}
+	set := func(k string, v interface{}) {
+		if fields == nil {
+			fields = make(map[string]interface{})
+		}
+		fields[k] = v
+	}
+	customFieldsMu.RLock()
+	funcs := customFieldsFuncs
+	customFieldsMu.RUnlock()
+	for _, f := range funcs {
+		for k, v := range f(requestInfo) {
+			set(k, v)
+		}
+	}
+	requestFields, _ := gls.Get("customFields").(map[string]interface{})
+	for k, v := range requestFields {
+		set(k, v)
+	}
+	return fields
+}
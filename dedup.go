@@ -0,0 +1,70 @@
+package openrasp
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// AlarmFilter collapses identical alarms within alarm.dedup.window seconds
+// into one event carrying a hit counter, and samples distinct alarms once
+// more than alarm.sample.threshold of them are raised within a second
+type AlarmFilter struct {
+	deduplicator    *model.Deduplicator
+	sampleThreshold int
+	sampleRate      float64
+	secondStart     time.Time
+	secondCount     int
+	mu              sync.Mutex
+}
+
+func NewAlarmFilter() *AlarmFilter {
+	af := &AlarmFilter{
+		deduplicator: model.NewDeduplicator(0),
+	}
+	af.OnConfigUpdate()
+	go af.flushLoop()
+	return af
+}
+
+func (af *AlarmFilter) OnConfigUpdate() {
+	af.deduplicator.SetWindow(time.Duration(GetGeneral().GetInt64("alarm.dedup.window")) * time.Second)
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	af.sampleThreshold = GetGeneral().GetInt("alarm.sample.threshold")
+	af.sampleRate = GetGeneral().GetFloat64("alarm.sample.rate")
+}
+
+// Allow reports whether attackLog should be written now, repeated alarms are
+// counted and written once their window expires
+func (af *AlarmFilter) Allow(attackLog *model.AttackLog) bool {
+	now := time.Now()
+	if !af.deduplicator.Allow(attackLog, now) {
+		return false
+	}
+	af.mu.Lock()
+	defer af.mu.Unlock()
+	if af.sampleThreshold > 0 {
+		if now.Sub(af.secondStart) >= time.Second {
+			af.secondStart = now
+			af.secondCount = 0
+		}
+		af.secondCount++
+		if af.secondCount > af.sampleThreshold && rand.Float64() >= af.sampleRate {
+			return false
+		}
+	}
+	return true
+}
+
+func (af *AlarmFilter) flushLoop() {
+	for range time.Tick(time.Second) {
+		for _, summary := range af.deduplicator.Expired(time.Now()) {
+			if attackLogString := summary.String(); len(attackLogString) > 0 {
+				GetLog().AlarmInfoForApp(attackLogString, summary.AppId)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package openrasp
+
+import (
+	v8 "github.com/baidu-security/openrasp-v8/go"
+)
+
+// defaultPluginName is the filename of the embedded plugin in logs and stacks
+const defaultPluginName = "default"
+
+// defaultPluginSource is loaded when the plugin directory holds no
+// javascript plugin, so an agent started without any file detects the
+// common attacks driven by user input and logs them locally. Every
+// algorithm only logs, blocking is left to a plugin deployed on purpose.
+const defaultPluginSource = `
+var plugin = new RASP('default')
+
+RASP.algorithmConfig = {
+	sql_userinput: {action: 'log'},
+	sql_exception: {action: 'log'},
+	readFile_userinput: {action: 'log'},
+	writeFile_userinput: {action: 'log'},
+	ssrf_userinput: {action: 'log'}
+}
+
+function userInputs(context) {
+	var inputs = []
+	var parameter = context.parameter || {}
+	Object.keys(parameter).forEach(function (name) {
+		var values = parameter[name]
+		;(Array.isArray(values) ? values : [values]).forEach(function (value) {
+			if (typeof value === 'string' && value.length > 1) {
+				inputs.push(value)
+			}
+		})
+	})
+	return inputs
+}
+
+function findInput(context, value) {
+	var inputs = userInputs(context)
+	for (var i = 0; i < inputs.length; i++) {
+		if (value.indexOf(inputs[i]) !== -1) {
+			return inputs[i]
+		}
+	}
+}
+
+function result(algorithm, message, severity) {
+	return {
+		action: RASP.algorithmConfig[algorithm].action,
+		message: message,
+		confidence: 90,
+		severity: severity,
+		algorithm: algorithm
+	}
+}
+
+// user input which spans several sql tokens changed the structure of the query
+plugin.register('sql', function (params, context) {
+	var input = findInput(context, params.query)
+	if (!input) {
+		return
+	}
+	var start = params.query.indexOf(input)
+	var stop = start + input.length
+	var tokens = RASP.sql_tokenize(params.query, params.server)
+	var spanned = 0
+	for (var i = 0; i < tokens.length; i++) {
+		if (tokens[i].stop > start && tokens[i].start < stop) {
+			spanned++
+		}
+	}
+	if (spanned > 1) {
+		return result('sql_userinput', 'SQL injection - user input changed the query: ' + input, 'critical')
+	}
+})
+
+function traversal(algorithm, operation) {
+	return function (params, context) {
+		var input = findInput(context, params.path)
+		if (input && /(^|[\\/])\.\.([\\/]|$)/.test(input)) {
+			return result(algorithm, 'Path traversal - ' + operation + ' ' + params.realpath + ' from user input: ' + input, 'high')
+		}
+	}
+}
+
+plugin.register('readFile', traversal('readFile_userinput', 'reading'))
+plugin.register('writeFile', traversal('writeFile_userinput', 'writing'))
+
+var internalAddress = /^(127\.|10\.|192\.168\.|172\.(1[6-9]|2[0-9]|3[01])\.|169\.254\.|0\.|::1$|f[cd])/
+
+// a host given by the user resolved to an internal address
+plugin.register('ssrf', function (params, context) {
+	if (!findInput(context, params.hostname)) {
+		return
+	}
+	var ips = params.ip || []
+	for (var i = 0; i < ips.length; i++) {
+		if (internalAddress.test(ips[i])) {
+			return result('ssrf_userinput', 'SSRF - user input ' + params.hostname + ' resolved to internal address ' + ips[i], 'high')
+		}
+	}
+})
+
+plugin.log('embedded default plugin loaded')
+`
+
+// defaultPlugins returns the embedded plugin
+func defaultPlugins() []v8.Plugin {
+	return []v8.Plugin{{
+		Source:   defaultPluginSource,
+		Filename: defaultPluginName,
+	}}
+}
@@ -0,0 +1,152 @@
+package openrasp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// vulnDBFile is the advisory database read from the conf directory, which
+// an offline bundle relocates
+const vulnDBFile = "vulndb.json"
+
+// Advisory is a vulnerability of the module versions from Introduced,
+// inclusive, to Fixed, exclusive, an empty bound is open
+type Advisory struct {
+	Id         string `json:"id"`
+	Module     string `json:"module"`
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed,omitempty"`
+	Summary    string `json:"summary"`
+}
+
+func (a *Advisory) Affects(version string) bool {
+	if len(version) == 0 || version == "(devel)" {
+		return false
+	}
+	return (len(a.Introduced) == 0 || utils.CompareVersions(version, a.Introduced) >= 0) &&
+		(len(a.Fixed) == 0 || utils.CompareVersions(version, a.Fixed) < 0)
+}
+
+// bundledAdvisories are well known vulnerabilities of popular modules, the
+// database is completed by vulndb.json and security.dependency_advisories
+var bundledAdvisories = []Advisory{
+	{"GO-2023-2402", "golang.org/x/crypto", "", "v0.17.0", "Terrapin attack on the ssh transport, CVE-2023-48795"},
+	{"GO-2023-2102", "golang.org/x/net", "", "v0.17.0", "HTTP/2 rapid reset denial of service, CVE-2023-39325"},
+	{"GO-2022-1059", "golang.org/x/text", "", "v0.3.8", "Denial of service parsing language tags, CVE-2022-32149"},
+	{"GO-2020-0017", "github.com/dgrijalva/jwt-go", "", "", "Audience check bypass, CVE-2020-26160, the module is unmaintained"},
+	{"GO-2020-0019", "github.com/gorilla/websocket", "", "v1.4.1", "Integer overflow leading to denial of service, CVE-2020-27813"},
+	{"GO-2023-1737", "github.com/gin-gonic/gin", "", "v1.9.1", "Improper filename sanitization in Context.FileAttachment, CVE-2023-29401"},
+	{"GO-2020-0036", "gopkg.in/yaml.v2", "", "v2.2.8", "Excessive resource consumption parsing yaml, CVE-2019-11254"},
+}
+
+// DependencyParam is a module built into the binary which has a known
+// vulnerability
+type DependencyParam struct {
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Advisory string `json:"advisory"`
+	Fixed    string `json:"fixed,omitempty"`
+	Summary  string `json:"summary"`
+}
+
+func (dp *DependencyParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	message := "Dependency security - " + dp.Module + "@" + dp.Version + " is affected by " + dp.Advisory
+	if len(dp.Fixed) > 0 {
+		message += ", fixed in " + dp.Fixed
+	}
+	return model.Log, model.NewPolicyResult(message, 3020)
+}
+
+// DependencyScanner compares the modules of the build info against the
+// advisories whenever the config changes, an affected module is reported
+// once per advisory
+type DependencyScanner struct {
+	mu       sync.Mutex
+	reported map[string]bool
+}
+
+func NewDependencyScanner() *DependencyScanner {
+	ds := &DependencyScanner{reported: make(map[string]bool)}
+	ds.OnConfigUpdate()
+	return ds
+}
+
+func (ds *DependencyScanner) OnConfigUpdate() {
+	if !GetGeneral().GetBool("security.dependency_check") {
+		return
+	}
+	modules, ok := readModules()
+	if !ok {
+		return
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for _, dependencyParam := range affectedModules(modules, loadAdvisories()) {
+		key := dependencyParam.Advisory + "|" + dependencyParam.Module
+		if ds.reported[key] {
+			continue
+		}
+		ds.reported[key] = true
+		interceptCode, policyResult := dependencyParam.PolicyCheck()
+		if GetHookSwitch().FilterPolicy(interceptCode, policyResult) == model.Ignore {
+			continue
+		}
+		if policyLogString := NewPolicyLog(policyResult, dependencyParam).String(); len(policyLogString) > 0 {
+			GetLog().PolicyInfo(policyLogString)
+		}
+	}
+}
+
+// loadAdvisories merges the bundled advisories with the ones of vulndb.json
+// and of the cloud
+func loadAdvisories() []Advisory {
+	advisories := append([]Advisory(nil), bundledAdvisories...)
+	if confDir, err := GetWorkSpace().GetDir(common.Conf); err == nil {
+		if b, err := ioutil.ReadFile(filepath.Join(confDir, vulnDBFile)); err == nil {
+			advisories = appendAdvisories(advisories, b, vulnDBFile)
+		}
+	}
+	if cloudAdvisories := GetGeneral().GetString("security.dependency_advisories"); len(strings.TrimSpace(cloudAdvisories)) > 0 {
+		advisories = appendAdvisories(advisories, []byte(cloudAdvisories), "security.dependency_advisories")
+	}
+	return advisories
+}
+
+func appendAdvisories(advisories []Advisory, b []byte, source string) []Advisory {
+	var more []Advisory
+	if err := json.Unmarshal(b, &more); err != nil {
+		GetLog().RaspWarn("Unable to read advisories of "+source+", cuz of "+err.Error(), orlog.Config)
+		return advisories
+	}
+	return append(advisories, more...)
+}
+
+func affectedModules(modules map[string]string, advisories []Advisory) []*DependencyParam {
+	var affected []*DependencyParam
+	for _, advisory := range advisories {
+		version, ok := modules[advisory.Module]
+		if !ok || !advisory.Affects(version) {
+			continue
+		}
+		affected = append(affected, &DependencyParam{
+			Module:   advisory.Module,
+			Version:  version,
+			Advisory: advisory.Id,
+			Fixed:    advisory.Fixed,
+			Summary:  advisory.Summary,
+		})
+	}
+	sort.Slice(affected, func(i, j int) bool {
+		return affected[i].Module < affected[j].Module
+	})
+	return affected
+}
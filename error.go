@@ -1,9 +1,45 @@
 package openrasp
 
-import (
-	"errors"
-)
-
 var (
-	ErrBlock = errors.New("Blocked by OpenRASP")
+	ErrBlock error = &blockError{}
 )
+
+type blockError struct{}
+
+func (*blockError) Error() string {
+	return "Blocked by OpenRASP"
+}
+
+// Abort lets gls.Go end a spawned goroutine which is blocked without
+// crashing the process
+func (*blockError) Abort() {}
+
+// Protect runs f and returns ErrBlock when a hook blocked it, for background
+// workers and cron jobs which have no recovery layer like orhttp. Any other
+// panic is passed on.
+func Protect(f func()) (err error) {
+	defer RecoverBlock(&err)
+	f()
+	return nil
+}
+
+// RecoverBlock turns a panic with ErrBlock into *err, it must be deferred
+// directly:
+//
+//	defer openrasp.RecoverBlock(&err)
+func RecoverBlock(err *error) {
+	if r := recover(); r != nil {
+		if r != ErrBlock {
+			panic(r)
+		}
+		*err = ErrBlock
+	}
+}
+
+// Block interrupts the current request through its blocker, which panics,
+// and returns ErrBlock for the hook to return when there is no request to
+// interrupt, such as in a background goroutine
+func Block() error {
+	BlockRequest()
+	return ErrBlock
+}
@@ -0,0 +1,43 @@
+package openrasp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtect(t *testing.T) {
+	assert.Equal(t, ErrBlock, Protect(func() {
+		panic(ErrBlock)
+	}))
+	ran := false
+	assert.Nil(t, Protect(func() {
+		ran = true
+	}))
+	assert.True(t, ran)
+
+	other := errors.New("boom")
+	assert.PanicsWithValue(t, other, func() {
+		Protect(func() {
+			panic(other)
+		})
+	})
+	assert.PanicsWithValue(t, "boom", func() {
+		Protect(func() {
+			panic("boom")
+		})
+	})
+}
+
+func TestRecoverBlock(t *testing.T) {
+	job := func(block bool) (err error) {
+		defer RecoverBlock(&err)
+		if block {
+			panic(ErrBlock)
+		}
+		return errors.New("done")
+	}
+	assert.Equal(t, ErrBlock, job(true))
+	assert.EqualError(t, job(false), "done")
+}
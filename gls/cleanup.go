@@ -0,0 +1,135 @@
+package gls
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	tracking int32
+	removed  uint64
+)
+
+// Stats describes the local storages in use
+type Stats struct {
+	// Live is the number of goroutines holding a local storage
+	Live int
+	// Oldest is the age of the oldest storage created while tracking
+	Oldest time.Duration
+	// Removed counts the storages removed by Cleanup so far
+	Removed uint64
+}
+
+// SetTracking records the creation time of the storages initialized from
+// now on, so GetStats reports how old the oldest one is
+func SetTracking(enable bool) {
+	if enable {
+		atomic.StoreInt32(&tracking, 1)
+	} else {
+		atomic.StoreInt32(&tracking, 0)
+	}
+}
+
+// GetStats reports the storages in use
+func GetStats() Stats {
+	stats := Stats{Removed: atomic.LoadUint64(&removed)}
+	oldest := int64(0)
+	for i := range gShards {
+		sp := &gShards[i]
+		sp.mu.RLock()
+		stats.Live += len(sp.items)
+		for _, item := range sp.items {
+			if item.created > 0 && (oldest == 0 || item.created < oldest) {
+				oldest = item.created
+			}
+		}
+		sp.mu.RUnlock()
+	}
+	if oldest > 0 {
+		stats.Oldest = time.Since(time.Unix(0, oldest))
+	}
+	return stats
+}
+
+// Cleanup removes the storages of goroutines which exited without Clear and
+// returns how many were removed. Listing the goroutines stops the world, so
+// it is meant to run every few minutes at most.
+func Cleanup() int {
+	var ids []int64
+	for i := range gShards {
+		sp := &gShards[i]
+		sp.mu.RLock()
+		for id := range sp.items {
+			ids = append(ids, id)
+		}
+		sp.mu.RUnlock()
+	}
+	if len(ids) == 0 {
+		return 0
+	}
+	// the storages were listed first and goroutine ids are never reused, so
+	// a goroutine missing from the later dump has exited
+	live := liveGoroutines()
+	count := 0
+	for _, id := range ids {
+		if !live[id] {
+			removeGls(id)
+			count++
+		}
+	}
+	atomic.AddUint64(&removed, uint64(count))
+	return count
+}
+
+// StartCleanup runs Cleanup every interval until stop is called, report
+// receives the number of storages removed by each run which removed some
+func StartCleanup(interval time.Duration, report func(count int)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if count := Cleanup(); count > 0 && report != nil {
+					report(count)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+// liveGoroutines parses the ids out of a dump of all goroutines
+func liveGoroutines() map[int64]bool {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	live := make(map[int64]bool)
+	prefix := []byte("goroutine ")
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		if !bytes.HasPrefix(line, prefix) {
+			continue
+		}
+		line = line[len(prefix):]
+		if i := bytes.IndexByte(line, ' '); i > 0 {
+			if id, err := strconv.ParseInt(string(line[:i]), 10, 64); err == nil {
+				live[id] = true
+			}
+		}
+	}
+	return live
+}
@@ -3,17 +3,37 @@ package gls
 import (
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/baidu-security/openrasp-golang/goid"
 )
 
-type shardItemType map[int64]map[interface{}]interface{}
+// slot is the local storage of a goroutine, created is only recorded while
+// tracking is on
+type slot struct {
+	values  map[interface{}]interface{}
+	created int64
+}
+
+type shardItemType map[int64]slot
+
+// cacheLineSize pads the shards, so locking one does not invalidate the
+// cache line of its neighbours
+const cacheLineSize = 64
 
-var shardsCount int64 = 1 << 4
+// minShardsCount keeps contention low on machines with few cpus, where
+// thousands of requests are still in flight at once
+const minShardsCount = 64
+
+// goroutine ids are handed out sequentially, so the low bits spread the
+// goroutines evenly over the shards and a mask picks the shard
+var shardsMask int64
 
 type shardPair struct {
 	mu    sync.RWMutex
 	items shardItemType
+	_     [cacheLineSize]byte
 }
 
 var gShards []shardPair
@@ -31,9 +51,13 @@ func (sp *shardPair) setShardItem(goid int64, value map[interface{}]interface{})
 	if sp == nil {
 		return
 	}
+	var created int64
+	if atomic.LoadInt32(&tracking) != 0 {
+		created = time.Now().UnixNano()
+	}
 	sp.mu.Lock()
 	defer sp.mu.Unlock()
-	sp.items[goid] = value
+	sp.items[goid] = slot{values: value, created: created}
 }
 
 func (sp *shardPair) getShardItem(goid int64) map[interface{}]interface{} {
@@ -41,13 +65,9 @@ func (sp *shardPair) getShardItem(goid int64) map[interface{}]interface{} {
 		return nil
 	}
 	sp.mu.RLock()
-	defer sp.mu.RUnlock()
-	gls, found := sp.items[goid]
-	if found {
-		return gls
-	} else {
-		return nil
-	}
+	gls := sp.items[goid]
+	sp.mu.RUnlock()
+	return gls.values
 }
 
 func (sp *shardPair) removeShardItem(goid int64) {
@@ -59,32 +79,41 @@ func (sp *shardPair) removeShardItem(goid int64) {
 	delete(sp.items, goid)
 }
 
+// shardsCountFor returns the power of two at or above 16 shards per cpu
+func shardsCountFor(cpus int) int64 {
+	count := int64(minShardsCount)
+	for count < int64(cpus)*16 {
+		count <<= 1
+	}
+	return count
+}
+
 func init() {
-	shardsCount = int64(runtime.NumCPU()) * 4
+	shardsCount := shardsCountFor(runtime.NumCPU())
+	shardsMask = shardsCount - 1
 	gShards = make([]shardPair, shardsCount)
 	for i := int64(0); i < shardsCount; i++ {
 		gShards[i].initShardItem()
 	}
 }
 
+func shardOf(goid int64) *shardPair {
+	return &gShards[goid&shardsMask]
+}
+
 // getGls get local storage for specified goroutine
 func getGls(goid int64) map[interface{}]interface{} {
-	shardIndex := goid % shardsCount
-	return gShards[shardIndex].getShardItem(goid)
+	return shardOf(goid).getShardItem(goid)
 }
 
-//setGls set local storage for specified goroutine
+// setGls set local storage for specified goroutine
 func setGls(goid int64, value map[interface{}]interface{}) {
-	shardIndex := goid % shardsCount
-	gShards[shardIndex].setShardItem(goid, value)
-	return
+	shardOf(goid).setShardItem(goid, value)
 }
 
 // removeGls remove local storage for specified goroutine
 func removeGls(goid int64) {
-	shardIndex := goid % shardsCount
-	gShards[shardIndex].removeShardItem(goid)
-	return
+	shardOf(goid).removeShardItem(goid)
 }
 
 // glsActivated return whether gls is activated for specified goroutine
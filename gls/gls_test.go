@@ -3,6 +3,9 @@ package gls
 import (
 	"sync"
 	"testing"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/goid"
 )
 
 func TestGls(t *testing.T) {
@@ -38,3 +41,187 @@ func TestGls(t *testing.T) {
 		t.Errorf("parent gls should be not activated")
 	}
 }
+
+type abort struct{}
+
+func (abort) Abort() {}
+
+func TestGo(t *testing.T) {
+	Inherit("request")
+	done := make(chan bool)
+	Go(func() {
+		if Activated() {
+			t.Errorf("gls should not be activated without a parent gls")
+		}
+		done <- true
+	})
+	<-done
+
+	Initialize()
+	defer Clear()
+	Set("request", "r1")
+	Set("local", "l1")
+	Go(func() {
+		defer func() {
+			done <- true
+		}()
+		if "r1" != Get("request") {
+			t.Errorf("the value of key 'request' should be inherited")
+		}
+		if nil != Get("local") {
+			t.Errorf("key 'local' should not be inherited")
+		}
+		Set("request", "r2")
+	})
+	<-done
+	if "r1" != Get("request") {
+		t.Errorf("the parent value should not be changed by the child")
+	}
+
+	Go(func() {
+		defer func() {
+			done <- true
+		}()
+		panic(abort{})
+	})
+	<-done
+}
+
+func TestShardsCount(t *testing.T) {
+	if count := shardsCountFor(1); count != minShardsCount {
+		t.Errorf("shards count of 1 cpu should be %d, got %d", minShardsCount, count)
+	}
+	if count := shardsCountFor(6); count != 128 {
+		t.Errorf("shards count of 6 cpus should be 128, got %d", count)
+	}
+}
+
+// benchmarkRequests runs the gls calls of a request in concurrent goroutines
+func benchmarkRequests(b *testing.B, concurrency int) {
+	b.ReportAllocs()
+	per := b.N/concurrency + 1
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			<-start
+			for n := 0; n < per; n++ {
+				Initialize()
+				Set("requestInfo", n)
+				for k := 0; k < 8; k++ {
+					Get("requestInfo")
+				}
+				Clear()
+			}
+		}()
+	}
+	b.ResetTimer()
+	close(start)
+	wg.Wait()
+}
+
+func BenchmarkRequests100(b *testing.B) {
+	benchmarkRequests(b, 100)
+}
+
+func BenchmarkRequests10k(b *testing.B) {
+	benchmarkRequests(b, 10000)
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		Initialize()
+		defer Clear()
+		Set("requestInfo", 1)
+		for pb.Next() {
+			Get("requestInfo")
+		}
+	})
+}
+
+func TestCleanup(t *testing.T) {
+	SetTracking(true)
+	defer SetTracking(false)
+	before := GetStats()
+	done := make(chan bool)
+	leaked := make(chan bool)
+	go func() {
+		Initialize()
+		leaked <- true
+	}()
+	<-leaked
+	go func() {
+		Initialize()
+		defer Clear()
+		leaked <- true
+		<-done
+	}()
+	<-leaked
+	stats := GetStats()
+	if stats.Live != before.Live+2 {
+		t.Errorf("2 more storages should be live, got %d and %d", before.Live, stats.Live)
+	}
+	if stats.Oldest <= 0 {
+		t.Errorf("the age of the oldest storage should be tracked")
+	}
+	// the first goroutine may still be exiting
+	for i := 0; i < 100 && Cleanup() == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	stats = GetStats()
+	if stats.Live != before.Live+1 {
+		t.Errorf("the storage of the exited goroutine should be removed, %d live", stats.Live)
+	}
+	if stats.Removed != before.Removed+1 {
+		t.Errorf("1 storage should be removed, got %d", stats.Removed-before.Removed)
+	}
+	close(done)
+}
+
+func TestParents(t *testing.T) {
+	Initialize()
+	defer Clear()
+	request := goid.GoIDAsm()
+	done := make(chan []int64)
+	Go(func() {
+		worker := goid.GoIDAsm()
+		Go(func() {
+			done <- append(Parents(), worker)
+		})
+	})
+	ids := <-done
+	if len(ids) != 3 || ids[1] != request || ids[0] != ids[2] {
+		t.Errorf("unexpected parents %v of request goroutine %d", ids, request)
+	}
+	if len(Parents()) != 0 {
+		t.Errorf("the request goroutine has no parent")
+	}
+}
+
+func TestRunWith(t *testing.T) {
+	if Capture() != nil {
+		t.Errorf("nothing is captured without gls")
+	}
+	Inherit("request")
+	Initialize()
+	Set("request", "r1")
+	Set("local", "l1")
+	values := Capture()
+	Clear()
+
+	done := make(chan bool)
+	go func() {
+		RunWith(values, func() {
+			if "r1" != Get("request") || nil != Get("local") {
+				t.Errorf("only the inherited values should be captured")
+			}
+		})
+		if Activated() {
+			t.Errorf("gls should be cleared after RunWith")
+		}
+		done <- true
+	}()
+	<-done
+}
@@ -0,0 +1,85 @@
+package gls
+
+import (
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/goid"
+)
+
+// Aborter is implemented by panic values which are meant to end the
+// goroutine only, Go recovers them instead of crashing the process
+type Aborter interface {
+	Abort()
+}
+
+var (
+	inheritedMu sync.RWMutex
+	inherited   []interface{}
+)
+
+// parentsKey holds the goroutines which spawned the current one through Go
+type parentsKey struct{}
+
+// Parents returns the ids of the goroutines which spawned the current one
+// through Go, from its parent up to the goroutine which started the chain
+func Parents() []int64 {
+	parents, _ := Get(parentsKey{}).([]int64)
+	return parents
+}
+
+// Inherit marks keys whose values Go copies into spawned goroutines
+func Inherit(keys ...interface{}) {
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+	inherited = append(inherited, keys...)
+}
+
+// Go runs f in a new goroutine whose local storage starts with the inherited
+// values of the current goroutine, f runs without local storage when the
+// current goroutine has none
+func Go(f func()) {
+	values := Capture()
+	if values == nil {
+		go f()
+		return
+	}
+	go RunWith(values, f)
+}
+
+// Capture returns the inherited values of the current goroutine, which Go
+// copies into a spawned goroutine and RunWith installs on a running one, nil
+// when the current goroutine has no local storage
+func Capture() map[interface{}]interface{} {
+	id := goid.GoIDAsm()
+	localMap := getGls(id)
+	if localMap == nil {
+		return nil
+	}
+	values := make(map[interface{}]interface{})
+	inheritedMu.RLock()
+	for _, key := range inherited {
+		if value, ok := localMap[key]; ok {
+			values[key] = value
+		}
+	}
+	inheritedMu.RUnlock()
+	parents, _ := localMap[parentsKey{}].([]int64)
+	values[parentsKey{}] = append([]int64{id}, parents...)
+	return values
+}
+
+// RunWith runs f with values as the local storage of the current goroutine
+// and clears it afterwards, a panic of an Aborter only ends f. values must
+// not be shared with another goroutine.
+func RunWith(values map[interface{}]interface{}, f func()) {
+	setGls(goid.GoIDAsm(), values)
+	defer Clear()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(Aborter); !ok {
+				panic(r)
+			}
+		}
+	}()
+	f()
+}
@@ -0,0 +1,50 @@
+package openrasp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// GlsMonitor removes the goroutine local storages leaked by goroutines which
+// exited without gls.Clear every gls.cleanup_interval seconds, 0 turns it
+// off. gls.tracking records the age of the storages for Stats.
+type GlsMonitor struct {
+	interval int64
+	stop     func()
+	mu       sync.Mutex
+}
+
+func NewGlsMonitor() *GlsMonitor {
+	gm := &GlsMonitor{}
+	gm.OnConfigUpdate()
+	return gm
+}
+
+func (gm *GlsMonitor) OnConfigUpdate() {
+	gls.SetTracking(GetGeneral().GetBool("gls.tracking"))
+	interval := GetGeneral().GetInt64("gls.cleanup_interval")
+	gm.mu.Lock()
+	defer gm.mu.Unlock()
+	if interval == gm.interval {
+		return
+	}
+	if gm.stop != nil {
+		gm.stop()
+		gm.stop = nil
+	}
+	gm.interval = interval
+	if interval > 0 {
+		gm.stop = gls.StartCleanup(time.Duration(interval)*time.Second, func(count int) {
+			GetLog().RaspWarn(fmt.Sprintf("%d goroutine local storages leaked by exited goroutines are removed, a gls.Clear is missing", count), orlog.Runtime)
+		})
+	}
+}
+
+// Stats reports the goroutine local storages in use
+func (gm *GlsMonitor) Stats() gls.Stats {
+	return gls.GetStats()
+}
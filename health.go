@@ -0,0 +1,156 @@
+package openrasp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// Statuses of a HealthCheck
+const (
+	// HealthOK means the agent is protecting the application
+	HealthOK = "ok"
+	// HealthDegraded means the agent protects the application but some of
+	// its logs or its link to the cloud console suffer
+	HealthDegraded = "degraded"
+	// HealthDown means the agent does not protect the application
+	HealthDown = "down"
+)
+
+// queueHealthyRatio is the share of a log queue above which the logs are
+// about to be dropped
+const queueHealthyRatio = 0.9
+
+// Health is the self-diagnosis of the agent, Problems explain a status
+// other than HealthOK
+type Health struct {
+	Status      string                      `json:"status"`
+	Problems    []string                    `json:"problems,omitempty"`
+	Initialized bool                        `json:"initialized"`
+	Config      ConfigHealth                `json:"config"`
+	Plugins     []PluginInfo                `json:"plugins"`
+	Cloud       *CloudHealth                `json:"cloud,omitempty"`
+	LogQueues   map[string]QueueHealth      `json:"log_queues"`
+	LogDropped  map[string]orlog.DropTotals `json:"log_dropped"`
+	Hooks       []HookHealth                `json:"hooks"`
+}
+
+// ConfigHealth tells whether the config files were loaded on start
+type ConfigHealth struct {
+	Loaded bool   `json:"loaded"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CloudHealth is the link to the cloud console, LastHeartbeat is the unix
+// time of the last answered heartbeat, 0 before the first one
+type CloudHealth struct {
+	Reachable     bool   `json:"reachable"`
+	LastHeartbeat int64  `json:"last_heartbeat"`
+	Error         string `json:"error,omitempty"`
+}
+
+// QueueHealth is how full the async queue of a log destination is
+type QueueHealth struct {
+	Depth    int `json:"depth"`
+	Capacity int `json:"capacity"`
+}
+
+// HookHealth tells whether a hook is enabled and whether the application
+// called it since the start, a hook never called is not wrapped or not
+// reached yet
+type HookHealth struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Called  bool   `json:"called"`
+}
+
+// HealthCheck reports whether the agent is actually protecting the
+// application, for readiness probes and troubleshooting
+func HealthCheck() *Health {
+	health := &Health{
+		Status:      HealthOK,
+		Initialized: IsComplete(),
+		Config:      ConfigHealth{Loaded: configErr == nil},
+		Plugins:     []PluginInfo{},
+		LogQueues:   make(map[string]QueueHealth),
+		LogDropped:  make(map[string]orlog.DropTotals),
+	}
+	if !health.Initialized {
+		health.down("the agent failed to initialize, see rasp.log")
+	}
+	if configErr != nil {
+		health.Config.Error = configErr.Error()
+		health.degrade("the config files were not loaded, the defaults are used")
+	}
+	if GetPluginManager() != nil {
+		health.Plugins = GetPluginManager().Plugins()
+	}
+	if len(health.Plugins) == 0 {
+		health.down("no plugin is loaded")
+	}
+	if CloudEnabled() && GetCloudManager() != nil {
+		health.Cloud = cloudHealth()
+		if !health.Cloud.Reachable {
+			health.degrade("the cloud console is unreachable")
+		}
+	}
+	if lm := GetLog(); lm != nil {
+		capacity := GetGeneral().GetInt("log.async.queue_size")
+		for destination, depth := range lm.QueueDepth() {
+			health.LogQueues[destination] = QueueHealth{Depth: depth, Capacity: capacity}
+			if capacity > 0 && float64(depth) >= queueHealthyRatio*float64(capacity) {
+				health.degrade(fmt.Sprintf("the log queue of %s is %d/%d full", destination, depth, capacity))
+			}
+		}
+		health.LogDropped = lm.Dropped()
+	}
+	enabled := 0
+	for _, hk := range hookKeys {
+		hook := HookHealth{
+			Name:    hk.key,
+			Enabled: GetHookSwitch().HookEnabled(hk.hook),
+			Called:  hookCalled(hk.hook),
+		}
+		// the request body is read by the wrapped handler
+		if hk.hook == HookHttpBody {
+			hook.Called = hookCalled(HookHttp)
+		}
+		if hook.Enabled {
+			enabled++
+		}
+		health.Hooks = append(health.Hooks, hook)
+	}
+	if enabled == 0 {
+		health.down("every hook is disabled")
+	}
+	return health
+}
+
+// cloudHealth deems the console unreachable when the latest heartbeat failed
+// or none was answered for three heartbeat intervals
+func cloudHealth() *CloudHealth {
+	last, err := GetCloudManager().HeartBeatStatus()
+	ch := &CloudHealth{}
+	if !last.IsZero() {
+		ch.LastHeartbeat = last.Unix()
+	}
+	interval := time.Duration(GetBasic().GetInt64("cloud.heartbeat_interval")) * time.Second
+	ch.Reachable = err == nil && !last.IsZero() && time.Since(last) <= 3*interval
+	if err != nil {
+		ch.Error = err.Error()
+	}
+	return ch
+}
+
+func (h *Health) down(problem string) {
+	h.Status = HealthDown
+	h.Problems = append(h.Problems, problem)
+}
+
+func (h *Health) degrade(problem string) {
+	if h.Status == HealthOK {
+		h.Status = HealthDegraded
+	}
+	h.Problems = append(h.Problems, problem)
+}
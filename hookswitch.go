@@ -0,0 +1,237 @@
+package openrasp
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/spf13/cast"
+)
+
+// Hook is a wrapped call of a support package
+type Hook uint64
+
+const (
+	HookHttp Hook = 1 << iota
+	HookHttpBody
+	HookSql
+	HookFile
+	HookDial
+	HookDns
+	HookXml
+	HookTemplate
+	HookDeserialization
+	HookLdap
+	HookMemcache
+	HookMail
+	HookArchive
+	HookPlugin
+	HookGraphql
+	HookGrpc
+)
+
+// hookKeys name the switch hook.<key>.enable of every hook
+var hookKeys = []struct {
+	hook Hook
+	key  string
+}{
+	{HookHttp, "http"},
+	{HookHttpBody, "http.body"},
+	{HookSql, "sql"},
+	{HookFile, "file"},
+	{HookDial, "dial"},
+	{HookDns, "dns"},
+	{HookXml, "xml"},
+	{HookTemplate, "template"},
+	{HookDeserialization, "deserialization"},
+	{HookLdap, "ldap"},
+	{HookMemcache, "memcache"},
+	{HookMail, "mail"},
+	{HookArchive, "archive"},
+	{HookPlugin, "plugin"},
+	{HookGraphql, "graphql"},
+	{HookGrpc, "grpc"},
+}
+
+// HookSwitch turns checks off at runtime, hook.disabled lists check types
+// like sql_exception or all, policy.disabled lists policy ids like 3006.
+// hook.<name>.enable turns a whole hook off, the wrapped call then skips
+// openrasp entirely. detect.dry_run logs every decision to block or challenge
+// instead, marked with would_block, to review them before enforcing.
+type HookSwitch struct {
+	// disabledHooks comes first to be 64-bit aligned for atomic access
+	disabledHooks    uint64
+	dryRun           uint32
+	disabledTypes    common.CheckType
+	disabledPolicies map[uint64]bool
+	mu               sync.RWMutex
+}
+
+func NewHookSwitch() *HookSwitch {
+	hs := &HookSwitch{
+		disabledPolicies: make(map[uint64]bool),
+	}
+	return hs
+}
+
+func (hs *HookSwitch) OnConfigUpdate() {
+	var disabledTypes common.CheckType
+	for _, name := range GetGeneral().GetStringSlice("hook.disabled") {
+		ct := common.CheckStringToType(name)
+		if ct == common.InvalidType {
+			GetLog().RaspWarn("Unknown check type "+name+" in hook.disabled", orlog.Config)
+			continue
+		}
+		disabledTypes |= ct
+	}
+	disabledPolicies := make(map[uint64]bool)
+	for _, id := range GetGeneral().GetIntSlice("policy.disabled") {
+		disabledPolicies[uint64(id)] = true
+	}
+	var disabledHooks Hook
+	for _, hk := range hookKeys {
+		if !GetGeneral().GetBool("hook." + hk.key + ".enable") {
+			disabledHooks |= hk.hook
+		}
+	}
+	atomic.StoreUint64(&hs.disabledHooks, uint64(disabledHooks))
+	var dryRun uint32
+	if GetGeneral().GetBool("detect.dry_run") {
+		dryRun = 1
+	}
+	atomic.StoreUint32(&hs.dryRun, dryRun)
+	if IsComplete() {
+		publishActiveHooks()
+	}
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.disabledTypes = disabledTypes
+	hs.disabledPolicies = disabledPolicies
+}
+
+// HookEnabled reports whether the wrapped call of hook goes through
+// openrasp, it is cheap enough for the top of every wrapped call
+func (hs *HookSwitch) HookEnabled(hook Hook) bool {
+	if hs == nil {
+		return true
+	}
+	return atomic.LoadUint64(&hs.disabledHooks)&uint64(hook) == 0
+}
+
+// activeHooks has the bit of every hook which goes through openrasp, none
+// before init completes
+var activeHooks uint64
+
+func publishActiveHooks() {
+	atomic.StoreUint64(&activeHooks, ^atomic.LoadUint64(&GetHookSwitch().disabledHooks))
+}
+
+// calledHooks has the bit of every hook whose wrapped call ran at least once
+var calledHooks uint64
+
+// HookActive reports whether init completed and hook is enabled with atomic
+// loads only, a wrapped call returns to the plain call right away otherwise,
+// without allocating nor touching gls. The first call records that the
+// application goes through hook for HealthCheck.
+func HookActive(hook Hook) bool {
+	if atomic.LoadUint64(&calledHooks)&uint64(hook) == 0 {
+		markCalled(hook)
+	}
+	return atomic.LoadUint64(&activeHooks)&uint64(hook) != 0
+}
+
+func markCalled(hook Hook) {
+	for {
+		called := atomic.LoadUint64(&calledHooks)
+		if atomic.CompareAndSwapUint64(&calledHooks, called, called|uint64(hook)) {
+			return
+		}
+	}
+}
+
+// hookCalled reports whether the wrapped call of hook ran since the start,
+// a hook never called is not wrapped by the application or not reached yet
+func hookCalled(hook Hook) bool {
+	return atomic.LoadUint64(&calledHooks)&uint64(hook) != 0
+}
+
+// DryRun reports whether the decisions to block are only logged
+func (hs *HookSwitch) DryRun() bool {
+	return hs != nil && atomic.LoadUint32(&hs.dryRun) != 0
+}
+
+// CheckEnabled reports whether ct runs, everything runs before init
+func (hs *HookSwitch) CheckEnabled(ct common.CheckType) bool {
+	if hs == nil {
+		return true
+	}
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	return hs.disabledTypes&ct == 0
+}
+
+func (hs *HookSwitch) PolicyEnabled(policyId uint64) bool {
+	if hs == nil {
+		return true
+	}
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	return !hs.disabledPolicies[policyId]
+}
+
+// FilterPolicy ignores the result of a disabled policy and turns a block
+// into a log in dry run, it is meant to wrap PolicyCheck of every policy hook
+func (hs *HookSwitch) FilterPolicy(interceptCode model.InterceptCode, policyResult *model.PolicyResult) model.InterceptCode {
+	if policyResult != nil && !hs.PolicyEnabled(policyResult.PolicyId) {
+		return model.Ignore
+	}
+	if interceptCode == model.Block && hs.DryRun() {
+		if policyResult != nil {
+			policyResult.WouldBlock = true
+		}
+		return model.Log
+	}
+	return interceptCode
+}
+
+// validateHookSettings rejects unknown keys and check types of a hook_config command
+func validateHookSettings(settings map[string]interface{}) error {
+	for key, value := range settings {
+		switch key {
+		case "hook.disabled":
+			names, err := cast.ToStringSliceE(value)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if common.CheckStringToType(name) == common.InvalidType {
+					return fmt.Errorf("unknown check type %s", name)
+				}
+			}
+		case "policy.disabled":
+			if _, err := cast.ToIntSliceE(value); err != nil {
+				return err
+			}
+		default:
+			if !isHookEnableKey(key) {
+				return fmt.Errorf("%s is not a hook setting", key)
+			}
+			if _, err := cast.ToBoolE(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isHookEnableKey(key string) bool {
+	for _, hk := range hookKeys {
+		if key == "hook."+hk.key+".enable" {
+			return true
+		}
+	}
+	return false
+}
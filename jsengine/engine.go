@@ -0,0 +1,266 @@
+package jsengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+var ErrTimeout = errors.New("plugin check timeout")
+
+// Plugin is the source of a javascript plugin, Filename shows up in stacks
+type Plugin struct {
+	Source   string
+	Filename string
+}
+
+// Context lazily provides the request to the checkers, a []byte value is
+// decoded as JSON
+type Context map[string]func() interface{}
+
+// Engine runs plugins written against the RASP API of the official plugin
+// in goja runtimes. A runtime is not safe for concurrent use, so each check
+// borrows one from a pool which is refilled on demand.
+type Engine struct {
+	logger     func(string)
+	pool       chan *runtime
+	mu         sync.RWMutex
+	programs   []*goja.Program
+	generation int
+}
+
+type runtime struct {
+	vm         *goja.Runtime
+	check      goja.Callable
+	generation int
+}
+
+func NewEngine(poolSize int, logger func(string)) *Engine {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	if logger == nil {
+		logger = func(string) {}
+	}
+	return &Engine{
+		logger: logger,
+		pool:   make(chan *runtime, poolSize),
+	}
+}
+
+// Load compiles the plugins and replaces the running ones, the running ones
+// are kept when a plugin fails to compile or to register
+func (e *Engine) Load(plugins []Plugin) error {
+	programs := make([]*goja.Program, 0, len(plugins)+1)
+	program, err := goja.Compile("prelude", prelude, false)
+	if err != nil {
+		return err
+	}
+	programs = append(programs, program)
+	for _, plugin := range plugins {
+		program, err := goja.Compile(plugin.Filename, plugin.Source, false)
+		if err != nil {
+			return fmt.Errorf("unable to compile plugin %s, %v", plugin.Filename, err)
+		}
+		programs = append(programs, program)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rt, err := e.newRuntime(programs, e.generation+1)
+	if err != nil {
+		return err
+	}
+	e.programs = programs
+	e.generation++
+	e.put(rt)
+	return nil
+}
+
+// Loaded reports whether plugins have been loaded
+func (e *Engine) Loaded() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.programs) > 0
+}
+
+func (e *Engine) newRuntime(programs []*goja.Program, generation int) (*runtime, error) {
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	vm.Set("__log", func(message string) {
+		e.logger(message)
+	})
+	vm.Set("__sql_tokenize", SqlTokenize)
+	vm.Set("__cmd_tokenize", CmdTokenize)
+	for _, program := range programs {
+		if _, err := vm.RunProgram(program); err != nil {
+			return nil, err
+		}
+	}
+	rasp := vm.Get("RASP").ToObject(vm)
+	check, ok := goja.AssertFunction(rasp.Get("check"))
+	if !ok {
+		return nil, errors.New("RASP.check is not a function")
+	}
+	return &runtime{vm: vm, check: check, generation: generation}, nil
+}
+
+func (e *Engine) get() (*runtime, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for {
+		select {
+		case rt := <-e.pool:
+			if rt.generation == e.generation {
+				return rt, nil
+			}
+		default:
+			if len(e.programs) == 0 {
+				return nil, errors.New("no plugin loaded")
+			}
+			return e.newRuntime(e.programs, e.generation)
+		}
+	}
+}
+
+// put keeps rt for the next check, it is dropped when the pool is full
+func (e *Engine) put(rt *runtime) {
+	select {
+	case e.pool <- rt:
+	default:
+	}
+}
+
+// Check runs the checkers registered for checkType and returns the results
+// which are not ignored as a JSON array
+func (e *Engine) Check(checkType string, params []byte, context Context, timeout time.Duration) ([]byte, error) {
+	var p interface{}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	var results interface{}
+	err := e.run(timeout, func(rt *runtime) error {
+		value, err := rt.check(goja.Undefined(), rt.vm.ToValue(checkType), rt.vm.ToValue(p), e.newContext(rt.vm, context))
+		if err != nil {
+			return err
+		}
+		results = value.Export()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(results)
+}
+
+// Exec evaluates source and returns the result as JSON
+func (e *Engine) Exec(source, filename string) (string, error) {
+	var result interface{}
+	err := e.run(0, func(rt *runtime) error {
+		value, err := rt.vm.RunScript(filename, source)
+		if err != nil {
+			return err
+		}
+		result = value.Export()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(result)
+	return string(b), err
+}
+
+// run interrupts f after timeout and turns a panic of a native function into
+// an error, a runtime which might still receive the interrupt or whose state
+// is unknown after a panic is not put back into the pool
+func (e *Engine) run(timeout time.Duration, f func(rt *runtime) error) error {
+	rt, err := e.get()
+	if err != nil {
+		return err
+	}
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, func() {
+			rt.vm.Interrupt(ErrTimeout)
+		})
+	}
+	panicked, err := safeCall(f, rt)
+	if !panicked && (timer == nil || timer.Stop()) {
+		e.put(rt)
+	}
+	if interrupted, ok := err.(*goja.InterruptedError); ok && interrupted.Value() == ErrTimeout {
+		return ErrTimeout
+	}
+	return err
+}
+
+func safeCall(f func(rt *runtime) error, rt *runtime) (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic %v", r)
+			panicked = true
+		}
+	}()
+	return false, f(rt)
+}
+
+func (e *Engine) newContext(vm *goja.Runtime, context Context) goja.Value {
+	return vm.NewDynamicObject(&dynamicContext{vm: vm, getters: context})
+}
+
+// dynamicContext calls a getter once on first access, like the v8 engine
+type dynamicContext struct {
+	vm      *goja.Runtime
+	getters Context
+	values  map[string]goja.Value
+}
+
+func (dc *dynamicContext) Get(key string) goja.Value {
+	if value, ok := dc.values[key]; ok {
+		return value
+	}
+	getter, ok := dc.getters[key]
+	if !ok {
+		return nil
+	}
+	v := getter()
+	if b, ok := v.([]byte); ok {
+		var decoded interface{}
+		if err := json.Unmarshal(b, &decoded); err == nil {
+			v = decoded
+		} else {
+			v = string(b)
+		}
+	}
+	value := dc.vm.ToValue(v)
+	if dc.values == nil {
+		dc.values = make(map[string]goja.Value)
+	}
+	dc.values[key] = value
+	return value
+}
+
+func (dc *dynamicContext) Set(key string, value goja.Value) bool {
+	return false
+}
+
+func (dc *dynamicContext) Has(key string) bool {
+	_, ok := dc.getters[key]
+	return ok
+}
+
+func (dc *dynamicContext) Delete(key string) bool {
+	return false
+}
+
+func (dc *dynamicContext) Keys() []string {
+	keys := make([]string, 0, len(dc.getters))
+	for key := range dc.getters {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,104 @@
+package jsengine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPlugin = `
+var plugin = new RASP('test')
+RASP.algorithmConfig = {sql_userinput: {action: 'block'}}
+plugin.register('sql', function (params, context) {
+	var tokens = RASP.sql_tokenize(params.query, params.server)
+	for (var i = 0; i < tokens.length; i++) {
+		if (tokens[i].text.toLowerCase() === 'union' && context.parameter.id) {
+			return {action: 'block', message: 'union from ' + context.path, confidence: 90, algorithm: 'sql_userinput'}
+		}
+	}
+	return {action: 'ignore'}
+})
+plugin.register('loop', function () {
+	while (true) {}
+})
+plugin.log('loaded')
+`
+
+func TestEngineCheck(t *testing.T) {
+	var logs []string
+	e := NewEngine(2, func(message string) {
+		logs = append(logs, message)
+	})
+	assert.False(t, e.Loaded())
+	_, err := e.Check("sql", []byte(`{}`), nil, 0)
+	assert.Error(t, err)
+
+	assert.NoError(t, e.Load([]Plugin{{Source: testPlugin, Filename: "test"}}))
+	assert.True(t, e.Loaded())
+	assert.Equal(t, []string{"[test] loaded"}, logs)
+
+	context := Context{
+		"path":      func() interface{} { return "/index" },
+		"parameter": func() interface{} { return []byte(`{"id":["1"]}`) },
+	}
+	result, err := e.Check("sql", []byte(`{"query":"select 1 union select 2","server":"mysql"}`), context, time.Second)
+	assert.NoError(t, err)
+	var ms []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(result, &ms))
+	assert.Len(t, ms, 1)
+	assert.Equal(t, "block", ms[0]["action"])
+	assert.Equal(t, "union from /index", ms[0]["message"])
+	assert.Equal(t, "test", ms[0]["name"])
+
+	result, err = e.Check("sql", []byte(`{"query":"select 1","server":"mysql"}`), context, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(result))
+
+	_, err = e.Check("loop", []byte(`{}`), nil, 10*time.Millisecond)
+	assert.Equal(t, ErrTimeout, err)
+
+	_, err = e.Check("sql", []byte(`{"query":"union"}`), Context{
+		"parameter": func() interface{} { panic("no request") },
+	}, time.Second)
+	assert.EqualError(t, err, "panic no request")
+
+	action, err := e.Exec(`JSON.stringify(Object.keys(RASP.algorithmConfig))`, "extract_buildin_action")
+	assert.NoError(t, err)
+	assert.Equal(t, `"[\"sql_userinput\"]"`, action)
+}
+
+func TestEngineReload(t *testing.T) {
+	e := NewEngine(1, nil)
+	assert.NoError(t, e.Load([]Plugin{{Source: testPlugin, Filename: "test"}}))
+	assert.Error(t, e.Load([]Plugin{{Source: "var plugin = new RASP(", Filename: "broken"}}))
+	assert.Error(t, e.Load([]Plugin{{Source: "throw new Error('init')", Filename: "throws"}}))
+	result, err := e.Check("sql", []byte(`{"query":"select 1 union select 2"}`), Context{
+		"parameter": func() interface{} { return []byte(`{"id":["1"]}`) },
+	}, time.Second)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(result), "block"))
+
+	assert.NoError(t, e.Load([]Plugin{{Source: "new RASP('empty')", Filename: "empty"}}))
+	result, err = e.Check("sql", []byte(`{"query":"select 1 union select 2"}`), nil, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(result))
+}
+
+func TestSqlTokenize(t *testing.T) {
+	texts := func(tokens []Token) []string {
+		var s []string
+		for _, token := range tokens {
+			s = append(s, token.Text)
+		}
+		return s
+	}
+	assert.Equal(t, []string{"select", "*", "from", "t", "where", "a", "=", "'it''s'", "or", "b", "<=", "1", "/* c */", "-- x"},
+		texts(SqlTokenize("select * from t where a='it''s' or b<=1 /* c */ -- x")))
+	tokens := SqlTokenize(" union\tselect")
+	assert.Equal(t, Token{Start: 1, Stop: 6, Text: "union"}, tokens[0])
+	assert.Equal(t, []string{"cat", "'/etc/passwd'", "|", "nc", "host", "&&", "echo", "$(", "id", ")"},
+		texts(CmdTokenize("cat '/etc/passwd' | nc host && echo $(id)")))
+}
@@ -0,0 +1,77 @@
+package jsengine
+
+// prelude provides the RASP class of the official plugins, the natives
+// prefixed with __ are set by newRuntime
+const prelude = `
+var RASP = (function () {
+	'use strict';
+	var plugins = [];
+	var stringify = function (value) {
+		return typeof value === 'string' ? value : JSON.stringify(value);
+	};
+
+	function RASP(name) {
+		if (typeof name !== 'string' || name.length === 0) {
+			throw new TypeError('Plugin name must be a string');
+		}
+		this.name = name;
+		this.checkPoints = {};
+		plugins.push(this);
+	}
+
+	RASP.prototype.register = function (type, checker) {
+		if (typeof checker !== 'function') {
+			throw new TypeError('Checker must be a function');
+		}
+		(this.checkPoints[type] = this.checkPoints[type] || []).push(checker);
+	};
+
+	RASP.prototype.log = function () {
+		__log('[' + this.name + '] ' + Array.prototype.map.call(arguments, stringify).join(' '));
+	};
+
+	RASP.check = function (type, params, context) {
+		var results = [];
+		plugins.forEach(function (plugin) {
+			(plugin.checkPoints[type] || []).forEach(function (checker) {
+				var result;
+				try {
+					result = checker(params, context);
+				} catch (e) {
+					__log('[' + plugin.name + '] ' + type + ' checker failed: ' + e);
+					return;
+				}
+				if (result && typeof result.action === 'string' && result.action !== 'ignore') {
+					result.name = result.name || plugin.name;
+					results.push(result);
+				}
+			});
+		});
+		return results;
+	};
+
+	RASP.algorithmConfig = {};
+	RASP.config = {};
+	RASP.config_set = function (key, value) {
+		RASP.config[key] = value;
+	};
+	RASP.get_jsengine = function () {
+		return 'goja';
+	};
+	RASP.sql_tokenize = function (query) {
+		return __sql_tokenize(String(query));
+	};
+	RASP.cmd_tokenize = function (command) {
+		return __cmd_tokenize(String(command));
+	};
+	return RASP;
+})();
+
+var console = {
+	log: function () {
+		__log(Array.prototype.map.call(arguments, function (value) {
+			return typeof value === 'string' ? value : JSON.stringify(value);
+		}).join(' '));
+	}
+};
+`
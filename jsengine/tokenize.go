@@ -0,0 +1,123 @@
+package jsengine
+
+import (
+	"strings"
+)
+
+// Token is a lexical unit of a sql query or a shell command, start and stop
+// are byte offsets of text in the input
+type Token struct {
+	Start int    `json:"start"`
+	Stop  int    `json:"stop"`
+	Text  string `json:"text"`
+}
+
+var sqlOperators = []string{"<=>", "<=", ">=", "<>", "!=", "||", "&&", "<<", ">>", ":="}
+
+// SqlTokenize splits query into quoted strings, comments, words and
+// operators, whitespace is dropped
+func SqlTokenize(query string) []Token {
+	var tokens []Token
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		switch {
+		case isSpace(c):
+			i++
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(query, i)
+		case strings.HasPrefix(query[i:], "--") || c == '#':
+			i = skipUntil(query, i, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+		case isWord(c):
+			for i < len(query) && isWord(query[i]) {
+				i++
+			}
+		default:
+			i++
+			for _, op := range sqlOperators {
+				if strings.HasPrefix(query[start:], op) {
+					i = start + len(op)
+					break
+				}
+			}
+		}
+		tokens = append(tokens, Token{Start: start, Stop: i, Text: query[start:i]})
+	}
+	return tokens
+}
+
+// CmdTokenize splits command into words and shell operators, quotes are kept
+// inside the word they belong to
+func CmdTokenize(command string) []Token {
+	var tokens []Token
+	for i := 0; i < len(command); {
+		c := command[i]
+		start := i
+		switch {
+		case isSpace(c):
+			i++
+			continue
+		case strings.IndexByte(";|&<>()`", c) >= 0:
+			i++
+			if i < len(command) && (c == '|' || c == '&' || c == '>' || c == '<') && command[i] == c {
+				i++
+			}
+		case c == '$' && i+1 < len(command) && command[i+1] == '(':
+			i += 2
+		default:
+			for i < len(command) && !isSpace(command[i]) && strings.IndexByte(";|&<>()`", command[i]) < 0 {
+				if command[i] == '\'' || command[i] == '"' {
+					i = skipQuoted(command, i)
+				} else if command[i] == '\\' && i+1 < len(command) {
+					i += 2
+				} else {
+					i++
+				}
+			}
+		}
+		tokens = append(tokens, Token{Start: start, Stop: i, Text: command[start:i]})
+	}
+	return tokens
+}
+
+// skipQuoted returns the offset after the string quoted at s[i], doubled
+// quotes and backslashes escape the quote
+func skipQuoted(s string, i int) int {
+	quote := s[i]
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func skipUntil(s string, i int, sep string) int {
+	if end := strings.Index(s[i:], sep); end >= 0 {
+		return i + end
+	}
+	return len(s)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c == '@' ||
+		(c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
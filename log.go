@@ -1,18 +1,26 @@
 package openrasp
 
 import (
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/baidu-security/openrasp-golang/cloud"
 	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/config"
 	"github.com/baidu-security/openrasp-golang/model"
 	"github.com/baidu-security/openrasp-golang/orlog"
 	"github.com/baidu-security/openrasp-golang/stacktrace"
 	"github.com/baidu-security/openrasp-golang/utils"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
 )
 
 type LogCode int
@@ -23,16 +31,30 @@ const (
 )
 
 type LogManager struct {
-	alarm  *WrapLogger
-	policy *WrapLogger
-	plugin *WrapLogger
-	rasp   *WrapLogger
+	alarm         *WrapLogger
+	policy        *WrapLogger
+	plugin        *WrapLogger
+	rasp          *WrapLogger
+	audit         *WrapLogger
+	syslogWriter  *orlog.SyslogWriter
+	kafkaWriter   *orlog.KafkaWriter
+	splunkWriter  *orlog.SplunkWriter
+	fluentdWriter *orlog.FluentdWriter
+	httpWriters   []*orlog.HttpWriter
+	writersMu     sync.Mutex
+	drops         map[string]*orlog.DropCounter
+	dropHandler   atomic.Value
 }
 
 type WrapLogger struct {
-	logger   *logrus.Logger
-	filename string
-	dirCode  common.WorkDirCode
+	logger    *logrus.Logger
+	formatter logrus.Formatter
+	output    io.Writer
+	filename  string
+	dirCode   common.WorkDirCode
+	drops     *orlog.DropCounter
+	userHooks []orlog.Hook
+	mu        sync.Mutex
 }
 
 func NewWrapLogger(dirCode common.WorkDirCode, f *orlog.OpenRASPFormatter) (*WrapLogger, error) {
@@ -45,9 +67,10 @@ func NewWrapLogger(dirCode common.WorkDirCode, f *orlog.OpenRASPFormatter) (*Wra
 		logrusLogger := logrus.New()
 		logrusLogger.Formatter = f
 		wl := &WrapLogger{
-			logger:   logrusLogger,
-			filename: logFilename,
-			dirCode:  dirCode,
+			logger:    logrusLogger,
+			formatter: f,
+			filename:  logFilename,
+			dirCode:   dirCode,
 		}
 		return wl, nil
 	}
@@ -65,22 +88,70 @@ func (wl *WrapLogger) Debug(message string) {
 	wl.logger.Debug(message)
 }
 
+// SetOutput swaps the destination and closes the previous one
 func (wl *WrapLogger) SetOutput(output io.Writer) {
 	wl.logger.SetOutput(output)
+	previous := wl.output
+	wl.output = output
+	if closer, ok := previous.(io.Closer); ok && previous != output {
+		closer.Close()
+	}
 }
 
 func (wl *WrapLogger) SetLevel(l orlog.Level) {
 	wl.logger.SetLevel(orlog.LevelTransform(l))
 }
 
+func (wl *WrapLogger) SetFormatter(formatter logrus.Formatter) {
+	wl.logger.SetFormatter(formatter)
+}
+
+// ClearHooks removes the hooks built from config, user hooks are kept
 func (wl *WrapLogger) ClearHooks() {
-	wl.logger.ReplaceHooks(make(logrus.LevelHooks))
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	hooks := make(logrus.LevelHooks)
+	for _, hook := range wl.userHooks {
+		hooks.Add(hook)
+	}
+	wl.logger.ReplaceHooks(hooks)
+}
+
+// AddUserHook attaches a hook which survives config updates
+func (wl *WrapLogger) AddUserHook(hook orlog.Hook) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+	wl.userHooks = append(wl.userHooks, hook)
+	wl.logger.AddHook(hook)
 }
 
 func (wl *WrapLogger) AddHook(hook orlog.Hook) {
 	wl.logger.AddHook(hook)
 }
 
+// channel is the name of the logger in log.<channel>.* settings
+func (wl *WrapLogger) channel() string {
+	return strings.TrimSuffix(dirCodeToName(wl.dirCode), ".log")
+}
+
+// newTokenBucket limits the logger by log.<channel>.maxburst and log.<channel>.rate,
+// the burst falls back to log.maxburst and a zero rate keeps the idle refill window
+func (wl *WrapLogger) newTokenBucket() *orlog.TokenBucket {
+	capacity := GetGeneral().GetInt64("log." + wl.channel() + ".maxburst")
+	if capacity <= 0 {
+		capacity = GetGeneral().GetInt64("log.maxburst")
+	}
+	rate := GetGeneral().GetFloat64("log." + wl.channel() + ".rate")
+	var tokenBucket *orlog.TokenBucket
+	if rate > 0 {
+		tokenBucket = orlog.NewRateTokenBucket(uint64(capacity), rate)
+	} else {
+		tokenBucket = orlog.NewTokenBucket(uint64(capacity), duration)
+	}
+	tokenBucket.SetDropCounter(wl.drops)
+	return tokenBucket
+}
+
 func dirCodeToName(dirCode common.WorkDirCode) string {
 	switch dirCode {
 	case common.LogAlarm:
@@ -91,6 +162,8 @@ func dirCodeToName(dirCode common.WorkDirCode) string {
 		return "plugin.log"
 	case common.LogRasp:
 		return "rasp.log"
+	case common.LogAudit:
+		return "audit.log"
 	default:
 		return ""
 	}
@@ -117,15 +190,119 @@ func InitLogManager() (*LogManager, error) {
 	if err != nil {
 		return nil, err
 	}
+	auditLogger, err := NewWrapLogger(common.LogAudit, &orlog.OpenRASPFormatter{})
+	if err != nil {
+		return nil, err
+	}
 	lm := &LogManager{
 		alarm:  alarmLogger,
 		policy: policyLogger,
 		plugin: pluginLogger,
 		rasp:   raspLogger,
+		audit:  auditLogger,
+		drops:  make(map[string]*orlog.DropCounter),
+	}
+	for _, wl := range lm.loggers() {
+		wl.drops = lm.dropCounter(wl.channel())
+	}
+	for _, destination := range []string{"syslog", "kafka", "splunk", "fluentd"} {
+		lm.dropCounter(destination)
 	}
 	return lm, nil
 }
 
+// dropCounter returns the counter of a logger or of a destination shared by
+// several loggers, it must only be called during init
+func (lm *LogManager) dropCounter(channel string) *orlog.DropCounter {
+	dc, ok := lm.drops[channel]
+	if !ok {
+		dc = orlog.NewDropCounter(channel, lm.onDrop)
+		lm.drops[channel] = dc
+	}
+	return dc
+}
+
+// sharedTokenBucket limits a destination shared by alarm and policy logs
+func (lm *LogManager) sharedTokenBucket(destination string) *orlog.TokenBucket {
+	capacity := GetGeneral().GetInt64("log.maxburst")
+	tokenBucket := orlog.NewTokenBucket(uint64(capacity), duration)
+	tokenBucket.SetDropCounter(lm.drops[destination])
+	return tokenBucket
+}
+
+func (lm *LogManager) onDrop(channel string, reason orlog.DropReason) {
+	if handler, ok := lm.dropHandler.Load().(orlog.DropHandler); ok && handler != nil {
+		handler(channel, reason)
+	}
+}
+
+// Dropped returns the entries each channel dropped since the start by
+// rate limiting and by queue overflow
+func (lm *LogManager) Dropped() map[string]orlog.DropTotals {
+	dropped := make(map[string]orlog.DropTotals, len(lm.drops))
+	for channel, dc := range lm.drops {
+		dropped[channel] = dc.Totals()
+	}
+	return dropped
+}
+
+// QueueDepth returns the entries waiting in the async queue of each
+// destination, the cloud console queues are keyed by log type
+func (lm *LogManager) QueueDepth() map[string]int {
+	lm.writersMu.Lock()
+	defer lm.writersMu.Unlock()
+	depth := make(map[string]int)
+	for _, hw := range lm.httpWriters {
+		depth["cloud."+hw.Type()] += hw.QueueLen()
+	}
+	if lm.splunkWriter != nil {
+		depth["splunk"] = lm.splunkWriter.QueueLen()
+	}
+	if lm.syslogWriter != nil {
+		depth["syslog"] = lm.syslogWriter.QueueLen()
+	}
+	return depth
+}
+
+// SetDropHandler lets the application watch log entries dropped by rate
+// limiting or queue overflow, handler runs on the logging goroutine and must
+// not log through openrasp itself
+func (lm *LogManager) SetDropHandler(handler orlog.DropHandler) {
+	lm.dropHandler.Store(handler)
+}
+
+// StartDropSummary writes how many entries each channel dropped to rasp.log
+// every log.drop.summary_interval seconds
+func (lm *LogManager) StartDropSummary() {
+	go func() {
+		for {
+			interval := GetGeneral().GetInt64("log.drop.summary_interval")
+			if interval <= 0 {
+				interval = 60
+			}
+			time.Sleep(time.Duration(interval) * time.Second)
+			lm.summarizeDrops()
+		}
+	}()
+}
+
+func (lm *LogManager) summarizeDrops() {
+	defer Recover()
+	channels := make([]string, 0, len(lm.drops))
+	for channel := range lm.drops {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	for _, channel := range channels {
+		rateLimited, overflowed := lm.drops[channel].Reset()
+		if rateLimited+overflowed == 0 {
+			continue
+		}
+		lm.RaspWarn(fmt.Sprintf("%d %s logs dropped, %d rate limited and %d by queue overflow",
+			rateLimited+overflowed, channel, rateLimited, overflowed), orlog.Log)
+	}
+}
+
 func (lm *LogManager) GetPolicy() *WrapLogger {
 	return lm.policy
 }
@@ -142,36 +319,410 @@ func (lm *LogManager) GetRasp() *WrapLogger {
 	return lm.rasp
 }
 
+func (lm *LogManager) GetAudit() *WrapLogger {
+	return lm.audit
+}
+
 func (lm *LogManager) UpdateFileWriter() {
+	if GetGeneral().GetString("log.output") == "stdout" {
+		lm.updateStdoutWriter()
+		return
+	}
 	maxBackup := GetGeneral().GetInt("log.maxbackup")
-	capacity := GetGeneral().GetInt64("log.maxburst")
-	lm.alarm.SetOutput(orlog.NewFileWriter(lm.alarm.filename, maxBackup, orlog.NewTokenBucket(uint64(capacity), duration)))
-	lm.policy.SetOutput(orlog.NewFileWriter(lm.policy.filename, maxBackup, orlog.NewTokenBucket(uint64(capacity), duration)))
-	lm.plugin.SetOutput(orlog.NewFileWriter(lm.plugin.filename, maxBackup, orlog.NewTokenBucket(uint64(capacity), duration)))
-	lm.rasp.SetOutput(orlog.NewFileWriter(lm.rasp.filename, maxBackup, orlog.NewTokenBucket(uint64(capacity), duration)))
+	opts := []orlog.FileWriterOption{
+		orlog.WithMaxSize(GetGeneral().GetInt("log.maxsize")),
+		orlog.WithMaxFiles(GetGeneral().GetInt("log.maxfiles")),
+		orlog.WithDaily(GetGeneral().GetBool("log.daily")),
+	}
+	fileEnable := GetGeneral().GetBool("log.file.enable")
+	encryptionEnable := GetGeneral().GetBool("log.encryption.enable")
+	var logCipher *orlog.LogCipher
+	var cipherErr error
+	if encryptionEnable {
+		logCipher, cipherErr = newLogCipher()
+	}
+	for _, wl := range lm.loggers() {
+		encrypted := encryptionEnable && (wl == lm.alarm || wl == lm.policy)
+		switch {
+		case !fileEnable:
+			wl.SetOutput(ioutil.Discard)
+		case encrypted && logCipher == nil:
+			// attack payloads must not hit the disk in plain text
+			wl.SetOutput(ioutil.Discard)
+		case encrypted:
+			wl.SetOutput(orlog.NewFileWriter(wl.filename, maxBackup, wl.newTokenBucket(), append(opts, orlog.WithCipher(logCipher))...))
+		default:
+			wl.SetOutput(orlog.NewFileWriter(wl.filename, maxBackup, wl.newTokenBucket(), opts...))
+		}
+	}
+	if cipherErr != nil {
+		lm.RaspWarn("Unable to init log encryption, alarm and policy files are disabled, cuz of "+cipherErr.Error(), orlog.Log)
+	}
+	lm.alarm.SetFormatter(orlog.NewFormatter(GetGeneral().GetString("log.format"), common.OpenRASPVersion))
+	lm.policy.SetFormatter(orlog.NewFormatter(GetGeneral().GetString("log.format"), common.OpenRASPVersion))
+	lm.plugin.SetFormatter(lm.plugin.formatter)
+	lm.rasp.SetFormatter(lm.rasp.formatter)
+	lm.audit.SetFormatter(lm.audit.formatter)
+	lm.UpdateLevel()
+}
+
+// updateStdoutWriter writes every channel as json lines to stdout, and
+// rasp.log to stderr, for container runtimes collecting both streams
+func (lm *LogManager) updateStdoutWriter() {
+	for _, wl := range lm.loggers() {
+		wl.SetFormatter(&orlog.StdoutFormatter{
+			Channel:       wl.channel(),
+			SchemaVersion: model.CurrentSchemaVersion,
+		})
+		if wl == lm.rasp {
+			wl.SetOutput(orlog.Stderr)
+		} else {
+			wl.SetOutput(orlog.Stdout)
+		}
+	}
+	lm.UpdateLevel()
+}
+
+func newLogCipher() (*orlog.LogCipher, error) {
+	key, err := orlog.LoadLogKey(GetGeneral().GetString("log.encryption.key"), GetGeneral().GetString("log.encryption.key_file"))
+	if err != nil {
+		return nil, err
+	}
+	return orlog.NewLogCipher(key)
+}
+
+// UpdateLevel applies log.level.<logger> to each logger, debug.level forces rasp.log to debug
+func (lm *LogManager) UpdateLevel() {
+	for _, wl := range lm.loggers() {
+		level, err := orlog.LevelFromString(GetGeneral().GetString("log.level." + wl.channel()))
+		if err != nil {
+			level = orlog.InfoLevel
+		}
+		wl.SetLevel(level)
+	}
 	debugLevel := GetGeneral().GetInt("debug.level")
 	if debugLevel > 0 {
 		lm.rasp.SetLevel(orlog.DebugLevel)
 	}
 }
 
+func (lm *LogManager) loggers() []*WrapLogger {
+	return []*WrapLogger{lm.alarm, lm.policy, lm.plugin, lm.rasp, lm.audit}
+}
+
 func (lm *LogManager) UpdateHttpHook() {
+	// the cloud console only accepts the native json format, the default,
+	// cef and leef suit a SIEM collector
+	format := GetGeneral().GetString("log.http.format")
+	_, native := orlog.NewFormatter(format, common.OpenRASPVersion).(*orlog.OpenRASPFormatter)
+	encryptionEnable := GetGeneral().GetBool("log.encryption.enable")
+	var logCipher *orlog.LogCipher
+	if encryptionEnable && GetGeneral().GetInt64("log.spool.max_size") > 0 {
+		var err error
+		if logCipher, err = newLogCipher(); err != nil {
+			lm.RaspWarn("Unable to init log encryption, alarms and policies are not spooled, cuz of "+err.Error(), orlog.Log)
+		}
+	}
+	newWriter := func(t string, wl *WrapLogger, cm *cloud.Client, spoolName string) *orlog.HttpWriter {
+		opts := []orlog.HttpWriterOption{
+			orlog.WithRetry(
+				GetGeneral().GetInt("log.retry.max_retries"),
+				time.Duration(GetGeneral().GetInt64("log.retry.initial_backoff"))*time.Millisecond,
+				time.Duration(GetGeneral().GetInt64("log.retry.max_backoff"))*time.Millisecond,
+			),
+			orlog.WithGzip(GetGeneral().GetBool("log.gzip")),
+			orlog.WithLines(!native),
+			orlog.WithDropCounter(wl.drops),
+		}
+		// alarms and policies are sealed like their log files, and not
+		// spooled at all without a cipher
+		var spoolCipher *orlog.LogCipher
+		encrypted := encryptionEnable && (wl == lm.alarm || wl == lm.policy)
+		if encrypted {
+			spoolCipher = logCipher
+		}
+		if spoolSize := GetGeneral().GetInt64("log.spool.max_size"); spoolSize > 0 && (!encrypted || spoolCipher != nil) {
+			// the lines and the JSON arrays are spooled apart
+			if !native {
+				spoolName += ".lines"
+			}
+			spoolFilename := filepath.Join(filepath.Dir(wl.filename), spoolName+".spool")
+			opts = append(opts, orlog.WithSpool(orlog.NewSpool(spoolFilename, spoolSize*1024*1024, spoolCipher)))
+		}
+		tokenBucket := wl.newTokenBucket()
+		if !GetGeneral().GetBool("log.async.enable") {
+			hw := orlog.NewHttpWriter(t, cm, tokenBucket, opts...)
+			lm.httpWriters = append(lm.httpWriters, hw)
+			return hw
+		}
+		hw := orlog.NewAsyncHttpWriter(t, cm, tokenBucket,
+			GetGeneral().GetInt("log.async.queue_size"),
+			GetGeneral().GetInt("log.async.batch_size"),
+			GetGeneral().GetInt("log.async.batch_bytes"),
+			time.Duration(GetGeneral().GetInt64("log.async.flush_interval"))*time.Millisecond,
+			orlog.DropPolicyFromString(GetGeneral().GetString("log.async.drop_policy")),
+			opts...,
+		)
+		lm.httpWriters = append(lm.httpWriters, hw)
+		return hw
+	}
+	newHook := func(hw *orlog.HttpWriter, level orlog.Level, appId string) *orlog.HttpHook {
+		hook := orlog.NewHttpHookWithWriter(hw, level)
+		hook.Formatter = orlog.NewFormatter(format, common.OpenRASPVersion)
+		hook.AppId = appId
+		return hook
+	}
 	cm := GetCloudManager()
-	capacity := GetGeneral().GetInt64("log.maxburst")
+	lm.alarm.AddHook(newHook(newWriter("attack", lm.alarm, cm, "attack"), orlog.InfoLevel, GetBasic().GetString("cloud.app_id")))
+	lm.policy.AddHook(newHook(newWriter("policy", lm.policy, cm, "policy"), orlog.InfoLevel, ""))
+	lm.rasp.AddHook(newHook(newWriter("error", lm.rasp, cm, "error"), orlog.WarnLevel, ""))
+	// alarms of the bound applications are uploaded with their own credentials
+	for _, binding := range GetAppRouter().Bindings() {
+		if binding.client != nil {
+			hw := newWriter("attack", lm.alarm, binding.client, "attack."+binding.AppId)
+			lm.alarm.AddHook(newHook(hw, orlog.InfoLevel, binding.AppId))
+		}
+	}
+}
+
+func (lm *LogManager) UpdateSyslogHook() {
+	syslogWriter, err := orlog.NewSyslogWriter(
+		GetGeneral().GetString("syslog.url"),
+		GetGeneral().GetString("syslog.tag"),
+		GetGeneral().GetInt("syslog.facility"),
+		time.Duration(GetGeneral().GetInt64("syslog.connection_timeout"))*time.Millisecond,
+		time.Duration(GetGeneral().GetInt64("syslog.read_timeout"))*time.Millisecond,
+		time.Duration(GetGeneral().GetInt64("syslog.reconnect_interval"))*time.Second,
+		lm.sharedTokenBucket("syslog"),
+	)
+	if err != nil {
+		lm.RaspWarn("Unable to init syslog writer, cuz of "+err.Error(), orlog.Log)
+		return
+	}
+	syslogWriter.StartQueue(
+		GetGeneral().GetInt("log.async.queue_size"),
+		orlog.DropPolicyFromString(GetGeneral().GetString("log.async.drop_policy")),
+		lm.drops["syslog"],
+	)
+	lm.syslogWriter = syslogWriter
+	alarmHook := orlog.NewSyslogHook("attack", syslogWriter, orlog.InfoLevel, GetGeneral().GetInt("syslog.alarm_severity"))
+	alarmHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("syslog.format"), common.OpenRASPVersion)
+	lm.alarm.AddHook(alarmHook)
+	policyHook := orlog.NewSyslogHook("policy", syslogWriter, orlog.InfoLevel, GetGeneral().GetInt("syslog.policy_severity"))
+	policyHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("syslog.format"), common.OpenRASPVersion)
+	lm.policy.AddHook(policyHook)
+}
+
+func (lm *LogManager) UpdateKafkaHook() {
+	kafkaWriter, err := orlog.NewKafkaWriter(&orlog.KafkaConfig{
+		Brokers:            GetGeneral().GetStringSlice("kafka.brokers"),
+		Topic:              GetGeneral().GetString("kafka.topic"),
+		SaslMechanism:      GetGeneral().GetString("kafka.sasl.mechanism"),
+		SaslUsername:       GetGeneral().GetString("kafka.sasl.username"),
+		SaslPassword:       GetGeneral().GetString("kafka.sasl.password"),
+		TlsEnable:          GetGeneral().GetBool("kafka.tls.enable"),
+		InsecureSkipVerify: GetGeneral().GetBool("kafka.tls.insecure_skip_verify"),
+		CaFile:             GetGeneral().GetString("kafka.tls.ca_file"),
+		CertFile:           GetGeneral().GetString("kafka.tls.cert_file"),
+		KeyFile:            GetGeneral().GetString("kafka.tls.key_file"),
+	}, lm.sharedTokenBucket("kafka"))
+	if err != nil {
+		lm.RaspWarn("Unable to init kafka writer, cuz of "+err.Error(), orlog.Log)
+		return
+	}
+	lm.kafkaWriter = kafkaWriter
+	key := GetGlobals().RaspId
+	if GetGeneral().GetString("kafka.partition_key") == "app_id" {
+		key = GetBasic().GetString("cloud.app_id")
+	}
+	alarmHook := orlog.NewKafkaHook(key, kafkaWriter, orlog.InfoLevel)
+	alarmHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("kafka.format"), common.OpenRASPVersion)
+	lm.alarm.AddHook(alarmHook)
+	policyHook := orlog.NewKafkaHook(key, kafkaWriter, orlog.InfoLevel)
+	policyHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("kafka.format"), common.OpenRASPVersion)
+	lm.policy.AddHook(policyHook)
+}
+
+func (lm *LogManager) UpdateSplunkHook() {
+	tlsConfig, err := utils.NewTLSConfig(
+		GetGeneral().GetString("splunk.tls.ca_file"),
+		GetGeneral().GetString("splunk.tls.cert_file"),
+		GetGeneral().GetString("splunk.tls.key_file"),
+		GetGeneral().GetBool("splunk.tls.insecure_skip_verify"),
+	)
+	if err != nil {
+		lm.RaspWarn("Unable to load splunk tls config, cuz of "+err.Error(), orlog.Log)
+		return
+	}
+	splunkWriter, err := orlog.NewSplunkWriter(&orlog.SplunkConfig{
+		Url:           GetGeneral().GetString("splunk.url"),
+		Token:         GetGeneral().GetString("splunk.token"),
+		Index:         GetGeneral().GetString("splunk.index"),
+		Source:        GetGeneral().GetString("splunk.source"),
+		QueueSize:     GetGeneral().GetInt("log.async.queue_size"),
+		BatchSize:     GetGeneral().GetInt("log.async.batch_size"),
+		BatchBytes:    GetGeneral().GetInt("log.async.batch_bytes"),
+		FlushInterval: time.Duration(GetGeneral().GetInt64("log.async.flush_interval")) * time.Millisecond,
+		Timeout:       time.Duration(GetGeneral().GetInt64("splunk.timeout")) * time.Millisecond,
+		TLSConfig:     tlsConfig,
+		DropCounter:   lm.drops["splunk"],
+	}, lm.sharedTokenBucket("splunk"))
+	if err != nil {
+		lm.RaspWarn("Unable to init splunk writer, cuz of "+err.Error(), orlog.Log)
+		return
+	}
+	lm.splunkWriter = splunkWriter
+	alarmHook := orlog.NewSplunkHook(GetGeneral().GetString("splunk.sourcetype.attack"), splunkWriter, orlog.InfoLevel)
+	alarmHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("splunk.format"), common.OpenRASPVersion)
+	lm.alarm.AddHook(alarmHook)
+	policyHook := orlog.NewSplunkHook(GetGeneral().GetString("splunk.sourcetype.policy"), splunkWriter, orlog.InfoLevel)
+	policyHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("splunk.format"), common.OpenRASPVersion)
+	lm.policy.AddHook(policyHook)
+}
+
+func (lm *LogManager) UpdateFluentdHook() {
+	fluentdWriter, err := orlog.NewFluentdWriter(&orlog.FluentdConfig{
+		Url:               GetGeneral().GetString("fluentd.url"),
+		RequireAck:        GetGeneral().GetBool("fluentd.require_ack"),
+		ConnectionTimeout: time.Duration(GetGeneral().GetInt64("fluentd.connection_timeout")) * time.Millisecond,
+		WriteTimeout:      time.Duration(GetGeneral().GetInt64("fluentd.write_timeout")) * time.Millisecond,
+		ReconnectInterval: time.Duration(GetGeneral().GetInt64("fluentd.reconnect_interval")) * time.Second,
+	}, lm.sharedTokenBucket("fluentd"))
+	if err != nil {
+		lm.RaspWarn("Unable to init fluentd writer, cuz of "+err.Error(), orlog.Log)
+		return
+	}
+	lm.fluentdWriter = fluentdWriter
+	tag := GetGeneral().GetString("fluentd.tag")
+	alarmHook := orlog.NewFluentdHook(tag+".attack", fluentdWriter, orlog.InfoLevel)
+	alarmHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("fluentd.format"), common.OpenRASPVersion)
+	lm.alarm.AddHook(alarmHook)
+	policyHook := orlog.NewFluentdHook(tag+".policy", fluentdWriter, orlog.InfoLevel)
+	policyHook.Formatter = orlog.NewFormatter(GetGeneral().GetString("fluentd.format"), common.OpenRASPVersion)
+	lm.policy.AddHook(policyHook)
+}
+
+func (lm *LogManager) clearHooks() {
 	lm.alarm.ClearHooks()
-	lm.alarm.AddHook(orlog.NewHttpHook("attack", cm, orlog.InfoLevel, orlog.NewTokenBucket(uint64(capacity), duration)))
 	lm.policy.ClearHooks()
-	lm.policy.AddHook(orlog.NewHttpHook("policy", cm, orlog.InfoLevel, orlog.NewTokenBucket(uint64(capacity), duration)))
 	lm.rasp.ClearHooks()
-	lm.rasp.AddHook(orlog.NewHttpHook("error", cm, orlog.WarnLevel, orlog.NewTokenBucket(uint64(capacity), duration)))
+	if lm.syslogWriter != nil {
+		lm.syslogWriter.Close()
+		lm.syslogWriter = nil
+	}
+	if lm.kafkaWriter != nil {
+		lm.kafkaWriter.Close()
+		lm.kafkaWriter = nil
+	}
+	if lm.splunkWriter != nil {
+		lm.splunkWriter.Close()
+		lm.splunkWriter = nil
+	}
+	if lm.fluentdWriter != nil {
+		lm.fluentdWriter.Close()
+		lm.fluentdWriter = nil
+	}
+	for _, hw := range lm.httpWriters {
+		hw.Close()
+	}
+	lm.httpWriters = nil
 }
 
 func (lm *LogManager) OnConfigUpdate() {
 	lm.UpdateFileWriter()
-	clouldEnable := GetBasic().GetBool("cloud.enable")
-	if clouldEnable {
+	lm.writersMu.Lock()
+	defer lm.writersMu.Unlock()
+	lm.clearHooks()
+	if CloudEnabled() && GetGeneral().GetBool("log.cloud.enable") {
 		lm.UpdateHttpHook()
 	}
+	if GetGeneral().GetBool("syslog.enable") {
+		lm.UpdateSyslogHook()
+	}
+	// alarms stay on local files and syslog in offline mode
+	if IsOffline() {
+		for _, name := range []string{"kafka", "splunk", "fluentd"} {
+			if GetGeneral().GetBool(name + ".enable") {
+				lm.RaspWarn(name+".enable is ignored in offline mode", orlog.Config)
+			}
+		}
+		return
+	}
+	if GetGeneral().GetBool("kafka.enable") {
+		lm.UpdateKafkaHook()
+	}
+	if GetGeneral().GetBool("splunk.enable") {
+		lm.UpdateSplunkHook()
+	}
+	if GetGeneral().GetBool("fluentd.enable") {
+		lm.UpdateFluentdHook()
+	}
+}
+
+// logSettingPrefixes are the keys Reconfigure accepts
+var logSettingPrefixes = []string{"log.", "syslog.", "kafka.", "splunk.", "fluentd."}
+
+// Reconfigure changes log settings at runtime, levels, hooks and destinations
+// of every logger are rebuilt from the merged config without a restart
+func (lm *LogManager) Reconfigure(settings map[string]interface{}) error {
+	return lm.reconfigure(config.SourceAPI, settings)
+}
+
+func (lm *LogManager) reconfigure(source config.Source, settings map[string]interface{}) error {
+	for key, value := range settings {
+		if !isLogSetting(key) {
+			return fmt.Errorf("%s is not a log setting", key)
+		}
+		if strings.HasPrefix(key, "log.level.") {
+			if _, err := orlog.LevelFromString(cast.ToString(value)); err != nil {
+				return err
+			}
+		}
+	}
+	return GetGeneral().UpdateFrom(source, settings)
+}
+
+// SetLevel changes the level of alarm, policy, plugin, rasp or audit logger
+func (lm *LogManager) SetLevel(name string, level orlog.Level) error {
+	return lm.Reconfigure(map[string]interface{}{
+		"log.level." + name: orlog.LevelName(level),
+	})
+}
+
+// SetHookEnable turns file, cloud, syslog, kafka, splunk or fluentd output on or off
+func (lm *LogManager) SetHookEnable(name string, enable bool) error {
+	switch name {
+	case "file", "cloud":
+		return lm.Reconfigure(map[string]interface{}{"log." + name + ".enable": enable})
+	case "syslog", "kafka", "splunk", "fluentd":
+		return lm.Reconfigure(map[string]interface{}{name + ".enable": enable})
+	default:
+		return fmt.Errorf("unknown log hook %s", name)
+	}
+}
+
+func isLogSetting(key string) bool {
+	if strings.HasPrefix(key, "log.level.") {
+		name := strings.TrimPrefix(key, "log.level.")
+		return name == "alarm" || name == "policy" || name == "plugin" || name == "rasp" || name == "audit"
+	}
+	for _, prefix := range logSettingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAlarmHook attaches a logrus hook to alarm logs, e.g. to forward attacks
+// to a pager or chat, the hook receives entries in the alarm format
+func (lm *LogManager) AddAlarmHook(hook orlog.Hook) {
+	lm.alarm.AddUserHook(hook)
+}
+
+// AddPolicyHook attaches a logrus hook to policy logs
+func (lm *LogManager) AddPolicyHook(hook orlog.Hook) {
+	lm.policy.AddUserHook(hook)
 }
 
 func (lm *LogManager) PolicyInfo(message string) {
@@ -182,10 +733,21 @@ func (lm *LogManager) PluginInfo(message string) {
 	lm.GetPlugin().Info(message)
 }
 
+// AuditInfo writes a config change to audit.log
+func (lm *LogManager) AuditInfo(message string) {
+	lm.GetAudit().Info(message)
+}
+
 func (lm *LogManager) AlarmInfo(message string) {
 	lm.GetAlarm().Info(message)
 }
 
+// AlarmInfoForApp tags the alarm with the application it is attributed to,
+// the cloud hook of that application uploads it
+func (lm *LogManager) AlarmInfoForApp(message, appId string) {
+	lm.GetAlarm().logger.WithField("app_id", appId).Info(message)
+}
+
 func (lm *LogManager) RaspInfo(message string, moduleCode orlog.ModuleCode) {
 	lm.GetRasp().Info(buildRaspLog(message, orlog.LevelName(orlog.InfoLevel), moduleCode))
 }
@@ -201,7 +763,7 @@ func (lm *LogManager) RaspDebug(message string, moduleCode orlog.ModuleCode) {
 func buildRaspLog(message, level string, moduleCode orlog.ModuleCode) string {
 	rl := &model.RaspLog{
 		System:     GetGlobals().System,
-		StackTrace: strings.Join(stacktrace.LogFormat(stacktrace.AppendStacktrace(nil, 1, GetGeneral().GetInt("log.maxstack"))), "\n"),
+		StackTrace: goroutineLine() + "\n" + formatStack(stacktrace.Frames(collectStack())),
 		RaspId:     GetGlobals().RaspId,
 		AppId:      GetBasic().GetString("cloud.app_id"),
 		EventTime:  utils.CurrentISO8601Time(),
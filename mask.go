@@ -0,0 +1,229 @@
+package openrasp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+const maskedValue = "******"
+
+// dsnCredentialRegex matches the password of user:password@host style DSNs
+var dsnCredentialRegex = regexp.MustCompile(`([^\s:/@'"]+):([^\s@'"/]+)@`)
+
+// dsnFields are the lower-cased names of the param fields holding a DSN or
+// url, the passwords of the other strings such as queries are left alone
+var dsnFields = map[string]bool{
+	"server":           true,
+	"servers":          true,
+	"dsn":              true,
+	"connectionstring": true,
+	"url":              true,
+	"hostname":         true,
+}
+
+// Masker redacts credentials from logs: configured headers, fields whose
+// name matches log.mask.fields and passwords embedded in DSNs
+type Masker struct {
+	enable     bool
+	headers    map[string]bool
+	fieldRegex *regexp.Regexp
+	formRegex  *regexp.Regexp
+	jsonRegex  *regexp.Regexp
+	privacy    privacyRules
+	mu         sync.RWMutex
+}
+
+func NewMasker() *Masker {
+	m := &Masker{}
+	m.OnConfigUpdate()
+	return m
+}
+
+func (m *Masker) OnConfigUpdate() {
+	headers := make(map[string]bool)
+	for _, header := range GetGeneral().GetStringSlice("log.mask.headers") {
+		headers[strings.ToLower(header)] = true
+	}
+	fields := GetGeneral().GetString("log.mask.fields")
+	fieldRegex, err := regexp.Compile(fields)
+	if err != nil {
+		GetLog().RaspWarn("Invalid log.mask.fields, cuz of "+err.Error(), orlog.Config)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enable = GetGeneral().GetBool("log.mask.enable")
+	m.headers = headers
+	m.fieldRegex = fieldRegex
+	m.privacy = newPrivacyRules()
+	m.formRegex = regexp.MustCompile(`([^&=\s]*(?:` + fields + `)[^&=\s]*)=[^&\s]*`)
+	m.jsonRegex = regexp.MustCompile(`("[^"]*(?:` + fields + `)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+}
+
+// MaskDSN hides the password of a DSN or url
+func MaskDSN(dsn string) string {
+	return dsnCredentialRegex.ReplaceAllString(dsn, "$1:"+maskedValue+"@")
+}
+
+func (m *Masker) sensitiveField(name string) bool {
+	return m.fieldRegex != nil && m.fieldRegex.MatchString(name)
+}
+
+func (m *Masker) maskUrl(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return MaskDSN(rawurl)
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), maskedValue)
+		}
+	}
+	if len(u.RawQuery) > 0 {
+		u.RawQuery = m.formRegex.ReplaceAllString(u.RawQuery, "$1="+maskedValue)
+	}
+	return u.String()
+}
+
+func (m *Masker) maskBody(body string) string {
+	body = m.formRegex.ReplaceAllString(body, "$1="+maskedValue)
+	return m.jsonRegex.ReplaceAllString(body, `$1"`+maskedValue+`"`)
+}
+
+// MaskRequestInfo returns a redacted copy, the original is still used by
+// the checks of the ongoing request
+func (m *Masker) MaskRequestInfo(ri *model.RequestInfo) *model.RequestInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ri == nil || (!m.enable && !m.privacy.enable) {
+		return ri
+	}
+	masked := *ri
+	if ri.RequestBody != nil {
+		body := *ri.RequestBody
+		masked.RequestBody = &body
+	}
+	if m.enable {
+		masked.Header = make(map[string]string, len(ri.Header))
+		for k, v := range ri.Header {
+			if m.headers[strings.ToLower(k)] {
+				v = maskedValue
+			}
+			masked.Header[k] = v
+		}
+		masked.UrlFull = m.maskUrl(ri.UrlFull)
+		if body := masked.RequestBody; body != nil {
+			body.Truncated = m.maskBody(body.Truncated)
+			if body.Form != nil {
+				body.Form = make(url.Values, len(ri.RequestBody.Form))
+				for k, v := range ri.RequestBody.Form {
+					if m.sensitiveField(k) {
+						v = []string{maskedValue}
+					}
+					body.Form[k] = v
+				}
+			}
+		}
+	}
+	if m.privacy.enable {
+		m.privacy.apply(&masked)
+	}
+	return &masked
+}
+
+// secretSettings are config keys holding a secret which log.mask.fields
+// does not match
+var secretSettings = map[string]bool{
+	"log.encryption.key": true,
+	"log.privacy.salt":   true,
+	"challenge.secret":   true,
+	"admin.token":        true,
+}
+
+// MaskSetting redacts the value of a config key for the audit log, secrets
+// are masked even when log.mask.enable is off
+func (m *Masker) MaskSetting(key string, value interface{}) interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if value == nil {
+		return nil
+	}
+	name := key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		name = key[i+1:]
+	}
+	if secretSettings[key] || m.sensitiveField(name) {
+		return maskedValue
+	}
+	generic, err := toGeneric(value)
+	if err != nil {
+		return value
+	}
+	return m.maskValue(generic, true)
+}
+
+// MaskParams redacts attack or policy params through their JSON form, DSN
+// passwords are masked in dsnFields only
+func (m *Masker) MaskParams(params interface{}) interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if params == nil || !m.enable {
+		return params
+	}
+	generic, err := toGeneric(params)
+	if err != nil {
+		return params
+	}
+	return m.maskValue(generic, false)
+}
+
+// toGeneric round trips v through JSON, numbers are kept as json.Number so
+// large integers are not turned into floats
+func toGeneric(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// maskValue masks the sensitive fields of v, and the DSN passwords of its
+// strings when dsn is set or under one of dsnFields
+func (m *Masker) maskValue(v interface{}, dsn bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			if _, isString := item.(string); isString && m.sensitiveField(k) {
+				value[k] = maskedValue
+			} else {
+				value[k] = m.maskValue(item, dsn || dsnFields[strings.ToLower(k)])
+			}
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = m.maskValue(item, dsn)
+		}
+		return value
+	case string:
+		if dsn {
+			return MaskDSN(value)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -1,28 +1,63 @@
 package model
 
-import "encoding/json"
+// StackResolver returns the stack trace and the source code of a log, it
+// runs when the log is marshaled, so a log which is filtered out is never
+// symbolized
+type StackResolver func() (stackTrace string, sourceCode []string)
 
 type AttackLog struct {
 	*AttackResult
 	*Server
 	*System
 	*RequestInfo
-	AttackParams interface{} `json:"attack_params"`
-	SourceCode   []string    `json:"source_code"`
-	StackTrace   string      `json:"stack_trace"`
-	RaspId       string      `json:"rasp_id"`
-	AppId        string      `json:"app_id"`
-	ServerIp     string      `json:"server_ip"`
-	EventTime    string      `json:"event_time"`
-	EventType    string      `json:"event_type"`
-	AttackType   string      `json:"attack_type"`
+	AttackParams interface{}            `json:"attack_params"`
+	SourceCode   []string               `json:"source_code"`
+	StackTrace   string                 `json:"stack_trace"`
+	RaspId       string                 `json:"rasp_id"`
+	AppId        string                 `json:"app_id"`
+	ServerIp     string                 `json:"server_ip"`
+	EventTime    string                 `json:"event_time"`
+	EventType    string                 `json:"event_type"`
+	AttackType   string                 `json:"attack_type"`
+	HitCount     int                    `json:"hit_count,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	TraceId      string                 `json:"trace_id,omitempty"`
+	SpanId       string                 `json:"span_id,omitempty"`
+	Schema       int                    `json:"schema_version"`
+	resolver     StackResolver
 }
 
 func (al *AttackLog) String() string {
-	b, err := json.Marshal(al)
+	str, err := stringVersion(al.current(), CurrentSchemaVersion)
 	if err != nil {
 		return ""
-	} else {
-		return string(b)
 	}
+	return str
+}
+
+// SetStackResolver defers StackTrace and SourceCode to ResolveStack
+func (al *AttackLog) SetStackResolver(resolver StackResolver) {
+	al.resolver = resolver
+}
+
+// ResolveStack fills in StackTrace and SourceCode with the resolver, once
+func (al *AttackLog) ResolveStack() {
+	if al.resolver != nil {
+		al.StackTrace, al.SourceCode = al.resolver()
+		al.resolver = nil
+	}
+}
+
+// MarshalVersion encodes the log in the layout of the given schema version
+func (al *AttackLog) MarshalVersion(version int) ([]byte, error) {
+	return marshalVersion(al.current(), version)
+}
+
+// current returns a copy of the log with its stack resolved, in the current
+// schema
+func (al *AttackLog) current() *AttackLog {
+	al.ResolveStack()
+	current := *al
+	current.Schema = CurrentSchemaVersion
+	return &current
 }
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AttackType describes a class of attack, Name is written to the attack_type
+// field of AttackLog and DefaultAction applies when nothing configures one.
+// DefaultSeverity rates the results which carry no severity of their own.
+type AttackType struct {
+	Name            string
+	DisplayName     string
+	DefaultAction   InterceptCode
+	DefaultSeverity string
+}
+
+// NewAttackResult creates a result of the attack type with its default action
+func (at *AttackType) NewAttackResult(message, algorithm string, confidence uint64) *AttackResult {
+	ar := NewAttackResult(InterceptCodeToString(at.DefaultAction), message, algorithm, at.Name, confidence)
+	ar.Severity = at.DefaultSeverity
+	return ar
+}
+
+var attackTypes = struct {
+	sync.RWMutex
+	m map[string]*AttackType
+}{m: make(map[string]*AttackType)}
+
+func init() {
+	for _, at := range []AttackType{
+		{"sql_exception", "SQL exception", Log, SeverityMedium},
+		{"sql", "SQL injection", Log, SeverityCritical},
+		{"readFile", "Arbitrary file read", Log, SeverityHigh},
+		{"writeFile", "Arbitrary file write", Log, SeverityCritical},
+		{"webshell_file", "Webshell file", Log, SeverityCritical},
+		{"xxe", "XML external entity", Log, SeverityHigh},
+		{"ssti", "Server side template injection", Log, SeverityCritical},
+		{"deserialization", "Deserialization", Log, SeverityCritical},
+		{"ldap", "LDAP injection", Log, SeverityHigh},
+		{"ssrf", "Server side request forgery", Log, SeverityHigh},
+		{"dns_exfiltration", "DNS exfiltration", Log, SeverityHigh},
+		{"zip_slip", "Zip slip", Log, SeverityHigh},
+		{"decompression_bomb", "Decompression bomb", Log, SeverityMedium},
+		{"memcache_injection", "Memcache injection", Log, SeverityHigh},
+		{"mail_header_injection", "Mail header injection", Log, SeverityMedium},
+		{"request", "Malicious request", Log, SeverityMedium},
+		{"graphql", "GraphQL injection or abuse", Log, SeverityHigh},
+		{"authentication", "Authentication attack", Log, SeverityHigh},
+		{"brute_force", "Brute force login", Log, SeverityHigh},
+		{"data_leak", "Sensitive data leak", Log, SeverityHigh},
+	} {
+		RegisterAttackTypeWithSeverity(at.Name, at.DisplayName, at.DefaultAction, at.DefaultSeverity)
+	}
+}
+
+// RegisterAttackType adds an attack type for hook packages outside of this
+// module, a name can only be registered once
+func RegisterAttackType(name, displayName string, defaultAction InterceptCode) error {
+	return RegisterAttackTypeWithSeverity(name, displayName, defaultAction, SeverityMedium)
+}
+
+// RegisterAttackTypeWithSeverity is RegisterAttackType rating the results of
+// the attack type defaultSeverity when they carry no severity of their own
+func RegisterAttackTypeWithSeverity(name, displayName string, defaultAction InterceptCode, defaultSeverity string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("attack type name is empty")
+	}
+	if defaultAction < Block || defaultAction > Challenge {
+		return fmt.Errorf("invalid default action %d of attack type %s", defaultAction, name)
+	}
+	if !ValidSeverity(defaultSeverity) {
+		return fmt.Errorf("invalid default severity %s of attack type %s", defaultSeverity, name)
+	}
+	if len(displayName) == 0 {
+		displayName = name
+	}
+	attackTypes.Lock()
+	defer attackTypes.Unlock()
+	if _, ok := attackTypes.m[name]; ok {
+		return fmt.Errorf("attack type %s is already registered", name)
+	}
+	attackTypes.m[name] = &AttackType{
+		Name:            name,
+		DisplayName:     displayName,
+		DefaultAction:   defaultAction,
+		DefaultSeverity: defaultSeverity,
+	}
+	return nil
+}
+
+func LookupAttackType(name string) (*AttackType, bool) {
+	attackTypes.RLock()
+	defer attackTypes.RUnlock()
+	at, ok := attackTypes.m[name]
+	return at, ok
+}
+
+// AttackTypeDisplayName returns the display name of name, name itself when
+// it is not registered
+func AttackTypeDisplayName(name string) string {
+	if at, ok := LookupAttackType(name); ok {
+		return at.DisplayName
+	}
+	return name
+}
+
+// AttackTypes returns all the registered attack types sorted by name
+func AttackTypes() []*AttackType {
+	attackTypes.RLock()
+	defer attackTypes.RUnlock()
+	ats := make([]*AttackType, 0, len(attackTypes.m))
+	for _, at := range attackTypes.m {
+		ats = append(ats, at)
+	}
+	sort.Slice(ats, func(i, j int) bool {
+		return ats[i].Name < ats[j].Name
+	})
+	return ats
+}
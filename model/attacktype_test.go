@@ -0,0 +1,35 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterAttackType(t *testing.T) {
+	assert.Equal(t, "SQL injection", AttackTypeDisplayName("sql"))
+	assert.Equal(t, "doom", AttackTypeDisplayName("doom"))
+
+	assert.NoError(t, RegisterAttackType("graphql_injection", "GraphQL injection", Block))
+	assert.Error(t, RegisterAttackType("graphql_injection", "", Log))
+	assert.Error(t, RegisterAttackType("sql", "", Log))
+	assert.Error(t, RegisterAttackType("", "", Log))
+	assert.Error(t, RegisterAttackType("bad_action", "", InterceptCode(4)))
+	assert.NoError(t, RegisterAttackType("cc", "Challenge collapsar", Challenge))
+	assert.Equal(t, Challenge, InterceptStringToCode(InterceptCodeToString(Challenge)))
+
+	at, ok := LookupAttackType("graphql_injection")
+	assert.True(t, ok)
+	assert.Equal(t, "GraphQL injection", at.DisplayName)
+	ar := at.NewAttackResult("introspection query", "go_custom_plugin", 90)
+	assert.Equal(t, "block", ar.InterceptState)
+	assert.Equal(t, "graphql_injection", ar.PluginName)
+	assert.Equal(t, Block, ar.GetInterceptState())
+
+	names := []string{}
+	for _, at := range AttackTypes() {
+		names = append(names, at.Name)
+	}
+	assert.Contains(t, names, "graphql_injection")
+	assert.IsIncreasing(t, names)
+}
@@ -0,0 +1,31 @@
+package model
+
+// AuditLog records a single config change, the values of secret keys are
+// masked
+type AuditLog struct {
+	Source    string      `json:"source"`
+	Key       string      `json:"key"`
+	OldValue  interface{} `json:"old_value"`
+	NewValue  interface{} `json:"new_value"`
+	RaspId    string      `json:"rasp_id"`
+	AppId     string      `json:"app_id"`
+	EventTime string      `json:"event_time"`
+	EventType string      `json:"event_type"`
+	Schema    int         `json:"schema_version"`
+}
+
+func (al *AuditLog) String() string {
+	b, err := al.MarshalVersion(CurrentSchemaVersion)
+	if err != nil {
+		return ""
+	} else {
+		return string(b)
+	}
+}
+
+// MarshalVersion encodes the log in the layout of the given schema version
+func (al *AuditLog) MarshalVersion(version int) ([]byte, error) {
+	current := *al
+	current.Schema = CurrentSchemaVersion
+	return marshalVersion(&current, version)
+}
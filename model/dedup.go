@@ -0,0 +1,116 @@
+package model
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type dedupEntry struct {
+	start      time.Time
+	attackLog  *AttackLog
+	suppressed int
+	lastTime   string
+}
+
+// Deduplicator collapses identical attack logs, of the same attack type, url
+// and parameters, raised within a window into the first one, which is
+// emitted again with the hit count once the window expires. It is
+// independent from the rate limiting of the log destinations.
+type Deduplicator struct {
+	window  time.Duration
+	entries map[string]*dedupEntry
+	// pending holds the aggregated records of the entries replaced by Allow
+	// before Expired ran
+	pending []*AttackLog
+	mu      sync.Mutex
+}
+
+// NewDeduplicator returns a Deduplicator, a window of 0 lets every log pass
+func NewDeduplicator(window time.Duration) *Deduplicator {
+	return &Deduplicator{
+		window:  window,
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+// SetWindow changes the window of the logs raised from now on
+func (d *Deduplicator) SetWindow(window time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.window = window
+}
+
+// DedupKey identifies an attack by type, url and a hash of its parameters
+func DedupKey(attackLog *AttackLog) string {
+	h := sha1.New()
+	h.Write([]byte(attackLog.AttackType))
+	h.Write([]byte{0})
+	if attackLog.RequestInfo != nil {
+		h.Write([]byte(attackLog.RequestInfo.UrlFull))
+	}
+	h.Write([]byte{0})
+	if params, err := json.Marshal(attackLog.AttackParams); err == nil {
+		h.Write(params)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Allow reports whether attackLog raised at now should be written, a repeated
+// one is counted instead
+func (d *Deduplicator) Allow(attackLog *AttackLog, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.window <= 0 {
+		return true
+	}
+	key := DedupKey(attackLog)
+	if entry, ok := d.entries[key]; ok {
+		if now.Sub(entry.start) < d.window {
+			entry.suppressed++
+			entry.lastTime = attackLog.EventTime
+			return false
+		}
+		if entry.suppressed > 0 {
+			d.pending = append(d.pending, entry.summary())
+		}
+	}
+	// the caller may put attackLog back into the pool once it is written
+	first := *attackLog
+	d.entries[key] = &dedupEntry{
+		start:     now,
+		attackLog: &first,
+	}
+	return true
+}
+
+// Expired forgets the logs whose window expired at now and returns the
+// aggregated records of the ones which were repeated, each carries the hit
+// count and the time of the last repetition, along with the records of the
+// entries Allow replaced since the last call
+func (d *Deduplicator) Expired(now time.Time) []*AttackLog {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	summaries := d.pending
+	d.pending = nil
+	for key, entry := range d.entries {
+		if now.Sub(entry.start) < d.window {
+			continue
+		}
+		delete(d.entries, key)
+		if entry.suppressed > 0 {
+			summaries = append(summaries, entry.summary())
+		}
+	}
+	return summaries
+}
+
+// summary is the aggregated record of a repeated entry
+func (entry *dedupEntry) summary() *AttackLog {
+	summary := *entry.attackLog
+	summary.HitCount = entry.suppressed + 1
+	summary.EventTime = entry.lastTime
+	return &summary
+}
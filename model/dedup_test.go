@@ -0,0 +1,65 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicator(t *testing.T) {
+	newLog := func(url, eventTime string) *AttackLog {
+		return &AttackLog{
+			RequestInfo:  &RequestInfo{UrlFull: url},
+			AttackParams: map[string]string{"query": "select 1"},
+			AttackType:   "sql",
+			EventTime:    eventTime,
+		}
+	}
+	start := time.Now()
+	d := NewDeduplicator(10 * time.Second)
+	assert.True(t, d.Allow(newLog("/a", "t0"), start))
+	assert.False(t, d.Allow(newLog("/a", "t1"), start.Add(time.Second)))
+	assert.False(t, d.Allow(newLog("/a", "t2"), start.Add(2*time.Second)))
+	assert.True(t, d.Allow(newLog("/b", "t3"), start.Add(3*time.Second)))
+	assert.Empty(t, d.Expired(start.Add(5*time.Second)))
+
+	summaries := d.Expired(start.Add(10 * time.Second))
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, 3, summaries[0].HitCount)
+	assert.Equal(t, "t2", summaries[0].EventTime)
+	assert.Equal(t, "/a", summaries[0].RequestInfo.UrlFull)
+	assert.True(t, d.Allow(newLog("/a", "t4"), start.Add(11*time.Second)))
+
+	// /b was not repeated, it is forgotten without a summary
+	assert.Empty(t, d.Expired(start.Add(13*time.Second)))
+
+	d.SetWindow(0)
+	assert.True(t, d.Allow(newLog("/a", "t5"), start.Add(12*time.Second)))
+	assert.NotEqual(t, DedupKey(newLog("/a", "")), DedupKey(newLog("/b", "")))
+}
+
+func TestDeduplicatorRepeatBeforeExpired(t *testing.T) {
+	newLog := func(eventTime string) *AttackLog {
+		return &AttackLog{AttackType: "sql", EventTime: eventTime}
+	}
+	start := time.Now()
+	d := NewDeduplicator(10 * time.Second)
+	assert.True(t, d.Allow(newLog("t0"), start))
+	assert.False(t, d.Allow(newLog("t1"), start.Add(time.Second)))
+	assert.False(t, d.Allow(newLog("t2"), start.Add(9*time.Second)))
+	// the window expired and Expired has not run yet
+	assert.True(t, d.Allow(newLog("t3"), start.Add(10*time.Second)))
+	assert.False(t, d.Allow(newLog("t4"), start.Add(11*time.Second)))
+
+	summaries := d.Expired(start.Add(12 * time.Second))
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, 3, summaries[0].HitCount)
+		assert.Equal(t, "t2", summaries[0].EventTime)
+	}
+	summaries = d.Expired(start.Add(20 * time.Second))
+	if assert.Len(t, summaries, 1) {
+		assert.Equal(t, 2, summaries[0].HitCount)
+		assert.Equal(t, "t4", summaries[0].EventTime)
+	}
+}
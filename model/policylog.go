@@ -1,24 +1,51 @@
 package model
 
-import "encoding/json"
-
 type PolicyLog struct {
 	*PolicyResult
 	*Server
 	*System
-	PolicyParams interface{} `json:"policy_params"`
-	SourceCode   []string    `json:"source_code"`
-	StackTrace   string      `json:"stack_trace"`
-	RaspId       string      `json:"rasp_id"`
-	AppId        string      `json:"app_id"`
-	EventTime    string      `json:"event_time"`
+	PolicyParams interface{}            `json:"policy_params"`
+	SourceCode   []string               `json:"source_code"`
+	StackTrace   string                 `json:"stack_trace"`
+	RaspId       string                 `json:"rasp_id"`
+	AppId        string                 `json:"app_id"`
+	EventTime    string                 `json:"event_time"`
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	Schema       int                    `json:"schema_version"`
+	resolver     StackResolver
 }
 
 func (pl *PolicyLog) String() string {
-	b, err := json.Marshal(pl)
+	str, err := stringVersion(pl.current(), CurrentSchemaVersion)
 	if err != nil {
 		return ""
-	} else {
-		return string(b)
 	}
+	return str
+}
+
+// SetStackResolver defers StackTrace and SourceCode to ResolveStack
+func (pl *PolicyLog) SetStackResolver(resolver StackResolver) {
+	pl.resolver = resolver
+}
+
+// ResolveStack fills in StackTrace and SourceCode with the resolver, once
+func (pl *PolicyLog) ResolveStack() {
+	if pl.resolver != nil {
+		pl.StackTrace, pl.SourceCode = pl.resolver()
+		pl.resolver = nil
+	}
+}
+
+// MarshalVersion encodes the log in the layout of the given schema version
+func (pl *PolicyLog) MarshalVersion(version int) ([]byte, error) {
+	return marshalVersion(pl.current(), version)
+}
+
+// current returns a copy of the log with its stack resolved, in the current
+// schema
+func (pl *PolicyLog) current() *PolicyLog {
+	pl.ResolveStack()
+	current := *pl
+	current.Schema = CurrentSchemaVersion
+	return &current
 }
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"bytes"
+	"sync"
+)
+
+// logBufferSize fits a typical log with its request and stack trace
+const logBufferSize = 4 << 10
+
+// maxPooledBuffer keeps the buffers of huge logs out of the pool
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return bytes.NewBuffer(make([]byte, 0, logBufferSize))
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+var attackLogPool = sync.Pool{
+	New: func() interface{} {
+		return new(AttackLog)
+	},
+}
+
+// AcquireAttackLog returns a zeroed AttackLog, which goes back to the pool
+// with Release once nothing references it
+func AcquireAttackLog() *AttackLog {
+	return attackLogPool.Get().(*AttackLog)
+}
+
+// Release zeroes al and puts it back into the pool, al must not be used
+// afterwards
+func (al *AttackLog) Release() {
+	*al = AttackLog{}
+	attackLogPool.Put(al)
+}
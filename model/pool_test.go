@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttackLogRelease(t *testing.T) {
+	al := AcquireAttackLog()
+	al.AttackType = "sql"
+	al.SetStackResolver(func() (string, []string) { return "", nil })
+	al.Release()
+	assert.Equal(t, AttackLog{}, *al)
+}
+
+func TestDeduplicatorKeepsCopy(t *testing.T) {
+	d := NewDeduplicator(10 * time.Second)
+	al := AcquireAttackLog()
+	al.AttackType = "sql"
+	al.RequestInfo = &RequestInfo{UrlFull: "/a"}
+	start := time.Now()
+	assert.True(t, d.Allow(al, start))
+	al.Release()
+	assert.False(t, d.Allow(&AttackLog{AttackType: "sql", RequestInfo: &RequestInfo{UrlFull: "/a"}}, start))
+
+	summaries := d.Expired(start.Add(10 * time.Second))
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, "sql", summaries[0].AttackType)
+	assert.Equal(t, "/a", summaries[0].RequestInfo.UrlFull)
+}
+
+func TestEncodeJSON(t *testing.T) {
+	v := map[string]string{"body": "<script>alert(1)</script>&"}
+	expected, err := json.Marshal(v)
+	assert.Nil(t, err)
+	b, err := marshalVersion(v, CurrentSchemaVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(b))
+	str, err := stringVersion(v, CurrentSchemaVersion)
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), str)
+}
+
+func BenchmarkAttackLogString(b *testing.B) {
+	al := &AttackLog{
+		AttackResult: NewAttackResult("block", "m", "sql", "p", 90),
+		RequestInfo:  &RequestInfo{UrlFull: "/a", Header: map[string]string{"User-Agent": "curl"}},
+		AttackParams: map[string]string{"query": "select 1"},
+		AttackType:   "sql",
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = al.String()
+	}
+}
@@ -1,7 +1,5 @@
 package model
 
-import "encoding/json"
-
 type RaspLog struct {
 	*System
 	StackTrace string `json:"stack_trace"`
@@ -12,13 +10,21 @@ type RaspLog struct {
 	Message    string `json:"message"`
 	Pid        int    `json:"pid"`
 	ErrorCode  int    `json:"error_code,omitempty"`
+	Schema     int    `json:"schema_version"`
 }
 
 func (rl *RaspLog) String() string {
-	b, err := json.Marshal(rl)
+	b, err := rl.MarshalVersion(CurrentSchemaVersion)
 	if err != nil {
 		return ""
 	} else {
 		return string(b)
 	}
 }
+
+// MarshalVersion encodes the log in the layout of the given schema version
+func (rl *RaspLog) MarshalVersion(version int) ([]byte, error) {
+	current := *rl
+	current.Schema = CurrentSchemaVersion
+	return marshalVersion(&current, version)
+}
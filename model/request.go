@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"runtime"
@@ -55,6 +56,8 @@ type RequestInfo struct {
 	AppBasePath  string            `json:"-"`
 	HeaderBytes  []byte            `json:"-"`
 	GetBytes     []byte            `json:"-"`
+	TraceId      string            `json:"-"`
+	SpanId       string            `json:"-"`
 	*RequestBody
 }
 
@@ -87,10 +90,38 @@ func NewRequestInfo(request *http.Request, clientIpHeader string, bodySize int)
 	getBytes, _ := json.Marshal(ri.Get)
 	ri.GetBytes = getBytes
 
+	ri.TraceId, ri.SpanId, _ = ParseTraceparent(request.Header.Get(TraceparentHeader))
+
 	ri.SetRequestBody(rb)
 	return ri
 }
 
+// RequestClient returns the address of the client of request, the value of
+// clientIpHeader behind a proxy or the host of the remote address
+func RequestClient(request *http.Request, clientIpHeader string) string {
+	if len(clientIpHeader) > 0 {
+		if clientIp := request.Header.Get(clientIpHeader); len(clientIp) > 0 {
+			return clientIp
+		}
+	}
+	return remoteHost(request.RemoteAddr)
+}
+
+// Client returns the address of the client as RequestClient does
+func (ri *RequestInfo) Client() string {
+	if len(ri.ClientIp) > 0 {
+		return ri.ClientIp
+	}
+	return remoteHost(ri.RemoteAddr)
+}
+
+func remoteHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
 func joinHeader(source map[string][]string) map[string]string {
 	joinMap := make(map[string]string, len(source))
 	for k, headers := range source {
@@ -99,10 +130,14 @@ func joinHeader(source map[string][]string) map[string]string {
 	return joinMap
 }
 
+// extractHeader keeps the first value of every key, the keys are taken as
+// they are since query parameters are case sensitive
 func extractHeader(source map[string][]string) map[string]string {
 	extractMap := make(map[string]string, len(source))
-	for k, _ := range source {
-		extractMap[k] = http.Header(source).Get(k)
+	for k, values := range source {
+		if len(values) > 0 {
+			extractMap[k] = values[0]
+		}
 	}
 	return extractMap
 }
@@ -115,10 +150,26 @@ func (ri *RequestInfo) GetRequestId() string {
 	return ri.RequestId
 }
 
+// Parameters returns the values of query string and form parameters
+func (ri *RequestInfo) Parameters() []string {
+	var values []string
+	for _, value := range ri.Get {
+		values = append(values, value)
+	}
+	if ri.RequestBody != nil {
+		for _, formValues := range ri.RequestBody.Form {
+			values = append(values, formValues...)
+		}
+	}
+	return values
+}
+
+// NewRequestBody captures the body of req truncated to size, a negative size
+// leaves the body alone
 func NewRequestBody(req *http.Request, size int) *RequestBody {
 	out := &RequestBody{}
 
-	if req.Body == nil {
+	if req.Body == nil || size < 0 {
 		return out
 	}
 
@@ -157,6 +208,11 @@ func NewRequestBody(req *http.Request, size int) *RequestBody {
 
 	r := io.MultiReader(bytes.NewReader(bc.buffer.Bytes()), bc.originalBody)
 	all, err := ioutil.ReadAll(r)
+	// the handler reads the body after the checks, hand it back in full
+	req.Body = &readerCloser{
+		Reader: bytes.NewReader(all),
+		Closer: bc.originalBody,
+	}
 	if err == nil {
 		out.Raw = string(all)
 		out.Truncated = utils.TruncateString(string(all), size)
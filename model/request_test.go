@@ -0,0 +1,40 @@
+package model
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestClient(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.1:51234"
+	assert.Equal(t, "192.0.2.1", RequestClient(req, ""))
+	assert.Equal(t, "192.0.2.1", RequestClient(req, "X-Forwarded-For"))
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	assert.Equal(t, "198.51.100.7", RequestClient(req, "X-Forwarded-For"))
+
+	ri := &RequestInfo{RemoteAddr: "[2001:db8::1]:443"}
+	assert.Equal(t, "2001:db8::1", ri.Client())
+	ri.ClientIp = "198.51.100.7"
+	assert.Equal(t, "198.51.100.7", ri.Client())
+}
+
+func TestRequestInfoGet(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?id=1&id=2&Name=rasp", nil)
+	ri := NewRequestInfo(req, "", 0)
+	assert.Equal(t, map[string]string{"id": "1", "Name": "rasp"}, ri.Get)
+}
+
+func TestNewRequestBody(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"rasp"}`))
+	rb := NewRequestBody(req, 8)
+	assert.Equal(t, `{"name":"rasp"}`, rb.Raw)
+	assert.Equal(t, `{"name":`, rb.Truncated)
+	body, err := ioutil.ReadAll(req.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"name":"rasp"}`, string(body))
+}
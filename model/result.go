@@ -6,6 +6,9 @@ const (
 	Block InterceptCode = iota
 	Log
 	Ignore
+	// Challenge asks the client to solve a challenge page before the request
+	// goes on, a milder Block for floods and uncertain detections
+	Challenge
 )
 
 func InterceptCodeToString(code InterceptCode) string {
@@ -14,6 +17,8 @@ func InterceptCodeToString(code InterceptCode) string {
 		return "block"
 	case Log:
 		return "log"
+	case Challenge:
+		return "challenge"
 	default:
 		return "ignore"
 	}
@@ -25,6 +30,8 @@ func InterceptStringToCode(key string) InterceptCode {
 		return Block
 	case "ignore":
 		return Ignore
+	case "challenge":
+		return Challenge
 	default:
 		return Log
 	}
@@ -36,6 +43,8 @@ type AttackResult struct {
 	PluginAlgorithm  string `json:"plugin_algorithm"`
 	PluginName       string `json:"plugin_name"`
 	InterceptState   string `json:"intercept_state"`
+	Severity         string `json:"severity,omitempty"`
+	WouldBlock       bool   `json:"would_block,omitempty"`
 }
 
 func NewAttackResult(state, message, algorithm, name string, confidence uint64) *AttackResult {
@@ -62,7 +71,10 @@ func NewAttackResultFromMap(m map[string]interface{}) *AttackResult {
 				ar.PluginMessage = message
 			}
 		case "confidence":
-			if confidence, ok := v.(int); ok {
+			switch confidence := v.(type) {
+			case int:
+				ar.PluginConfidence = uint64(confidence)
+			case float64:
 				ar.PluginConfidence = uint64(confidence)
 			}
 		case "name":
@@ -73,6 +85,10 @@ func NewAttackResultFromMap(m map[string]interface{}) *AttackResult {
 			if algorithm, ok := v.(string); ok {
 				ar.PluginAlgorithm = algorithm
 			}
+		case "severity":
+			if severity, ok := v.(string); ok {
+				ar.Severity = severity
+			}
 		default:
 		}
 	}
@@ -84,9 +100,10 @@ func (ar *AttackResult) GetInterceptState() InterceptCode {
 }
 
 type PolicyResult struct {
-	EventType string `json:"event_type"`
-	Message   string `json:"message"`
-	PolicyId  uint64 `json:"policy_id"`
+	EventType  string `json:"event_type"`
+	Message    string `json:"message"`
+	PolicyId   uint64 `json:"policy_id"`
+	WouldBlock bool   `json:"would_block,omitempty"`
 }
 
 func NewPolicyResult(message string, policyId uint64) *PolicyResult {
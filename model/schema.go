@@ -0,0 +1,80 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Schema versions of the JSON logs, downstream parsers should dispatch on
+// the schema_version field and treat a missing one as version 1.
+//
+// Version 1 is the original layout of AttackLog, PolicyLog and RaspLog.
+// Version 2 adds schema_version to all of them and hit_count to AttackLog.
+//
+// Fields are never renamed or removed within a version, a new version is
+// introduced instead and MarshalVersion keeps producing the older ones.
+const (
+	SchemaVersion1       = 1
+	SchemaVersion2       = 2
+	CurrentSchemaVersion = SchemaVersion2
+)
+
+// fieldsSince lists the fields introduced by each version
+var fieldsSince = map[int][]string{
+	SchemaVersion2: {"schema_version", "hit_count"},
+}
+
+// marshalVersion marshals v, which is expected to carry the current schema,
+// and strips the fields introduced after version
+func marshalVersion(v interface{}, version int) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeVersion(buf, v, version); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// stringVersion is marshalVersion without the copy of a []byte result
+func stringVersion(v interface{}, version int) (string, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := encodeVersion(buf, v, version); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// encodeVersion writes the JSON of v in the layout of version to buf
+func encodeVersion(buf *bytes.Buffer, v interface{}, version int) error {
+	if version < SchemaVersion1 || version > CurrentSchemaVersion {
+		return fmt.Errorf("unknown log schema version %d", version)
+	}
+	if err := encodeJSON(buf, v); err != nil || version == CurrentSchemaVersion {
+		return err
+	}
+	var m map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&m); err != nil {
+		return err
+	}
+	for since := version + 1; since <= CurrentSchemaVersion; since++ {
+		for _, field := range fieldsSince[since] {
+			delete(m, field)
+		}
+	}
+	buf.Reset()
+	return encodeJSON(buf, m)
+}
+
+// encodeJSON writes the JSON of v to buf as json.Marshal returns it
+func encodeJSON(buf *bytes.Buffer, v interface{}) error {
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+	// Encode terminates the value with a newline
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttackLogSchema(t *testing.T) {
+	al := &AttackLog{
+		AttackResult: NewAttackResult("block", "m", "sql", "p", 90),
+		EventType:    "attack",
+		AttackType:   "sql",
+		HitCount:     3,
+	}
+	b, err := al.MarshalVersion(SchemaVersion2)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"schema_version":2`)
+	assert.Contains(t, string(b), `"hit_count":3`)
+	assert.Equal(t, string(b), al.String())
+	assert.Equal(t, 0, al.Schema)
+
+	b, err = al.MarshalVersion(SchemaVersion1)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(b), "schema_version")
+	assert.NotContains(t, string(b), "hit_count")
+	assert.Contains(t, string(b), `"attack_type":"sql"`)
+	assert.Contains(t, string(b), `"intercept_state":"block"`)
+}
+
+func TestPolicyLogSchema(t *testing.T) {
+	pl := &PolicyLog{
+		PolicyResult: NewPolicyResult("m", 3006),
+		EventTime:    "now",
+	}
+	assert.Contains(t, pl.String(), `"schema_version":2`)
+	b, err := pl.MarshalVersion(SchemaVersion1)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(b), "schema_version")
+	assert.Contains(t, string(b), `"policy_id":3006`)
+}
+
+func TestRaspLogSchema(t *testing.T) {
+	rl := &RaspLog{Message: "m", Level: "INFO"}
+	assert.Contains(t, rl.String(), `"schema_version":2`)
+	b, err := rl.MarshalVersion(SchemaVersion1)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"app_id":"","event_time":"","level":"INFO","message":"m","pid":0,"rasp_id":"","stack_trace":""}`, string(b))
+}
+
+func TestUnknownSchemaVersion(t *testing.T) {
+	rl := &RaspLog{}
+	_, err := rl.MarshalVersion(0)
+	assert.NotNil(t, err)
+	_, err = rl.MarshalVersion(CurrentSchemaVersion + 1)
+	assert.NotNil(t, err)
+}
+
+func TestCustomFields(t *testing.T) {
+	al := &AttackLog{AttackType: "sql"}
+	assert.NotContains(t, al.String(), "custom_fields")
+	al.CustomFields = map[string]interface{}{"tenant": "t1"}
+	assert.Contains(t, al.String(), `"custom_fields":{"tenant":"t1"}`)
+	pl := &PolicyLog{PolicyResult: NewPolicyResult("m", 3006), CustomFields: map[string]interface{}{"region": "eu"}}
+	assert.Contains(t, pl.String(), `"custom_fields":{"region":"eu"}`)
+}
+
+func TestStackResolver(t *testing.T) {
+	resolved := 0
+	al := &AttackLog{AttackResult: NewAttackResult("log", "m", "sql", "p", 90)}
+	al.SetStackResolver(func() (string, []string) {
+		resolved++
+		return "main.go(main.main:3)", []string{"3: db.Query(q)"}
+	})
+	assert.Equal(t, 0, resolved)
+	assert.Contains(t, al.String(), `"stack_trace":"main.go(main.main:3)"`)
+	assert.Contains(t, al.String(), `"source_code":["3: db.Query(q)"]`)
+	assert.Equal(t, 1, resolved)
+}
@@ -0,0 +1,56 @@
+package model
+
+// Severities of an AttackResult from the least to the most severe
+const (
+	SeverityInfo     = "info"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severities = []string{SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// MaxConfidence is the confidence of a detection which is certain
+const MaxConfidence = 100
+
+// lowConfidence is the confidence below which a detection is rated one
+// severity lower than its attack type
+const lowConfidence = 60
+
+// SeverityRank returns the position of severity from 0 for info to 4 for
+// critical, -1 for an unknown one
+func SeverityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// ValidSeverity reports whether severity is one of the known severities
+func ValidSeverity(severity string) bool {
+	return SeverityRank(severity) >= 0
+}
+
+// Score fills in the severity of a result whose checker or plugin gave none
+// or an unknown one with the default severity of attackType, lowered by one
+// level when the confidence is low, and caps the confidence at MaxConfidence
+func (ar *AttackResult) Score(attackType string) {
+	if ar.PluginConfidence > MaxConfidence {
+		ar.PluginConfidence = MaxConfidence
+	}
+	if ValidSeverity(ar.Severity) {
+		return
+	}
+	severity := SeverityMedium
+	if at, ok := LookupAttackType(attackType); ok {
+		severity = at.DefaultSeverity
+	}
+	rank := SeverityRank(severity)
+	if ar.PluginConfidence < lowConfidence && rank > 0 {
+		rank--
+	}
+	ar.Severity = severities[rank]
+}
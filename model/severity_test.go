@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	ar := NewAttackResult("log", "m", "sql_userinput", "p", 90)
+	ar.Score("sql")
+	assert.Equal(t, SeverityCritical, ar.Severity)
+
+	ar = NewAttackResult("log", "m", "sql_userinput", "p", 30)
+	ar.Score("sql")
+	assert.Equal(t, SeverityHigh, ar.Severity)
+
+	ar = NewAttackResult("log", "m", "a", "p", 150)
+	ar.Score("unknown")
+	assert.Equal(t, SeverityMedium, ar.Severity)
+	assert.Equal(t, uint64(MaxConfidence), ar.PluginConfidence)
+
+	ar = NewAttackResultFromMap(map[string]interface{}{"action": "log", "confidence": 10.0, "severity": "low"})
+	ar.Score("sql")
+	assert.Equal(t, SeverityLow, ar.Severity)
+
+	ar = NewAttackResultFromMap(map[string]interface{}{"action": "log", "confidence": 90.0, "severity": "urgent"})
+	ar.Score("readFile")
+	assert.Equal(t, SeverityHigh, ar.Severity)
+
+	assert.Error(t, RegisterAttackTypeWithSeverity("bad_severity", "", Log, "urgent"))
+	assert.True(t, SeverityRank(SeverityCritical) > SeverityRank(SeverityInfo))
+}
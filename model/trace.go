@@ -0,0 +1,41 @@
+package model
+
+import "strings"
+
+// TraceparentHeader carries the trace context of W3C Trace Context
+const TraceparentHeader = "Traceparent"
+
+// ParseTraceparent returns the trace id and parent span id of a traceparent
+// header such as 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01,
+// ok is false when the header is malformed or carries an all zero id
+func ParseTraceparent(traceparent string) (traceId, spanId string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false
+	}
+	// version 00 has exactly four fields, later ones may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return "", "", false
+	}
+	traceId, spanId = parts[1], parts[2]
+	if !validTraceId(traceId, 32) || !validTraceId(spanId, 16) {
+		return "", "", false
+	}
+	return traceId, spanId, true
+}
+
+// validTraceId reports whether id is made of n lowercase hex digits which
+// are not all zero
+func validTraceId(id string, n int) bool {
+	if len(id) != n {
+		return false
+	}
+	zero := true
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+		zero = zero && c == '0'
+	}
+	return !zero
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	traceId, spanId, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceId)
+	assert.Equal(t, "00f067aa0ba902b7", spanId)
+
+	_, _, ok = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future")
+	assert.True(t, ok)
+
+	for _, traceparent := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		_, _, ok := ParseTraceparent(traceparent)
+		assert.False(t, ok, traceparent)
+	}
+}
+
+func TestRequestInfoTrace(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ri := NewRequestInfo(req, "", 0)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", ri.TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", ri.SpanId)
+
+	al := &AttackLog{RequestInfo: ri, TraceId: ri.TraceId, SpanId: ri.SpanId}
+	assert.Contains(t, al.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7"`)
+	assert.NotContains(t, (&AttackLog{}).String(), "trace_id")
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/baidu-security/openrasp-golang/common"
 	"github.com/baidu-security/openrasp-golang/config"
 	"github.com/baidu-security/openrasp-golang/orlog"
-	v8 "github.com/baidu-security/openrasp-v8/go"
+	"github.com/baidu-security/openrasp-golang/utils"
 )
 
 var workSpace *common.WorkSpace
@@ -20,10 +20,28 @@ var general *config.GeneralConfig
 var logManager *LogManager
 var pluginManager *PluginManager
 var whiteList *WhiteList
+var alarmFilter *AlarmFilter
+var masker *Masker
+var hookSwitch *HookSwitch
+var algorithmConfig *AlgorithmConfig
+var verdictCache *VerdictCache
+var statistics *Statistics
+var glsMonitor *GlsMonitor
+var weakPasswords *WeakPasswords
+var sampler *Sampler
+var asyncDetector *AsyncDetector
+var statsdReporter *StatsdReporter
+var adminServer *AdminServer
+var dependencyScanner *DependencyScanner
+var appRouter *AppRouter
 var buildinAction *BuildinAction
 var cloudManager *cloud.Client
 var complete bool
 
+// configErr is why the config files were not loaded on start, the embedded
+// defaults are used then
+var configErr error
+
 func init() {
 	executeDir, err := getExecutableDir()
 	if err != nil {
@@ -31,7 +49,13 @@ func init() {
 		return
 	}
 	workSpace = common.NewWorkSpace(executeDir)
-	workSpace.Init()
+	if err := workSpace.Init(); err != nil {
+		// the directory of the executable is often read-only in containers
+		log.Printf("Unable to init workspace in %s, cuz of %v, falling back to %s", executeDir, err, os.TempDir())
+		workSpace.Close()
+		workSpace = common.NewWorkSpace(os.TempDir())
+		workSpace.Init()
+	}
 	if !workSpace.Active() {
 		log.Printf("Fail to init workspace.")
 		return
@@ -53,17 +77,51 @@ func init() {
 		return
 	}
 	logManager.UpdateFileWriter()
+	logManager.StartDropSummary()
+	initErrorReport()
 	GetGeneral().AttachListener(logManager)
+	GetGeneral().AttachListener(NewStackFilter())
 
 	whiteList = NewWhiteList()
 	GetGeneral().AttachListener(whiteList)
 
-	if !v8.Initialize(logManager.PluginInfo) {
-		GetLog().RaspWarn("Unable to initialize v8.", orlog.Plugin)
-		return
-	} else {
-		GetLog().RaspDebug("Initialize v8 successfully.", orlog.Plugin)
-	}
+	alarmFilter = NewAlarmFilter()
+	GetGeneral().AttachListener(alarmFilter)
+
+	masker = NewMasker()
+	GetGeneral().AttachListener(masker)
+	GetBasic().SetAuditor(auditConfigChange)
+	GetGeneral().SetAuditor(auditConfigChange)
+
+	hookSwitch = NewHookSwitch()
+	GetGeneral().AttachListener(hookSwitch)
+
+	algorithmConfig = NewAlgorithmConfig()
+	GetGeneral().AttachListener(algorithmConfig)
+
+	verdictCache = NewVerdictCache()
+	GetGeneral().AttachListener(verdictCache)
+
+	statistics = NewStatistics()
+	GetGeneral().AttachListener(statistics)
+
+	glsMonitor = NewGlsMonitor()
+	GetGeneral().AttachListener(glsMonitor)
+
+	weakPasswords = NewWeakPasswords()
+	GetGeneral().AttachListener(weakPasswords)
+
+	sampler = NewSampler()
+	GetGeneral().AttachListener(sampler)
+
+	asyncDetector = NewAsyncDetector()
+	GetGeneral().AttachListener(asyncDetector)
+
+	statsdReporter = NewStatsdReporter()
+	GetGeneral().AttachListener(statsdReporter)
+
+	adminServer = NewAdminServer()
+	GetGeneral().AttachListener(adminServer)
 
 	confDir, err := workSpace.GetDir(common.Conf)
 	if err != nil {
@@ -71,6 +129,36 @@ func init() {
 		return
 	}
 
+	// without config files the embedded defaults detect and log locally
+	if _, err := basic.LoadFiles(configPaths(confDir)...); err != nil {
+		configErr = err
+		GetLog().RaspWarn(err.Error(), orlog.Log)
+	}
+
+	if IsOffline() {
+		if basic.GetBool("cloud.enable") {
+			GetLog().RaspWarn("cloud.enable is ignored in offline mode", orlog.Config)
+		}
+		if bundleDir := basic.GetString("offline.bundle_dir"); len(bundleDir) > 0 {
+			if err := relocateBundle(bundleDir); err != nil {
+				GetLog().RaspWarn("Unable to load offline bundle, cuz of "+err.Error(), orlog.Config)
+				return
+			}
+			confDir = bundleDir
+		}
+	}
+
+	if !initPluginEngine() {
+		return
+	}
+	initWasmEngine()
+
+	var bindings []*AppBinding
+	if err := basic.UnmarshalKey("cloud.apps", &bindings); err != nil {
+		GetLog().RaspWarn("Unable to load cloud.apps, cuz of "+err.Error(), orlog.Config)
+	}
+	appRouter = NewAppRouter(bindings)
+
 	pluginDir, err := workSpace.GetDir(common.Plugins)
 	if err != nil {
 		GetLog().RaspWarn(err.Error(), orlog.Config)
@@ -81,27 +169,91 @@ func init() {
 	buildinAction = NewBuildinAction()
 	pluginManager.AttachListener(buildinAction)
 
-	yamlPath := filepath.Join(confDir, "openrasp.yml")
-	err = basic.LoadYaml(yamlPath)
-	if err != nil {
-		GetLog().RaspWarn(err.Error(), orlog.Log)
-	}
-
-	if !basic.GetBool("cloud.enable") {
-		general.LoadYaml(yamlPath)
+	if !CloudEnabled() {
+		configReloader := NewConfigReloader(confDir)
+		configReloader.Load()
 		pluginManager.buildLocalSnapshot()
 		workSpace.StartWatch(common.Conf)
-		workSpace.RegisterListener(common.Conf, general)
+		workSpace.RegisterListener(common.Conf, configReloader)
 		workSpace.StartWatch(common.Plugins)
 		workSpace.RegisterListener(common.Plugins, pluginManager)
 	} else {
-		cloudManager = cloud.NewClient(
-			basic.GetString("cloud.backend_url"),
-			basic.GetString("cloud.app_id"),
-			basic.GetString("cloud.app_secret"),
-			time.Duration(10)*time.Second,
-		)
-		err = cloudManager.Register(
+		// detect with the cached cloud plugin until the first heartbeat
+		pluginManager.buildLocalSnapshot()
+		cloudManager, err = newCloudClient(basic.GetString("cloud.app_id"), basic.GetString("cloud.app_secret"))
+		if err != nil {
+			logManager.RaspWarn("Unable to init cloud client, cuz of "+err.Error(), orlog.Config)
+			return
+		}
+		for _, binding := range appRouter.Bindings() {
+			if binding.client, err = newCloudClient(binding.AppId, binding.AppSecret); err != nil {
+				logManager.RaspWarn("Unable to init cloud client of app "+binding.AppId+", cuz of "+err.Error(), orlog.Config)
+			}
+		}
+		cloudManager.SetCommandHandler(handleCommand)
+		go func() {
+			defer Recover()
+			registerCloud()
+			cloudManager.StartHeartBeat(
+				time.Duration(basic.GetInt64("cloud.heartbeat_interval"))*time.Second,
+				pluginManager.OnUpdateCloud,
+				func(config *map[string]interface{}) {
+					if err := general.Replace(*config); err != nil {
+						logManager.RaspWarn("Unable to apply cloud config, cuz of "+err.Error(), orlog.Config)
+					}
+				},
+				func(err error) {
+					logManager.RaspWarn(err.Error(), orlog.Heartbeat)
+				},
+			)
+		}()
+	}
+	InitContextGetters()
+
+	if general.GetBool("security.env_baseline") {
+		EnvBaselineCheck()
+	}
+	dependencyScanner = NewDependencyScanner()
+	GetGeneral().AttachListener(dependencyScanner)
+
+	complete = true
+	publishActiveHooks()
+	GetLog().RaspInfo("Initialize OpenRASP successfully.", orlog.Runtime)
+}
+
+// newCloudClient connects to cloud.backend_url as the given application with
+// the shared tls and proxy settings
+func newCloudClient(appId, appSecret string) (*cloud.Client, error) {
+	client := cloud.NewClient(
+		basic.GetString("cloud.backend_url"),
+		appId,
+		appSecret,
+		time.Duration(10)*time.Second,
+	)
+	tlsConfig, err := utils.NewTLSConfig(
+		basic.GetString("cloud.tls.ca_file"),
+		basic.GetString("cloud.tls.cert_file"),
+		basic.GetString("cloud.tls.key_file"),
+		basic.GetBool("cloud.tls.insecure_skip_verify"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	client.SetTLSConfig(tlsConfig)
+	if err := client.SetProxy(basic.GetString("cloud.proxy")); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// registerCloud retries the registration with a backoff up to the heartbeat
+// interval, the console keeps a single entry per rasp_id however often the
+// agent registers
+func registerCloud() {
+	interval := time.Duration(basic.GetInt64("cloud.heartbeat_interval")) * time.Second
+	backoff := time.Second
+	for {
+		err := cloudManager.Register(
 			commonGlobals.RaspId,
 			commonGlobals.RootDir,
 			commonGlobals.Hostname,
@@ -109,23 +261,35 @@ func init() {
 			commonGlobals.Language.LanguageVersion,
 			basic.GetInt64("cloud.heartbeat_interval"),
 		)
-		if err != nil {
-			logManager.RaspWarn("Unable to register client.", orlog.Register)
+		if err == nil {
+			logManager.RaspInfo("Register client successfully, rasp_id "+commonGlobals.RaspId, orlog.Register)
 			return
 		}
-		cloudManager.StartHeartBeat(
-			time.Duration(basic.GetInt64("cloud.heartbeat_interval"))*time.Second,
-			pluginManager.OnUpdateCloud,
-			general.OnUpdateCloud,
-			func(err error) {
-				logManager.RaspWarn(err.Error(), orlog.Heartbeat)
-			},
-		)
+		logManager.RaspWarn("Unable to register client, cuz of "+err.Error(), orlog.Register)
+		time.Sleep(backoff)
+		if backoff *= 2; interval > 0 && backoff > interval {
+			backoff = interval
+		}
 	}
-	InitContextGetters()
+}
 
-	complete = true
-	GetLog().RaspInfo("Initialize OpenRASP successfully.", orlog.Runtime)
+// relocateBundle reads the config files and plugins/ from a local policy bundle
+// instead of the work space, logs are still written to the work space
+func relocateBundle(bundleDir string) error {
+	if err := workSpace.Relocate(common.Conf, bundleDir); err != nil {
+		return err
+	}
+	return workSpace.Relocate(common.Plugins, filepath.Join(bundleDir, "plugins"))
+}
+
+// IsOffline reports whether the agent must never contact a backend
+func IsOffline() bool {
+	return basic != nil && basic.GetBool("offline.enable")
+}
+
+// CloudEnabled reports whether the agent talks to the cloud console
+func CloudEnabled() bool {
+	return basic != nil && basic.GetBool("cloud.enable") && !IsOffline()
 }
 
 func IsComplete() bool {
@@ -156,6 +320,62 @@ func GetWhite() *WhiteList {
 	return whiteList
 }
 
+func GetAlarmFilter() *AlarmFilter {
+	return alarmFilter
+}
+
+func GetMasker() *Masker {
+	return masker
+}
+
+func GetHookSwitch() *HookSwitch {
+	return hookSwitch
+}
+
+func GetAlgorithmConfig() *AlgorithmConfig {
+	return algorithmConfig
+}
+
+func GetVerdictCache() *VerdictCache {
+	return verdictCache
+}
+
+func GetStatistics() *Statistics {
+	return statistics
+}
+
+func GetGlsMonitor() *GlsMonitor {
+	return glsMonitor
+}
+
+func GetWeakPasswords() *WeakPasswords {
+	return weakPasswords
+}
+
+func GetSampler() *Sampler {
+	return sampler
+}
+
+func GetAsyncDetector() *AsyncDetector {
+	return asyncDetector
+}
+
+func GetStatsdReporter() *StatsdReporter {
+	return statsdReporter
+}
+
+func GetAdminServer() *AdminServer {
+	return adminServer
+}
+
+func GetDependencyScanner() *DependencyScanner {
+	return dependencyScanner
+}
+
+func GetAppRouter() *AppRouter {
+	return appRouter
+}
+
 func GetAction() *BuildinAction {
 	return buildinAction
 }
@@ -0,0 +1,181 @@
+package orlog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type DropPolicy int
+
+const (
+	DropNewest DropPolicy = iota
+	DropOldest
+	BlockCaller
+)
+
+func DropPolicyFromString(policy string) DropPolicy {
+	switch policy {
+	case "drop_oldest":
+		return DropOldest
+	case "block":
+		return BlockCaller
+	default:
+		return DropNewest
+	}
+}
+
+type FlushFunc func(batch [][]byte)
+
+// AsyncQueue hands entries over to a single worker which flushes them in
+// batches of batchSize entries or batchBytes bytes, or earlier once
+// flushInterval has elapsed
+type AsyncQueue struct {
+	queue         chan []byte
+	batchSize     int
+	batchBytes    int
+	flushInterval time.Duration
+	dropPolicy    DropPolicy
+	flush         FlushFunc
+	dropped       uint64
+	dropCounter   *DropCounter
+	done          chan struct{}
+	wg            sync.WaitGroup
+	closeOnce     sync.Once
+}
+
+func NewAsyncQueue(size, batchSize, batchBytes int, flushInterval time.Duration, dropPolicy DropPolicy, flush FlushFunc) *AsyncQueue {
+	if size <= 0 {
+		size = 1
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+	q := &AsyncQueue{
+		queue:         make(chan []byte, size),
+		batchSize:     batchSize,
+		batchBytes:    batchBytes,
+		flushInterval: flushInterval,
+		dropPolicy:    dropPolicy,
+		flush:         flush,
+		done:          make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// Push enqueues p and reports whether it was accepted, p must not be
+// modified afterwards
+func (q *AsyncQueue) Push(p []byte) bool {
+	select {
+	case <-q.done:
+		return false
+	default:
+	}
+	switch q.dropPolicy {
+	case BlockCaller:
+		select {
+		case q.queue <- p:
+			return true
+		case <-q.done:
+			return false
+		}
+	case DropOldest:
+		for {
+			select {
+			case q.queue <- p:
+				return true
+			default:
+			}
+			select {
+			case <-q.queue:
+				atomic.AddUint64(&q.dropped, 1)
+				q.dropCounter.Add(QueueOverflow)
+			default:
+			}
+		}
+	default:
+		select {
+		case q.queue <- p:
+			return true
+		default:
+			atomic.AddUint64(&q.dropped, 1)
+			q.dropCounter.Add(QueueOverflow)
+			return false
+		}
+	}
+}
+
+// Dropped returns the number of entries discarded because the queue was full
+func (q *AsyncQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// Len returns the number of entries waiting for the worker
+func (q *AsyncQueue) Len() int {
+	return len(q.queue)
+}
+
+// SetDropCounter counts the discarded entries, it must be called before the
+// first Push
+func (q *AsyncQueue) SetDropCounter(dc *DropCounter) {
+	q.dropCounter = dc
+}
+
+func (q *AsyncQueue) run() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.flushInterval)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, q.batchSize)
+	batchBytes := 0
+	flush := func() {
+		if len(batch) > 0 {
+			q.safeFlush(batch)
+			batch = make([][]byte, 0, q.batchSize)
+			batchBytes = 0
+		}
+	}
+	add := func(p []byte) {
+		batch = append(batch, p)
+		batchBytes += len(p)
+		if len(batch) >= q.batchSize || (q.batchBytes > 0 && batchBytes >= q.batchBytes) {
+			flush()
+		}
+	}
+	for {
+		select {
+		case p := <-q.queue:
+			add(p)
+		case <-ticker.C:
+			flush()
+		case <-q.done:
+			for {
+				select {
+				case p := <-q.queue:
+					add(p)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// safeFlush keeps the worker alive when flush panics, the batch is lost
+func (q *AsyncQueue) safeFlush(batch [][]byte) {
+	defer recoverPanic("async queue")
+	q.flush(batch)
+}
+
+// Close stops accepting entries and waits until the queued ones are flushed
+func (q *AsyncQueue) Close() {
+	q.closeOnce.Do(func() {
+		close(q.done)
+	})
+	q.wg.Wait()
+}
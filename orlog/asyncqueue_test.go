@@ -0,0 +1,62 @@
+package orlog
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncQueueFlush(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][][]byte
+	q := NewAsyncQueue(10, 2, 0, time.Hour, DropNewest, func(batch [][]byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, batch)
+	})
+	for _, p := range []string{"a", "b", "c"} {
+		assert.True(t, q.Push([]byte(p)))
+	}
+	q.Close()
+	assert.False(t, q.Push([]byte("d")))
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, [][][]byte{{[]byte("a"), []byte("b")}, {[]byte("c")}}, batches)
+}
+
+func TestAsyncQueueDrop(t *testing.T) {
+	release := make(chan struct{})
+	var flushed [][]byte
+	q := NewAsyncQueue(1, 1, 0, time.Hour, DropNewest, func(batch [][]byte) {
+		<-release
+		flushed = append(flushed, batch...)
+	})
+	assert.True(t, q.Push([]byte("a")))
+	time.Sleep(50 * time.Millisecond)
+	assert.True(t, q.Push([]byte("b")))
+	assert.False(t, q.Push([]byte("c")))
+	assert.Equal(t, uint64(1), q.Dropped())
+	close(release)
+	q.Close()
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, flushed)
+}
+
+func TestDropPolicyFromString(t *testing.T) {
+	assert.Equal(t, DropOldest, DropPolicyFromString("drop_oldest"))
+	assert.Equal(t, BlockCaller, DropPolicyFromString("block"))
+	assert.Equal(t, DropNewest, DropPolicyFromString(""))
+}
+
+func TestAsyncQueueBatchBytes(t *testing.T) {
+	var batches [][][]byte
+	q := NewAsyncQueue(10, 10, 4, time.Hour, DropNewest, func(batch [][]byte) {
+		batches = append(batches, batch)
+	})
+	for _, p := range []string{"ab", "cd", "e"} {
+		assert.True(t, q.Push([]byte(p)))
+	}
+	q.Close()
+	assert.Equal(t, [][][]byte{{[]byte("ab"), []byte("cd")}, {[]byte("e")}}, batches)
+}
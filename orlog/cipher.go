@@ -0,0 +1,140 @@
+package orlog
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// maxChunkSize bounds a single sealed entry when decrypting
+const maxChunkSize = 64 * 1024 * 1024
+
+// chunkMagic starts every chunk, plain text logs never hold a NUL byte
+var chunkMagic = []byte("\x00ORC")
+
+// LogCipher seals every entry written to a log file as an independent
+// AES-GCM chunk, chunkMagic and a 4 byte big endian length followed by the
+// nonce and the sealed entry, so appending after a restart or a torn chunk
+// never affects the other chunks
+type LogCipher struct {
+	aead cipher.AEAD
+}
+
+// NewLogCipher accepts 16, 24 or 32 byte keys for AES-128, AES-192 or AES-256
+func NewLogCipher(key []byte) (*LogCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &LogCipher{aead: aead}, nil
+}
+
+// LoadLogKey decodes a base64 key, keyFile is read when key is empty
+func LoadLogKey(key, keyFile string) ([]byte, error) {
+	if len(key) == 0 {
+		if len(keyFile) == 0 {
+			return nil, fmt.Errorf("no log encryption key configured")
+		}
+		raw, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		key = string(raw)
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+}
+
+func (lc *LogCipher) Seal(p []byte) ([]byte, error) {
+	headerSize := len(chunkMagic) + 4
+	nonceSize := lc.aead.NonceSize()
+	chunk := make([]byte, headerSize+nonceSize, headerSize+nonceSize+len(p)+lc.aead.Overhead())
+	copy(chunk, chunkMagic)
+	nonce := chunk[headerSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	chunk = lc.aead.Seal(chunk, nonce, p, nil)
+	binary.BigEndian.PutUint32(chunk[len(chunkMagic):], uint32(len(chunk)-headerSize))
+	return chunk, nil
+}
+
+// Open returns the entry sealed in chunk, a single chunk made by Seal
+func (lc *LogCipher) Open(chunk []byte) ([]byte, error) {
+	headerSize := len(chunkMagic) + 4
+	nonceSize := lc.aead.NonceSize()
+	if !IsEncrypted(chunk) || len(chunk) < headerSize+nonceSize ||
+		int(binary.BigEndian.Uint32(chunk[len(chunkMagic):])) != len(chunk)-headerSize {
+		return nil, fmt.Errorf("not a valid encrypted log chunk")
+	}
+	sealed := chunk[headerSize:]
+	return lc.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+}
+
+// IsEncrypted reports whether head, the start of a log file, is a chunk
+func IsEncrypted(head []byte) bool {
+	return bytes.HasPrefix(head, chunkMagic)
+}
+
+// Decrypt writes the plain entries of an encrypted log file read from r to
+// w. The bytes which are not a valid chunk, such as a torn chunk or plain
+// text, are skipped up to the next chunkMagic and reported in the error
+// once all entries are written.
+func (lc *LogCipher) Decrypt(r io.Reader, w io.Writer) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	headerSize := len(chunkMagic) + 4
+	nonceSize := lc.aead.NonceSize()
+	skipped := 0
+	for pos := 0; pos < len(data); {
+		if !bytes.HasPrefix(data[pos:], chunkMagic) {
+			next := bytes.Index(data[pos+1:], chunkMagic)
+			if next < 0 {
+				skipped += len(data) - pos
+				break
+			}
+			skipped += next + 1
+			pos += next + 1
+			continue
+		}
+		var plain []byte
+		opened := false
+		end := pos + headerSize
+		if end <= len(data) {
+			size := binary.BigEndian.Uint32(data[pos+len(chunkMagic):])
+			if size >= uint32(nonceSize+lc.aead.Overhead()) && size <= maxChunkSize && end+int(size) <= len(data) {
+				chunk := data[end : end+int(size)]
+				if plain, err = lc.aead.Open(nil, chunk[:nonceSize], chunk[nonceSize:], nil); err == nil {
+					opened = true
+					end += int(size)
+				}
+			}
+		}
+		if !opened {
+			// resync on the next chunkMagic
+			skipped++
+			pos++
+			continue
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		pos = end
+	}
+	if skipped > 0 {
+		return fmt.Errorf("skipped %d bytes which are not valid encrypted log chunks", skipped)
+	}
+	return nil
+}
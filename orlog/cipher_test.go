@@ -0,0 +1,97 @@
+package orlog
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadLogKey(t *testing.T) {
+	key, err := LoadLogKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)), "")
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(key))
+	_, err = LoadLogKey("", "")
+	assert.NotNil(t, err)
+}
+
+func TestEncryptedFileWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	lc, err := NewLogCipher(bytes.Repeat([]byte{7}, 32))
+	assert.Nil(t, err)
+	filename := filepath.Join(dir, "alarm.log")
+	l := NewFileWriter(filename, 2, nil, WithCipher(lc))
+	n, err := l.Write([]byte("{\"attack_type\":\"sql\"}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 22, n)
+	l.Write([]byte("{\"attack_type\":\"xxe\"}\n"))
+	l.Close()
+
+	raw, err := ioutil.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(raw), "attack_type")
+	var plain bytes.Buffer
+	assert.Nil(t, lc.Decrypt(bytes.NewReader(raw), &plain))
+	assert.Equal(t, "{\"attack_type\":\"sql\"}\n{\"attack_type\":\"xxe\"}\n", plain.String())
+
+	other, _ := NewLogCipher(bytes.Repeat([]byte{8}, 32))
+	assert.NotNil(t, other.Decrypt(bytes.NewReader(raw), &plain))
+	assert.NotNil(t, lc.Decrypt(bytes.NewReader(raw[:len(raw)-1]), &plain))
+}
+
+func TestDecryptResync(t *testing.T) {
+	lc, err := NewLogCipher(bytes.Repeat([]byte{7}, 32))
+	assert.Nil(t, err)
+	first, _ := lc.Seal([]byte("first\n"))
+	second, _ := lc.Seal([]byte("second\n"))
+	third, _ := lc.Seal([]byte("third\n"))
+	// a torn chunk and plain text between whole chunks
+	var raw []byte
+	raw = append(raw, first...)
+	raw = append(raw, second[:len(second)-3]...)
+	raw = append(raw, "{\"attack_type\":\"sql\"}\n"...)
+	raw = append(raw, third...)
+	var plain bytes.Buffer
+	err = lc.Decrypt(bytes.NewReader(raw), &plain)
+	assert.NotNil(t, err)
+	assert.Equal(t, "first\nthird\n", plain.String())
+}
+
+func TestEncryptionRotates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	lc, err := NewLogCipher(bytes.Repeat([]byte{7}, 32))
+	assert.Nil(t, err)
+	filename := filepath.Join(dir, "alarm.log")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("{\"attack_type\":\"sql\"}\n"), 0644))
+
+	l := NewFileWriter(filename, 2, nil, WithCipher(lc))
+	l.Write([]byte("{\"attack_type\":\"xxe\"}\n"))
+	l.Close()
+	raw, err := ioutil.ReadFile(filename)
+	assert.Nil(t, err)
+	var plain bytes.Buffer
+	assert.Nil(t, lc.Decrypt(bytes.NewReader(raw), &plain))
+	assert.Equal(t, "{\"attack_type\":\"xxe\"}\n", plain.String())
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(files)) {
+		backup, _ := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+		assert.Equal(t, "{\"attack_type\":\"sql\"}\n", string(backup))
+	}
+
+	// turning encryption off rotates again
+	l = NewFileWriter(filename, 2, nil)
+	l.Write([]byte("{\"attack_type\":\"ssrf\"}\n"))
+	l.Close()
+	raw, err = ioutil.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"attack_type\":\"ssrf\"}\n", string(raw))
+}
@@ -9,4 +9,6 @@ const (
 	Runtime   ModuleCode = 20006
 	Register  ModuleCode = 20008
 	Heartbeat ModuleCode = 20009
+	Panic     ModuleCode = 20010
+	Report    ModuleCode = 20011
 )
@@ -0,0 +1,85 @@
+package orlog
+
+import "sync/atomic"
+
+type DropReason int
+
+const (
+	RateLimited DropReason = iota
+	QueueOverflow
+)
+
+func (r DropReason) String() string {
+	switch r {
+	case RateLimited:
+		return "rate_limited"
+	case QueueOverflow:
+		return "queue_overflow"
+	default:
+		return "unknown"
+	}
+}
+
+// DropHandler is notified of every entry discarded on channel
+type DropHandler func(channel string, reason DropReason)
+
+// DropCounter counts the entries of a channel discarded by rate limiting or
+// queue overflow, a nil counter counts nothing
+type DropCounter struct {
+	channel          string
+	rateLimited      uint64
+	overflowed       uint64
+	totalRateLimited uint64
+	totalOverflowed  uint64
+	handler          DropHandler
+}
+
+func NewDropCounter(channel string, handler DropHandler) *DropCounter {
+	dc := &DropCounter{
+		channel: channel,
+		handler: handler,
+	}
+	return dc
+}
+
+func (dc *DropCounter) Channel() string {
+	return dc.channel
+}
+
+func (dc *DropCounter) Add(reason DropReason) {
+	if dc == nil {
+		return
+	}
+	if reason == QueueOverflow {
+		atomic.AddUint64(&dc.overflowed, 1)
+		atomic.AddUint64(&dc.totalOverflowed, 1)
+	} else {
+		atomic.AddUint64(&dc.rateLimited, 1)
+		atomic.AddUint64(&dc.totalRateLimited, 1)
+	}
+	if dc.handler != nil {
+		dc.handler(dc.channel, reason)
+	}
+}
+
+// DropTotals are the entries of a channel dropped since the start
+type DropTotals struct {
+	RateLimited uint64 `json:"rate_limited"`
+	Overflowed  uint64 `json:"overflowed"`
+}
+
+// Totals returns the counts since the start, which Reset leaves alone
+func (dc *DropCounter) Totals() DropTotals {
+	if dc == nil {
+		return DropTotals{}
+	}
+	return DropTotals{
+		RateLimited: atomic.LoadUint64(&dc.totalRateLimited),
+		Overflowed:  atomic.LoadUint64(&dc.totalOverflowed),
+	}
+}
+
+// Reset returns the counts since the last call and starts over
+func (dc *DropCounter) Reset() (rateLimited, overflowed uint64) {
+	return atomic.SwapUint64(&dc.rateLimited, 0), atomic.SwapUint64(&dc.overflowed, 0)
+}
@@ -0,0 +1,42 @@
+package orlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropCounter(t *testing.T) {
+	var reasons []DropReason
+	dc := NewDropCounter("alarm", func(channel string, reason DropReason) {
+		assert.Equal(t, "alarm", channel)
+		reasons = append(reasons, reason)
+	})
+	tb := NewTokenBucket(1, time.Hour)
+	tb.SetDropCounter(dc)
+	tb.Consume()
+	tb.Consume()
+	tb.Consume()
+	q := NewAsyncQueue(1, 1, 0, time.Hour, DropNewest, func([][]byte) {
+		time.Sleep(100 * time.Millisecond)
+	})
+	q.SetDropCounter(dc)
+	for i := 0; i < 5; i++ {
+		q.Push([]byte("entry"))
+	}
+	q.Close()
+	rateLimited, overflowed := dc.Reset()
+	assert.Equal(t, uint64(2), rateLimited)
+	assert.Equal(t, q.Dropped(), overflowed)
+	assert.True(t, overflowed > 0)
+	assert.Equal(t, RateLimited, reasons[0])
+	assert.Equal(t, QueueOverflow, reasons[len(reasons)-1])
+	rateLimited, overflowed = dc.Reset()
+	assert.Zero(t, rateLimited+overflowed)
+}
+
+func TestNilDropCounter(t *testing.T) {
+	var dc *DropCounter
+	dc.Add(RateLimited)
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -24,15 +25,52 @@ var _ io.WriteCloser = (*FileWriter)(nil)
 type FileWriter struct {
 	filename     string
 	maxBackups   int
+	maxFiles     int
+	maxSize      int64
+	daily        bool
+	size         int64
 	lastedSuffix string
 	tokenBucket  *TokenBucket
+	cipher       *LogCipher
 	file         *os.File
 	mu           sync.Mutex
 	millCh       chan bool
-	startMill    sync.Once
 }
 
-func NewFileWriter(filename string, maxBackups int, tokenBucket *TokenBucket) *FileWriter {
+type FileWriterOption func(*FileWriter)
+
+// WithMaxSize rotates the file once it would grow beyond maxSize megabytes
+func WithMaxSize(maxSize int) FileWriterOption {
+	return func(l *FileWriter) {
+		l.maxSize = int64(maxSize) * int64(megabyte)
+	}
+}
+
+// WithMaxFiles keeps at most maxFiles rotated files, zero or less keeps all
+// of them within the days of maxBackups
+func WithMaxFiles(maxFiles int) FileWriterOption {
+	return func(l *FileWriter) {
+		l.maxFiles = maxFiles
+	}
+}
+
+// WithDaily turns the rollover at midnight on or off, it is on by default
+func WithDaily(daily bool) FileWriterOption {
+	return func(l *FileWriter) {
+		l.daily = daily
+	}
+}
+
+// WithCipher encrypts every entry before it reaches the disk
+func WithCipher(cipher *LogCipher) FileWriterOption {
+	return func(l *FileWriter) {
+		l.cipher = cipher
+	}
+}
+
+// NewFileWriter removes the files rotated next to filename more than
+// maxBackups days before the current one
+func NewFileWriter(filename string, maxBackups int, tokenBucket *TokenBucket, opts ...FileWriterOption) *FileWriter {
 	lastModTime := currentTime()
 	lastModified, existed := utils.GetLastModified(filename)
 	if existed {
@@ -41,9 +79,13 @@ func NewFileWriter(filename string, maxBackups int, tokenBucket *TokenBucket) *F
 	logger := &FileWriter{
 		filename:     filename,
 		maxBackups:   maxBackups,
+		daily:        true,
 		tokenBucket:  tokenBucket,
 		lastedSuffix: lastModTime.Format(backupFormat),
 	}
+	for _, opt := range opts {
+		opt(logger)
+	}
 	return logger
 }
 
@@ -57,25 +99,41 @@ func (l *FileWriter) Write(p []byte) (n int, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	data := p
+	if l.cipher != nil {
+		if data, err = l.cipher.Seal(p); err != nil {
+			return 0, err
+		}
+	}
 	if l.file == nil {
 		if err = l.openExistingOrNew(); err != nil {
 			return 0, err
 		}
 	}
-	err = l.rollover()
+	err = l.rollover(int64(len(data)))
 	if err != nil {
 		return 0, err
 	}
 	if l.tokenBucket != nil && l.tokenBucket.Consume() {
 		return 0, nil
 	}
-	n, err = l.file.Write(p)
+	n, err = l.file.Write(data)
+	l.size += int64(n)
+	if l.cipher != nil && n == len(data) {
+		n = len(p)
+	}
 	return n, err
 }
 
+// Close closes the file and stops the goroutine removing old backups, a
+// later Write opens the file again
 func (l *FileWriter) Close() error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.millCh != nil {
+		close(l.millCh)
+		l.millCh = nil
+	}
 	return l.close()
 }
 
@@ -88,18 +146,20 @@ func (l *FileWriter) close() error {
 	return err
 }
 
-func (l *FileWriter) rollover() error {
+func (l *FileWriter) rollover(writeLen int64) error {
 	cur := currentTime()
 	curSuffix := cur.Format(backupFormat)
-	if curSuffix == l.lastedSuffix {
-		return nil
-	} else {
+	if l.daily && curSuffix != l.lastedSuffix {
 		err := l.rotate()
 		if err == nil {
 			l.lastedSuffix = curSuffix
 		}
 		return err
 	}
+	if l.maxSize > 0 && l.size > 0 && l.size+writeLen > l.maxSize {
+		return l.rotate()
+	}
+	return nil
 }
 
 func (l *FileWriter) Rotate() error {
@@ -130,7 +190,7 @@ func (l *FileWriter) openNew() error {
 	info, err := os_Stat(name)
 	if err == nil {
 		mode = info.Mode()
-		newname := backupName(name, l.lastedSuffix)
+		newname := l.nextBackupName()
 		if err := os.Rename(name, newname); err != nil {
 			return fmt.Errorf("can't rename log file: %s", err)
 		}
@@ -145,6 +205,7 @@ func (l *FileWriter) openNew() error {
 	}
 	l.lastedSuffix = currentTime().Format(backupFormat)
 	l.file = f
+	l.size = 0
 	return nil
 }
 
@@ -154,6 +215,19 @@ func backupName(name, timeSuffix string) string {
 	return filepath.Join(dir, fmt.Sprintf("%s.%s", filename, timeSuffix))
 }
 
+// nextBackupName appends an increasing index when the file is rotated more
+// than once a day
+func (l *FileWriter) nextBackupName() string {
+	name := backupName(l.filename, l.lastedSuffix)
+	candidate := name
+	for i := 1; ; i++ {
+		if _, err := os_Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+		candidate = name + "." + strconv.Itoa(i)
+	}
+}
+
 func (l *FileWriter) openExistingOrNew() error {
 	l.mill()
 	filename := l.filename
@@ -164,16 +238,41 @@ func (l *FileWriter) openExistingOrNew() error {
 	if err != nil {
 		return fmt.Errorf("error getting log file info: %s", err)
 	}
+	// encrypted chunks are never appended to plain text or the other way
+	// round, the file is rotated when encryption is turned on or off
+	if l.encryptionChanged() {
+		return l.openNew()
+	}
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return l.openNew()
 	}
 	l.file = file
+	l.size = 0
+	if info, err := file.Stat(); err == nil {
+		l.size = info.Size()
+	}
 	return nil
 }
 
+// encryptionChanged reports whether the existing file is encrypted when l is
+// not or the other way round, an empty file fits both
+func (l *FileWriter) encryptionChanged() bool {
+	file, err := os.Open(l.filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, len(chunkMagic))
+	n, _ := io.ReadFull(file, head)
+	if n == 0 {
+		return false
+	}
+	return IsEncrypted(head[:n]) != (l.cipher != nil)
+}
+
 func (l *FileWriter) regexPattern() string {
-	return l.filename + "(?P<Date>" + backupFormatRegex + ")"
+	return "^" + regexp.QuoteMeta(filepath.Base(l.filename)) + "\\.(?P<Date>" + backupFormatRegex + ")(?:\\.(?P<Index>\\d+))?$"
 }
 
 func (l *FileWriter) millRunOnce() error {
@@ -182,12 +281,15 @@ func (l *FileWriter) millRunOnce() error {
 		return err
 	}
 	var remove []logInfo
+	l.mu.Lock()
+	lastedSuffix := l.lastedSuffix
+	l.mu.Unlock()
 	diff := time.Duration(int64(24*time.Hour) * int64(l.maxBackups))
-	baseTime, _ := time.Parse(backupFormat, l.lastedSuffix)
+	baseTime, _ := time.Parse(backupFormat, lastedSuffix)
 	cutoff := baseTime.Add(-1 * diff)
 
-	for _, f := range files {
-		if f.timestamp.Before(cutoff) {
+	for i, f := range files {
+		if f.timestamp.Before(cutoff) || (l.maxFiles > 0 && i >= l.maxFiles) {
 			remove = append(remove, f)
 		}
 	}
@@ -201,17 +303,23 @@ func (l *FileWriter) millRunOnce() error {
 	return err
 }
 
-func (l *FileWriter) millRun() {
-	for _ = range l.millCh {
-		_ = l.millRunOnce()
+func (l *FileWriter) millRun(millCh chan bool) {
+	for _ = range millCh {
+		l.safeMillRunOnce()
 	}
 }
 
+func (l *FileWriter) safeMillRunOnce() {
+	defer recoverPanic("file writer")
+	_ = l.millRunOnce()
+}
+
+// mill wakes the goroutine removing old backups, the caller holds l.mu
 func (l *FileWriter) mill() {
-	l.startMill.Do(func() {
+	if l.millCh == nil {
 		l.millCh = make(chan bool, 1)
-		go l.millRun()
-	})
+		go l.millRun(l.millCh)
+	}
 	select {
 	case l.millCh <- true:
 	default:
@@ -233,7 +341,8 @@ func (l *FileWriter) oldLogFiles() ([]logInfo, error) {
 		if fssm != nil {
 			t, err := time.Parse(backupFormat, fssm[1])
 			if err == nil {
-				logFiles = append(logFiles, logInfo{t, f})
+				index, _ := strconv.Atoi(fssm[2])
+				logFiles = append(logFiles, logInfo{t, index, f})
 				continue
 			}
 
@@ -249,12 +358,16 @@ func (l *FileWriter) dir() string {
 
 type logInfo struct {
 	timestamp time.Time
+	index     int
 	os.FileInfo
 }
 
 type byFormatTime []logInfo
 
 func (b byFormatTime) Less(i, j int) bool {
+	if b[i].timestamp.Equal(b[j].timestamp) {
+		return b[i].index > b[j].index
+	}
 	return b[i].timestamp.After(b[j].timestamp)
 }
 
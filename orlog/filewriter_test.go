@@ -0,0 +1,93 @@
+package orlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegexPattern(t *testing.T) {
+	l := NewFileWriter("/tmp/logs/alarm.log", 3, nil)
+	r := regexp.MustCompile(l.regexPattern())
+	assert.Equal(t, []string{"alarm.log.2020-01-02", "2020-01-02", ""}, r.FindStringSubmatch("alarm.log.2020-01-02"))
+	assert.Equal(t, []string{"alarm.log.2020-01-02.3", "2020-01-02", "3"}, r.FindStringSubmatch("alarm.log.2020-01-02.3"))
+	assert.Nil(t, r.FindStringSubmatch("policy.log.2020-01-02"))
+	assert.Nil(t, r.FindStringSubmatch("alarm.log"))
+}
+
+func TestSizeRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "alarm.log")
+	l := NewFileWriter(filename, 30, nil, WithDaily(false), WithMaxFiles(2))
+	l.maxSize = 10
+	defer l.Close()
+	for i := 0; i < 5; i++ {
+		_, err := l.Write([]byte("0123456789"))
+		assert.Nil(t, err)
+	}
+	l.millRunOnce()
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(files))
+	suffix := time.Now().Format(backupFormat)
+	assert.Equal(t, "alarm.log."+suffix+".3", files[0].Name())
+	assert.Equal(t, "alarm.log."+suffix+".2", files[1].Name())
+}
+
+func TestCloseStopsMill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	l := NewFileWriter(filepath.Join(dir, "alarm.log"), 3, nil)
+	_, err = l.Write([]byte("first\n"))
+	assert.Nil(t, err)
+	millCh := l.millCh
+	assert.NotNil(t, millCh)
+	assert.Nil(t, l.Close())
+	stopped := make(chan struct{})
+	go func() {
+		for _ = range millCh {
+		}
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Error("mill not stopped by Close")
+	}
+	// writing after Close opens the file and starts a new mill
+	_, err = l.Write([]byte("second\n"))
+	assert.Nil(t, err)
+	assert.NotNil(t, l.millCh)
+	assert.Nil(t, l.Close())
+}
+
+func TestMaxBackupDays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "alarm.log")
+	now := time.Now()
+	for _, days := range []int{1, 2, 3, 5} {
+		backup := filename + "." + now.AddDate(0, 0, -days).Format(backupFormat)
+		assert.Nil(t, ioutil.WriteFile(backup, nil, 0644))
+	}
+	l := NewFileWriter(filename, 3, nil)
+	assert.Nil(t, l.millRunOnce())
+	files, err := l.oldLogFiles()
+	assert.Nil(t, err)
+	// the backups count does not matter, their age does
+	assert.Equal(t, 3, len(files))
+	l.maxFiles = 1
+	assert.Nil(t, l.millRunOnce())
+	files, err = l.oldLogFiles()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+}
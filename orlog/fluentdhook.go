@@ -0,0 +1,43 @@
+package orlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+type FluentdHook struct {
+	hookLevel Level
+	tag       string
+	Formatter logrus.Formatter
+	Writer    *FluentdWriter
+}
+
+// NewFluentdHook sends every entry with tag, fluentd routes events by tag
+func NewFluentdHook(tag string, writer *FluentdWriter, level Level) *FluentdHook {
+	fh := &FluentdHook{
+		hookLevel: level,
+		tag:       tag,
+		Writer:    writer,
+	}
+	return fh
+}
+
+func (hook *FluentdHook) Fire(entry *logrus.Entry) error {
+	line, err := formatEntry(hook.Formatter, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read entry, %v", err)
+		return err
+	}
+	return hook.Writer.WriteWithTag(hook.tag, []byte(line))
+}
+
+func (hook *FluentdHook) Levels() []logrus.Level {
+	switch hook.hookLevel {
+	case WarnLevel:
+		return []logrus.Level{logrus.WarnLevel}
+	default:
+		return []logrus.Level{logrus.InfoLevel}
+	}
+}
@@ -0,0 +1,204 @@
+package orlog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var errFluentdUnavailable = errors.New("fluentd is unavailable, waiting to reconnect")
+
+type FluentdConfig struct {
+	Url               string
+	RequireAck        bool
+	ConnectionTimeout time.Duration
+	WriteTimeout      time.Duration
+	ReconnectInterval time.Duration
+}
+
+// FluentdWriter sends events in the message mode of the Fluentd forward
+// protocol, [tag, time, record, option] encoded with msgpack, over tcp or a
+// unix socket, so fluentd and fluent-bit can receive them with in_forward
+type FluentdWriter struct {
+	network           string
+	address           string
+	requireAck        bool
+	connectionTimeout time.Duration
+	writeTimeout      time.Duration
+	reconnectInterval time.Duration
+	tokenBucket       *TokenBucket
+	conn              net.Conn
+	lastDial          time.Time
+	mu                sync.Mutex
+}
+
+// NewFluentdWriter accepts urls like tcp://host:24224 or unix:///var/run/fluent.sock,
+// an empty url means the forward input of the local node at 127.0.0.1:24224
+func NewFluentdWriter(fc *FluentdConfig, tokenBucket *TokenBucket) (*FluentdWriter, error) {
+	network, address, err := parseFluentdUrl(fc.Url)
+	if err != nil {
+		return nil, err
+	}
+	fw := &FluentdWriter{
+		network:           network,
+		address:           address,
+		requireAck:        fc.RequireAck,
+		connectionTimeout: fc.ConnectionTimeout,
+		writeTimeout:      fc.WriteTimeout,
+		reconnectInterval: fc.ReconnectInterval,
+		tokenBucket:       tokenBucket,
+	}
+	return fw, nil
+}
+
+func parseFluentdUrl(rawurl string) (string, string, error) {
+	if len(rawurl) == 0 {
+		return "tcp", "127.0.0.1:24224", nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "tcp":
+		if len(u.Port()) == 0 {
+			return u.Scheme, net.JoinHostPort(u.Hostname(), "24224"), nil
+		}
+		return u.Scheme, u.Host, nil
+	case "unix":
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", fmt.Errorf("unsupported fluentd url %s", rawurl)
+	}
+}
+
+func (fw *FluentdWriter) dial() error {
+	if !fw.lastDial.IsZero() && time.Since(fw.lastDial) < fw.reconnectInterval {
+		return errFluentdUnavailable
+	}
+	fw.lastDial = time.Now()
+	conn, err := net.DialTimeout(fw.network, fw.address, fw.connectionTimeout)
+	if err != nil {
+		return err
+	}
+	fw.conn = conn
+	fw.lastDial = time.Time{}
+	return nil
+}
+
+// newRecord keeps the fields of a json line, any other format is sent as
+// the message field
+func newRecord(line []byte) map[string]interface{} {
+	line = bytes.TrimRight(line, "\n")
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	var record map[string]interface{}
+	if err := decoder.Decode(&record); err != nil || decoder.More() {
+		return map[string]interface{}{"message": string(line)}
+	}
+	return convertNumbers(record).(map[string]interface{})
+}
+
+// convertNumbers turns json numbers into integers where possible, so they
+// are not encoded as strings or floats
+func convertNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range value {
+			value[k] = convertNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = convertNumbers(item)
+		}
+	}
+	return v
+}
+
+func (fw *FluentdWriter) encode(tag string, line []byte) ([]byte, string, error) {
+	option := map[string]interface{}{}
+	var chunk string
+	if fw.requireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, "", err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id)
+		option["chunk"] = chunk
+	}
+	b, err := msgpack.Marshal([]interface{}{tag, time.Now().Unix(), newRecord(line), option})
+	return b, chunk, err
+}
+
+// WriteWithTag sends msg tagged with tag, with ack enabled it waits for the
+// server to confirm the chunk, a failed write drops the connection and the
+// next one is attempted after the reconnect interval
+func (fw *FluentdWriter) WriteWithTag(tag string, msg []byte) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.tokenBucket != nil && fw.tokenBucket.Consume() {
+		return nil
+	}
+	b, chunk, err := fw.encode(tag, msg)
+	if err != nil {
+		return err
+	}
+	if fw.conn == nil {
+		if err := fw.dial(); err != nil {
+			return err
+		}
+	}
+	if fw.writeTimeout > 0 {
+		fw.conn.SetDeadline(time.Now().Add(fw.writeTimeout))
+	}
+	_, err = fw.conn.Write(b)
+	if err == nil && fw.requireAck {
+		err = fw.readAck(chunk)
+	}
+	if err != nil {
+		fw.conn.Close()
+		fw.conn = nil
+		fw.lastDial = time.Now()
+	}
+	return err
+}
+
+func (fw *FluentdWriter) readAck(chunk string) error {
+	var response struct {
+		Ack string `msgpack:"ack"`
+	}
+	if err := msgpack.NewDecoder(fw.conn).Decode(&response); err != nil {
+		return err
+	}
+	if response.Ack != chunk {
+		return fmt.Errorf("unexpected fluentd ack %s", strings.TrimSpace(response.Ack))
+	}
+	return nil
+}
+
+func (fw *FluentdWriter) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.conn == nil {
+		return nil
+	}
+	err := fw.conn.Close()
+	fw.conn = nil
+	return err
+}
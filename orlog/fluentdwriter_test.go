@@ -0,0 +1,84 @@
+package orlog
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestParseFluentdUrl(t *testing.T) {
+	network, address, err := parseFluentdUrl("tcp://fluentd.logging")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "fluentd.logging:24224", address)
+	network, address, err = parseFluentdUrl("")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "127.0.0.1:24224", address)
+	network, address, err = parseFluentdUrl("unix:///var/run/fluent.sock")
+	assert.Nil(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/var/run/fluent.sock", address)
+	_, _, err = parseFluentdUrl("udp://127.0.0.1")
+	assert.NotNil(t, err)
+}
+
+func TestNewRecord(t *testing.T) {
+	record := newRecord([]byte("{\"attack_type\":\"sql\",\"plugin_confidence\":90}\n"))
+	assert.Equal(t, "sql", record["attack_type"])
+	assert.Equal(t, int64(90), record["plugin_confidence"])
+	record = newRecord([]byte("CEF:0|Baidu|OpenRASP|1.0|sql|m|9|\n"))
+	assert.Equal(t, "CEF:0|Baidu|OpenRASP|1.0|sql|m|9|", record["message"])
+}
+
+func TestFluentdWriterAck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	received := make(chan []interface{}, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var event []interface{}
+		if err := msgpack.NewDecoder(conn).Decode(&event); err != nil {
+			return
+		}
+		received <- event
+		option := event[3].(map[string]interface{})
+		msgpack.NewEncoder(conn).Encode(map[string]interface{}{"ack": option["chunk"]})
+	}()
+	fw, err := NewFluentdWriter(&FluentdConfig{
+		Url:               "tcp://" + ln.Addr().String(),
+		RequireAck:        true,
+		ConnectionTimeout: time.Second,
+		WriteTimeout:      time.Second,
+		ReconnectInterval: time.Minute,
+	}, nil)
+	assert.Nil(t, err)
+	defer fw.Close()
+	assert.Nil(t, fw.WriteWithTag("openrasp.attack", []byte("{\"attack_type\":\"sql\"}\n")))
+	event := <-received
+	assert.Equal(t, "openrasp.attack", event[0])
+	assert.Equal(t, map[string]interface{}{"attack_type": "sql"}, event[2])
+}
+
+func TestFluentdWriterUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := ln.Addr().String()
+	ln.Close()
+	fw, err := NewFluentdWriter(&FluentdConfig{
+		Url:               "tcp://" + address,
+		ConnectionTimeout: time.Second,
+		ReconnectInterval: time.Minute,
+	}, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, fw.WriteWithTag("openrasp.attack", []byte("{}")))
+	assert.Equal(t, errFluentdUnavailable, fw.WriteWithTag("openrasp.attack", []byte("{}")))
+}
@@ -16,7 +16,11 @@ type Hook interface {
 
 type HttpHook struct {
 	hookLevel Level
+	Formatter logrus.Formatter
 	Writer    *HttpWriter
+	// AppId skips the entries tagged with the app_id field of another
+	// application, untagged entries are always written
+	AppId string
 }
 
 func NewHttpHook(t string, cm *cloud.Client, level Level, tokenBucket *TokenBucket) *HttpHook {
@@ -28,16 +32,39 @@ func NewHttpHook(t string, cm *cloud.Client, level Level, tokenBucket *TokenBuck
 	return hh
 }
 
+func NewHttpHookWithWriter(hw *HttpWriter, level Level) *HttpHook {
+	hh := &HttpHook{
+		hookLevel: level,
+		Writer:    hw,
+	}
+	return hh
+}
+
 func (hook *HttpHook) Fire(entry *logrus.Entry) error {
-	line, err := entry.String()
+	if appId, ok := entry.Data["app_id"].(string); ok && len(hook.AppId) > 0 && appId != hook.AppId {
+		return nil
+	}
+	line, err := formatEntry(hook.Formatter, entry)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to read entry, %v", err)
 		return err
 	}
-	_, err = hook.Writer.Write([]byte("[\n" + line + "]"))
+	_, err = hook.Writer.Write([]byte(line))
 	return err
 }
 
+// formatEntry uses formatter when set, the logger's formatter otherwise
+func formatEntry(formatter logrus.Formatter, entry *logrus.Entry) (string, error) {
+	if formatter == nil {
+		return entry.String()
+	}
+	b, err := formatter.Format(entry)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
 func (hook *HttpHook) Levels() []logrus.Level {
 	switch hook.hookLevel {
 	case WarnLevel:
@@ -1,33 +1,262 @@
 package orlog
 
 import (
+	"bytes"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/baidu-security/openrasp-golang/cloud"
 )
 
+// maxSyncSends bounds the uploads a synchronous writer runs at once, the
+// entries written while all of them are busy are spooled or dropped
+const maxSyncSends = 4
+
+// defaultMaxBackoff caps the wait between retries when WithRetry sets none
+const defaultMaxBackoff = 30 * time.Second
+
 type HttpWriter struct {
-	t           string
-	cm          *cloud.Client
-	tokenBucket *TokenBucket
-	mu          sync.Mutex
+	t              string
+	cm             *cloud.Client
+	tokenBucket    *TokenBucket
+	queue          *AsyncQueue
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	spool          *Spool
+	gzip           bool
+	lines          bool
+	dropCounter    *DropCounter
+	replaying      int32
+	sends          chan struct{}
+	closed         chan struct{}
+	closeOnce      sync.Once
+	mu             sync.Mutex
+}
+
+type HttpWriterOption func(*HttpWriter)
+
+// WithRetry retries a failed upload maxRetries times, waiting initialBackoff
+// first and doubling the wait up to maxBackoff
+func WithRetry(maxRetries int, initialBackoff, maxBackoff time.Duration) HttpWriterOption {
+	return func(hw *HttpWriter) {
+		hw.maxRetries = maxRetries
+		hw.initialBackoff = initialBackoff
+		hw.maxBackoff = maxBackoff
+	}
+}
+
+// WithDropCounter counts the entries discarded when the async queue is full,
+// or when a synchronous writer has maxSyncSends uploads running and no spool
+func WithDropCounter(dc *DropCounter) HttpWriterOption {
+	return func(hw *HttpWriter) {
+		hw.dropCounter = dc
+	}
+}
+
+// WithSpool keeps entries that still fail after the retries in spool, they
+// are replayed after the next successful upload
+func WithSpool(spool *Spool) HttpWriterOption {
+	return func(hw *HttpWriter) {
+		hw.spool = spool
+	}
+}
+
+// WithGzip compresses the uploaded JSON arrays
+func WithGzip(gzip bool) HttpWriterOption {
+	return func(hw *HttpWriter) {
+		hw.gzip = gzip
+	}
 }
 
-func NewHttpWriter(t string, cm *cloud.Client, tokenBucket *TokenBucket) *HttpWriter {
+// WithLines uploads the entries as newline-delimited text instead of a JSON
+// array, for the CEF and LEEF formats
+func WithLines(lines bool) HttpWriterOption {
+	return func(hw *HttpWriter) {
+		hw.lines = lines
+	}
+}
+
+func NewHttpWriter(t string, cm *cloud.Client, tokenBucket *TokenBucket, opts ...HttpWriterOption) *HttpWriter {
 	hw := &HttpWriter{
 		t:           t,
 		cm:          cm,
 		tokenBucket: tokenBucket,
+		sends:       make(chan struct{}, maxSyncSends),
+		closed:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(hw)
 	}
 	return hw
 }
 
+// NewAsyncHttpWriter queues entries in memory and uploads them from a single
+// worker as one JSON array, or one block of lines, per batch, so a slow
+// backend never holds up the caller
+func NewAsyncHttpWriter(t string, cm *cloud.Client, tokenBucket *TokenBucket, queueSize, batchSize, batchBytes int, flushInterval time.Duration, dropPolicy DropPolicy, opts ...HttpWriterOption) *HttpWriter {
+	hw := NewHttpWriter(t, cm, tokenBucket, opts...)
+	hw.queue = NewAsyncQueue(queueSize, batchSize, batchBytes, flushInterval, dropPolicy, hw.flush)
+	hw.queue.SetDropCounter(hw.dropCounter)
+	return hw
+}
+
+// Type returns the type of the logs hw uploads
+func (hw *HttpWriter) Type() string {
+	return hw.t
+}
+
+// QueueLen returns the number of entries waiting in the async queue, 0 when
+// hw writes synchronously
+func (hw *HttpWriter) QueueLen() int {
+	if hw.queue == nil {
+		return 0
+	}
+	return hw.queue.Len()
+}
+
 func (hw *HttpWriter) Write(p []byte) (n int, err error) {
 	hw.mu.Lock()
 	defer hw.mu.Unlock()
 	if hw.tokenBucket != nil && hw.tokenBucket.Consume() {
 		return 0, nil
 	}
-	go hw.cm.Log(hw.t, p)
+	if hw.queue != nil {
+		entry := make([]byte, len(p))
+		copy(entry, p)
+		hw.queue.Push(entry)
+		return len(p), nil
+	}
+	payload := hw.encode([][]byte{p})
+	select {
+	case hw.sends <- struct{}{}:
+	default:
+		if hw.spool != nil {
+			hw.spool.Append(payload)
+		} else {
+			hw.dropCounter.Add(QueueOverflow)
+		}
+		return len(p), nil
+	}
+	go func() {
+		defer func() { <-hw.sends }()
+		hw.send(payload)
+	}()
 	return len(p), nil
 }
+
+// encodeBatch joins formatted entries into a JSON array
+func encodeBatch(batch [][]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("[\n")
+	for i, entry := range batch {
+		if i > 0 {
+			b.WriteString(",\n")
+		}
+		b.Write(bytes.TrimRight(entry, "\n"))
+	}
+	b.WriteString("\n]")
+	return b.Bytes()
+}
+
+// encodeLines joins formatted entries into newline-delimited text
+func encodeLines(batch [][]byte) []byte {
+	var b bytes.Buffer
+	for _, entry := range batch {
+		b.Write(bytes.TrimRight(entry, "\n"))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func (hw *HttpWriter) encode(batch [][]byte) []byte {
+	if hw.lines {
+		return encodeLines(batch)
+	}
+	return encodeBatch(batch)
+}
+
+func (hw *HttpWriter) flush(batch [][]byte) {
+	hw.send(hw.encode(batch))
+}
+
+func (hw *HttpWriter) send(payload []byte) {
+	defer recoverPanic("http writer")
+	if err := hw.post(payload); err != nil {
+		if hw.spool != nil {
+			hw.spool.Append(payload)
+		}
+		return
+	}
+	hw.replay()
+}
+
+func (hw *HttpWriter) upload(payload []byte) error {
+	if hw.lines {
+		return hw.cm.LogLines(hw.t, payload, hw.gzip)
+	}
+	if hw.gzip {
+		return hw.cm.LogGzip(hw.t, payload)
+	}
+	return hw.cm.Log(hw.t, payload)
+}
+
+// post uploads payload, retrying with backoff until hw is closed
+func (hw *HttpWriter) post(payload []byte) error {
+	maxBackoff := hw.maxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	backoff := hw.initialBackoff
+	err := hw.upload(payload)
+	for i := 0; err != nil && i < hw.maxRetries; i++ {
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-hw.closed:
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+		err = hw.upload(payload)
+	}
+	return err
+}
+
+// replay uploads the spooled entries once the backend is reachable again,
+// only one replay runs at a time
+func (hw *HttpWriter) replay() {
+	if hw.spool == nil || hw.spool.Empty() {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&hw.replaying, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&hw.replaying, 0)
+	entries, err := hw.spool.Drain()
+	if err != nil {
+		return
+	}
+	for i, entry := range entries {
+		if err := hw.upload(entry); err != nil {
+			hw.spool.Restore(entries[i:])
+			return
+		}
+	}
+}
+
+// Close ends the retries in progress and flushes the queued entries of an
+// async writer with a single attempt each, the failed ones are spooled
+func (hw *HttpWriter) Close() error {
+	hw.closeOnce.Do(func() {
+		close(hw.closed)
+	})
+	if hw.queue != nil {
+		hw.queue.Close()
+	}
+	return nil
+}
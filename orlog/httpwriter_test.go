@@ -0,0 +1,151 @@
+package orlog
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/cloud"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHttpWriterSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	spool := NewSpool(filepath.Join(dir, "attack.spool"), 1024, nil)
+
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	down := NewHttpWriter("attack", cloud.NewClient("http://127.0.0.1:1", "", "", time.Second), nil,
+		WithRetry(2, time.Millisecond, 2*time.Millisecond), WithSpool(spool))
+	down.send(encodeBatch([][]byte{[]byte("first")}))
+	assert.False(t, spool.Empty())
+
+	up := NewHttpWriter("attack", cloud.NewClient(server.URL, "", "", time.Second), nil, WithSpool(spool))
+	up.send(encodeBatch([][]byte{[]byte("second")}))
+	assert.True(t, spool.Empty())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"[\nsecond\n]", "[\nfirst\n]"}, received)
+}
+
+func TestHttpWriterBoundsSends(t *testing.T) {
+	release := make(chan struct{})
+	received := make(chan struct{}, maxSyncSends+3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	dc := NewDropCounter("attack", nil)
+	hw := NewHttpWriter("attack", cloud.NewClient(server.URL, "", "", 5*time.Second), nil, WithDropCounter(dc))
+	for i := 0; i < maxSyncSends+3; i++ {
+		hw.Write([]byte("{}\n"))
+	}
+	assert.Equal(t, uint64(3), dc.Totals().Overflowed)
+	close(release)
+	for i := 0; i < maxSyncSends; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("alarm not uploaded")
+		}
+	}
+	select {
+	case <-received:
+		t.Error("dropped alarm uploaded")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHttpWriterCloseEndsRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	spool := NewSpool(filepath.Join(dir, "attack.spool"), 1024, nil)
+
+	hw := NewHttpWriter("attack", cloud.NewClient("http://127.0.0.1:1", "", "", time.Second), nil,
+		WithRetry(3, time.Hour, time.Hour), WithSpool(spool))
+	done := make(chan struct{})
+	go func() {
+		hw.send(encodeBatch([][]byte{[]byte("first")}))
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	hw.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("retry not interrupted by Close")
+	}
+	assert.False(t, spool.Empty())
+}
+
+func TestHttpWriterLines(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- r
+		bodies <- string(body)
+	}))
+	defer server.Close()
+	hw := NewHttpWriter("attack", cloud.NewClient(server.URL, "", "", time.Second), nil, WithLines(true))
+	hw.send(hw.encode([][]byte{[]byte("CEF:0|a\n"), []byte("CEF:0|b\n")}))
+	r := <-received
+	assert.Equal(t, "/v1/agent/log/attack", r.URL.Path)
+	assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+	assert.Equal(t, "CEF:0|a\nCEF:0|b\n", <-bodies)
+}
+
+func TestEncodeBatch(t *testing.T) {
+	assert.Equal(t, "[\n{\"a\":1}\n]", string(encodeBatch([][]byte{[]byte("{\"a\":1}\n")})))
+	assert.Equal(t, "[\n{\"a\":1},\n{\"b\":2}\n]", string(encodeBatch([][]byte{[]byte("{\"a\":1}\n"), []byte("{\"b\":2}\n")})))
+}
+
+func TestHttpHookAppId(t *testing.T) {
+	received := make(chan string, 4)
+	newServer := func(appId string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, appId, r.Header.Get("X-OpenRASP-AppID"))
+			received <- appId
+		}))
+	}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	for _, appId := range []string{"first", "second"} {
+		server := newServer(appId)
+		defer server.Close()
+		hook := NewHttpHookWithWriter(NewHttpWriter("attack", cloud.NewClient(server.URL, appId, "", time.Second), nil), InfoLevel)
+		hook.AppId = appId
+		logger.AddHook(hook)
+	}
+	logger.WithField("app_id", "second").Info("attack")
+	select {
+	case appId := <-received:
+		assert.Equal(t, "second", appId)
+	case <-time.After(time.Second):
+		t.Fatal("alarm not uploaded")
+	}
+	select {
+	case appId := <-received:
+		t.Errorf("alarm of second uploaded to %s", appId)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,45 @@
+package orlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+type KafkaHook struct {
+	hookLevel Level
+	key       string
+	Formatter logrus.Formatter
+	Writer    *KafkaWriter
+}
+
+// NewKafkaHook publishes every entry with key as the partition key, so events
+// of the same app or rasp instance stay ordered
+func NewKafkaHook(key string, writer *KafkaWriter, level Level) *KafkaHook {
+	kh := &KafkaHook{
+		hookLevel: level,
+		key:       key,
+		Writer:    writer,
+	}
+	return kh
+}
+
+func (hook *KafkaHook) Fire(entry *logrus.Entry) error {
+	line, err := formatEntry(hook.Formatter, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read entry, %v", err)
+		return err
+	}
+	return hook.Writer.WriteWithKey(hook.key, []byte(strings.TrimRight(line, "\n")))
+}
+
+func (hook *KafkaHook) Levels() []logrus.Level {
+	switch hook.hookLevel {
+	case WarnLevel:
+		return []logrus.Level{logrus.WarnLevel}
+	default:
+		return []logrus.Level{logrus.InfoLevel}
+	}
+}
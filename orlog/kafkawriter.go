@@ -0,0 +1,197 @@
+package orlog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/baidu-security/openrasp-golang/utils"
+	"github.com/xdg-go/scram"
+)
+
+// kafkaRetryInterval is the wait between two attempts to reach the brokers
+const kafkaRetryInterval = 10 * time.Second
+
+var (
+	errKafkaUnavailable = errors.New("kafka brokers not reachable yet")
+	errKafkaQueueFull   = errors.New("kafka producer queue is full")
+)
+
+type KafkaConfig struct {
+	Brokers            []string
+	Topic              string
+	SaslMechanism      string
+	SaslUsername       string
+	SaslPassword       string
+	TlsEnable          bool
+	InsecureSkipVerify bool
+	CaFile             string
+	CertFile           string
+	KeyFile            string
+}
+
+// KafkaWriter publishes log lines with an asynchronous sarama producer, the
+// brokers are reached in the background and delivery errors go to
+// sarama.Logger, so they never reach the request path
+type KafkaWriter struct {
+	brokers     []string
+	topic       string
+	config      *sarama.Config
+	producer    sarama.AsyncProducer
+	tokenBucket *TokenBucket
+	closed      chan struct{}
+	closeOnce   sync.Once
+	mu          sync.Mutex
+}
+
+func NewKafkaWriter(kc *KafkaConfig, tokenBucket *TokenBucket) (*KafkaWriter, error) {
+	if len(kc.Brokers) == 0 {
+		return nil, fmt.Errorf("no kafka broker configured")
+	}
+	if len(kc.Topic) == 0 {
+		return nil, fmt.Errorf("no kafka topic configured")
+	}
+	config := sarama.NewConfig()
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Partitioner = sarama.NewHashPartitioner
+	config.Producer.Flush.Frequency = 100 * time.Millisecond
+	config.Producer.Return.Successes = false
+	config.Producer.Return.Errors = false
+	if err := saslConfig(config, kc.SaslMechanism, kc.SaslUsername, kc.SaslPassword); err != nil {
+		return nil, err
+	}
+	if kc.TlsEnable {
+		tlsConfig, err := utils.NewTLSConfig(kc.CaFile, kc.CertFile, kc.KeyFile, kc.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	kw := &KafkaWriter{
+		brokers:     kc.Brokers,
+		topic:       kc.Topic,
+		config:      config,
+		tokenBucket: tokenBucket,
+		closed:      make(chan struct{}),
+	}
+	go kw.connect()
+	return kw, nil
+}
+
+// saslConfig sets the sasl mechanism of config, none when name is empty
+func saslConfig(config *sarama.Config, name, username, password string) error {
+	switch strings.ToLower(name) {
+	case "":
+		return nil
+	case "plain":
+		config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case "scram-sha-256":
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: scram.SHA256} }
+	case "scram-sha-512":
+		config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: scram.SHA512} }
+	default:
+		return fmt.Errorf("unsupported kafka sasl mechanism %s", name)
+	}
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = username
+	config.Net.SASL.Password = password
+	return nil
+}
+
+// scramClient runs the SCRAM exchange of sarama with xdg-go/scram
+type scramClient struct {
+	hash         scram.HashGeneratorFcn
+	conversation *scram.ClientConversation
+}
+
+func (sc *scramClient) Begin(userName, password, authzID string) error {
+	client, err := sc.hash.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	sc.conversation = client.NewConversation()
+	return nil
+}
+
+func (sc *scramClient) Step(challenge string) (string, error) {
+	return sc.conversation.Step(challenge)
+}
+
+func (sc *scramClient) Done() bool {
+	return sc.conversation.Done()
+}
+
+// connect creates the producer, retrying every kafkaRetryInterval until the
+// brokers answer or kw is closed
+func (kw *KafkaWriter) connect() {
+	defer recoverPanic("kafka writer")
+	for {
+		producer, err := sarama.NewAsyncProducer(kw.brokers, kw.config)
+		if err == nil {
+			kw.mu.Lock()
+			defer kw.mu.Unlock()
+			select {
+			case <-kw.closed:
+				producer.Close()
+			default:
+				kw.producer = producer
+			}
+			return
+		}
+		select {
+		case <-time.After(kafkaRetryInterval):
+		case <-kw.closed:
+			return
+		}
+	}
+}
+
+// WriteWithKey queues p without blocking, it is dropped with an error while
+// the brokers are not reachable or the producer queue is full
+func (kw *KafkaWriter) WriteWithKey(key string, p []byte) error {
+	kw.mu.Lock()
+	defer kw.mu.Unlock()
+	throttled := kw.tokenBucket != nil && kw.tokenBucket.Consume()
+	if throttled {
+		return nil
+	}
+	if kw.producer == nil {
+		return errKafkaUnavailable
+	}
+	value := make([]byte, len(p))
+	copy(value, p)
+	select {
+	case kw.producer.Input() <- &sarama.ProducerMessage{
+		Topic: kw.topic,
+		Key:   sarama.StringEncoder(key),
+		Value: sarama.ByteEncoder(value),
+	}:
+		return nil
+	default:
+		return errKafkaQueueFull
+	}
+}
+
+// Close stops the connection attempts and flushes the queued messages
+func (kw *KafkaWriter) Close() error {
+	kw.closeOnce.Do(func() {
+		close(kw.closed)
+	})
+	kw.mu.Lock()
+	producer := kw.producer
+	kw.producer = nil
+	kw.mu.Unlock()
+	if producer == nil {
+		return nil
+	}
+	return producer.Close()
+}
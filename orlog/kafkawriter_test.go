@@ -0,0 +1,42 @@
+package orlog
+
+import (
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaslConfig(t *testing.T) {
+	config := sarama.NewConfig()
+	assert.Nil(t, saslConfig(config, "", "", ""))
+	assert.False(t, config.Net.SASL.Enable)
+	config = sarama.NewConfig()
+	assert.Nil(t, saslConfig(config, "PLAIN", "user", "pass"))
+	assert.True(t, config.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypePlaintext), config.Net.SASL.Mechanism)
+	config = sarama.NewConfig()
+	assert.Nil(t, saslConfig(config, "scram-sha-512", "user", "pass"))
+	assert.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), config.Net.SASL.Mechanism)
+	client := config.Net.SASL.SCRAMClientGeneratorFunc()
+	assert.Nil(t, client.Begin("user", "pass", ""))
+	first, err := client.Step("")
+	assert.Nil(t, err)
+	assert.Contains(t, first, "n=user")
+	assert.False(t, client.Done())
+	assert.NotNil(t, saslConfig(sarama.NewConfig(), "gssapi", "user", "pass"))
+}
+
+func TestNewKafkaWriter(t *testing.T) {
+	_, err := NewKafkaWriter(&KafkaConfig{Topic: "openrasp"}, nil)
+	assert.NotNil(t, err)
+	_, err = NewKafkaWriter(&KafkaConfig{Brokers: []string{"127.0.0.1:9092"}}, nil)
+	assert.NotNil(t, err)
+	_, err = NewKafkaWriter(&KafkaConfig{Brokers: []string{"127.0.0.1:9092"}, Topic: "openrasp", SaslMechanism: "gssapi"}, nil)
+	assert.NotNil(t, err)
+	// the brokers are reached in the background, nothing listens here
+	kw, err := NewKafkaWriter(&KafkaConfig{Brokers: []string{"127.0.0.1:1"}, Topic: "openrasp"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, errKafkaUnavailable, kw.WriteWithKey("rasp", []byte("entry")))
+	assert.Nil(t, kw.Close())
+}
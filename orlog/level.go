@@ -1,6 +1,11 @@
 package orlog
 
-import "github.com/sirupsen/logrus"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
 
 type Level uint32
 
@@ -17,6 +22,8 @@ func LevelTransform(l Level) logrus.Level {
 		return logrus.DebugLevel
 	case InfoLevel:
 		return logrus.InfoLevel
+	case ErrorLevel:
+		return logrus.ErrorLevel
 	default:
 		return logrus.WarnLevel
 	}
@@ -36,3 +43,18 @@ func LevelName(level Level) string {
 		return "UNKNOWN"
 	}
 }
+
+func LevelFromString(name string) (Level, error) {
+	switch strings.ToUpper(name) {
+	case "ERROR":
+		return ErrorLevel, nil
+	case "WARN", "WARNING":
+		return WarnLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "DEBUG":
+		return DebugLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("unknown log level %s", name)
+	}
+}
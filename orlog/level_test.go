@@ -0,0 +1,22 @@
+package orlog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelFromString(t *testing.T) {
+	l, err := LevelFromString("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, DebugLevel, l)
+	l, err = LevelFromString("Warning")
+	assert.Nil(t, err)
+	assert.Equal(t, WarnLevel, l)
+	l, err = LevelFromString("ERROR")
+	assert.Nil(t, err)
+	assert.Equal(t, logrus.ErrorLevel, LevelTransform(l))
+	_, err = LevelFromString("verbose")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,28 @@
+package orlog
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+type PanicHandler func(where string, value interface{})
+
+var panicHandler atomic.Value
+
+// SetPanicHandler receives the panics recovered in the background goroutines
+// of writers and queues, they are printed to stderr otherwise
+func SetPanicHandler(handler PanicHandler) {
+	panicHandler.Store(handler)
+}
+
+// recoverPanic must be deferred directly by the function it protects
+func recoverPanic(where string) {
+	if r := recover(); r != nil {
+		if handler, ok := panicHandler.Load().(PanicHandler); ok && handler != nil {
+			handler(where, r)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Recovered from panic in %s, %v\n", where, r)
+	}
+}
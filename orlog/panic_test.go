@@ -0,0 +1,28 @@
+package orlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	recovered := make(chan string, 1)
+	SetPanicHandler(func(where string, value interface{}) {
+		recovered <- where
+	})
+	defer SetPanicHandler(nil)
+	flushed := make(chan int, 2)
+	q := NewAsyncQueue(4, 1, 0, time.Hour, DropNewest, func(batch [][]byte) {
+		if string(batch[0]) == "bad" {
+			panic("broken flush")
+		}
+		flushed <- len(batch)
+	})
+	q.Push([]byte("bad"))
+	q.Push([]byte("good"))
+	q.Close()
+	assert.Equal(t, "async queue", <-recovered)
+	assert.Equal(t, 1, <-flushed)
+}
@@ -0,0 +1,205 @@
+package orlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	siemVendor  = "Baidu"
+	siemProduct = "OpenRASP"
+)
+
+type siemField struct {
+	cefKey  string
+	leefKey string
+	value   string
+}
+
+type siemEvent struct {
+	signature string
+	name      string
+	severity  int
+	fields    []siemField
+}
+
+// NewFormatter returns the formatter for name, json keeps the native
+// OpenRASP format
+func NewFormatter(name, version string) logrus.Formatter {
+	switch strings.ToLower(name) {
+	case "cef":
+		return &CEFFormatter{Version: version}
+	case "leef":
+		return &LEEFFormatter{Version: version}
+	default:
+		return &OpenRASPFormatter{}
+	}
+}
+
+func jsonString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+// newSiemEvent maps an AttackLog or PolicyLog message, anything else is kept
+// as a generic event carrying the raw message
+func newSiemEvent(message string) *siemEvent {
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(message), &m); err != nil {
+		return &siemEvent{
+			signature: "log",
+			name:      message,
+			severity:  3,
+		}
+	}
+	field := func(cefKey, leefKey, key string) siemField {
+		return siemField{cefKey: cefKey, leefKey: leefKey, value: jsonString(m[key])}
+	}
+	se := &siemEvent{}
+	switch jsonString(m["event_type"]) {
+	case "attack":
+		se.signature = jsonString(m["attack_type"])
+		se.name = jsonString(m["plugin_message"])
+		switch jsonString(m["severity"]) {
+		case "critical":
+			se.severity = 10
+		case "high":
+			se.severity = 8
+		case "medium":
+			se.severity = 5
+		case "low":
+			se.severity = 3
+		case "info":
+			se.severity = 1
+		default:
+			// logs written before the severity was scored
+			switch jsonString(m["intercept_state"]) {
+			case "block":
+				se.severity = 9
+			case "log":
+				se.severity = 6
+			default:
+				se.severity = 3
+			}
+		}
+		se.fields = []siemField{
+			field("rt", "devTime", "event_time"),
+			field("act", "action", "intercept_state"),
+			field("src", "src", "attack_source"),
+			field("dst", "dst", "server_ip"),
+			field("dhost", "dstHost", "server_hostname"),
+			field("request", "url", "url"),
+			field("requestMethod", "requestMethod", "request_method"),
+			field("cn1", "confidence", "plugin_confidence"),
+			field("cs1", "raspId", "rasp_id"),
+			field("cs2", "appId", "app_id"),
+			field("cs3", "attackParams", "attack_params"),
+			field("cs4", "requestId", "request_id"),
+			field("cs5", "severity", "severity"),
+		}
+	case "security_policy":
+		se.signature = jsonString(m["policy_id"])
+		se.name = jsonString(m["message"])
+		se.severity = 5
+		se.fields = []siemField{
+			field("rt", "devTime", "event_time"),
+			field("dhost", "dstHost", "server_hostname"),
+			field("cs1", "raspId", "rasp_id"),
+			field("cs2", "appId", "app_id"),
+			field("cs3", "policyParams", "policy_params"),
+		}
+	default:
+		se.signature = "log"
+		se.name = message
+		se.severity = 3
+	}
+	return se
+}
+
+// CEFFormatter emits ArcSight Common Event Format lines
+type CEFFormatter struct {
+	Version string
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer("\\", "\\\\", "=", "\\=", "\r", "\\r", "\n", "\\n")
+)
+
+func (f *CEFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	se := newSiemEvent(entry.Message)
+	var b bytes.Buffer
+	b.WriteString("CEF:0|")
+	for _, header := range []string{siemVendor, siemProduct, f.Version, se.signature, se.name} {
+		b.WriteString(cefHeaderEscaper.Replace(header))
+		b.WriteByte('|')
+	}
+	b.WriteString(strconv.Itoa(se.severity))
+	b.WriteByte('|')
+	first := true
+	for _, field := range se.fields {
+		if len(field.value) == 0 {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		// custom string and number fields carry their name in a label
+		if strings.HasPrefix(field.cefKey, "cs") || strings.HasPrefix(field.cefKey, "cn") {
+			b.WriteString(field.cefKey + "Label=" + field.leefKey + " ")
+		}
+		b.WriteString(field.cefKey + "=" + cefExtensionEscaper.Replace(field.value))
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// LEEFFormatter emits QRadar Log Event Extended Format 1.0 lines
+type LEEFFormatter struct {
+	Version string
+}
+
+var (
+	leefHeaderEscaper    = strings.NewReplacer("|", "\\|", "\r", " ", "\n", " ")
+	leefAttributeEscaper = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+)
+
+func (f *LEEFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	se := newSiemEvent(entry.Message)
+	var b bytes.Buffer
+	b.WriteString("LEEF:1.0|")
+	for _, header := range []string{siemVendor, siemProduct, f.Version, se.signature} {
+		b.WriteString(leefHeaderEscaper.Replace(header))
+		b.WriteByte('|')
+	}
+	b.WriteString("sev=" + strconv.Itoa(se.severity))
+	b.WriteString("\tname=" + leefAttributeEscaper.Replace(se.name))
+	for _, field := range se.fields {
+		if len(field.value) == 0 {
+			continue
+		}
+		b.WriteString("\t" + field.leefKey + "=" + leefAttributeEscaper.Replace(field.value))
+		if field.leefKey == "devTime" {
+			b.WriteString("\tdevTimeFormat=yyyy-MM-dd'T'HH:mm:ssZ")
+		}
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,34 @@
+package orlog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+const attackMessage = `{"event_type":"attack","attack_type":"sql","plugin_message":"SQL injection | union","intercept_state":"block","attack_source":"10.0.0.1","url":"http://a/b?id=1=1","plugin_confidence":90,"rasp_id":"r1","attack_params":{"query":"select 1"}}`
+
+func TestCEFFormatter(t *testing.T) {
+	f := &CEFFormatter{Version: "1.1"}
+	b, err := f.Format(&logrus.Entry{Message: attackMessage})
+	assert.Nil(t, err)
+	assert.Equal(t, "CEF:0|Baidu|OpenRASP|1.1|sql|SQL injection \\| union|9|act=block src=10.0.0.1 request=http://a/b?id\\=1\\=1 cn1Label=confidence cn1=90 cs1Label=raspId cs1=r1 cs3Label=attackParams cs3={\"query\":\"select 1\"}\n", string(b))
+
+	b, err = f.Format(&logrus.Entry{Message: `{"event_type":"attack","attack_type":"sql","plugin_message":"m","intercept_state":"log","severity":"critical"}`})
+	assert.Nil(t, err)
+	assert.Equal(t, "CEF:0|Baidu|OpenRASP|1.1|sql|m|10|act=log cs5Label=severity cs5=critical\n", string(b))
+}
+
+func TestLEEFFormatter(t *testing.T) {
+	f := &LEEFFormatter{Version: "1.1"}
+	b, err := f.Format(&logrus.Entry{Message: `{"event_type":"security_policy","policy_id":3006,"message":"high privileged","event_time":"2020-01-02T03:04:05+0800"}`})
+	assert.Nil(t, err)
+	assert.Equal(t, "LEEF:1.0|Baidu|OpenRASP|1.1|3006|sev=5\tname=high privileged\tdevTime=2020-01-02T03:04:05+0800\tdevTimeFormat=yyyy-MM-dd'T'HH:mm:ssZ\n", string(b))
+}
+
+func TestNewFormatter(t *testing.T) {
+	assert.IsType(t, &CEFFormatter{}, NewFormatter("CEF", "1.1"))
+	assert.IsType(t, &LEEFFormatter{}, NewFormatter("leef", "1.1"))
+	assert.IsType(t, &OpenRASPFormatter{}, NewFormatter("json", "1.1"))
+}
@@ -0,0 +1,42 @@
+package orlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+type SplunkHook struct {
+	hookLevel  Level
+	sourcetype string
+	Formatter  logrus.Formatter
+	Writer     *SplunkWriter
+}
+
+func NewSplunkHook(sourcetype string, writer *SplunkWriter, level Level) *SplunkHook {
+	sh := &SplunkHook{
+		hookLevel:  level,
+		sourcetype: sourcetype,
+		Writer:     writer,
+	}
+	return sh
+}
+
+func (hook *SplunkHook) Fire(entry *logrus.Entry) error {
+	line, err := formatEntry(hook.Formatter, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read entry, %v", err)
+		return err
+	}
+	return hook.Writer.WriteEvent(hook.sourcetype, []byte(line))
+}
+
+func (hook *SplunkHook) Levels() []logrus.Level {
+	switch hook.hookLevel {
+	case WarnLevel:
+		return []logrus.Level{logrus.WarnLevel}
+	default:
+		return []logrus.Level{logrus.InfoLevel}
+	}
+}
@@ -0,0 +1,151 @@
+package orlog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SplunkConfig struct {
+	Url           string
+	Token         string
+	Index         string
+	Source        string
+	QueueSize     int
+	BatchSize     int
+	BatchBytes    int
+	FlushInterval time.Duration
+	Timeout       time.Duration
+	TLSConfig     *tls.Config
+	DropCounter   *DropCounter
+}
+
+type hecEvent struct {
+	Time       float64         `json:"time"`
+	Host       string          `json:"host,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Sourcetype string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// SplunkWriter sends events to a Splunk HTTP Event Collector, events are
+// queued and posted in batches from a single worker
+type SplunkWriter struct {
+	url         string
+	token       string
+	index       string
+	source      string
+	host        string
+	client      *http.Client
+	queue       *AsyncQueue
+	tokenBucket *TokenBucket
+	mu          sync.Mutex
+}
+
+func NewSplunkWriter(sc *SplunkConfig, tokenBucket *TokenBucket) (*SplunkWriter, error) {
+	if len(sc.Url) == 0 {
+		return nil, fmt.Errorf("no splunk hec url configured")
+	}
+	if len(sc.Token) == 0 {
+		return nil, fmt.Errorf("no splunk hec token configured")
+	}
+	url := strings.TrimRight(sc.Url, "/")
+	if !strings.Contains(url, "/services/collector") {
+		url += "/services/collector/event"
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = sc.TLSConfig
+	hostname, _ := os.Hostname()
+	sw := &SplunkWriter{
+		url:         url,
+		token:       sc.Token,
+		index:       sc.Index,
+		source:      sc.Source,
+		host:        hostname,
+		client:      &http.Client{Timeout: sc.Timeout, Transport: transport},
+		tokenBucket: tokenBucket,
+	}
+	sw.queue = NewAsyncQueue(sc.QueueSize, sc.BatchSize, sc.BatchBytes, sc.FlushInterval, DropNewest, sw.flush)
+	sw.queue.SetDropCounter(sc.DropCounter)
+	return sw, nil
+}
+
+func (sw *SplunkWriter) newEvent(sourcetype string, line []byte) ([]byte, error) {
+	event := bytes.TrimRight(line, "\n")
+	if !json.Valid(event) {
+		quoted, err := json.Marshal(string(event))
+		if err != nil {
+			return nil, err
+		}
+		event = quoted
+	}
+	return json.Marshal(&hecEvent{
+		Time:       float64(time.Now().UnixNano()/int64(time.Millisecond)) / 1000,
+		Host:       sw.host,
+		Source:     sw.source,
+		Sourcetype: sourcetype,
+		Index:      sw.index,
+		Event:      event,
+	})
+}
+
+// QueueLen returns the number of events waiting to be posted
+func (sw *SplunkWriter) QueueLen() int {
+	return sw.queue.Len()
+}
+
+func (sw *SplunkWriter) WriteEvent(sourcetype string, line []byte) error {
+	sw.mu.Lock()
+	consumed := sw.tokenBucket != nil && sw.tokenBucket.Consume()
+	sw.mu.Unlock()
+	if consumed {
+		return nil
+	}
+	event, err := sw.newEvent(sourcetype, line)
+	if err != nil {
+		return err
+	}
+	sw.queue.Push(event)
+	return nil
+}
+
+// flush posts the batch as concatenated event objects, the format HEC
+// expects for batched events
+func (sw *SplunkWriter) flush(batch [][]byte) {
+	if err := sw.post(bytes.Join(batch, []byte("\n"))); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to send events to splunk, %v", err)
+	}
+}
+
+func (sw *SplunkWriter) post(body []byte) error {
+	req, err := http.NewRequest("POST", sw.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+sw.token)
+	resp, err := sw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("splunk hec responded %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func (sw *SplunkWriter) Close() error {
+	sw.queue.Close()
+	return nil
+}
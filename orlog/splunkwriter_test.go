@@ -0,0 +1,57 @@
+package orlog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplunkWriter(t *testing.T) {
+	var path, authorization string
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		authorization = r.Header.Get("Authorization")
+		body, _ := ioutil.ReadAll(r.Body)
+		decoder := json.NewDecoder(strings.NewReader(string(body)))
+		for decoder.More() {
+			var event map[string]interface{}
+			decoder.Decode(&event)
+			events = append(events, event)
+		}
+	}))
+	defer server.Close()
+	sw, err := NewSplunkWriter(&SplunkConfig{
+		Url:           server.URL,
+		Token:         "secret",
+		Index:         "rasp",
+		QueueSize:     10,
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Timeout:       time.Second,
+	}, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, sw.WriteEvent("openrasp:attack", []byte("{\"attack_type\":\"sql\"}\n")))
+	assert.Nil(t, sw.WriteEvent("openrasp:policy", []byte("CEF:0|Baidu|OpenRASP\n")))
+	sw.Close()
+	assert.Equal(t, "/services/collector/event", path)
+	assert.Equal(t, "Splunk secret", authorization)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "openrasp:attack", events[0]["sourcetype"])
+	assert.Equal(t, "rasp", events[0]["index"])
+	assert.Equal(t, map[string]interface{}{"attack_type": "sql"}, events[0]["event"])
+	assert.Equal(t, "CEF:0|Baidu|OpenRASP", events[1]["event"])
+}
+
+func TestNewSplunkWriter(t *testing.T) {
+	_, err := NewSplunkWriter(&SplunkConfig{Token: "secret"}, nil)
+	assert.NotNil(t, err)
+	_, err = NewSplunkWriter(&SplunkConfig{Url: "https://splunk:8088"}, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,119 @@
+package orlog
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var errSpoolFull = errors.New("spool file reached its size limit")
+
+// Spool keeps entries that could not be delivered in a local file as length
+// prefixed records, the file never grows beyond maxSize bytes
+type Spool struct {
+	filename string
+	maxSize  int64
+	cipher   *LogCipher
+	mu       sync.Mutex
+}
+
+// NewSpool seals every record with lc unless it is nil
+func NewSpool(filename string, maxSize int64, lc *LogCipher) *Spool {
+	s := &Spool{
+		filename: filename,
+		maxSize:  maxSize,
+		cipher:   lc,
+	}
+	return s
+}
+
+func (s *Spool) Append(entry []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append([][]byte{entry})
+}
+
+func (s *Spool) append(entries [][]byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.filename), 0744); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	for _, entry := range entries {
+		if s.cipher != nil {
+			if entry, err = s.cipher.Seal(entry); err != nil {
+				return err
+			}
+		}
+		recordSize := int64(4 + len(entry))
+		if s.maxSize > 0 && size+recordSize > s.maxSize {
+			return errSpoolFull
+		}
+		var header [4]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(entry)))
+		if _, err := f.Write(append(header[:], entry...)); err != nil {
+			return err
+		}
+		size += recordSize
+	}
+	return nil
+}
+
+// Drain returns all spooled entries and empties the file, a truncated
+// trailing record is discarded, and so are the records written before
+// encryption was turned on or off
+func (s *Spool) Drain() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.Open(s.filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries [][]byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			break
+		}
+		entry := make([]byte, binary.BigEndian.Uint32(header[:]))
+		if _, err := io.ReadFull(f, entry); err != nil {
+			break
+		}
+		if s.cipher != nil {
+			if entry, err = s.cipher.Open(entry); err != nil {
+				continue
+			}
+		} else if IsEncrypted(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	f.Close()
+	return entries, os.Remove(s.filename)
+}
+
+// Restore puts entries that failed again during a replay back
+func (s *Spool) Restore(entries [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(entries)
+}
+
+func (s *Spool) Empty() bool {
+	info, err := os.Stat(s.filename)
+	return err != nil || info.Size() == 0
+}
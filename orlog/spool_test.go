@@ -0,0 +1,55 @@
+package orlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	s := NewSpool(filepath.Join(dir, "attack.spool"), 25, nil)
+	assert.True(t, s.Empty())
+	assert.Nil(t, s.Append([]byte("[\nfirst]")))
+	assert.Nil(t, s.Append([]byte("second")))
+	assert.Equal(t, errSpoolFull, s.Append([]byte("third")))
+	assert.False(t, s.Empty())
+	entries, err := s.Drain()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("[\nfirst]"), []byte("second")}, entries)
+	assert.True(t, s.Empty())
+	entries, err = s.Drain()
+	assert.Nil(t, err)
+	assert.Nil(t, entries)
+}
+
+func TestSpoolCipher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orlog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	lc, err := NewLogCipher(bytes.Repeat([]byte{7}, 32))
+	assert.Nil(t, err)
+	filename := filepath.Join(dir, "attack.spool")
+	// a record spooled before encryption was turned on is dropped
+	assert.Nil(t, NewSpool(filename, 0, nil).Append([]byte("plain")))
+	s := NewSpool(filename, 0, lc)
+	payload := []byte(`[{"attack_type":"sql","attack_params":{"query":"select 1"}}]`)
+	assert.Nil(t, s.Append(payload))
+	raw, err := ioutil.ReadFile(filename)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(raw, []byte("attack_type")))
+	entries, err := s.Drain()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{payload}, entries)
+	// and a sealed one after it was turned off
+	assert.Nil(t, s.Append(payload))
+	entries, err = NewSpool(filename, 0, nil).Drain()
+	assert.Nil(t, err)
+	assert.Nil(t, entries)
+}
@@ -0,0 +1,62 @@
+package orlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var consoleMu sync.Mutex
+
+// Stdout and Stderr share a lock, so lines of different channels never
+// interleave when a container runtime collects both streams
+var (
+	Stdout io.Writer = &lockedWriter{w: os.Stdout}
+	Stderr io.Writer = &lockedWriter{w: os.Stderr}
+)
+
+type lockedWriter struct {
+	w io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	consoleMu.Lock()
+	defer consoleMu.Unlock()
+	return lw.w.Write(p)
+}
+
+// StdoutFormatter writes every entry as a single json line carrying the
+// channel, level and schema fields, json messages are merged into the line
+// and any other message is kept in the message field
+type StdoutFormatter struct {
+	Channel       string
+	SchemaVersion int
+}
+
+func (f *StdoutFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	fields := make(map[string]interface{})
+	message := bytes.TrimSpace([]byte(entry.Message))
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil || decoder.More() {
+		fields = map[string]interface{}{"message": string(message)}
+	}
+	fields["channel"] = f.Channel
+	fields["level"] = entry.Level.String()
+	if _, ok := fields["schema_version"]; !ok {
+		fields["schema_version"] = f.SchemaVersion
+	}
+	if _, ok := fields["event_time"]; !ok {
+		fields["event_time"] = entry.Time.Format(utils.ISO8601TimestampFormat)
+	}
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,40 @@
+package orlog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStdoutFormatter(t *testing.T) {
+	f := &StdoutFormatter{Channel: "alarm", SchemaVersion: 2}
+	entry := &logrus.Entry{
+		Message: "{\"attack_type\":\"sql\",\"plugin_confidence\":90,\"schema_version\":2}\n",
+		Level:   logrus.InfoLevel,
+		Time:    time.Now(),
+	}
+	b, err := f.Format(entry)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(string(b), "}\n"))
+	assert.Equal(t, 1, strings.Count(string(b), "\n"))
+	assert.Contains(t, string(b), `"plugin_confidence":90`)
+	var fields map[string]interface{}
+	assert.Nil(t, json.Unmarshal(b, &fields))
+	assert.Equal(t, "alarm", fields["channel"])
+	assert.Equal(t, "info", fields["level"])
+	assert.Equal(t, "sql", fields["attack_type"])
+
+	f = &StdoutFormatter{Channel: "plugin", SchemaVersion: 2}
+	entry.Message = "first line\nsecond line"
+	b, err = f.Format(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(b), "\n"))
+	assert.Nil(t, json.Unmarshal(b, &fields))
+	assert.Equal(t, "first line\nsecond line", fields["message"])
+	assert.Equal(t, float64(2), fields["schema_version"])
+	assert.NotEmpty(t, fields["event_time"])
+}
@@ -0,0 +1,44 @@
+package orlog
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+)
+
+type SyslogHook struct {
+	hookLevel Level
+	severity  int
+	msgId     string
+	Formatter logrus.Formatter
+	Writer    *SyslogWriter
+}
+
+func NewSyslogHook(msgId string, writer *SyslogWriter, level Level, severity int) *SyslogHook {
+	sh := &SyslogHook{
+		hookLevel: level,
+		severity:  severity,
+		msgId:     msgId,
+		Writer:    writer,
+	}
+	return sh
+}
+
+func (hook *SyslogHook) Fire(entry *logrus.Entry) error {
+	line, err := formatEntry(hook.Formatter, entry)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read entry, %v", err)
+		return err
+	}
+	return hook.Writer.WriteWithSeverity(hook.severity, hook.msgId, []byte(line))
+}
+
+func (hook *SyslogHook) Levels() []logrus.Level {
+	switch hook.hookLevel {
+	case WarnLevel:
+		return []logrus.Level{logrus.WarnLevel}
+	default:
+		return []logrus.Level{logrus.InfoLevel}
+	}
+}
@@ -0,0 +1,200 @@
+package orlog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errSyslogUnavailable = errors.New("syslog daemon is unavailable, waiting to reconnect")
+
+// SyslogWriter sends RFC 5424 messages over udp, tcp or a unix socket, tcp
+// uses octet counting framing from RFC 6587. After StartQueue the messages
+// are sent from a single worker.
+type SyslogWriter struct {
+	network           string
+	address           string
+	tag               string
+	hostname          string
+	facility          int
+	connectionTimeout time.Duration
+	writeTimeout      time.Duration
+	reconnectInterval time.Duration
+	tokenBucket       *TokenBucket
+	queue             *AsyncQueue
+	conn              net.Conn
+	lastDial          time.Time
+	mu                sync.Mutex
+}
+
+// NewSyslogWriter accepts urls like udp://host:514, tcp://host:601 or
+// unix:///dev/log, an empty url means the local daemon at /dev/log
+func NewSyslogWriter(rawurl, tag string, facility int, connectionTimeout, writeTimeout, reconnectInterval time.Duration, tokenBucket *TokenBucket) (*SyslogWriter, error) {
+	network, address, err := parseSyslogUrl(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if facility < 0 || facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility %d", facility)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || len(hostname) == 0 {
+		hostname = "-"
+	}
+	sw := &SyslogWriter{
+		network:           network,
+		address:           address,
+		tag:               tag,
+		hostname:          hostname,
+		facility:          facility,
+		connectionTimeout: connectionTimeout,
+		writeTimeout:      writeTimeout,
+		reconnectInterval: reconnectInterval,
+		tokenBucket:       tokenBucket,
+	}
+	return sw, nil
+}
+
+func parseSyslogUrl(rawurl string) (string, string, error) {
+	if len(rawurl) == 0 {
+		return "unix", "/dev/log", nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", "", err
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if len(u.Port()) == 0 {
+			return u.Scheme, net.JoinHostPort(u.Hostname(), "514"), nil
+		}
+		return u.Scheme, u.Host, nil
+	case "unix":
+		return u.Scheme, u.Path, nil
+	default:
+		return "", "", fmt.Errorf("unsupported syslog url %s", rawurl)
+	}
+}
+
+func (sw *SyslogWriter) dial() error {
+	if !sw.lastDial.IsZero() && time.Since(sw.lastDial) < sw.reconnectInterval {
+		return errSyslogUnavailable
+	}
+	sw.lastDial = time.Now()
+	var conn net.Conn
+	var err error
+	if sw.network == "unix" {
+		conn, err = net.DialTimeout("unixgram", sw.address, sw.connectionTimeout)
+		if err != nil {
+			conn, err = net.DialTimeout("unix", sw.address, sw.connectionTimeout)
+		}
+	} else {
+		conn, err = net.DialTimeout(sw.network, sw.address, sw.connectionTimeout)
+	}
+	if err != nil {
+		return err
+	}
+	sw.conn = conn
+	sw.lastDial = time.Time{}
+	return nil
+}
+
+func (sw *SyslogWriter) format(severity int, msgId string, msg []byte) []byte {
+	line := fmt.Sprintf("<%d>1 %s %s %s %d %s - %s",
+		sw.facility*8+severity,
+		time.Now().Format("2006-01-02T15:04:05.000000Z07:00"),
+		sw.hostname,
+		nilValue(sw.tag),
+		os.Getpid(),
+		nilValue(msgId),
+		strings.TrimRight(string(msg), "\n"))
+	if sw.network == "tcp" {
+		line = strconv.Itoa(len(line)) + " " + line
+	}
+	return []byte(line)
+}
+
+func nilValue(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return strings.Replace(value, " ", "_", -1)
+}
+
+// StartQueue queues up to size messages for a single worker, which sends
+// them one by one, so a slow daemon no longer holds up the caller. It must
+// be called before the first write.
+func (sw *SyslogWriter) StartQueue(size int, dropPolicy DropPolicy, dc *DropCounter) {
+	sw.queue = NewAsyncQueue(size, 1, 0, time.Second, dropPolicy, sw.flush)
+	sw.queue.SetDropCounter(dc)
+}
+
+// QueueLen returns the number of messages waiting to be sent
+func (sw *SyslogWriter) QueueLen() int {
+	if sw.queue == nil {
+		return 0
+	}
+	return sw.queue.Len()
+}
+
+// WriteWithSeverity sends msg with the given severity, or queues it after
+// StartQueue. A failed write drops the connection and the next one is
+// attempted after the reconnect interval.
+func (sw *SyslogWriter) WriteWithSeverity(severity int, msgId string, msg []byte) error {
+	if sw.tokenBucket != nil && sw.tokenBucket.Consume() {
+		return nil
+	}
+	line := sw.format(severity, msgId, msg)
+	if sw.queue != nil {
+		sw.queue.Push(line)
+		return nil
+	}
+	return sw.send(line)
+}
+
+func (sw *SyslogWriter) flush(batch [][]byte) {
+	for _, line := range batch {
+		sw.send(line)
+	}
+}
+
+func (sw *SyslogWriter) send(line []byte) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.conn == nil {
+		if err := sw.dial(); err != nil {
+			return err
+		}
+	}
+	if sw.writeTimeout > 0 {
+		sw.conn.SetWriteDeadline(time.Now().Add(sw.writeTimeout))
+	}
+	_, err := sw.conn.Write(line)
+	if err != nil {
+		sw.conn.Close()
+		sw.conn = nil
+		sw.lastDial = time.Now()
+	}
+	return err
+}
+
+// Close sends the queued messages and closes the connection
+func (sw *SyslogWriter) Close() error {
+	if sw.queue != nil {
+		sw.queue.Close()
+	}
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	if sw.conn == nil {
+		return nil
+	}
+	err := sw.conn.Close()
+	sw.conn = nil
+	return err
+}
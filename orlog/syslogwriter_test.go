@@ -0,0 +1,75 @@
+package orlog
+
+import (
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyslogUrl(t *testing.T) {
+	network, address, err := parseSyslogUrl("udp://127.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "udp", network)
+	assert.Equal(t, "127.0.0.1:514", address)
+	network, address, err = parseSyslogUrl("tcp://logs.example.com:601")
+	assert.Nil(t, err)
+	assert.Equal(t, "tcp", network)
+	assert.Equal(t, "logs.example.com:601", address)
+	network, address, err = parseSyslogUrl("")
+	assert.Nil(t, err)
+	assert.Equal(t, "unix", network)
+	assert.Equal(t, "/dev/log", address)
+	_, _, err = parseSyslogUrl("http://127.0.0.1")
+	assert.NotNil(t, err)
+}
+
+func TestSyslogWriterUdp(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+	sw, err := NewSyslogWriter("udp://"+pc.LocalAddr().String(), "OpenRASP", 1, time.Second, time.Second, time.Minute, nil)
+	assert.Nil(t, err)
+	defer sw.Close()
+	assert.Nil(t, sw.WriteWithSeverity(4, "attack", []byte("{\"attack_type\":\"sql\"}\n")))
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^<12>1 \S+ \S+ OpenRASP \d+ attack - \{"attack_type":"sql"\}$`), string(buf[:n]))
+}
+
+func TestSyslogWriterQueue(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+	sw, err := NewSyslogWriter("udp://"+pc.LocalAddr().String(), "OpenRASP", 1, time.Second, time.Second, time.Minute, nil)
+	assert.Nil(t, err)
+	dc := NewDropCounter("syslog", nil)
+	sw.StartQueue(2, DropNewest, dc)
+
+	// a stuck daemon holds up the worker, not the writer
+	sw.mu.Lock()
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, sw.WriteWithSeverity(4, "attack", []byte("{}\n")))
+	}
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+	dropped := dc.Totals().Overflowed
+	assert.True(t, dropped >= 2)
+	sw.mu.Unlock()
+	sw.Close()
+
+	buf := make([]byte, 1024)
+	received := uint64(0)
+	for {
+		pc.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		if _, _, err := pc.ReadFrom(buf); err != nil {
+			break
+		}
+		received++
+	}
+	assert.Equal(t, 5-dropped, received)
+}
@@ -1,38 +1,73 @@
 package orlog
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 type TokenBucket struct {
 	refillInterval     time.Duration
+	rate               float64
 	capacity           uint64
-	currentTokenAmount uint64
+	currentTokenAmount float64
 	lastConsumedTime   time.Time
+	lastRefillTime     time.Time
+	dropCounter        *DropCounter
+	mu                 sync.Mutex
 }
 
+// NewTokenBucket refills the whole capacity once no token was consumed for refillInterval
 func NewTokenBucket(capacity uint64, refillInterval time.Duration) *TokenBucket {
 	tb := &TokenBucket{
 		refillInterval:     refillInterval,
 		capacity:           capacity,
-		currentTokenAmount: capacity,
+		currentTokenAmount: float64(capacity),
 		lastConsumedTime:   time.Now(),
+		lastRefillTime:     time.Now(),
 	}
 	return tb
 }
 
+// NewRateTokenBucket refills rate tokens per second, up to capacity
+func NewRateTokenBucket(capacity uint64, rate float64) *TokenBucket {
+	tb := NewTokenBucket(capacity, 0)
+	tb.rate = rate
+	return tb
+}
+
 func (tb *TokenBucket) Consume() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
 	tb.refill()
-	isEmpty := tb.currentTokenAmount <= 0
+	isEmpty := tb.currentTokenAmount < 1
 	if !isEmpty {
 		tb.currentTokenAmount--
 		tb.lastConsumedTime = time.Now()
+	} else {
+		tb.dropCounter.Add(RateLimited)
 	}
 	return isEmpty
 }
 
+// SetDropCounter counts the tokens refused from now on
+func (tb *TokenBucket) SetDropCounter(dc *DropCounter) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.dropCounter = dc
+}
+
 func (tb *TokenBucket) refill() {
 	current := time.Now()
+	if tb.rate > 0 {
+		tb.currentTokenAmount += current.Sub(tb.lastRefillTime).Seconds() * tb.rate
+		if tb.currentTokenAmount > float64(tb.capacity) {
+			tb.currentTokenAmount = float64(tb.capacity)
+		}
+		tb.lastRefillTime = current
+		return
+	}
 	elapsedTimeFromLastConsumed := current.Sub(tb.lastConsumedTime)
 	if int64(elapsedTimeFromLastConsumed) > int64(tb.refillInterval) {
-		tb.currentTokenAmount = tb.capacity
+		tb.currentTokenAmount = float64(tb.capacity)
 	}
 }
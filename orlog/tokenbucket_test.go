@@ -0,0 +1,23 @@
+package orlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tb := NewTokenBucket(2, time.Hour)
+	assert.False(t, tb.Consume())
+	assert.False(t, tb.Consume())
+	assert.True(t, tb.Consume())
+}
+
+func TestRateTokenBucket(t *testing.T) {
+	tb := NewRateTokenBucket(1, 50)
+	assert.False(t, tb.Consume())
+	assert.True(t, tb.Consume())
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, tb.Consume())
+}
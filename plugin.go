@@ -2,12 +2,17 @@ package openrasp
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/wasmengine"
 	v8 "github.com/baidu-security/openrasp-v8/go"
 )
 
@@ -15,11 +20,18 @@ type UpdateListener interface {
 	OnPluginUpdate()
 }
 
+// reloadDelay lets the writes to the plugin directory settle, editors and
+// copies fire several events for a single update
+const reloadDelay = 500 * time.Millisecond
+
 type PluginManager struct {
-	dirPath   string
-	plugins   []v8.Plugin
-	listeners []UpdateListener
-	mu        sync.RWMutex
+	dirPath     string
+	plugins     []v8.Plugin
+	wasmPlugins []wasmengine.Plugin
+	listeners   []UpdateListener
+	reloadTimer *time.Timer
+	mu          sync.RWMutex
+	reloadMu    sync.Mutex
 }
 
 func NewPluginManager(dir string) *PluginManager {
@@ -35,52 +47,166 @@ func (pm *PluginManager) AttachListener(listener UpdateListener) {
 	pm.listeners = append(pm.listeners, listener)
 }
 
-func (pm *PluginManager) walkFunc(path string, info os.FileInfo, err error) error {
-	if !info.IsDir() && filepath.Ext(path) == ".js" {
-		plugin, err := newPlugin(path)
-		if err == nil {
-			pm.plugins = append(pm.plugins, *plugin)
+// PluginInfo describes an active plugin
+type PluginInfo struct {
+	Filename string `json:"filename"`
+	Version  string `json:"version,omitempty"`
+	Wasm     bool   `json:"wasm,omitempty"`
+}
+
+// pluginVersionPattern finds the plugin_version a javascript plugin declares
+var pluginVersionPattern = regexp.MustCompile(`plugin_version\s*=\s*['"]([^'"]+)['"]`)
+
+// Plugins lists the active javascript and wasm plugins
+func (pm *PluginManager) Plugins() []PluginInfo {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	infos := make([]PluginInfo, 0, len(pm.plugins)+len(pm.wasmPlugins))
+	for _, plugin := range pm.plugins {
+		info := PluginInfo{Filename: plugin.Filename}
+		if match := pluginVersionPattern.FindStringSubmatch(plugin.Source); match != nil {
+			info.Version = match[1]
 		}
+		infos = append(infos, info)
 	}
-	return nil
+	for _, plugin := range pm.wasmPlugins {
+		infos = append(infos, PluginInfo{Filename: plugin.Name, Wasm: true})
+	}
+	return infos
 }
 
-func (pm *PluginManager) loadLocalPlugins() {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-	pm.plugins = nil
-	filepath.Walk(pm.dirPath, pm.walkFunc)
+// scanPlugins reads the javascript and wasm plugins of the plugin directory
+func (pm *PluginManager) scanPlugins() ([]v8.Plugin, []wasmengine.Plugin) {
+	var plugins []v8.Plugin
+	var wasmPlugins []wasmengine.Plugin
+	filepath.Walk(pm.dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".js":
+			plugin, err := newPlugin(path)
+			if err == nil {
+				plugins = append(plugins, *plugin)
+			}
+		case ".wasm":
+			plugin, err := newWasmPlugin(path)
+			if err == nil {
+				wasmPlugins = append(wasmPlugins, *plugin)
+			}
+		}
+		return nil
+	})
+	return plugins, wasmPlugins
 }
 
+// buildLocalSnapshot loads the plugins of the plugin directory, the embedded
+// default plugin takes over when the directory holds no javascript plugin
 func (pm *PluginManager) buildLocalSnapshot() {
-	pm.loadLocalPlugins()
-	pm.createSnapshot()
+	plugins, wasmPlugins := pm.scanPlugins()
+	if len(plugins) == 0 {
+		GetLog().RaspInfo("No plugin found in "+pm.dirPath+", the embedded default plugin is loaded", orlog.Plugin)
+		plugins = defaultPlugins()
+	}
+	if err := pm.swap(plugins); err != nil {
+		GetLog().RaspWarn("Unable to reload plugins, the previous version is kept, cuz of "+err.Error(), orlog.Plugin)
+	}
+	pm.mu.Lock()
+	pm.wasmPlugins = wasmPlugins
+	pm.mu.Unlock()
+	loadWasmPlugins(wasmPlugins)
 }
 
-func (pm *PluginManager) createSnapshot() {
-	pm.mu.RLock()
-	defer pm.mu.RUnlock()
-	if len(pm.plugins) > 0 {
-		if v8.CreateSnapshot("", pm.plugins) {
-			for _, l := range pm.listeners {
-				l.OnPluginUpdate()
-			}
+// swap activates plugins while requests are in flight, the running plugins
+// stay active when the new ones fail to initialize
+func (pm *PluginManager) swap(plugins []v8.Plugin) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic %v", r)
 		}
+	}()
+	if len(plugins) == 0 {
+		return nil
+	}
+	pm.reloadMu.Lock()
+	defer pm.reloadMu.Unlock()
+	if err := loadPlugins(plugins); err != nil {
+		return err
 	}
+	pm.mu.Lock()
+	pm.plugins = plugins
+	listeners := pm.listeners
+	pm.mu.Unlock()
+	for _, l := range listeners {
+		l.OnPluginUpdate()
+	}
+	return nil
 }
 
 func (pm *PluginManager) OnUpdate(absPath string) {
-	if filepath.Ext(absPath) == ".js" {
-		pm.buildLocalSnapshot()
+	if ext := filepath.Ext(absPath); ext == ".js" || ext == ".wasm" {
+		pm.mu.Lock()
+		defer pm.mu.Unlock()
+		if pm.reloadTimer != nil {
+			pm.reloadTimer.Stop()
+		}
+		pm.reloadTimer = time.AfterFunc(reloadDelay, pm.buildLocalSnapshot)
 	}
 }
 
+// cloudPluginDir is the subdirectory of the plugin directory caching the
+// cloud plugin, the plugins installed next to it are left alone
+const cloudPluginDir = "cloud"
+
+// OnUpdateCloud hot-swaps the plugin downloaded from the cloud and caches it
+// under the plugin directory, so it is loaded on the next start before the first
+// heartbeat. A plugin which fails to initialize is neither activated nor
+// cached.
 func (pm *PluginManager) OnUpdateCloud(source string, filename string) {
-	pm.plugins = []v8.Plugin{v8.Plugin{
+	plugins := []v8.Plugin{v8.Plugin{
 		Source:   source,
 		Filename: filename,
 	}}
-	pm.createSnapshot()
+	if err := pm.swap(plugins); err != nil {
+		GetLog().RaspWarn("Unable to load cloud plugin "+filename+", the previous version is kept, cuz of "+err.Error(), orlog.Plugin)
+		return
+	}
+	if err := pm.cachePlugin(source, filename); err != nil {
+		GetLog().RaspWarn("Unable to cache cloud plugin, cuz of "+err.Error(), orlog.Plugin)
+	}
+}
+
+// cachePlugin replaces the cloud plugin cached in cloudPluginDir, the file is
+// renamed into place so it is never read half written
+func (pm *PluginManager) cachePlugin(source string, filename string) error {
+	dir := filepath.Join(pm.dirPath, cloudPluginDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".plugin")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(source)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	stale, _ := filepath.Glob(filepath.Join(dir, "*.js"))
+	target := filepath.Join(dir, filepath.Base(filename)+".js")
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	for _, path := range stale {
+		if path != target {
+			os.Remove(path)
+		}
+	}
+	return nil
 }
 
 func newPlugin(path string) (*v8.Plugin, error) {
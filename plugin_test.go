@@ -0,0 +1,34 @@
+package openrasp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachePlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	local := filepath.Join(dir, "custom.js")
+	assert.Nil(t, ioutil.WriteFile(local, []byte("// installed by hand"), 0644))
+	pm := NewPluginManager(dir)
+
+	assert.Nil(t, pm.cachePlugin("// v1", "official"))
+	assert.Nil(t, pm.cachePlugin("// v2", "official-2"))
+	cached, _ := filepath.Glob(filepath.Join(dir, cloudPluginDir, "*"))
+	assert.Equal(t, []string{filepath.Join(dir, cloudPluginDir, "official-2.js")}, cached)
+	content, err := ioutil.ReadFile(local)
+	assert.Nil(t, err)
+	assert.Equal(t, "// installed by hand", string(content))
+
+	plugins, _ := pm.scanPlugins()
+	var names []string
+	for _, plugin := range plugins {
+		names = append(names, plugin.Filename)
+	}
+	assert.ElementsMatch(t, []string{"custom", "official-2"}, names)
+}
@@ -0,0 +1,135 @@
+package openrasp
+
+import (
+	"encoding/json"
+	"errors"
+	goruntime "runtime"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/jsengine"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	v8 "github.com/baidu-security/openrasp-v8/go"
+)
+
+// jsEngine runs the plugins when plugin.engine is goja, v8 is used otherwise
+var jsEngine *jsengine.Engine
+
+func initPluginEngine() bool {
+	switch engine := GetBasic().GetString("plugin.engine"); engine {
+	case "goja":
+		jsEngine = jsengine.NewEngine(goruntime.NumCPU(), GetLog().PluginInfo)
+		GetLog().RaspDebug("Initialize goja successfully.", orlog.Plugin)
+		return true
+	case "v8":
+		if !v8.Initialize(GetLog().PluginInfo) {
+			GetLog().RaspWarn("Unable to initialize v8.", orlog.Plugin)
+			return false
+		}
+		GetLog().RaspDebug("Initialize v8 successfully.", orlog.Plugin)
+		return true
+	default:
+		GetLog().RaspWarn("Unknown plugin.engine "+engine, orlog.Plugin)
+		return false
+	}
+}
+
+// loadPlugins swaps the running plugins, they are kept when plugins fail to
+// initialize
+func loadPlugins(plugins []v8.Plugin) error {
+	if jsEngine == nil {
+		if !v8.CreateSnapshot("", plugins) {
+			return errors.New("unable to create v8 snapshot")
+		}
+		GetVerdictCache().Invalidate()
+		return nil
+	}
+	jsPlugins := make([]jsengine.Plugin, 0, len(plugins))
+	for _, plugin := range plugins {
+		jsPlugins = append(jsPlugins, jsengine.Plugin{Source: plugin.Source, Filename: plugin.Filename})
+	}
+	if err := jsEngine.Load(jsPlugins); err != nil {
+		return err
+	}
+	GetVerdictCache().Invalidate()
+	return nil
+}
+
+// PluginCheck runs the checkers the plugins registered for checkType against
+// params and the current request, the results are returned as a JSON array.
+// Verdicts are served from the verdict cache, a failed check is not cached.
+func PluginCheck(checkType string, params []byte) []byte {
+	key, cacheable := GetVerdictCache().Key(checkType, params)
+	if cacheable {
+		if result, ok := GetVerdictCache().Get(key); ok {
+			return result.([]byte)
+		}
+	}
+	timeout := GetGeneral().GetInt("plugin.timeout.millis")
+	if jsEngine == nil {
+		result := v8.Check(checkType, params, DefaultContextGetters(), timeout)
+		if cacheable && result != nil {
+			GetVerdictCache().Add(key, result)
+		}
+		return result
+	}
+	result, err := jsEngine.Check(checkType, params, jsContext(DefaultContextGetters()), time.Duration(timeout)*time.Millisecond)
+	if err != nil {
+		GetLog().RaspDebug("Unable to run "+checkType+" checkers, cuz of "+err.Error(), orlog.Plugin)
+		result, _ = json.Marshal(failureResults(err))
+	} else if cacheable {
+		GetVerdictCache().Add(key, result)
+	}
+	return result
+}
+
+// failureResults turns a plugin which timed out, panicked or failed into
+// the decision of plugin.failure_action, ignore keeps the service running
+// and block fails closed
+func failureResults(err error) []map[string]interface{} {
+	action := model.InterceptStringToCode(GetGeneral().GetString("plugin.failure_action"))
+	if action == model.Ignore {
+		return nil
+	}
+	return []map[string]interface{}{{
+		"action":    model.InterceptCodeToString(action),
+		"message":   "Detection failed, cuz of " + err.Error(),
+		"algorithm": "plugin_failure",
+		"name":      "openrasp",
+	}}
+}
+
+// ExecScript evaluates source with the loaded plugins and returns the result
+// as JSON
+func ExecScript(source, filename string) string {
+	if jsEngine == nil {
+		return v8.ExecScript(source, filename)
+	}
+	result, err := jsEngine.Exec(source, filename)
+	if err != nil {
+		GetLog().RaspWarn("Unable to exec "+filename+", cuz of "+err.Error(), orlog.Plugin)
+	}
+	return result
+}
+
+func jsContext(cg *v8.ContextGetters) jsengine.Context {
+	return jsengine.Context{
+		"url":         cg.Url,
+		"path":        cg.Path,
+		"querystring": cg.Querystring,
+		"method":      cg.Method,
+		"protocol":    cg.Protocol,
+		"remoteAddr":  cg.RemoteAddr,
+		"header":      cg.Header,
+		"parameter":   cg.Parameter,
+		"json": func() interface{} {
+			if raw, ok := cg.Json().(string); ok {
+				return []byte(raw)
+			}
+			return nil
+		},
+		"server":      cg.Server,
+		"appBasePath": cg.AppBasePath,
+		"body":        cg.Body,
+	}
+}
@@ -0,0 +1,22 @@
+package openrasp
+
+import (
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+func NewPolicyLog(policyResult *model.PolicyResult, policyParams interface{}) *model.PolicyLog {
+	policyLog := &model.PolicyLog{
+		PolicyResult: policyResult,
+		Server:       GetGlobals().Server,
+		System:       GetGlobals().System,
+		PolicyParams: GetMasker().MaskParams(policyParams),
+		SourceCode:   []string{},
+		RaspId:       GetGlobals().RaspId,
+		AppId:        GetBasic().GetString("cloud.app_id"),
+		EventTime:    utils.CurrentISO8601Time(),
+		CustomFields: currentCustomFields(currentRequestInfo()),
+	}
+	policyLog.SetStackResolver(stackResolver(collectStack(), false))
+	return policyLog
+}
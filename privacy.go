@@ -0,0 +1,119 @@
+package openrasp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// privacyRules decide how request bodies, query strings and client ips are
+// written to attack logs in privacy mode: kept, truncated to
+// log.privacy.truncate_length, replaced by a keyed hash, which still lets
+// identical payloads be correlated, or dropped. Truncating an ip zeroes its
+// host part.
+type privacyRules struct {
+	enable         bool
+	body           string
+	query          string
+	clientIp       string
+	truncateLength int
+	salt           []byte
+	ipHeaders      map[string]bool
+}
+
+func newPrivacyRules() privacyRules {
+	return privacyRules{
+		enable:         GetGeneral().GetBool("log.privacy.enable"),
+		body:           GetGeneral().GetString("log.privacy.body"),
+		query:          GetGeneral().GetString("log.privacy.query"),
+		clientIp:       GetGeneral().GetString("log.privacy.client_ip"),
+		truncateLength: GetGeneral().GetInt("log.privacy.truncate_length"),
+		salt:           []byte(GetGeneral().GetString("log.privacy.salt")),
+		ipHeaders: map[string]bool{
+			"x-forwarded-for": true,
+			"x-real-ip":       true,
+			strings.ToLower(GetGeneral().GetString("clientip.header")): true,
+		},
+	}
+}
+
+func (pr *privacyRules) hash(value string) string {
+	mac := hmac.New(sha256.New, pr.salt)
+	mac.Write([]byte(value))
+	return "sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (pr *privacyRules) value(rule, value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	switch rule {
+	case "truncate":
+		return utils.TruncateString(value, pr.truncateLength)
+	case "hash":
+		return pr.hash(value)
+	case "drop":
+		return ""
+	default:
+		return value
+	}
+}
+
+func (pr *privacyRules) ip(value string) string {
+	if len(value) == 0 || pr.clientIp != "truncate" {
+		return pr.value(pr.clientIp, value)
+	}
+	// X-Forwarded-For style headers list several addresses
+	ips := strings.Split(value, ",")
+	for i, ip := range ips {
+		ips[i] = utils.AnonymizeIP(strings.TrimSpace(ip))
+	}
+	return strings.Join(ips, ", ")
+}
+
+func (pr *privacyRules) address(value string) string {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return pr.ip(value)
+	}
+	return net.JoinHostPort(pr.ip(host), port)
+}
+
+// apply rewrites ri, whose body must not be shared with the request, the
+// header is copied
+func (pr *privacyRules) apply(ri *model.RequestInfo) {
+	if u, err := url.Parse(ri.UrlFull); err == nil && len(u.RawQuery) > 0 {
+		u.RawQuery = pr.value(pr.query, u.RawQuery)
+		ri.UrlFull = u.String()
+	}
+	ri.AttackSource = pr.address(ri.AttackSource)
+	ri.ClientIp = pr.ip(ri.ClientIp)
+	header := make(map[string]string, len(ri.Header))
+	for k, v := range ri.Header {
+		if pr.ipHeaders[strings.ToLower(k)] {
+			v = pr.ip(v)
+		}
+		header[k] = v
+	}
+	ri.Header = header
+	if ri.RequestBody != nil {
+		ri.RequestBody.Truncated = pr.value(pr.body, ri.RequestBody.Truncated)
+		if pr.body == "drop" {
+			ri.RequestBody.Form = nil
+		} else if ri.RequestBody.Form != nil && pr.body != "keep" {
+			form := make(url.Values, len(ri.RequestBody.Form))
+			for k, values := range ri.RequestBody.Form {
+				for _, v := range values {
+					form[k] = append(form[k], pr.value(pr.body, v))
+				}
+			}
+			ri.RequestBody.Form = form
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package openrasp
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/goid"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+type requestContextKey struct{}
+
+// inheritedGlsKeys are copied into the goroutines spawned by gls.Go, so their
+// checks are attributed to the request and can block it
+var inheritedGlsKeys = []interface{}{"requestInfo", "responseWriter", "whiteMask", "appId", "customFields", "verdictRequestKey", "challengePassed", "traceContext"}
+
+func init() {
+	gls.Inherit(inheritedGlsKeys...)
+}
+
+// requestGlsKeys are the gls keys EnterContext replaces, the ones derived
+// from the request are cleared so they are rebuilt for it
+var requestGlsKeys = []string{"requestInfo", "responseWriter", "whiteMask", "appId", "customFields", "verdictRequestKey", "challengePassed", "challenge", "dnsLookups", "traceContext"}
+
+// RequestContext is the request the checks read from gls, carried by a
+// context.Context for code which hands the request over to other goroutines
+type RequestContext struct {
+	RequestInfo  *model.RequestInfo
+	Blocker      Blocker
+	whiteMask    interface{}
+	appId        interface{}
+	customFields map[string]interface{}
+	passed       bool
+	goid         int64
+}
+
+// NewContext returns a copy of parent carrying the request and the blocker
+// interrupting it. The whitelist and application are taken from gls when it
+// holds the same request, they are looked up otherwise.
+func NewContext(parent context.Context, requestInfo *model.RequestInfo, blocker Blocker) context.Context {
+	rc := &RequestContext{
+		RequestInfo: requestInfo,
+		Blocker:     blocker,
+		goid:        goid.GoIDAsm(),
+	}
+	if gls.Activated() && gls.Get("requestInfo") == requestInfo {
+		rc.whiteMask = gls.Get("whiteMask")
+		rc.appId = gls.Get("appId")
+		rc.customFields, _ = gls.Get("customFields").(map[string]interface{})
+		rc.passed = challengePassed()
+	} else if requestInfo != nil && IsComplete() {
+		if u, err := url.Parse(requestInfo.UrlFull); err == nil {
+			rc.whiteMask = GetWhite().PrefixSearch(ExtractWhiteKey(u))
+			if binding := GetAppRouter().Match(u.Host, u.Path); binding != nil {
+				rc.appId = binding.AppId
+			}
+		}
+	}
+	return context.WithValue(parent, requestContextKey{}, rc)
+}
+
+// FromContext returns the request carried by ctx
+func FromContext(ctx context.Context) (*RequestContext, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	rc, ok := ctx.Value(requestContextKey{}).(*RequestContext)
+	return rc, ok && rc.RequestInfo != nil
+}
+
+// EnterContext makes the request carried by ctx current for the checks run by
+// this goroutine, ahead of the one in gls. It reports whether a request is
+// current either way and returns a function restoring gls, which must be
+// deferred. When gls holds that request already only the span of ctx is
+// taken, the state the request gathered in gls, like its dns answers, stays. The blocker is only called on the goroutine which created the
+// context, a panic elsewhere would bring down a goroutine the request does not
+// own, so BlockRequest does nothing there and hooks return ErrBlock instead.
+func EnterContext(ctx context.Context) (leave func(), ok bool) {
+	rc, found := FromContext(ctx)
+	if !found {
+		return func() {}, gls.Activated()
+	}
+	if gls.Activated() && gls.Get("requestInfo") == rc.RequestInfo {
+		previous := gls.Get("traceContext")
+		gls.Set("traceContext", ctx)
+		return func() {
+			gls.Set("traceContext", previous)
+		}, true
+	}
+	var blocker interface{}
+	if rc.Blocker != nil && rc.goid == goid.GoIDAsm() {
+		blocker = rc.Blocker
+	}
+	values := map[string]interface{}{
+		"requestInfo":     rc.RequestInfo,
+		"responseWriter":  blocker,
+		"whiteMask":       rc.whiteMask,
+		"appId":           rc.appId,
+		"customFields":    rc.customFields,
+		"challengePassed": rc.passed,
+		"traceContext":    ctx,
+	}
+	if !gls.Activated() {
+		gls.Initialize()
+		for _, key := range requestGlsKeys {
+			gls.Set(key, values[key])
+		}
+		return gls.Clear, true
+	}
+	previous := make(map[string]interface{}, len(requestGlsKeys))
+	for _, key := range requestGlsKeys {
+		previous[key] = gls.Get(key)
+		gls.Set(key, values[key])
+	}
+	return func() {
+		for _, key := range requestGlsKeys {
+			gls.Set(key, previous[key])
+		}
+	}, true
+}
@@ -0,0 +1,82 @@
+package openrasp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// Sampler picks the requests which go through full detection when
+// sampling.percent is below 100. Requests are picked in turn rather than at
+// random so that the share is exact at any rate, and every request of a
+// client flagged by an earlier attack is picked for sampling.flagged_ttl
+// seconds. Up to sampling.flagged_max_size clients are remembered.
+type Sampler struct {
+	// percent and counter come first to be 64-bit aligned for atomic access
+	percent uint64
+	counter uint64
+	ttl     time.Duration
+	size    int
+	flagged *utils.LRU
+	mu      sync.RWMutex
+}
+
+func NewSampler() *Sampler {
+	s := &Sampler{}
+	s.OnConfigUpdate()
+	return s
+}
+
+func (s *Sampler) OnConfigUpdate() {
+	size := GetGeneral().GetInt("sampling.flagged_max_size")
+	ttl := time.Duration(GetGeneral().GetInt64("sampling.flagged_ttl")) * time.Second
+	s.mu.Lock()
+	if s.flagged == nil || s.size != size {
+		s.size = size
+		s.flagged = utils.NewLRU(size)
+	}
+	s.ttl = ttl
+	s.mu.Unlock()
+	atomic.StoreUint64(&s.percent, uint64(GetGeneral().GetInt("sampling.percent")))
+}
+
+// Sampling reports whether some requests skip detection, client addresses
+// need not be computed otherwise
+func (s *Sampler) Sampling() bool {
+	return s != nil && atomic.LoadUint64(&s.percent) < 100
+}
+
+// Sample reports whether the request of client goes through detection
+func (s *Sampler) Sample(client string) bool {
+	if !s.Sampling() {
+		return true
+	}
+	if s.Flagged(client, time.Now()) {
+		return true
+	}
+	return atomic.AddUint64(&s.counter, 1)%100 < atomic.LoadUint64(&s.percent)
+}
+
+// Flag has every request of client detected for sampling.flagged_ttl seconds
+func (s *Sampler) Flag(client string) {
+	if s == nil || len(client) == 0 {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.flagged.Add(client, time.Now().Add(s.ttl))
+}
+
+// Flagged reports whether client was flagged and its flag is still valid at
+// now
+func (s *Sampler) Flagged(client string, now time.Time) bool {
+	if s == nil || len(client) == 0 {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	expiry, ok := s.flagged.Get(client)
+	return ok && now.Before(expiry.(time.Time))
+}
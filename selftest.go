@@ -0,0 +1,158 @@
+package openrasp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// selfTestParameter is the query parameter of the synthetic request which
+// carries the input of a SelfTestCase
+const selfTestParameter = "selftest"
+
+// SelfTestCase exercises the checks of a hook with the params of a benign
+// call and of a malicious one. The synthetic request of the test passes
+// Input as a query parameter, for the algorithms detecting user input.
+type SelfTestCase struct {
+	Hook      Hook
+	Name      string
+	Input     string
+	Benign    func() common.AttackChecker
+	Malicious func() common.AttackChecker
+}
+
+var (
+	selfTestsMu sync.RWMutex
+	selfTests   []SelfTestCase
+)
+
+// RegisterSelfTest adds c to the cases run by SelfTest, support packages
+// register theirs from an init function
+func RegisterSelfTest(c SelfTestCase) {
+	selfTestsMu.Lock()
+	defer selfTestsMu.Unlock()
+	selfTests = append(selfTests, c)
+}
+
+// SelfTestResult is the outcome of a SelfTestCase. Fired lists the
+// algorithms which detected the malicious call with their action, BenignFired
+// the ones which detected the benign call, i.e. false positives.
+type SelfTestResult struct {
+	Hook        string   `json:"hook"`
+	Name        string   `json:"name"`
+	CheckType   string   `json:"check_type"`
+	Enabled     bool     `json:"enabled"`
+	Called      bool     `json:"called"`
+	Fired       []string `json:"fired"`
+	BenignFired []string `json:"benign_fired,omitempty"`
+	Passed      bool     `json:"passed"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// SelfTestReport tells which hooks are active and whether their checks
+// detect an attack, Untested lists the hooks without a registered case,
+// whose support package is not imported by the application
+type SelfTestReport struct {
+	Passed   bool             `json:"passed"`
+	Results  []SelfTestResult `json:"results"`
+	Untested []string         `json:"untested"`
+}
+
+// SelfTest runs every registered case through the checks of its hook, the
+// plugins and the registered checkers, within a synthetic request so that
+// deployments can verify the protection after startup. The checks are
+// neither logged nor counted and block nothing. A case passes when its hook
+// is enabled, the malicious call is detected and the benign one is not.
+func SelfTest() *SelfTestReport {
+	selfTestsMu.RLock()
+	cases := selfTests
+	selfTestsMu.RUnlock()
+	report := &SelfTestReport{Passed: true, Results: []SelfTestResult{}, Untested: []string{}}
+	tested := make(map[Hook]bool)
+	for _, c := range cases {
+		tested[c.Hook] = true
+		result := runSelfTest(c)
+		report.Passed = report.Passed && result.Passed
+		report.Results = append(report.Results, result)
+	}
+	for _, hk := range hookKeys {
+		if !tested[hk.hook] {
+			report.Untested = append(report.Untested, hk.key)
+		}
+	}
+	return report
+}
+
+func runSelfTest(c SelfTestCase) SelfTestResult {
+	result := SelfTestResult{
+		Hook:    hookName(c.Hook),
+		Name:    c.Name,
+		Enabled: GetHookSwitch().HookEnabled(c.Hook),
+		Called:  hookCalled(c.Hook),
+		Fired:   []string{},
+	}
+	var err error
+	result.BenignFired, err = selfTestCheck(c.Input, c.Benign, &result.CheckType)
+	if err == nil {
+		var fired []string
+		if fired, err = selfTestCheck(c.Input, c.Malicious, &result.CheckType); len(fired) > 0 {
+			result.Fired = fired
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Passed = err == nil && result.Enabled && len(result.Fired) > 0 && len(result.BenignFired) == 0
+	return result
+}
+
+// selfTestCheck runs the checks of the params built by newParams on a
+// goroutine of its own, which holds the synthetic request, and returns the
+// algorithms which fired
+func selfTestCheck(input string, newParams func() common.AttackChecker, checkType *string) (fired []string, err error) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		gls.Initialize()
+		defer gls.Clear()
+		req := &http.Request{
+			Method: http.MethodGet,
+			URL:    &url.URL{Path: "/", RawQuery: url.Values{selfTestParameter: {input}}.Encode()},
+			Proto:  "HTTP/1.1",
+			Header: make(http.Header),
+		}
+		gls.Set("requestInfo", model.NewRequestInfo(req, "", 0))
+		ac := newParams()
+		*checkType = ac.GetTypeString()
+		if !GetHookSwitch().CheckEnabled(ac.GetType()) {
+			return
+		}
+		for _, attackResult := range append(ac.AttackCheck(), runCheckers(ac)...) {
+			GetAlgorithmConfig().Override(ac.GetTypeString(), attackResult)
+			if action := attackResult.GetInterceptState(); action != model.Ignore {
+				fired = append(fired, attackResult.PluginAlgorithm+" "+model.InterceptCodeToString(action))
+			}
+		}
+	}()
+	<-done
+	return fired, err
+}
+
+func hookName(hook Hook) string {
+	for _, hk := range hookKeys {
+		if hk.hook == hook {
+			return hk.key
+		}
+	}
+	return fmt.Sprintf("%#x", uint64(hook))
+}
@@ -0,0 +1,77 @@
+package openrasp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/goid"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/stacktrace"
+)
+
+// minStackDepth is the depth of the stack collected when log.maxstack is
+// shallower, so that application frames are left once the skipped ones are
+// dropped and the source of the application frame can be found
+const minStackDepth = 64
+
+// StackFilter applies log.stack.skip_prefixes to the stacks of the logs
+type StackFilter struct{}
+
+func NewStackFilter() *StackFilter {
+	sf := &StackFilter{}
+	sf.OnConfigUpdate()
+	return sf
+}
+
+func (sf *StackFilter) OnConfigUpdate() {
+	stacktrace.SetSkipPrefixes(GetGeneral().GetStringSlice("log.stack.skip_prefixes"))
+}
+
+// collectStack returns the program counters of the stack from the function
+// calling it, they are symbolized when the log is written
+func collectStack() []uintptr {
+	depth := GetGeneral().GetInt("log.maxstack")
+	if depth < minStackDepth {
+		depth = minStackDepth
+	}
+	return stacktrace.Callers(1, depth)
+}
+
+// stackResolver formats the stack of pcs for a log, with the source lines of
+// the application frame when sourceCode is set
+func stackResolver(pcs []uintptr, sourceCode bool) model.StackResolver {
+	goroutine := goroutineLine()
+	return func() (string, []string) {
+		frames := stacktrace.Frames(pcs)
+		stack := goroutine + "\n" + formatStack(frames)
+		if !sourceCode {
+			return stack, []string{}
+		}
+		return stack, appSourceCode(frames)
+	}
+}
+
+// goroutineLine heads a stack with the current goroutine and the ones which
+// spawned it through gls.Go, such as the goroutine of the request which
+// handed work over to a pool
+func goroutineLine() string {
+	line := "goroutine " + strconv.FormatInt(goid.GoIDAsm(), 10)
+	for i, parent := range gls.Parents() {
+		if i == 0 {
+			line += " spawned by " + strconv.FormatInt(parent, 10)
+		} else {
+			line += " <- " + strconv.FormatInt(parent, 10)
+		}
+	}
+	return line
+}
+
+// formatStack formats up to log.maxstack frames which are not skipped
+func formatStack(frames []stacktrace.Frame) string {
+	lines := stacktrace.LogFormat(frames)
+	if maxStack := GetGeneral().GetInt("log.maxstack"); len(lines) > maxStack {
+		lines = lines[:maxStack]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,138 @@
+package stacktrace
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxSourceSize bounds the files read for a snippet
+const maxSourceSize = 1 << 20
+
+// maxCachedSources bounds the files kept in memory, the cache is emptied
+// when it is full
+const maxCachedSources = 128
+
+var sources = struct {
+	sync.Mutex
+	m map[string][]string
+}{m: make(map[string][]string)}
+
+// AppFrame returns the first frame of frames which belongs neither to the
+// standard library nor to a package whose path starts with one of skipped
+func AppFrame(frames []Frame, skipped ...string) (Frame, bool) {
+	for _, frame := range frames {
+		if isStandard(frame.Function) {
+			continue
+		}
+		internal := false
+		for _, prefix := range skipped {
+			if strings.HasPrefix(frame.Function, prefix) {
+				internal = true
+				break
+			}
+		}
+		if !internal {
+			return frame, true
+		}
+	}
+	return Frame{}, false
+}
+
+// isStandard reports whether function is in the standard library, whose
+// package paths have no dot in their first element, except for main
+func isStandard(function string) bool {
+	first := function
+	if i := strings.IndexByte(first, '/'); i >= 0 {
+		first = first[:i]
+	} else if i := strings.IndexByte(first, '.'); i >= 0 {
+		first = first[:i]
+	}
+	return first != "main" && !strings.Contains(first, ".")
+}
+
+// SourceLines returns the lines of file from line-around to line+around,
+// each prefixed with its number. A file missing where it was built is looked
+// up under roots, with the leading directories of its path dropped one by one
+// until it is found, which also resolves the paths of a -trimpath build.
+func SourceLines(file string, line, around int, roots ...string) []string {
+	lines := readSource(file, roots)
+	if line <= 0 || line > len(lines) {
+		return nil
+	}
+	start, stop := line-around, line+around
+	if start < 1 {
+		start = 1
+	}
+	if stop > len(lines) {
+		stop = len(lines)
+	}
+	snippet := make([]string, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		snippet = append(snippet, strconv.Itoa(i)+": "+lines[i-1])
+	}
+	return snippet
+}
+
+func readSource(file string, roots []string) []string {
+	key := file + "|" + strings.Join(roots, "|")
+	sources.Lock()
+	lines, ok := sources.m[key]
+	sources.Unlock()
+	if ok {
+		return lines
+	}
+	for _, candidate := range sourceCandidates(file, roots) {
+		if lines, ok = readLines(candidate); ok {
+			break
+		}
+	}
+	sources.Lock()
+	defer sources.Unlock()
+	if len(sources.m) >= maxCachedSources {
+		sources.m = make(map[string][]string)
+	}
+	// a missing file is cached as well, it is not looked up for every attack
+	sources.m[key] = lines
+	return lines
+}
+
+func sourceCandidates(file string, roots []string) []string {
+	candidates := []string{}
+	if filepath.IsAbs(file) {
+		candidates = append(candidates, file)
+	}
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	for _, root := range roots {
+		if len(root) == 0 {
+			continue
+		}
+		for i := range parts {
+			if rest := filepath.Join(parts[i:]...); len(rest) > 0 {
+				candidates = append(candidates, filepath.Join(root, rest))
+			}
+		}
+	}
+	return candidates
+}
+
+func readLines(path string) ([]string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() || info.Size() > maxSourceSize {
+		return nil, false
+	}
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxSourceSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err() == nil
+}
@@ -0,0 +1,38 @@
+package stacktrace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppFrame(t *testing.T) {
+	frames := []Frame{
+		{Function: "github.com/baidu-security/openrasp-golang.AttackCheck"},
+		{Function: "database/sql.(*DB).Query"},
+		{Function: "runtime.goexit"},
+		{Function: "main.handler", Line: 12},
+	}
+	frame, ok := AppFrame(frames, "github.com/baidu-security/openrasp-golang")
+	assert.True(t, ok)
+	assert.Equal(t, "main.handler", frame.Function)
+
+	_, ok = AppFrame(frames[:3], "github.com/baidu-security/openrasp-golang")
+	assert.False(t, ok)
+}
+
+func TestSourceLines(t *testing.T) {
+	root, err := ioutil.TempDir("", "source")
+	assert.Nil(t, err)
+	defer os.RemoveAll(root)
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "app"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(root, "app", "main.go"), []byte("a\nb\nc\nd\ne\n"), 0644))
+
+	assert.Equal(t, []string{"2: b", "3: c", "4: d"}, SourceLines("/build/src/app/main.go", 3, 1, root))
+	assert.Equal(t, []string{"1: a", "2: b"}, SourceLines("example.com/app/main.go", 1, 1, root))
+	assert.Nil(t, SourceLines("/build/src/app/missing.go", 1, 1, root))
+	assert.Nil(t, SourceLines("/build/src/app/main.go", 9, 1, root))
+}
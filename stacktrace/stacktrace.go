@@ -3,6 +3,8 @@ package stacktrace
 import (
 	"runtime"
 	"strconv"
+	"strings"
+	"sync/atomic"
 )
 
 type Frame struct {
@@ -11,7 +13,42 @@ type Frame struct {
 	Function string
 }
 
+var skipPrefixes atomic.Value
+
+// SetSkipPrefixes sets the prefixes of the functions LogFormat leaves out,
+// such as runtime. or net/http.
+func SetSkipPrefixes(prefixes []string) {
+	skipPrefixes.Store(append([]string(nil), prefixes...))
+}
+
+// filterFrames drops the frames of the skipped functions, all the frames
+// are kept when none would be left
+func filterFrames(frames []Frame) []Frame {
+	prefixes, _ := skipPrefixes.Load().([]string)
+	if len(prefixes) == 0 {
+		return frames
+	}
+	kept := make([]Frame, 0, len(frames))
+	for _, frame := range frames {
+		skipped := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(frame.Function, prefix) {
+				skipped = true
+				break
+			}
+		}
+		if !skipped {
+			kept = append(kept, frame)
+		}
+	}
+	if len(kept) == 0 {
+		return frames
+	}
+	return kept
+}
+
 func LogFormat(frames []Frame) []string {
+	frames = filterFrames(frames)
 	formattedStacks := make([]string, len(frames))
 	for i, frame := range frames {
 		formattedStacks[i] = frame.File + "(" + frame.Function + ":" + strconv.Itoa(frame.Line) + ")"
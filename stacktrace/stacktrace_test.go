@@ -66,3 +66,19 @@ type panicker struct{}
 func (*panicker) panic() {
 	panic("oh noes")
 }
+
+func TestSkipPrefixes(t *testing.T) {
+	frames := []Frame{
+		{File: "/src/runtime/panic.go", Function: "runtime.gopanic", Line: 1},
+		{File: "/app/main.go", Function: "main.handler", Line: 2},
+		{File: "/src/net/http/server.go", Function: "net/http.HandlerFunc.ServeHTTP", Line: 3},
+	}
+	SetSkipPrefixes([]string{"runtime.", "net/http."})
+	defer SetSkipPrefixes(nil)
+	if diff := cmp.Diff(LogFormat(frames), []string{"/app/main.go(main.handler:2)"}); diff != "" {
+		t.Fatalf("%s", diff)
+	}
+	if len(LogFormat(frames[:1])) != 1 {
+		t.Fatalf("all the frames are skipped")
+	}
+}
@@ -0,0 +1,61 @@
+package stacktrace
+
+import (
+	"runtime"
+	"sync"
+)
+
+// maxCachedPCs bounds the program counters whose frames are kept, the
+// cache is emptied when it is full
+const maxCachedPCs = 1 << 16
+
+var symbols = struct {
+	sync.RWMutex
+	m map[uintptr][]Frame
+}{m: make(map[uintptr][]Frame)}
+
+// Callers returns up to n program counters of the stack starting skip
+// frames above the function calling it, they are symbolized by Frames only
+// when the stack is needed
+func Callers(skip, n int) []uintptr {
+	if n <= 0 {
+		return nil
+	}
+	pc := make([]uintptr, n)
+	return pc[:runtime.Callers(skip+2, pc)]
+}
+
+// Frames symbolizes the program counters returned by Callers, a program
+// counter yields several frames when calls were inlined into it. The frames
+// of each program counter are cached, stacks of a hook repeat the same ones.
+func Frames(pcs []uintptr) []Frame {
+	frames := make([]Frame, 0, len(pcs))
+	for _, pc := range pcs {
+		frames = append(frames, framesOf(pc)...)
+	}
+	return frames
+}
+
+func framesOf(pc uintptr) []Frame {
+	symbols.RLock()
+	frames, ok := symbols.m[pc]
+	symbols.RUnlock()
+	if ok {
+		return frames
+	}
+	runtimeFrames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		runtimeFrame, more := runtimeFrames.Next()
+		frames = append(frames, RuntimeFrame(runtimeFrame))
+		if !more {
+			break
+		}
+	}
+	symbols.Lock()
+	defer symbols.Unlock()
+	if len(symbols.m) >= maxCachedPCs {
+		symbols.m = make(map[uintptr][]Frame)
+	}
+	symbols.m[pc] = frames
+	return frames
+}
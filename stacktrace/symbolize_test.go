@@ -0,0 +1,19 @@
+package stacktrace
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	pcs := Callers(0, 3)
+	if len(pcs) == 0 {
+		t.Fatalf("no program counter")
+	}
+	for i := 0; i < 2; i++ {
+		frames := Frames(pcs)
+		if !strings.HasSuffix(frames[0].Function, "stacktrace.TestFrames") {
+			t.Fatalf("unexpected caller %s", frames[0].Function)
+		}
+	}
+}
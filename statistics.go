@@ -0,0 +1,270 @@
+package openrasp
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/cloud"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// Statistics aggregates the requests served, the attacks per check type and
+// the check latencies between two reports to the cloud console. It also
+// keeps the latency percentiles of every check type since the start and
+// holds each check type to its latency budget: once the 90th percentile of
+// latency.budget_window checks exceeds algorithm.config.<check
+// type>.latency_budget_micros, or latency.budget_micros, the check type is
+// downgraded to run off the request path and only log, see AsyncDetector.
+type Statistics struct {
+	requestSum   int64
+	requestTotal int64
+	mu           sync.Mutex
+	attackTotal  map[AttackKey]int64
+	attackSum    map[string]int64
+	blockSum     map[string]int64
+	checkTime    map[string]*cloud.CheckTime
+	latency      map[string]*checkLatency
+	budgetWindow int64
+}
+
+type checkLatency struct {
+	total  utils.Histogram
+	window utils.Histogram
+}
+
+// AttackKey identifies the attacks of a check type which ended in the same
+// action
+type AttackKey struct {
+	CheckType string
+	Action    string
+}
+
+// Snapshot are the counters of Statistics since the start of the process,
+// unlike the reports they are never reset
+type Snapshot struct {
+	Requests int64
+	Attacks  map[AttackKey]int64
+	Latency  map[string]utils.Histogram
+}
+
+// LatencyStats are the percentiles of the detection time of a check type
+// since the start of the process
+type LatencyStats struct {
+	Count      int64         `json:"count"`
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P99        time.Duration `json:"p99"`
+	Downgraded bool          `json:"downgraded"`
+}
+
+func NewStatistics() *Statistics {
+	s := &Statistics{
+		attackTotal: make(map[AttackKey]int64),
+		latency:     make(map[string]*checkLatency),
+	}
+	s.reset()
+	s.OnConfigUpdate()
+	return s
+}
+
+func (s *Statistics) OnConfigUpdate() {
+	window := GetGeneral().GetInt64("latency.budget_window")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budgetWindow = window
+}
+
+func (s *Statistics) reset() {
+	s.attackSum = make(map[string]int64)
+	s.blockSum = make(map[string]int64)
+	s.checkTime = make(map[string]*cloud.CheckTime)
+}
+
+// AddRequest counts a request served by a wrapped handler
+func (s *Statistics) AddRequest() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.requestSum, 1)
+	atomic.AddInt64(&s.requestTotal, 1)
+}
+
+// AddAttack counts an attack detected by a check of checkType, action is
+// the intercept state it ended in
+func (s *Statistics) AddAttack(checkType, action string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attackTotal[AttackKey{CheckType: checkType, Action: action}]++
+}
+
+// AddCheck records the latency of a check and the attacks it detected
+func (s *Statistics) AddCheck(checkType string, elapsed time.Duration, attacks int, blocked bool) {
+	if s == nil {
+		return
+	}
+	if p90, over := s.addCheck(checkType, elapsed, attacks, blocked); over {
+		if GetAsyncDetector().Downgrade(common.CheckStringToType(checkType)) {
+			GetLog().RaspWarn(fmt.Sprintf("The %s check exceeded its latency budget with a 90th percentile of %v, it only logs off the request path from now on", checkType, p90), orlog.Runtime)
+		}
+	}
+}
+
+// addCheck records a check and reports whether it filled up a budget window
+// whose 90th percentile, which it returns, is over the budget
+func (s *Statistics) addCheck(checkType string, elapsed time.Duration, attacks int, blocked bool) (time.Duration, bool) {
+	ms := durationMillis(elapsed)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ct, ok := s.checkTime[checkType]
+	if !ok {
+		ct = &cloud.CheckTime{Histogram: &utils.Histogram{}}
+		s.checkTime[checkType] = ct
+	}
+	ct.Histogram.Observe(elapsed)
+	ct.Count++
+	ct.Total += ms
+	if ms > ct.Max {
+		ct.Max = ms
+	}
+	if attacks > 0 {
+		s.attackSum[checkType] += int64(attacks)
+	}
+	if blocked {
+		s.blockSum[checkType]++
+	}
+	cl, ok := s.latency[checkType]
+	if !ok {
+		cl = &checkLatency{}
+		s.latency[checkType] = cl
+	}
+	cl.total.Observe(elapsed)
+	cl.window.Observe(elapsed)
+	if cl.window.Count() < s.budgetWindow {
+		return 0, false
+	}
+	p90 := cl.window.Quantile(0.9)
+	cl.window.Reset()
+	budget := AlgorithmInt(common.CheckStringToType(checkType), "latency_budget_micros", "latency.budget_micros")
+	return p90, budget > 0 && p90 > time.Duration(budget)*time.Microsecond
+}
+
+// Latency returns the latency percentiles of every check type which ran
+func (s *Statistics) Latency() map[string]LatencyStats {
+	latency := make(map[string]LatencyStats)
+	if s == nil {
+		return latency
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for checkType, cl := range s.latency {
+		latency[checkType] = LatencyStats{
+			Count:      cl.total.Count(),
+			P50:        cl.total.Quantile(0.5),
+			P90:        cl.total.Quantile(0.9),
+			P99:        cl.total.Quantile(0.99),
+			Downgraded: GetAsyncDetector().Downgraded(common.CheckStringToType(checkType)),
+		}
+	}
+	return latency
+}
+
+// Snapshot returns a copy of the counters since the start of the process
+func (s *Statistics) Snapshot() *Snapshot {
+	snapshot := &Snapshot{
+		Attacks: make(map[AttackKey]int64),
+		Latency: make(map[string]utils.Histogram),
+	}
+	if s == nil {
+		return snapshot
+	}
+	snapshot.Requests = atomic.LoadInt64(&s.requestTotal)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, count := range s.attackTotal {
+		snapshot.Attacks[key] = count
+	}
+	for checkType, cl := range s.latency {
+		snapshot.Latency[checkType] = cl.total
+	}
+	return snapshot
+}
+
+// drain returns the counters collected so far and starts over
+func (s *Statistics) drain() *cloud.ReportReq {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ct := range s.checkTime {
+		ct.P50 = durationMillis(ct.Histogram.Quantile(0.5))
+		ct.P90 = durationMillis(ct.Histogram.Quantile(0.9))
+		ct.P99 = durationMillis(ct.Histogram.Quantile(0.99))
+	}
+	report := &cloud.ReportReq{
+		RequestSum: atomic.SwapInt64(&s.requestSum, 0),
+		AttackSum:  s.attackSum,
+		BlockSum:   s.blockSum,
+		CheckTime:  s.checkTime,
+	}
+	s.reset()
+	return report
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// restore puts back a report which failed to upload so that it is sent with
+// the next one
+func (s *Statistics) restore(report *cloud.ReportReq) {
+	atomic.AddInt64(&s.requestSum, report.RequestSum)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range report.AttackSum {
+		s.attackSum[k] += v
+	}
+	for k, v := range report.BlockSum {
+		s.blockSum[k] += v
+	}
+	for k, v := range report.CheckTime {
+		ct, ok := s.checkTime[k]
+		if !ok {
+			s.checkTime[k] = v
+			continue
+		}
+		ct.Histogram.Merge(v.Histogram)
+		ct.Count += v.Count
+		ct.Total += v.Total
+		if v.Max > ct.Max {
+			ct.Max = v.Max
+		}
+	}
+}
+
+// StartReport uploads the statistics every interval until the process exits
+func (s *Statistics) StartReport(client *cloud.Client, interval time.Duration) {
+	if s == nil || client == nil || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.report(client)
+		}
+	}()
+}
+
+func (s *Statistics) report(client *cloud.Client) {
+	defer Recover()
+	report := s.drain()
+	if err := client.Report(report); err != nil {
+		s.restore(report)
+		GetLog().RaspWarn("Unable to upload statistics, cuz of "+err.Error(), orlog.Report)
+	}
+}
@@ -0,0 +1,153 @@
+package statsd
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPacketSize keeps a datagram under the MTU of most networks
+const maxPacketSize = 1432
+
+// Tags qualify a metric, e.g. the check type of an attack count
+type Tags map[string]string
+
+// Client sends metrics over UDP in the StatsD line protocol. The tags are
+// sent as DogStatsD tags when dogStatsD is set, they are appended to the name
+// of the metric otherwise since plain StatsD has no tags. Metrics are
+// buffered until Flush or until a packet is full, a lost packet is not
+// reported.
+type Client struct {
+	conn       net.Conn
+	prefix     string
+	sampleRate float64
+	dogStatsD  bool
+	buf        bytes.Buffer
+	mu         sync.Mutex
+	rand       *rand.Rand
+}
+
+// NewClient returns a client sending to addr, a host:port. The counts and
+// timings are sent at sampleRate between 0 and 1, 1 sends all of them.
+func NewClient(addr, prefix string, sampleRate float64, dogStatsD bool) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	return &Client{
+		conn:       conn,
+		prefix:     prefix,
+		sampleRate: sampleRate,
+		dogStatsD:  dogStatsD,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Count adds value to the counter name
+func (c *Client) Count(name string, value int64, tags Tags) {
+	c.send(name, strconv.FormatInt(value, 10), "c", tags, true)
+}
+
+// Gauge sets the gauge name to value
+func (c *Client) Gauge(name string, value float64, tags Tags) {
+	c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags, false)
+}
+
+// Timing records a duration of the timer name in milliseconds
+func (c *Client) Timing(name string, d time.Duration, tags Tags) {
+	ms := float64(d) / float64(time.Millisecond)
+	c.send(name, strconv.FormatFloat(ms, 'f', -1, 64), "ms", tags, true)
+}
+
+func (c *Client) send(name, value, metricType string, tags Tags, sampled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sampled && c.sampleRate < 1 && c.rand.Float64() >= c.sampleRate {
+		return
+	}
+	line := c.line(name, value, metricType, tags, sampled)
+	if c.buf.Len() > 0 && c.buf.Len()+1+len(line) > maxPacketSize {
+		c.flush()
+	}
+	if c.buf.Len() > 0 {
+		c.buf.WriteByte('\n')
+	}
+	c.buf.WriteString(line)
+}
+
+func (c *Client) line(name, value, metricType string, tags Tags, sampled bool) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	b.WriteString(c.prefix)
+	b.WriteString(name)
+	if !c.dogStatsD {
+		for _, k := range keys {
+			b.WriteByte('.')
+			b.WriteString(sanitize(tags[k]))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(metricType)
+	if sampled && c.sampleRate < 1 {
+		b.WriteString("|@")
+		b.WriteString(strconv.FormatFloat(c.sampleRate, 'f', -1, 64))
+	}
+	if c.dogStatsD && len(keys) > 0 {
+		b.WriteString("|#")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(sanitize(k))
+			b.WriteByte(':')
+			b.WriteString(sanitize(tags[k]))
+		}
+	}
+	return b.String()
+}
+
+// sanitize replaces the separators of the line protocol in a name or a tag
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// Flush sends the buffered metrics
+func (c *Client) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flush()
+}
+
+func (c *Client) flush() {
+	if c.buf.Len() == 0 {
+		return
+	}
+	c.conn.Write(c.buf.Bytes())
+	c.buf.Reset()
+}
+
+// Close flushes the buffered metrics and closes the connection
+func (c *Client) Close() error {
+	c.Flush()
+	return c.conn.Close()
+}
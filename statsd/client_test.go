@@ -0,0 +1,90 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func listen(t *testing.T) net.PacketConn {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	return pc
+}
+
+func readPacket(t *testing.T, pc net.PacketConn) string {
+	buf := make([]byte, 2*maxPacketSize)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	return string(buf[:n])
+}
+
+func TestDogStatsD(t *testing.T) {
+	pc := listen(t)
+	defer pc.Close()
+	c, err := NewClient(pc.LocalAddr().String(), "openrasp.", 1, true)
+	assert.Nil(t, err)
+	defer c.Close()
+	c.Count("attacks", 3, Tags{"check_type": "sql", "action": "block"})
+	c.Gauge("log_queue_depth", 12, Tags{"destination": "splunk"})
+	c.Timing("check", 1500*time.Microsecond, nil)
+	c.Flush()
+	assert.Equal(t, "openrasp.attacks:3|c|#action:block,check_type:sql\n"+
+		"openrasp.log_queue_depth:12|g|#destination:splunk\n"+
+		"openrasp.check:1.5|ms", readPacket(t, pc))
+}
+
+func TestStatsD(t *testing.T) {
+	pc := listen(t)
+	defer pc.Close()
+	c, err := NewClient(pc.LocalAddr().String(), "", 1, false)
+	assert.Nil(t, err)
+	defer c.Close()
+	c.Count("attacks", 1, Tags{"check_type": "sql", "action": "log"})
+	c.Gauge("log_queue_depth", 0, Tags{"destination": "cloud:alarm"})
+	c.Flush()
+	assert.Equal(t, "attacks.log.sql:1|c\nlog_queue_depth.cloud_alarm:0|g", readPacket(t, pc))
+}
+
+func TestSampleRate(t *testing.T) {
+	pc := listen(t)
+	defer pc.Close()
+	c, err := NewClient(pc.LocalAddr().String(), "", 0.5, true)
+	assert.Nil(t, err)
+	defer c.Close()
+	for i := 0; i < 50; i++ {
+		c.Count("requests", 1, nil)
+	}
+	c.Gauge("depth", 1, nil)
+	c.Flush()
+	lines := strings.Split(readPacket(t, pc), "\n")
+	assert.True(t, len(lines) > 10 && len(lines) < 40, "%d lines", len(lines))
+	assert.Equal(t, "requests:1|c|@0.5", lines[0])
+	assert.Equal(t, "depth:1|g", lines[len(lines)-1])
+}
+
+func TestPacketSize(t *testing.T) {
+	pc := listen(t)
+	defer pc.Close()
+	c, err := NewClient(pc.LocalAddr().String(), "openrasp.", 1, true)
+	assert.Nil(t, err)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Count("requests_inspected", 1, nil)
+	}
+	c.Flush()
+	lines := 0
+	for lines < 100 {
+		packet := readPacket(t, pc)
+		if !assert.NotEmpty(t, packet) {
+			break
+		}
+		assert.True(t, len(packet) <= maxPacketSize)
+		lines += strings.Count(packet, "\n") + 1
+	}
+	assert.Equal(t, 100, lines)
+}
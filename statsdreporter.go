@@ -0,0 +1,132 @@
+package openrasp
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/statsd"
+)
+
+// StatsdReporter sends the metrics the prometheus collector exposes to a
+// StatsD or DogStatsD agent every statsd.interval seconds. Counters are sent
+// as the increase since the last report and the check latency as gauges of
+// its percentiles in milliseconds.
+type StatsdReporter struct {
+	settings statsdSettings
+	stop     chan struct{}
+	mu       sync.Mutex
+	// the counters of the previous report, a goroutine stopped by a config
+	// update may still be reporting when the next one starts
+	requests int64
+	attacks  map[AttackKey]int64
+	dropped  map[string]orlog.DropTotals
+	reportMu sync.Mutex
+}
+
+type statsdSettings struct {
+	enable     bool
+	addr       string
+	prefix     string
+	sampleRate float64
+	dogStatsD  bool
+	interval   time.Duration
+}
+
+func NewStatsdReporter() *StatsdReporter {
+	sr := &StatsdReporter{
+		attacks: make(map[AttackKey]int64),
+		dropped: make(map[string]orlog.DropTotals),
+	}
+	sr.OnConfigUpdate()
+	return sr
+}
+
+func (sr *StatsdReporter) OnConfigUpdate() {
+	settings := statsdSettings{
+		enable:     GetGeneral().GetBool("statsd.enable"),
+		addr:       net.JoinHostPort(GetGeneral().GetString("statsd.host"), strconv.Itoa(GetGeneral().GetInt("statsd.port"))),
+		prefix:     GetGeneral().GetString("statsd.prefix"),
+		sampleRate: GetGeneral().GetFloat64("statsd.sample_rate"),
+		dogStatsD:  GetGeneral().GetBool("statsd.dogstatsd"),
+		interval:   time.Duration(GetGeneral().GetInt64("statsd.interval")) * time.Second,
+	}
+	if settings.enable && IsOffline() {
+		GetLog().RaspWarn("statsd.enable is ignored in offline mode", orlog.Config)
+		settings.enable = false
+	}
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if settings == sr.settings {
+		return
+	}
+	sr.settings = settings
+	if sr.stop != nil {
+		close(sr.stop)
+		sr.stop = nil
+	}
+	if !settings.enable {
+		return
+	}
+	client, err := statsd.NewClient(settings.addr, settings.prefix, settings.sampleRate, settings.dogStatsD)
+	if err != nil {
+		GetLog().RaspWarn("Unable to init statsd client, cuz of "+err.Error(), orlog.Config)
+		return
+	}
+	sr.stop = make(chan struct{})
+	go sr.run(client, settings.interval, sr.stop)
+}
+
+func (sr *StatsdReporter) run(client *statsd.Client, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer client.Close()
+	for {
+		select {
+		case <-ticker.C:
+			sr.report(client)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// report sends the metrics to client, the counters as their increase since
+// the previous report
+func (sr *StatsdReporter) report(client *statsd.Client) {
+	defer Recover()
+	sr.reportMu.Lock()
+	defer sr.reportMu.Unlock()
+	snapshot := GetStatistics().Snapshot()
+	dropped := GetLog().Dropped()
+	client.Count("requests_inspected", snapshot.Requests-sr.requests, nil)
+	for key, count := range snapshot.Attacks {
+		if delta := count - sr.attacks[key]; delta > 0 {
+			client.Count("attacks", delta, statsd.Tags{"check_type": key.CheckType, "action": key.Action})
+		}
+	}
+	for channel, totals := range dropped {
+		previous := sr.dropped[channel]
+		if delta := int64(totals.RateLimited - previous.RateLimited); delta > 0 {
+			client.Count("log_dropped", delta, statsd.Tags{"channel": channel, "reason": "rate_limit"})
+		}
+		if delta := int64(totals.Overflowed - previous.Overflowed); delta > 0 {
+			client.Count("log_dropped", delta, statsd.Tags{"channel": channel, "reason": "queue_overflow"})
+		}
+	}
+	sr.requests = snapshot.Requests
+	sr.attacks = snapshot.Attacks
+	sr.dropped = dropped
+	for checkType, h := range snapshot.Latency {
+		tags := statsd.Tags{"check_type": checkType}
+		client.Gauge("check_duration.p50", durationMillis(h.Quantile(0.5)), tags)
+		client.Gauge("check_duration.p90", durationMillis(h.Quantile(0.9)), tags)
+		client.Gauge("check_duration.p99", durationMillis(h.Quantile(0.99)), tags)
+	}
+	for destination, depth := range GetLog().QueueDepth() {
+		client.Gauge("log_queue_depth", float64(depth), statsd.Tags{"destination": destination})
+	}
+	client.Flush()
+}
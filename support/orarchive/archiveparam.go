@@ -0,0 +1,94 @@
+package orarchive
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+var (
+	ErrZipSlip           = errors.New("archive entry escapes the destination directory")
+	ErrDecompressionBomb = errors.New("archive exceeds the entry count or decompressed size limit")
+)
+
+type ArchiveParam struct {
+	Archive     string `json:"archive"`
+	Destination string `json:"destination"`
+	Entry       string `json:"entry"`
+	Entries     int    `json:"entries"`
+	Size        int64  `json:"size"`
+	message     string
+	checkType   common.CheckType
+}
+
+func newArchiveParam(checkType common.CheckType, archive, destination, entry, message string) *ArchiveParam {
+	ap := &ArchiveParam{
+		Archive:     archive,
+		Destination: destination,
+		Entry:       entry,
+		message:     message,
+		checkType:   checkType,
+	}
+	return ap
+}
+
+func (ap *ArchiveParam) GetType() common.CheckType {
+	return ap.checkType
+}
+
+func (ap *ArchiveParam) GetTypeString() string {
+	return common.CheckTypeToString(ap.GetType())
+}
+
+func (ap *ArchiveParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(ap) {
+			return results
+		}
+	}
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("archive.action"))
+	ar := model.NewAttackResult(model.InterceptCodeToString(ic), ap.message, "go_builtin_plugin", ap.GetTypeString(), 100)
+	results = append(results, ar)
+	return results
+}
+
+// report logs the violation when extracting inside a request and returns the
+// error aborting the extraction
+func (ap *ArchiveParam) report() error {
+	if openrasp.HookActive(openrasp.HookArchive) && gls.Activated() {
+		if openrasp.AttackCheck(ap, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+	if ap.checkType == common.ZipSlip {
+		return ErrZipSlip
+	}
+	return ErrDecompressionBomb
+}
+
+// SafeJoin joins the entry name to dest, failing when the result is not
+// inside dest
+func SafeJoin(dest, name string) (string, bool) {
+	target := filepath.Join(dest, name)
+	rel, err := filepath.Rel(filepath.Clean(dest), target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return target, false
+	}
+	return target, true
+}
+
+// safeLink validates a link target relative to the entry at target
+func safeLink(dest, target, linkname string) bool {
+	if filepath.IsAbs(linkname) {
+		_, ok := SafeJoin(dest, linkname)
+		return ok && strings.HasPrefix(filepath.Clean(linkname), filepath.Clean(dest))
+	}
+	rel, err := filepath.Rel(dest, filepath.Join(filepath.Dir(target), linkname))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,59 @@
+package orarchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeJoin(t *testing.T) {
+	target, ok := SafeJoin("/tmp/dest", "a/b.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "/tmp/dest/a/b.txt", target)
+	_, ok = SafeJoin("/tmp/dest", "../../etc/cron.d/x")
+	assert.False(t, ok)
+	_, ok = SafeJoin("/tmp/dest", "a/../../dest2/x")
+	assert.False(t, ok)
+}
+
+func TestSafeLink(t *testing.T) {
+	assert.True(t, safeLink("/tmp/dest", "/tmp/dest/a/link", "../b.txt"))
+	assert.False(t, safeLink("/tmp/dest", "/tmp/dest/a/link", "../../../etc/passwd"))
+	assert.False(t, safeLink("/tmp/dest", "/tmp/dest/link", "/etc/passwd"))
+}
+
+func TestExtractZipSlip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	f, err := w.Create("../evil.txt")
+	assert.Nil(t, err)
+	f.Write([]byte("evil"))
+	assert.Nil(t, w.Close())
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(t, err)
+	dest, err := ioutil.TempDir("", "orarchive")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dest)
+	assert.Equal(t, ErrZipSlip, ExtractZip(r, dest))
+}
+
+func TestExtractTar(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := tar.NewWriter(buf)
+	assert.Nil(t, w.WriteHeader(&tar.Header{Name: "a/b.txt", Mode: 0644, Size: 5, Typeflag: tar.TypeReg}))
+	w.Write([]byte("hello"))
+	assert.Nil(t, w.Close())
+	dest, err := ioutil.TempDir("", "orarchive")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dest)
+	assert.Nil(t, ExtractTar(tar.NewReader(buf), dest))
+	content, err := ioutil.ReadFile(filepath.Join(dest, "a", "b.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(content))
+}
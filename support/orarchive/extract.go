@@ -0,0 +1,196 @@
+package orarchive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+)
+
+type limits struct {
+	archive    string
+	dest       string
+	maxEntries int
+	maxSize    int64
+	entries    int
+	size       int64
+}
+
+func newLimits(archive, dest string) *limits {
+	l := &limits{
+		archive: archive,
+		dest:    dest,
+	}
+	if openrasp.HookActive(openrasp.HookArchive) {
+		l.maxEntries = openrasp.AlgorithmInt(common.DecompressionBomb, "max_entries", "archive.max_entries")
+		l.maxSize = openrasp.GetGeneral().GetInt64("archive.max_size")
+	}
+	return l
+}
+
+func (l *limits) bomb(entry string) error {
+	ap := newArchiveParam(common.DecompressionBomb, l.archive, l.dest, entry,
+		"Decompression bomb - "+l.archive+" archive expands to "+strconv.Itoa(l.entries)+" entries and "+strconv.FormatInt(l.size, 10)+" bytes")
+	ap.Entries = l.entries
+	ap.Size = l.size
+	return ap.report()
+}
+
+func (l *limits) addEntry(entry string) error {
+	l.entries++
+	if l.maxEntries > 0 && l.entries > l.maxEntries {
+		return l.bomb(entry)
+	}
+	return nil
+}
+
+// copy writes at most the remaining size budget to w
+func (l *limits) copy(entry string, w io.Writer, r io.Reader) error {
+	if l.maxSize <= 0 {
+		n, err := io.Copy(w, r)
+		l.size += n
+		return err
+	}
+	n, err := io.Copy(w, io.LimitReader(r, l.maxSize-l.size+1))
+	l.size += n
+	if err != nil {
+		return err
+	}
+	if l.size > l.maxSize {
+		return l.bomb(entry)
+	}
+	return nil
+}
+
+func (l *limits) target(name string) (string, error) {
+	target, ok := SafeJoin(l.dest, name)
+	if !ok {
+		ap := newArchiveParam(common.ZipSlip, l.archive, l.dest, name, "Zip slip - "+l.archive+" entry "+name+" escapes destination "+l.dest)
+		return "", ap.report()
+	}
+	return target, nil
+}
+
+func (l *limits) link(name, target, linkname string) error {
+	if !safeLink(l.dest, target, linkname) {
+		ap := newArchiveParam(common.ZipSlip, l.archive, l.dest, name, "Zip slip - "+l.archive+" link "+name+" points to "+linkname+" outside destination "+l.dest)
+		return ap.report()
+	}
+	return nil
+}
+
+func writeFile(l *limits, name, target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return l.copy(name, f, r)
+}
+
+// ExtractZip extracts r into dest, refusing entries escaping dest and
+// archives exceeding archive.max_entries or archive.max_size
+func ExtractZip(r *zip.Reader, dest string) error {
+	l := newLimits("zip", dest)
+	for _, f := range r.File {
+		if err := l.addEntry(f.Name); err != nil {
+			return err
+		}
+		target, err := l.target(f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if l.maxSize > 0 && l.size+int64(f.UncompressedSize64) > l.maxSize {
+			l.size += int64(f.UncompressedSize64)
+			return l.bomb(f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			linkname, err := ioutil.ReadAll(io.LimitReader(rc, 4096))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := l.link(f.Name, target, string(linkname)); err != nil {
+				return err
+			}
+			if err := os.Symlink(string(linkname), target); err != nil {
+				return err
+			}
+			continue
+		}
+		err = writeFile(l, f.Name, target, rc, f.Mode())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtractTar extracts r into dest with the same checks as ExtractZip, link
+// entries must point inside dest as well
+func ExtractTar(r *tar.Reader, dest string) error {
+	l := newLimits("tar", dest)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := l.addEntry(header.Name); err != nil {
+			return err
+		}
+		target, err := l.target(header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := l.link(header.Name, target, header.Linkname); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			linkTarget, err := l.target(header.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := os.Link(linkTarget, target); err != nil {
+				return err
+			}
+		// '\x00' marks a regular file in old archives, older readers
+		// hand it through as it is
+		case tar.TypeReg, '\x00':
+			if err := writeFile(l, header.Name, target, r, header.FileInfo().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+}
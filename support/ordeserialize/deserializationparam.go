@@ -0,0 +1,222 @@
+package ordeserialize
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+type DeserializationParam struct {
+	Format    string `json:"type"`
+	Target    string `json:"target"`
+	Size      int    `json:"size"`
+	Depth     int    `json:"depth"`
+	UserInput bool   `json:"user_input"`
+	generic   bool
+	decoded   bool
+}
+
+// NewDeserializationParam describes data about to be decoded into v, a nil v
+// is left for the decoder to reject
+func NewDeserializationParam(format string, data []byte, v interface{}) *DeserializationParam {
+	target := "<nil>"
+	if t := reflect.TypeOf(v); t != nil {
+		target = t.String()
+	}
+	dp := &DeserializationParam{
+		Format:    format,
+		Target:    target,
+		Size:      len(data),
+		UserInput: fromUserInput(data),
+		generic:   isGenericTarget(reflect.TypeOf(v)),
+	}
+	return dp
+}
+
+func (dp *DeserializationParam) GetType() common.CheckType {
+	return common.Deserialization
+}
+
+func (dp *DeserializationParam) GetTypeString() string {
+	return common.CheckTypeToString(dp.GetType())
+}
+
+func (dp *DeserializationParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("deserialization.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", dp.GetTypeString(), confidence)
+}
+
+func (dp *DeserializationParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(dp) {
+			return results
+		}
+	}
+	if !dp.UserInput {
+		return results
+	}
+	if dp.decoded {
+		if maxDepth := openrasp.AlgorithmInt(common.Deserialization, "max_depth", "deserialization.max_depth"); maxDepth > 0 && dp.Depth > maxDepth {
+			results = append(results, dp.newAttackResult("Deserialization - "+dp.Format+" payload nested "+strconv.Itoa(dp.Depth)+" levels deep exceeds the limit", 90))
+		}
+		return results
+	}
+	if dp.generic {
+		results = append(results, dp.newAttackResult("Deserialization - decoding user input with "+dp.Format+" into generic target "+dp.Target, 80))
+	}
+	if maxBytes := openrasp.AlgorithmInt(common.Deserialization, "max_bytes", "deserialization.max_bytes"); maxBytes > 0 && dp.Size > maxBytes {
+		results = append(results, dp.newAttackResult("Deserialization - "+dp.Format+" payload of "+strconv.Itoa(dp.Size)+" bytes exceeds the limit", 70))
+	}
+	return results
+}
+
+// Check inspects the target and size of the payload before decoding
+func Check(dp *DeserializationParam) {
+	if openrasp.HookActive(openrasp.HookDeserialization) && gls.Activated() {
+		if openrasp.AttackCheck(dp, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+// CheckDecoded inspects the nesting depth of the decoded value
+func CheckDecoded(dp *DeserializationParam, decoded interface{}) {
+	if openrasp.HookActive(openrasp.HookDeserialization) && gls.Activated() && dp.UserInput {
+		dp.decoded = true
+		dp.Depth = Depth(reflect.ValueOf(decoded))
+		if openrasp.AttackCheck(dp, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+func fromUserInput(data []byte) bool {
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok || len(data) == 0 {
+		return false
+	}
+	if requestInfo.RequestBody != nil && len(requestInfo.RequestBody.Raw) > 0 && bytes.Contains([]byte(requestInfo.RequestBody.Raw), data) {
+		return true
+	}
+	for _, value := range requestInfo.Parameters() {
+		if value == string(data) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGenericTarget(t reflect.Type) bool {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return t.Elem().Kind() == reflect.Interface
+	}
+	return false
+}
+
+// maxWalkDepth bounds the walk of Depth, a deeper or cyclic value is reported
+// this deep
+const maxWalkDepth = 1000
+
+// visit is a map, slice or pointer met by Depth
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// Depth returns the nesting depth of containers in v, up to maxWalkDepth.
+// A map, slice or pointer shared by several parents, like the aliases of a
+// yaml document, is walked once.
+func Depth(v reflect.Value) int {
+	return depth(v, maxWalkDepth, make(map[visit]int))
+}
+
+func depth(v reflect.Value, budget int, seen map[visit]int) int {
+	if budget <= 0 {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return depth(v.Elem(), budget, seen)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return 0
+		}
+		return walkOnce(v, budget, seen, func() int {
+			return depth(v.Elem(), budget, seen)
+		})
+	case reflect.Map:
+		return walkOnce(v, budget, seen, func() int {
+			max := 0
+			for _, key := range v.MapKeys() {
+				if d := depth(v.MapIndex(key), budget-1, seen); d > max {
+					max = d
+				}
+			}
+			return max + 1
+		})
+	case reflect.Slice:
+		return walkOnce(v, budget, seen, func() int {
+			return elemsDepth(v, budget, seen)
+		})
+	case reflect.Array:
+		return elemsDepth(v, budget, seen)
+	case reflect.Struct:
+		max := 0
+		for i := 0; i < v.NumField(); i++ {
+			if d := depth(v.Field(i), budget-1, seen); d > max {
+				max = d
+			}
+		}
+		return max + 1
+	}
+	return 0
+}
+
+func elemsDepth(v reflect.Value, budget int, seen map[visit]int) int {
+	max := 0
+	for i := 0; i < v.Len(); i++ {
+		if d := depth(v.Index(i), budget-1, seen); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
+
+// walkOnce returns the depth walk finds for v, or the one found before. A
+// value met again while it is walked is a cycle, which is as deep as the
+// budget left.
+func walkOnce(v reflect.Value, budget int, seen map[visit]int, walk func() int) int {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() != reflect.Ptr {
+		key.len = v.Len()
+	}
+	if d, ok := seen[key]; ok {
+		if d < 0 {
+			return budget
+		}
+		return d
+	}
+	seen[key] = -1
+	d := walk()
+	seen[key] = d
+	return d
+}
@@ -0,0 +1,65 @@
+package ordeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsGenericTarget(t *testing.T) {
+	var i interface{}
+	var m map[string]interface{}
+	var s struct{ Name string }
+	assert.True(t, isGenericTarget(reflect.TypeOf(&i)))
+	assert.True(t, isGenericTarget(reflect.TypeOf(&m)))
+	assert.False(t, isGenericTarget(reflect.TypeOf(&s)))
+}
+
+func TestDepth(t *testing.T) {
+	var v interface{} = map[string]interface{}{
+		"a": []interface{}{map[string]interface{}{"b": 1}},
+	}
+	assert.Equal(t, 3, Depth(reflect.ValueOf(v)))
+	assert.Equal(t, 0, Depth(reflect.ValueOf(1)))
+}
+
+type node struct {
+	Next *node
+}
+
+func TestDepthShared(t *testing.T) {
+	cyclic := &node{}
+	cyclic.Next = cyclic
+	assert.Equal(t, maxWalkDepth, Depth(reflect.ValueOf(cyclic)))
+	loop := map[string]interface{}{}
+	loop["self"] = loop
+	assert.Equal(t, maxWalkDepth, Depth(reflect.ValueOf(loop)))
+
+	// a billion laughs of aliases is walked once per level
+	var laughs interface{} = []interface{}{"lol"}
+	for i := 0; i < 40; i++ {
+		level := laughs
+		laughs = []interface{}{level, level, level, level, level, level, level, level, level, level}
+	}
+	assert.Equal(t, 41, Depth(reflect.ValueOf(laughs)))
+
+	var deep interface{} = 1
+	for i := 0; i < 2*maxWalkDepth; i++ {
+		deep = []interface{}{deep}
+	}
+	assert.Equal(t, maxWalkDepth, Depth(reflect.ValueOf(deep)))
+}
+
+func TestNilTarget(t *testing.T) {
+	dp := NewDeserializationParam("gob", []byte("data"), nil)
+	assert.Equal(t, "<nil>", dp.Target)
+	assert.True(t, dp.generic)
+	var data bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&data).Encode(1))
+	assert.NotPanics(t, func() {
+		assert.Nil(t, GobUnmarshal(data.Bytes(), nil))
+	})
+}
@@ -0,0 +1,17 @@
+package ordeserialize
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// GobUnmarshal decodes data into v with encoding/gob
+func GobUnmarshal(data []byte, v interface{}) error {
+	dp := NewDeserializationParam("gob", data, v)
+	Check(dp)
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+	if err == nil {
+		CheckDecoded(dp, v)
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package oryaml
+
+import (
+	"github.com/baidu-security/openrasp-golang/support/ordeserialize"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Unmarshal is the wrapped version of yaml.Unmarshal
+func Unmarshal(in []byte, out interface{}) error {
+	dp := ordeserialize.NewDeserializationParam("yaml", in, out)
+	ordeserialize.Check(dp)
+	err := yaml.Unmarshal(in, out)
+	if err == nil {
+		ordeserialize.CheckDecoded(dp, out)
+	}
+	return err
+}
+
+// UnmarshalStrict is the wrapped version of yaml.UnmarshalStrict
+func UnmarshalStrict(in []byte, out interface{}) error {
+	dp := ordeserialize.NewDeserializationParam("yaml", in, out)
+	ordeserialize.Check(dp)
+	err := yaml.UnmarshalStrict(in, out)
+	if err == nil {
+		ordeserialize.CheckDecoded(dp, out)
+	}
+	return err
+}
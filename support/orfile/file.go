@@ -0,0 +1,82 @@
+package orfile
+
+import (
+	"io/ioutil"
+	"os"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+func fileAttackCheck(checkType common.CheckType, name string) {
+	if openrasp.HookActive(openrasp.HookFile) && gls.Activated() {
+		fileParam := NewFileParam(checkType, name)
+		if openrasp.AttackCheck(fileParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+func checkTypeOfFlag(flag int) common.CheckType {
+	if flag&writeFlags != 0 {
+		return common.WriteFile
+	}
+	return common.ReadFile
+}
+
+// Open is the wrapped version of os.Open
+func Open(name string) (*os.File, error) {
+	return OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create is the wrapped version of os.Create
+func Create(name string) (*os.File, error) {
+	return OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// OpenFile is the wrapped version of os.OpenFile. The content written through
+// the returned file is not scanned for webshell signatures, only WriteFile
+// does, so a script file served from file.webroots opened for writing is
+// reported as a suspected webshell instead.
+func OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	checkType := checkTypeOfFlag(flag)
+	fileAttackCheck(checkType, name)
+	if checkType == common.WriteFile {
+		openedWebshellAttackCheck(name)
+	}
+	return os.OpenFile(name, flag, perm)
+}
+
+// ReadFile is the wrapped version of ioutil.ReadFile
+func ReadFile(filename string) ([]byte, error) {
+	fileAttackCheck(common.ReadFile, filename)
+	return ioutil.ReadFile(filename)
+}
+
+func webshellAttackCheck(name string, data []byte) {
+	if openrasp.HookActive(openrasp.HookFile) && gls.Activated() {
+		webshellParam := NewWebshellParam(name, data)
+		if openrasp.AttackCheck(webshellParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+func openedWebshellAttackCheck(name string) {
+	if openrasp.HookActive(openrasp.HookFile) && gls.Activated() {
+		if openrasp.AttackCheck(newOpenedWebshellParam(name), openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+// WriteFile is the wrapped version of ioutil.WriteFile, the written content is
+// scanned for webshell signatures as well
+func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	fileAttackCheck(common.WriteFile, filename)
+	webshellAttackCheck(filename, data)
+	return ioutil.WriteFile(filename, data, perm)
+}
@@ -0,0 +1,169 @@
+package orfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+var sensitiveFiles = []string{
+	"/etc/passwd",
+	"/etc/shadow",
+	"/etc/group",
+	"/etc/sudoers",
+	"/proc/self/environ",
+	"/proc/self/cmdline",
+}
+
+var sensitiveDirs = []string{
+	".git",
+	".svn",
+	".hg",
+	".ssh",
+}
+
+type FileParam struct {
+	Path      string `json:"path"`
+	RealPath  string `json:"realpath"`
+	checkType common.CheckType
+}
+
+// a path built from user input which climbs out of its directory is a
+// traversal
+func init() {
+	openrasp.RegisterSelfTest(openrasp.SelfTestCase{
+		Hook:  openrasp.HookFile,
+		Name:  "path traversal",
+		Input: "../../../../etc/passwd",
+		Benign: func() common.AttackChecker {
+			return NewFileParam(common.ReadFile, "static/index.html")
+		},
+		Malicious: func() common.AttackChecker {
+			return NewFileParam(common.ReadFile, "static/../../../../etc/passwd")
+		},
+	})
+}
+
+func NewFileParam(checkType common.CheckType, path string) *FileParam {
+	fp := &FileParam{
+		Path:      path,
+		RealPath:  realPath(path),
+		checkType: checkType,
+	}
+	return fp
+}
+
+func realPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved
+	}
+	return absPath
+}
+
+func (fp *FileParam) GetType() common.CheckType {
+	return fp.checkType
+}
+
+func (fp *FileParam) GetTypeString() string {
+	return common.CheckTypeToString(fp.GetType())
+}
+
+func (fp *FileParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("file.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", fp.GetTypeString(), confidence)
+}
+
+func (fp *FileParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(fp) {
+			return results
+		}
+	}
+	if fp.traversalFromUserInput() {
+		results = append(results, fp.newAttackResult("Path traversal - accessing file "+fp.RealPath+" with user input containing ../", 90))
+	}
+	if !fp.insideRoots(openrasp.GetGeneral().GetStringSlice("file.roots")) {
+		results = append(results, fp.newAttackResult("Access outside of allowed roots - "+fp.RealPath, 80))
+	}
+	if fp.checkType == common.ReadFile {
+		if pattern, ok := sensitive.Match(fp.RealPath); ok {
+			ar := model.NewAttackResult(model.InterceptCodeToString(model.Block), "Reading sensitive file "+fp.RealPath+" matched by pattern "+pattern, "go_builtin_plugin", fp.GetTypeString(), 100)
+			results = append(results, ar)
+		} else if isSensitiveFile(fp.RealPath) {
+			results = append(results, fp.newAttackResult("Reading sensitive file "+fp.RealPath, 100))
+		}
+	}
+	return results
+}
+
+func (fp *FileParam) traversalFromUserInput() bool {
+	if !hasTraversal(fp.Path) {
+		return false
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return false
+	}
+	for _, value := range requestInfo.Parameters() {
+		if hasTraversal(value) && strings.Contains(fp.Path, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fp *FileParam) insideRoots(roots []string) bool {
+	if len(roots) == 0 {
+		return true
+	}
+	return isInside(fp.RealPath, roots)
+}
+
+func isInside(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(realPath(dir), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTraversal(path string) bool {
+	for _, element := range strings.FieldsFunc(path, isPathSeparator) {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func isSensitiveFile(path string) bool {
+	for _, sensitive := range sensitiveFiles {
+		if path == sensitive {
+			return true
+		}
+	}
+	for _, element := range strings.FieldsFunc(path, isPathSeparator) {
+		for _, dir := range sensitiveDirs {
+			if element == dir {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package orfile
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/support/orhttptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasTraversal(t *testing.T) {
+	assert.True(t, hasTraversal("../../etc/passwd"))
+	assert.True(t, hasTraversal("static\\..\\web.config"))
+	assert.False(t, hasTraversal("/var/www/a..b/index.html"))
+}
+
+func TestIsSensitiveFile(t *testing.T) {
+	assert.True(t, isSensitiveFile("/etc/passwd"))
+	assert.True(t, isSensitiveFile("/var/www/.git/config"))
+	assert.False(t, isSensitiveFile("/var/www/index.html"))
+}
+
+func TestInsideRoots(t *testing.T) {
+	fp := &FileParam{RealPath: "/var/www/static/a.txt", checkType: common.ReadFile}
+	assert.True(t, fp.insideRoots(nil))
+	assert.True(t, fp.insideRoots([]string{"/var/www"}))
+	assert.False(t, fp.insideRoots([]string{"/srv"}))
+}
+
+func TestSensitiveMatcher(t *testing.T) {
+	sm := &sensitiveMatcher{globs: []string{"*.pem", "/etc/shadow*"}}
+	pattern, ok := sm.Match("/srv/app/certs/server.pem")
+	assert.True(t, ok)
+	assert.Equal(t, "*.pem", pattern)
+	_, ok = sm.Match("/etc/shadow-")
+	assert.True(t, ok)
+	_, ok = sm.Match("/srv/app/index.html")
+	assert.False(t, ok)
+}
+
+func TestMatchWebshell(t *testing.T) {
+	_, ok := matchWebshell(`<?php @eval($_POST['cmd']); ?>`)
+	assert.True(t, ok)
+	_, ok = matchWebshell(`<% Runtime.getRuntime().exec(request.getParameter("c")); %>`)
+	assert.True(t, ok)
+	_, ok = matchWebshell(`<html><body>hello</body></html>`)
+	assert.False(t, ok)
+}
+
+func TestOpenedWebshell(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orfile")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	create := func(name string) *orhttptest.Result {
+		return orhttptest.Serve(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			f, err := Create(filepath.Join(dir, name))
+			if err == nil {
+				f.Close()
+			}
+		}), httptest.NewRequest("POST", "/upload", nil))
+	}
+	orhttptest.AssertBlocked(t, create("shell.php"), "webshell_file")
+	orhttptest.AssertNotBlocked(t, create("avatar.png"))
+	read := orhttptest.Serve(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if f, err := Open(filepath.Join(dir, "avatar.png")); err == nil {
+			f.Close()
+		}
+	}), httptest.NewRequest("GET", "/avatar", nil))
+	orhttptest.AssertNotBlocked(t, read)
+}
+
+func TestSelfTest(t *testing.T) {
+	report := openrasp.SelfTest()
+	var result *openrasp.SelfTestResult
+	for i := range report.Results {
+		if report.Results[i].Hook == "file" {
+			result = &report.Results[i]
+		}
+	}
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "readFile", result.CheckType)
+		assert.True(t, result.Enabled)
+		assert.Empty(t, result.BenignFired)
+		assert.NotEmpty(t, result.Fired)
+		assert.True(t, result.Passed)
+	}
+	assert.NotContains(t, report.Untested, "file")
+	assert.Contains(t, report.Untested, "sql")
+
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"hook.file.enable": false}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"hook.file.enable": true}))
+	}()
+	report = openrasp.SelfTest()
+	assert.False(t, report.Passed)
+}
@@ -0,0 +1,64 @@
+package orfile
+
+import (
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+var sensitive = &sensitiveMatcher{}
+
+type sensitiveMatcher struct {
+	globs   []string
+	regexes []*regexp.Regexp
+	mu      sync.RWMutex
+}
+
+func init() {
+	if openrasp.IsComplete() {
+		sensitive.OnConfigUpdate()
+		openrasp.GetGeneral().AttachListener(sensitive)
+	}
+}
+
+func (sm *sensitiveMatcher) OnConfigUpdate() {
+	globs := openrasp.AlgorithmStringSlice(common.ReadFile, "sensitive_globs", "file.sensitive.globs")
+	var regexes []*regexp.Regexp
+	for _, pattern := range openrasp.AlgorithmStringSlice(common.ReadFile, "sensitive_regexes", "file.sensitive.regexes") {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			openrasp.GetLog().RaspWarn("Invalid file.sensitive.regexes pattern: "+pattern+", "+err.Error(), orlog.Config)
+			continue
+		}
+		regexes = append(regexes, r)
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.globs = globs
+	sm.regexes = regexes
+}
+
+// Match returns the first configured pattern matching the path
+func (sm *sensitiveMatcher) Match(path string) (string, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	base := filepath.Base(path)
+	for _, glob := range sm.globs {
+		if matched, _ := filepath.Match(glob, path); matched {
+			return glob, true
+		}
+		if matched, _ := filepath.Match(glob, base); matched {
+			return glob, true
+		}
+	}
+	for _, r := range sm.regexes {
+		if r.MatchString(path) {
+			return r.String(), true
+		}
+	}
+	return "", false
+}
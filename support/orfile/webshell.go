@@ -0,0 +1,114 @@
+package orfile
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+const webshellContentMaxBytes = 1024
+
+var scriptExts = []string{".php", ".phtml", ".jsp", ".jspx", ".asp", ".aspx", ".ashx", ".cer", ".asa"}
+
+var webshellSignatures = []*regexp.Regexp{
+	regexp.MustCompile(`(?is)<\?(php|=)?.*\b(eval|assert|system|exec|passthru|shell_exec|popen|proc_open|pcntl_exec|create_function)\s*\(`),
+	regexp.MustCompile(`(?is)<%.*(Runtime\.getRuntime\(\)\.exec|ProcessBuilder|defineClass)`),
+	regexp.MustCompile(`(?is)<%@\s*Page\s+Language\s*=\s*"?(C#|Jscript).*(Process\.Start|eval\s*\()`),
+	regexp.MustCompile(`(?i)eval\s*\(\s*Request(\.Item)?\s*[\[\(]`),
+	regexp.MustCompile(`(?i)(c99shell|r57shell|b374k|phpspy|WSO\s+\d|China\s*Chopper|weevely)`),
+}
+
+// WebshellParam is a file written by WriteFile with its content, or a script
+// file opened for writing by OpenFile, whose content is never scanned
+type WebshellParam struct {
+	Path     string `json:"path"`
+	RealPath string `json:"realpath"`
+	Content  string `json:"content"`
+	content  string
+	opened   bool
+}
+
+func NewWebshellParam(path string, content []byte) *WebshellParam {
+	wp := &WebshellParam{
+		Path:     path,
+		RealPath: realPath(path),
+		Content:  utils.TruncateString(string(content), webshellContentMaxBytes),
+		content:  string(content),
+	}
+	return wp
+}
+
+// newOpenedWebshellParam is the script file at path opened for writing
+func newOpenedWebshellParam(path string) *WebshellParam {
+	wp := &WebshellParam{
+		Path:     path,
+		RealPath: realPath(path),
+		opened:   true,
+	}
+	return wp
+}
+
+func (wp *WebshellParam) GetType() common.CheckType {
+	return common.WebshellFile
+}
+
+func (wp *WebshellParam) GetTypeString() string {
+	return common.CheckTypeToString(wp.GetType())
+}
+
+func (wp *WebshellParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(wp) {
+			return results
+		}
+	}
+	if !wp.webServed(openrasp.GetGeneral().GetStringSlice("file.webroots")) {
+		return results
+	}
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("file.webshell.action"))
+	if wp.opened {
+		if isScript(wp.RealPath) {
+			msg := "Webshell suspected - opening script file " + wp.RealPath + " for writing, its content is not scanned"
+			results = append(results, model.NewAttackResult(model.InterceptCodeToString(ic), msg, "go_builtin_plugin", wp.GetTypeString(), 60))
+		}
+		return results
+	}
+	if signature, ok := matchWebshell(wp.content); ok {
+		msg := "Webshell detected - writing " + wp.RealPath + " with content matching " + signature
+		results = append(results, model.NewAttackResult(model.InterceptCodeToString(ic), msg, "go_builtin_plugin", wp.GetTypeString(), 100))
+	}
+	return results
+}
+
+// webServed treats every script file as served when no web root is configured
+func (wp *WebshellParam) webServed(webroots []string) bool {
+	if len(webroots) > 0 {
+		return isInside(wp.RealPath, webroots)
+	}
+	return isScript(wp.RealPath)
+}
+
+func isScript(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, scriptExt := range scriptExts {
+		if ext == scriptExt {
+			return true
+		}
+	}
+	return false
+}
+
+func matchWebshell(content string) (string, bool) {
+	for _, signature := range webshellSignatures {
+		if signature.MatchString(content) {
+			return signature.String(), true
+		}
+	}
+	return "", false
+}
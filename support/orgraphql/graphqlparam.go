@@ -0,0 +1,115 @@
+package orgraphql
+
+import (
+	"regexp"
+	"strconv"
+	"sync"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// GraphqlParam describes a GraphQL operation about to be executed, Argument
+// and Value are the string which matched an injection pattern
+type GraphqlParam struct {
+	OperationName string `json:"operation_name"`
+	OperationType string `json:"operation_type"`
+	Depth         int    `json:"depth"`
+	Complexity    int    `json:"complexity"`
+	Argument      string `json:"argument,omitempty"`
+	Value         string `json:"value,omitempty"`
+	Pattern       string `json:"pattern,omitempty"`
+	arguments     []argument
+}
+
+func (gp *GraphqlParam) GetType() common.CheckType {
+	return common.Graphql
+}
+
+func (gp *GraphqlParam) GetTypeString() string {
+	return common.CheckTypeToString(gp.GetType())
+}
+
+func (gp *GraphqlParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("graphql.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", gp.GetTypeString(), confidence)
+}
+
+func (gp *GraphqlParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(gp) {
+			return results
+		}
+	}
+	if maxDepth := openrasp.AlgorithmInt(common.Graphql, "max_depth", "graphql.max_depth"); maxDepth > 0 && gp.Depth > maxDepth {
+		results = append(results, gp.newAttackResult("GraphQL abuse - operation "+gp.operation()+" nests "+strconv.Itoa(gp.Depth)+
+			" levels of fields, more than "+strconv.Itoa(maxDepth), 90))
+	}
+	if maxComplexity := openrasp.AlgorithmInt(common.Graphql, "max_complexity", "graphql.max_complexity"); maxComplexity > 0 && gp.Complexity > maxComplexity {
+		results = append(results, gp.newAttackResult("GraphQL abuse - operation "+gp.operation()+" selects "+strconv.Itoa(gp.Complexity)+
+			" fields, more than "+strconv.Itoa(maxComplexity), 90))
+	}
+	for _, arg := range gp.arguments {
+		if pattern, ok := payloads.Match(arg.value); ok {
+			gp.Argument, gp.Value, gp.Pattern = arg.path, arg.value, pattern
+			results = append(results, gp.newAttackResult("GraphQL injection - argument "+arg.path+" of operation "+gp.operation()+
+				" carries a payload matched by "+pattern, 90))
+			break
+		}
+	}
+	return results
+}
+
+// operation names the operation in messages, an anonymous one by its type
+func (gp *GraphqlParam) operation() string {
+	if len(gp.OperationName) > 0 {
+		return gp.OperationName
+	}
+	return "anonymous " + gp.OperationType
+}
+
+// payloads matches the strings passed to the operations against
+// graphql.injection_patterns
+var payloads = &payloadMatcher{}
+
+type payloadMatcher struct {
+	regexes []*regexp.Regexp
+	mu      sync.RWMutex
+}
+
+func init() {
+	if openrasp.IsComplete() {
+		payloads.OnConfigUpdate()
+		openrasp.GetGeneral().AttachListener(payloads)
+	}
+}
+
+func (pm *payloadMatcher) OnConfigUpdate() {
+	var regexes []*regexp.Regexp
+	for _, pattern := range openrasp.AlgorithmStringSlice(common.Graphql, "injection_patterns", "graphql.injection_patterns") {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			openrasp.GetLog().RaspWarn("Invalid graphql.injection_patterns pattern: "+pattern+", "+err.Error(), orlog.Config)
+			continue
+		}
+		regexes = append(regexes, r)
+	}
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.regexes = regexes
+}
+
+// Match returns the first configured pattern matching value
+func (pm *payloadMatcher) Match(value string) (string, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	for _, r := range pm.regexes {
+		if r.MatchString(value) {
+			return r.String(), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,171 @@
+package orgraphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+// Wrap returns an http.Handler checking the GraphQL operations of a request
+// before h executes them. h is the endpoint of a GraphQL server, such as the
+// handler.Server of gqlgen or the relay.Handler of graphql-go, and is served
+// behind orhttp.Wrap which holds the request for the checks:
+//
+//	http.Handle("/query", orhttp.Wrap(orgraphql.Wrap(srv)))
+//
+// The operations are read from the query string of a GET request and from
+// the JSON body, batches included, or the application/graphql body of a POST
+// request. A body larger than graphql.max_bytes is not inspected.
+func Wrap(h http.Handler) http.Handler {
+	if h == nil {
+		panic("h == nil")
+	}
+	return &handler{handler: h}
+}
+
+type handler struct {
+	handler http.Handler
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if openrasp.HookActive(openrasp.HookGraphql) && gls.Activated() {
+		for _, gp := range NewGraphqlParams(req) {
+			if openrasp.AttackCheck(gp, openrasp.WhitelistOption) {
+				openrasp.BlockRequest()
+				return
+			}
+		}
+	}
+	h.handler.ServeHTTP(w, req)
+}
+
+// graphqlRequest is the payload of a GraphQL request over HTTP
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewGraphqlParams describes the operations req is about to execute, the
+// body of req is left for the GraphQL server to read
+func NewGraphqlParams(req *http.Request) []*GraphqlParam {
+	var params []*GraphqlParam
+	for _, gr := range readGraphqlRequests(req) {
+		params = append(params, gr.params()...)
+	}
+	return params
+}
+
+func readGraphqlRequests(req *http.Request) []graphqlRequest {
+	switch req.Method {
+	case http.MethodGet:
+		query := req.URL.Query()
+		gr := graphqlRequest{Query: query.Get("query"), OperationName: query.Get("operationName")}
+		if variables := query.Get("variables"); len(variables) > 0 {
+			json.Unmarshal([]byte(variables), &gr.Variables)
+		}
+		return []graphqlRequest{gr}
+	case http.MethodPost:
+		body, ok := peekBody(req, openrasp.GetGeneral().GetInt("graphql.max_bytes"))
+		if !ok {
+			return nil
+		}
+		mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if mediaType == "application/graphql" {
+			return []graphqlRequest{{Query: string(body), OperationName: req.URL.Query().Get("operationName")}}
+		}
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '[' {
+			var batch []graphqlRequest
+			json.Unmarshal(body, &batch)
+			return batch
+		}
+		var gr graphqlRequest
+		if json.Unmarshal(body, &gr) != nil {
+			return nil
+		}
+		return []graphqlRequest{gr}
+	}
+	return nil
+}
+
+// peekBody reads up to maxBytes of the body of req and puts them back, it
+// reports false for an empty body or a larger one
+func peekBody(req *http.Request, maxBytes int) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, false
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, int64(maxBytes)+1))
+	req.Body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(body), req.Body),
+		Closer: req.Body,
+	}
+	return body, err == nil && len(body) > 0 && len(body) <= maxBytes
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// params describes the operation named by the request, every operation of
+// the document when it names none
+func (gr *graphqlRequest) params() []*GraphqlParam {
+	if len(gr.Query) == 0 {
+		return nil
+	}
+	variables := variableArguments("", gr.Variables)
+	doc, err := parse(gr.Query)
+	if err == errNesting {
+		return []*GraphqlParam{{OperationName: gr.OperationName, OperationType: "unknown", Depth: maxNesting, arguments: variables}}
+	}
+	if err != nil {
+		// the server rejects the document before resolving anything
+		return nil
+	}
+	a := newAnalysis(doc)
+	var params []*GraphqlParam
+	for _, op := range doc.operations {
+		if len(gr.OperationName) > 0 && op.name != gr.OperationName {
+			continue
+		}
+		params = append(params, &GraphqlParam{
+			OperationName: op.name,
+			OperationType: op.kind,
+			Depth:         a.depth(op.selections),
+			Complexity:    a.complexity(op.selections),
+			arguments:     append(a.arguments(op.selections), variables...),
+		})
+	}
+	return params
+}
+
+// variableArguments collects the strings of the variables, named like $id
+// or $filter.name
+func variableArguments(path string, value interface{}) []argument {
+	var args []argument
+	switch v := value.(type) {
+	case string:
+		args = append(args, argument{path: path, value: v})
+	case map[string]interface{}:
+		for key, item := range v {
+			if len(path) == 0 {
+				args = append(args, variableArguments("$"+key, item)...)
+			} else {
+				args = append(args, variableArguments(path+"."+key, item)...)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			args = append(args, variableArguments(path+"."+strconv.Itoa(i), item)...)
+		}
+	}
+	return args
+}
@@ -0,0 +1,98 @@
+package orgraphql
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/support/orhttptest"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoHandler stands for the GraphQL server, it keeps the body it read
+type echoHandler struct {
+	body string
+}
+
+func (eh *echoHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	eh.body = string(body)
+}
+
+func post(body string) *http.Request {
+	req := httptest.NewRequest("POST", "/query", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestBenignOperation(t *testing.T) {
+	eh := &echoHandler{}
+	body := `{"query":"query User($id: ID!) { user(id: $id) { name } }","operationName":"User","variables":{"id":"42"}}`
+	result := orhttptest.Serve(Wrap(eh), post(body))
+	orhttptest.AssertNotBlocked(t, result)
+	assert.Empty(t, result.Attacks)
+	assert.Equal(t, body, eh.body)
+
+	query := url.Values{"query": {`{ search(text: "rasp") { id } }`}}
+	result = orhttptest.Serve(Wrap(eh), httptest.NewRequest("GET", "/query?"+query.Encode(), nil))
+	orhttptest.AssertNotBlocked(t, result)
+}
+
+func TestDepthLimit(t *testing.T) {
+	query := "query Deep " + strings.Repeat("{ friends ", 20) + "{ id }" + strings.Repeat(" }", 20)
+	req := httptest.NewRequest("POST", "/query", strings.NewReader(query))
+	req.Header.Set("Content-Type", "application/graphql")
+	eh := &echoHandler{}
+	result := orhttptest.Serve(Wrap(eh), req)
+	if orhttptest.AssertBlocked(t, result, "graphql") {
+		gp := result.Attacks[0].AttackParams.(map[string]interface{})
+		assert.Equal(t, "Deep", gp["operation_name"])
+		assert.Equal(t, json.Number("21"), gp["depth"])
+		assert.Contains(t, result.Attacks[0].PluginMessage, "operation Deep nests 21 levels")
+	}
+	assert.Empty(t, eh.body)
+}
+
+func TestComplexityLimit(t *testing.T) {
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"graphql.max_complexity": 3}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"graphql.max_complexity": 1000}))
+	}()
+	result := orhttptest.Serve(Wrap(&echoHandler{}), post(`[{"query":"{ a }"},{"query":"query Wide { a b c d }"}]`))
+	if orhttptest.AssertBlocked(t, result, "graphql") {
+		assert.Equal(t, "Wide", result.Attacks[0].AttackParams.(map[string]interface{})["operation_name"])
+	}
+}
+
+func TestInjection(t *testing.T) {
+	body := `{"query":"mutation Login($user: String!) { login(user: $user, password: \"x\") { token } }","variables":{"user":"admin' or '1'='1"}}`
+	result := orhttptest.Serve(Wrap(&echoHandler{}), post(body))
+	if orhttptest.AssertBlocked(t, result, "graphql") {
+		gp := result.Attacks[0].AttackParams.(map[string]interface{})
+		assert.Equal(t, "Login", gp["operation_name"])
+		assert.Equal(t, "mutation", gp["operation_type"])
+		assert.Equal(t, "$user", gp["argument"])
+		assert.Equal(t, "admin' or '1'='1", gp["value"])
+	}
+
+	query := url.Values{"query": {`{ file(path: "../../etc/passwd") { content } }`}}
+	result = orhttptest.Serve(Wrap(&echoHandler{}), httptest.NewRequest("GET", "/query?"+query.Encode(), nil))
+	if orhttptest.AssertBlocked(t, result, "graphql") {
+		assert.Equal(t, "file.path", result.Attacks[0].AttackParams.(map[string]interface{})["argument"])
+	}
+}
+
+func TestHookDisabled(t *testing.T) {
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"hook.graphql.enable": false}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"hook.graphql.enable": true}))
+	}()
+	body := `{"query":"{ user(name: \"x' or 1=1 --\") { id } }"}`
+	result := orhttptest.Serve(Wrap(&echoHandler{}), post(body))
+	assert.Empty(t, result.Attacks)
+}
@@ -0,0 +1,623 @@
+package orgraphql
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxNesting bounds the nesting of selection sets and values the parser
+// follows, a deeper document is an abuse whatever the configured limits
+const maxNesting = 256
+
+// errNesting is returned for a document nested deeper than maxNesting
+var errNesting = errors.New("graphql document is nested too deep")
+
+// maxComplexity caps the complexity computed for a document, fragments
+// spread several times can make it grow exponentially
+const maxComplexity = 1 << 28
+
+// byteOrderMark is ignored like white space
+const byteOrderMark = "\ufeff"
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenNumber
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// lexer splits a GraphQL document into tokens, commas and comments are
+// insignificant and skipped
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{tokenPunct, "..."}, nil
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		l.pos++
+		return token{tokenPunct, string(c)}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		return token{tokenName, l.src[start:l.pos]}, nil
+	case c == '-' || isDigit(c):
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.src) && strings.IndexByte("0123456789.eE+-", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		return token{tokenNumber, l.src[start:l.pos]}, nil
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		return l.blockString()
+	case c == '"':
+		return l.string()
+	}
+	return token{}, fmt.Errorf("unexpected character %q at %d", c, l.pos)
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ', '\t', '\n', '\r', ',':
+			l.pos++
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], byteOrderMark) {
+				l.pos += len(byteOrderMark)
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	l.pos++
+	var b bytes.Buffer
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{tokenString, b.String()}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		case c == '\\' && l.pos+1 < len(l.src):
+			escaped := l.src[l.pos+1]
+			l.pos += 2
+			switch escaped {
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("invalid unicode escape at %d", l.pos)
+				}
+				r, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("invalid unicode escape at %d", l.pos)
+				}
+				b.WriteRune(rune(r))
+				l.pos += 4
+			default:
+				b.WriteByte(escaped)
+			}
+		default:
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			b.WriteString(l.src[l.pos : l.pos+size])
+			l.pos += size
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at %d", start)
+}
+
+// blockString reads a """ string, its indentation is kept
+func (l *lexer) blockString() (token, error) {
+	start := l.pos
+	l.pos += 3
+	var b bytes.Buffer
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			b.WriteString(`"""`)
+			l.pos += 4
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.pos += 3
+			return token{tokenString, b.String()}, nil
+		default:
+			b.WriteByte(l.src[l.pos])
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated block string at %d", start)
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// document keeps the parts of a GraphQL document the checks need
+type document struct {
+	operations []*operation
+	fragments  map[string][]*selection
+}
+
+type operation struct {
+	kind       string
+	name       string
+	selections []*selection
+}
+
+// selection is a field, a fragment spread when spread is set, or an inline
+// fragment otherwise
+type selection struct {
+	field      string
+	spread     string
+	arguments  []argument
+	selections []*selection
+}
+
+// argument is a string passed to a field, path names the field, the
+// argument and the keys of the input objects holding the string
+type argument struct {
+	path  string
+	value string
+}
+
+type parser struct {
+	lex     lexer
+	tok     token
+	nesting int
+}
+
+// parse reads a document made of operations and fragments
+func parse(src string) (*document, error) {
+	p := &parser{lex: lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: make(map[string][]*selection)}
+	for p.tok.kind != tokenEOF {
+		if err := p.definition(doc); err != nil {
+			return nil, err
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, errors.New("graphql document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() (err error) {
+	p.tok, err = p.lex.next()
+	return err
+}
+
+func (p *parser) is(kind tokenKind, value string) bool {
+	return p.tok.kind == kind && p.tok.value == value
+}
+
+func (p *parser) expect(kind tokenKind, value string) error {
+	if !p.is(kind, value) {
+		return fmt.Errorf("expected %q, found %q at %d", value, p.tok.value, p.lex.pos)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", fmt.Errorf("expected a name, found %q at %d", p.tok.value, p.lex.pos)
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *parser) definition(doc *document) error {
+	if p.is(tokenPunct, "{") {
+		selections, err := p.selectionSet("")
+		if err != nil {
+			return err
+		}
+		doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+		return nil
+	}
+	if p.is(tokenName, "fragment") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(tokenName, "on"); err != nil {
+			return err
+		}
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if err := p.directives(name); err != nil {
+			return err
+		}
+		selections, err := p.selectionSet(name)
+		if err != nil {
+			return err
+		}
+		doc.fragments[name] = selections
+		return nil
+	}
+	if p.tok.kind != tokenName || (p.tok.value != "query" && p.tok.value != "mutation" && p.tok.value != "subscription") {
+		return fmt.Errorf("unexpected %q at %d", p.tok.value, p.lex.pos)
+	}
+	op := &operation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	if p.is(tokenPunct, "(") {
+		if err := p.variableDefinitions(); err != nil {
+			return err
+		}
+	}
+	if err := p.directives(op.name); err != nil {
+		return err
+	}
+	selections, err := p.selectionSet("")
+	if err != nil {
+		return err
+	}
+	op.selections = selections
+	doc.operations = append(doc.operations, op)
+	return nil
+}
+
+// variableDefinitions skips ($name: Type = default ...), the default values
+// are constants of the document itself
+func (p *parser) variableDefinitions() error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	for !p.is(tokenPunct, ")") {
+		if p.tok.kind == tokenEOF {
+			return errors.New("unterminated variable definitions")
+		}
+		if p.is(tokenPunct, "=") {
+			if err := p.advance(); err != nil {
+				return err
+			}
+			if err := p.value("", nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	return p.advance()
+}
+
+func (p *parser) directives(path string) error {
+	for p.is(tokenPunct, "@") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if _, err := p.name(); err != nil {
+			return err
+		}
+		if p.is(tokenPunct, "(") {
+			if _, err := p.arguments(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) selectionSet(path string) ([]*selection, error) {
+	if p.nesting++; p.nesting > maxNesting {
+		return nil, errNesting
+	}
+	defer func() { p.nesting-- }()
+	if err := p.expect(tokenPunct, "{"); err != nil {
+		return nil, err
+	}
+	var selections []*selection
+	for !p.is(tokenPunct, "}") {
+		if p.tok.kind == tokenEOF {
+			return nil, errors.New("unterminated selection set")
+		}
+		s, err := p.selection(path)
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) selection(path string) (*selection, error) {
+	s := &selection{}
+	if p.is(tokenPunct, "...") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokenName && p.tok.value != "on" {
+			s.spread = p.tok.value
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			return s, p.directives(path)
+		}
+		if p.is(tokenName, "on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if _, err := p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.directives(path); err != nil {
+			return nil, err
+		}
+		var err error
+		s.selections, err = p.selectionSet(path)
+		return s, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if p.is(tokenPunct, ":") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	s.field = name
+	fieldPath := joinPath(path, name)
+	if p.is(tokenPunct, "(") {
+		if s.arguments, err = p.arguments(fieldPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.directives(fieldPath); err != nil {
+		return nil, err
+	}
+	if p.is(tokenPunct, "{") {
+		s.selections, err = p.selectionSet(fieldPath)
+	}
+	return s, err
+}
+
+func (p *parser) arguments(path string) ([]argument, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var args []argument
+	for !p.is(tokenPunct, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenPunct, ":"); err != nil {
+			return nil, err
+		}
+		if err := p.value(joinPath(path, name), &args); err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+// value reads a value and adds the strings it holds to args
+func (p *parser) value(path string, args *[]argument) error {
+	if p.nesting++; p.nesting > maxNesting {
+		return errNesting
+	}
+	defer func() { p.nesting-- }()
+	switch {
+	case p.tok.kind == tokenString:
+		if args != nil {
+			*args = append(*args, argument{path: path, value: p.tok.value})
+		}
+		return p.advance()
+	case p.tok.kind == tokenName || p.tok.kind == tokenNumber:
+		return p.advance()
+	case p.is(tokenPunct, "$"):
+		if err := p.advance(); err != nil {
+			return err
+		}
+		_, err := p.name()
+		return err
+	case p.is(tokenPunct, "["):
+		if err := p.advance(); err != nil {
+			return err
+		}
+		for !p.is(tokenPunct, "]") {
+			if p.tok.kind == tokenEOF {
+				return errors.New("unterminated list")
+			}
+			if err := p.value(path, args); err != nil {
+				return err
+			}
+		}
+		return p.advance()
+	case p.is(tokenPunct, "{"):
+		if err := p.advance(); err != nil {
+			return err
+		}
+		for !p.is(tokenPunct, "}") {
+			name, err := p.name()
+			if err != nil {
+				return err
+			}
+			if err := p.expect(tokenPunct, ":"); err != nil {
+				return err
+			}
+			if err := p.value(joinPath(path, name), args); err != nil {
+				return err
+			}
+		}
+		return p.advance()
+	}
+	return fmt.Errorf("unexpected %q at %d", p.tok.value, p.lex.pos)
+}
+
+func joinPath(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+// analysis measures the operations of a document, the fragments are
+// expanded where they are spread, once for each of their spreads
+type analysis struct {
+	doc        *document
+	depths     map[string]int
+	costs      map[string]int
+	inProgress map[string]bool
+}
+
+func newAnalysis(doc *document) *analysis {
+	return &analysis{
+		doc:        doc,
+		depths:     make(map[string]int),
+		costs:      make(map[string]int),
+		inProgress: make(map[string]bool),
+	}
+}
+
+// depth is the number of nested fields, a fragment spread inside itself
+// counts for nothing since servers reject such documents
+func (a *analysis) depth(selections []*selection) int {
+	max := 0
+	for _, s := range selections {
+		var d int
+		switch {
+		case len(s.spread) > 0:
+			d = a.fragmentDepth(s.spread)
+		case len(s.field) > 0:
+			d = 1 + a.depth(s.selections)
+		default:
+			d = a.depth(s.selections)
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+func (a *analysis) fragmentDepth(name string) int {
+	if d, ok := a.depths[name]; ok {
+		return d
+	}
+	if a.inProgress[name] {
+		return 0
+	}
+	a.inProgress[name] = true
+	d := a.depth(a.doc.fragments[name])
+	delete(a.inProgress, name)
+	a.depths[name] = d
+	return d
+}
+
+// complexity is the number of fields the operation resolves
+func (a *analysis) complexity(selections []*selection) int {
+	total := 0
+	for _, s := range selections {
+		switch {
+		case len(s.spread) > 0:
+			total += a.fragmentComplexity(s.spread)
+		case len(s.field) > 0:
+			total += 1 + a.complexity(s.selections)
+		default:
+			total += a.complexity(s.selections)
+		}
+		if total > maxComplexity {
+			return maxComplexity
+		}
+	}
+	return total
+}
+
+func (a *analysis) fragmentComplexity(name string) int {
+	if c, ok := a.costs[name]; ok {
+		return c
+	}
+	if a.inProgress[name] {
+		return 0
+	}
+	a.inProgress[name] = true
+	c := a.complexity(a.doc.fragments[name])
+	delete(a.inProgress, name)
+	a.costs[name] = c
+	return c
+}
+
+// arguments collects the strings passed to the fields of selections and of
+// the fragments they spread
+func (a *analysis) arguments(selections []*selection) []argument {
+	var args []argument
+	visited := make(map[string]bool)
+	var walk func([]*selection)
+	walk = func(selections []*selection) {
+		for _, s := range selections {
+			args = append(args, s.arguments...)
+			if len(s.spread) > 0 && !visited[s.spread] {
+				visited[s.spread] = true
+				walk(a.doc.fragments[s.spread])
+			}
+			walk(s.selections)
+		}
+	}
+	walk(selections)
+	return args
+}
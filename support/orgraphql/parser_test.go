@@ -0,0 +1,106 @@
+package orgraphql
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const usersQuery = `
+# the users of a team with their friends
+query Users($team: ID!, $first: Int = 10) {
+	team(id: $team) {
+		name
+		members(first: $first, filter: {role: "admin", tags: ["a", "b"]}) {
+			...user
+			... on Admin @include(if: true) {
+				permissions
+			}
+		}
+	}
+}
+
+fragment user on User {
+	id
+	name(format: "short!")
+	friends {
+		...friend
+	}
+}
+
+fragment friend on User {
+	id
+	nickname: name
+}
+`
+
+func TestParse(t *testing.T) {
+	doc, err := parse(usersQuery)
+	if !assert.Nil(t, err) {
+		return
+	}
+	if assert.Len(t, doc.operations, 1) {
+		op := doc.operations[0]
+		assert.Equal(t, "query", op.kind)
+		assert.Equal(t, "Users", op.name)
+		a := newAnalysis(doc)
+		// team > members > friends > id
+		assert.Equal(t, 4, a.depth(op.selections))
+		// team, name, members, id, name, friends, id, name, permissions
+		assert.Equal(t, 9, a.complexity(op.selections))
+		assert.Equal(t, []argument{
+			{"team.members.filter.role", "admin"},
+			{"team.members.filter.tags", "a"},
+			{"team.members.filter.tags", "b"},
+			{"user.name.format", "short!"},
+		}, a.arguments(op.selections))
+	}
+	assert.Len(t, doc.fragments, 2)
+}
+
+func TestParseShorthand(t *testing.T) {
+	doc, err := parse(`{ search(text: """multi "line" \""" text""") { id } }`)
+	if assert.Nil(t, err) && assert.Len(t, doc.operations, 1) {
+		assert.Equal(t, "query", doc.operations[0].kind)
+		assert.Equal(t, []argument{{"search.text", `multi "line" """ text`}}, newAnalysis(doc).arguments(doc.operations[0].selections))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"query {",
+		`{ user(name: "unterminated) { id } }`,
+		"{ user(id: 1 { id } }",
+		"fragment f on User { id }",
+		"subscribe { id }",
+	} {
+		_, err := parse(src)
+		assert.NotNil(t, err, src)
+	}
+	_, err := parse(strings.Repeat("{a", maxNesting+1) + strings.Repeat("}", maxNesting+1))
+	assert.Equal(t, errNesting, err)
+}
+
+func TestFragmentCycle(t *testing.T) {
+	doc, err := parse(`{ ...a } fragment a on Query { x { ...b } } fragment b on Query { y { ...a } }`)
+	if assert.Nil(t, err) {
+		a := newAnalysis(doc)
+		assert.Equal(t, 2, a.depth(doc.operations[0].selections))
+		assert.Equal(t, 2, a.complexity(doc.operations[0].selections))
+	}
+}
+
+func TestComplexityBomb(t *testing.T) {
+	src := "{ ...f0 }"
+	for i := 0; i < 40; i++ {
+		src += " fragment f" + strconv.Itoa(i) + " on Query { a: x { ...f" + strconv.Itoa(i+1) + " } b: x { ...f" + strconv.Itoa(i+1) + " } }"
+	}
+	src += " fragment f40 on Query { id }"
+	doc, err := parse(src)
+	if assert.Nil(t, err) {
+		assert.Equal(t, maxComplexity, newAnalysis(doc).complexity(doc.operations[0].selections))
+	}
+}
@@ -0,0 +1,93 @@
+package orgrpc
+
+import (
+	"net/url"
+
+	"github.com/baidu-security/openrasp-golang/utils"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxFieldDepth bounds the nesting of messages walked by MessageFields
+const maxFieldDepth = 32
+
+// MessageFields collects the string fields of msg keyed by their path, like
+// user.name, tags for a repeated field or labels.env for a map entry, until
+// maxBytes of values are collected. Bytes fields are left out, they seldom
+// carry what a user typed.
+func MessageFields(msg interface{}, maxBytes int) url.Values {
+	m := protoMessage(msg)
+	if m == nil || maxBytes <= 0 {
+		return nil
+	}
+	fc := &fieldCollector{fields: url.Values{}, remaining: maxBytes}
+	fc.message("", m.ProtoReflect(), 0)
+	return fc.fields
+}
+
+// protoMessage returns msg as a message of the current API, the messages
+// generated for github.com/golang/protobuf before 1.4 are adapted
+func protoMessage(msg interface{}) proto.Message {
+	switch m := msg.(type) {
+	case proto.Message:
+		return m
+	case protoadapt.MessageV1:
+		return protoadapt.MessageV2Of(m)
+	}
+	return nil
+}
+
+type fieldCollector struct {
+	fields    url.Values
+	remaining int
+}
+
+func (fc *fieldCollector) message(prefix string, m protoreflect.Message, depth int) {
+	if depth > maxFieldDepth {
+		return
+	}
+	// the fields are walked in the order they are declared rather than the
+	// one of Range, so the same ones are collected up to maxBytes every time
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len() && fc.remaining > 0; i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+		path := joinPath(prefix, string(fd.Name()))
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for j := 0; j < list.Len() && fc.remaining > 0; j++ {
+				fc.value(path, fd, list.Get(j), depth)
+			}
+		case fd.IsMap():
+			v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+				fc.value(joinPath(path, key.String()), fd.MapValue(), value, depth)
+				return fc.remaining > 0
+			})
+		default:
+			fc.value(path, fd, v, depth)
+		}
+	}
+}
+
+func (fc *fieldCollector) value(path string, fd protoreflect.FieldDescriptor, v protoreflect.Value, depth int) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := utils.TruncateString(v.String(), fc.remaining)
+		fc.fields.Add(path, s)
+		fc.remaining -= len(s)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		fc.message(path, v.Message(), depth+1)
+	}
+}
+
+func joinPath(prefix, name string) string {
+	if len(prefix) == 0 {
+		return name
+	}
+	return prefix + "." + name
+}
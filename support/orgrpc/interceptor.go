@@ -0,0 +1,213 @@
+package orgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/support/orhttp"
+	"github.com/baidu-security/openrasp-golang/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns an interceptor checking the calls of a gRPC
+// server the way orhttp.Wrap checks HTTP requests:
+//
+//	grpc.NewServer(
+//		grpc.UnaryInterceptor(orgrpc.UnaryServerInterceptor()),
+//		grpc.StreamInterceptor(orgrpc.StreamServerInterceptor()),
+//	)
+//
+// The string fields of the request message join the parameters of the
+// request, so a payload sent over gRPC is traced to user input like one in a
+// query string. A blocked call fails with codes.PermissionDenied.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		if !active(ctx) {
+			return handler(ctx, req)
+		}
+		requestInfo := newRequestInfo(ctx, info.FullMethod)
+		addFields(requestInfo, req, bodyMaxBytes())
+		ctx, leave := enter(ctx, requestInfo)
+		defer leave()
+		defer recoverBlock(requestInfo, &err)
+		requestCheck()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor checking the streams of a
+// gRPC server, every message received adds its fields to the parameters
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		if !active(ss.Context()) {
+			return handler(srv, ss)
+		}
+		requestInfo := newRequestInfo(ss.Context(), info.FullMethod)
+		ctx, leave := enter(ss.Context(), requestInfo)
+		defer leave()
+		defer recoverBlock(requestInfo, &err)
+		requestCheck()
+		return handler(srv, &serverStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			requestInfo:  requestInfo,
+			remaining:    bodyMaxBytes(),
+		})
+	}
+}
+
+// serverStream carries the request in its context and collects the fields
+// of the messages received
+type serverStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	requestInfo *model.RequestInfo
+	remaining   int
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.remaining -= addFields(s.requestInfo, m, s.remaining)
+	}
+	return err
+}
+
+// blocker interrupts a call, the interceptor turns the panic into an error
+type blocker struct{}
+
+func (blocker) BlockByOpenRASP() {
+	panic(openrasp.ErrBlock)
+}
+
+// active reports whether the call goes through detection, see Sampler
+func active(ctx context.Context) bool {
+	if !openrasp.HookActive(openrasp.HookGrpc) {
+		return false
+	}
+	sampler := openrasp.GetSampler()
+	if !sampler.Sampling() {
+		return true
+	}
+	ri := &model.RequestInfo{ClientIp: clientIp(ctx), RemoteAddr: remoteAddr(ctx)}
+	return sampler.Sample(ri.Client())
+}
+
+// enter makes requestInfo current for the checks run by the call and returns
+// the context of the call carrying it along with the function clearing gls
+func enter(ctx context.Context, requestInfo *model.RequestInfo) (context.Context, func()) {
+	gls.Initialize()
+	openrasp.GetStatistics().AddRequest()
+	openrasp.BindApp(requestInfo.UrlHost, requestInfo.UrlPath)
+	if u, err := url.Parse(requestInfo.UrlFull); err == nil {
+		gls.Set("whiteMask", openrasp.GetWhite().PrefixSearch(openrasp.ExtractWhiteKey(u)))
+	}
+	gls.Set("requestInfo", requestInfo)
+	gls.Set("responseWriter", blocker{})
+	ctx = openrasp.NewContext(ctx, requestInfo, blocker{})
+	gls.Set("traceContext", ctx)
+	return ctx, gls.Clear
+}
+
+// requestCheck lets the plugins check the call before it is handled
+func requestCheck() {
+	if openrasp.AttackCheck(orhttp.NewRequestParam(), openrasp.WhitelistOption) {
+		openrasp.BlockRequest()
+	}
+}
+
+// recoverBlock turns the panic of a blocked call into the error it returns
+func recoverBlock(requestInfo *model.RequestInfo, err *error) {
+	if v := recover(); v != nil {
+		if v != openrasp.ErrBlock {
+			panic(v)
+		}
+		*err = status.Error(codes.PermissionDenied, "Request blocked by OpenRASP, request_id: "+requestInfo.GetRequestId())
+	}
+}
+
+// newRequestInfo describes a call like a request, the method as its path
+// and the metadata as its header
+func newRequestInfo(ctx context.Context, fullMethod string) *model.RequestInfo {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(map[string]string, len(md))
+	for k, values := range md {
+		header[k] = strings.Join(values, ", ")
+	}
+	authority := header[":authority"]
+	u := &url.URL{Scheme: "grpc", Host: authority, Path: fullMethod}
+	ri := &model.RequestInfo{
+		Method:       "POST",
+		UrlFull:      u.String(),
+		UrlHost:      authority,
+		UrlPath:      fullMethod,
+		AttackSource: remoteAddr(ctx),
+		ClientIp:     clientIp(ctx),
+		Header:       header,
+		RequestId:    utils.GenerateRequestId(),
+		Protocol:     "HTTP/2.0",
+		Get:          map[string]string{},
+		RemoteAddr:   remoteAddr(ctx),
+		AppBasePath:  "",
+		GetBytes:     []byte("{}"),
+		RequestBody:  &model.RequestBody{Form: url.Values{}},
+	}
+	ri.HeaderBytes, _ = json.Marshal(ri.Header)
+	ri.TraceId, ri.SpanId, _ = model.ParseTraceparent(header[strings.ToLower(model.TraceparentHeader)])
+	return ri
+}
+
+// remoteAddr returns the address of the peer of the call
+func remoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// clientIp returns the metadata named by clientip.header
+func clientIp(ctx context.Context) string {
+	clientIpHeader := openrasp.GetGeneral().GetString("clientip.header")
+	if len(clientIpHeader) == 0 {
+		return ""
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, clientIpHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// bodyMaxBytes bounds the fields collected from a call like body.maxbytes
+// bounds the body of a request, the http.body hook turns them off
+func bodyMaxBytes() int {
+	if !openrasp.GetHookSwitch().HookEnabled(openrasp.HookHttpBody) {
+		return 0
+	}
+	return openrasp.GetGeneral().GetInt("body.maxbytes")
+}
+
+// addFields adds the fields of msg to the form of requestInfo and returns
+// the bytes they take
+func addFields(requestInfo *model.RequestInfo, msg interface{}, maxBytes int) int {
+	var n int
+	for key, values := range MessageFields(msg, maxBytes) {
+		for _, value := range values {
+			requestInfo.Form.Add(key, value)
+			n += len(value)
+		}
+	}
+	return n
+}
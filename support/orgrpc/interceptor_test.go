@@ -0,0 +1,189 @@
+package orgrpc
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/support/orfile"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// readRequest is built at runtime in place of a message generated by protoc:
+//
+//	message ReadRequest {
+//		string path = 1;
+//		repeated string tags = 2;
+//		map<string, string> labels = 3;
+//		Owner owner = 4;
+//		bytes checksum = 5;
+//		message Owner { string name = 1; }
+//	}
+var readRequest = newReadRequestDescriptor()
+
+func newReadRequestDescriptor() protoreflect.MessageDescriptor {
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, kind descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     kind.Enum(),
+		}
+		if len(typeName) > 0 {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	str, msg := descriptorpb.FieldDescriptorProto_TYPE_STRING, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("orgrpc_test.proto"),
+		Package: proto.String("orgrpc"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("ReadRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("path", 1, optional, str, ""),
+				field("tags", 2, repeated, str, ""),
+				field("labels", 3, repeated, msg, ".orgrpc.ReadRequest.LabelsEntry"),
+				field("owner", 4, optional, msg, ".orgrpc.ReadRequest.Owner"),
+				field("checksum", 5, optional, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{
+				{
+					Name:    proto.String("LabelsEntry"),
+					Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, optional, str, ""), field("value", 2, optional, str, "")},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				},
+				{
+					Name:  proto.String("Owner"),
+					Field: []*descriptorpb.FieldDescriptorProto{field("name", 1, optional, str, "")},
+				},
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		panic(err)
+	}
+	return fd.Messages().Get(0)
+}
+
+func newReadRequest(path string) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(readRequest)
+	fields := readRequest.Fields()
+	m.Set(fields.ByName("path"), protoreflect.ValueOfString(path))
+	tags := m.Mutable(fields.ByName("tags")).List()
+	tags.Append(protoreflect.ValueOfString("a"))
+	tags.Append(protoreflect.ValueOfString("b"))
+	labels := m.Mutable(fields.ByName("labels")).Map()
+	labels.Set(protoreflect.ValueOfString("env").MapKey(), protoreflect.ValueOfString("prod"))
+	owner := m.Mutable(fields.ByName("owner")).Message()
+	owner.Set(owner.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString("rasp"))
+	m.Set(fields.ByName("checksum"), protoreflect.ValueOfBytes([]byte("../../bytes")))
+	return m
+}
+
+func TestMessageFields(t *testing.T) {
+	fields := MessageFields(newReadRequest("report.txt"), 4096)
+	assert.Equal(t, "report.txt", fields.Get("path"))
+	assert.Equal(t, []string{"a", "b"}, fields["tags"])
+	assert.Equal(t, "prod", fields.Get("labels.env"))
+	assert.Equal(t, "rasp", fields.Get("owner.name"))
+	assert.NotContains(t, fields, "checksum")
+
+	fields = MessageFields(newReadRequest("report.txt"), 6)
+	assert.Equal(t, "report", fields.Get("path"))
+	assert.Len(t, fields, 1)
+
+	assert.Nil(t, MessageFields("not a message", 4096))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"file.action": "block"}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"file.action": "log"}))
+	}()
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/files.Files/Read"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(":authority", "files.internal:50051"))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		rc, ok := openrasp.FromContext(ctx)
+		if assert.True(t, ok) {
+			assert.Equal(t, "grpc://files.internal:50051/files.Files/Read", rc.RequestInfo.UrlFull)
+		}
+		path := req.(*dynamicpb.Message).Get(readRequest.Fields().ByName("path")).String()
+		f, err := orfile.Open("/tmp/" + path)
+		if err != nil {
+			return nil, err
+		}
+		f.Close()
+		return "read", nil
+	}
+
+	resp, err := interceptor(ctx, newReadRequest("orgrpc-missing.txt"), info, handler)
+	assert.Nil(t, resp)
+	assert.NotEqual(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err = interceptor(ctx, newReadRequest("../../../etc/passwd"), info, handler)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+// stream replays the messages to a stream handler
+type stream struct {
+	grpc.ServerStream
+	messages []*dynamicpb.Message
+}
+
+func (s *stream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *stream) RecvMsg(m interface{}) error {
+	if len(s.messages) == 0 {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.messages[0])
+	s.messages = s.messages[1:]
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	interceptor := StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/files.Files/ReadAll", IsClientStream: true}
+	var parameters []string
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		for ss.RecvMsg(dynamicpb.NewMessage(readRequest)) == nil {
+		}
+		rc, _ := openrasp.FromContext(ss.Context())
+		parameters = rc.RequestInfo.Parameters()
+		return nil
+	}
+	err := interceptor(nil, &stream{messages: []*dynamicpb.Message{newReadRequest("one.txt"), newReadRequest("two.txt")}}, info, handler)
+	assert.Nil(t, err)
+	assert.Contains(t, parameters, "one.txt")
+	assert.Contains(t, parameters, "two.txt")
+
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"hook.grpc.enable": false}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"hook.grpc.enable": true}))
+	}()
+	err = interceptor(nil, &stream{}, info, func(srv interface{}, ss grpc.ServerStream) error {
+		_, ok := openrasp.FromContext(ss.Context())
+		assert.False(t, ok)
+		return nil
+	})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,161 @@
+package orhttp
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// BruteForceParam is a client or a user failing to log in more often than
+// brute_force.max_failures_per_ip or brute_force.max_failures_per_user
+// within brute_force.window seconds, or a denied client coming back
+type BruteForceParam struct {
+	Client   string `json:"client"`
+	User     string `json:"user,omitempty"`
+	Scope    string `json:"scope"`
+	Failures int    `json:"failures,omitempty"`
+	Window   int    `json:"window,omitempty"`
+}
+
+func (bp *BruteForceParam) GetType() common.CheckType {
+	return common.BruteForce
+}
+
+func (bp *BruteForceParam) GetTypeString() string {
+	return common.CheckTypeToString(bp.GetType())
+}
+
+func (bp *BruteForceParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("brute_force.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", bp.GetTypeString(), confidence)
+}
+
+func (bp *BruteForceParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(bp) {
+			return results
+		}
+	}
+	switch bp.Scope {
+	case "ip":
+		results = append(results, bp.newAttackResult("Brute force login - client "+bp.Client+" failed "+strconv.Itoa(bp.Failures)+
+			" logins within "+strconv.Itoa(bp.Window)+" seconds", 90))
+	case "user":
+		results = append(results, bp.newAttackResult("Brute force login - user "+bp.User+" failed "+strconv.Itoa(bp.Failures)+
+			" logins within "+strconv.Itoa(bp.Window)+" seconds, the last one from client "+bp.Client, 80))
+	case "denied":
+		results = append(results, bp.newAttackResult("Brute force login - client "+bp.Client+" is denied after failing too many logins", 90))
+	}
+	return results
+}
+
+// clientFailures and userFailures count the failed logins reported by
+// ReportAuth in fixed windows of brute_force.window seconds
+var (
+	clientFailures = newFailureCounter(maxTrackedClients)
+	userFailures   = newFailureCounter(maxTrackedClients)
+)
+
+// deniedClients maps the clients blocked by ReportAuth to their denial, for
+// brute_force.deny_ttl seconds
+var deniedClients = utils.NewLRU(maxTrackedClients)
+
+// denial is the time a client is denied until, the first denied request is
+// reported and the others are blocked silently
+type denial struct {
+	until    time.Time
+	reported int32
+}
+
+// ReportAuth tells openrasp whether user logged in during the request of
+// ctx, the one of a handler wrapped by Wrap:
+//
+//	if !checkPassword(user, password) {
+//		orhttp.ReportAuth(req.Context(), user, false)
+//		http.Error(w, "invalid user or password", http.StatusUnauthorized)
+//		return
+//	}
+//	orhttp.ReportAuth(req.Context(), user, true)
+//
+// A client or user failing too often is reported as brute_force, and the
+// request is blocked when brute_force.action is block. The client is then
+// flagged so sampling detects all of its requests, and when the request is
+// blocked a positive brute_force.deny_ttl denies its next requests for that
+// many seconds, the first of them is reported once more.
+// Applications with a rate limiter of their own can feed it from the
+// brute_force attacks passed to openrasp.OnDetect.
+func ReportAuth(ctx context.Context, user string, success bool) {
+	if !openrasp.HookActive(openrasp.HookHttp) {
+		return
+	}
+	leave, ok := openrasp.EnterContext(ctx)
+	defer leave()
+	if !ok {
+		return
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return
+	}
+	if success {
+		userFailures.Reset(user)
+		return
+	}
+	client := requestInfo.Client()
+	now := time.Now()
+	windowSeconds := openrasp.AlgorithmInt(common.BruteForce, "window", "brute_force.window")
+	window := time.Duration(windowSeconds) * time.Second
+	var bruteForceParam *BruteForceParam
+	failures := clientFailures.Add(client, now, window)
+	if max := openrasp.AlgorithmInt(common.BruteForce, "max_failures_per_ip", "brute_force.max_failures_per_ip"); max > 0 && failures >= max {
+		bruteForceParam = &BruteForceParam{Client: client, User: user, Scope: "ip", Failures: failures, Window: windowSeconds}
+	}
+	if len(user) > 0 {
+		failures = userFailures.Add(user, now, window)
+		if max := openrasp.AlgorithmInt(common.BruteForce, "max_failures_per_user", "brute_force.max_failures_per_user"); bruteForceParam == nil && max > 0 && failures >= max {
+			bruteForceParam = &BruteForceParam{Client: client, User: user, Scope: "user", Failures: failures, Window: windowSeconds}
+		}
+	}
+	if bruteForceParam == nil {
+		return
+	}
+	openrasp.GetSampler().Flag(client)
+	if openrasp.AttackCheck(bruteForceParam, openrasp.WhitelistOption) {
+		if ttl := openrasp.GetGeneral().GetInt64("brute_force.deny_ttl"); ttl > 0 {
+			deniedClients.Add(client, &denial{until: now.Add(time.Duration(ttl) * time.Second)})
+		}
+		openrasp.BlockRequest()
+	}
+}
+
+// deniedCheck blocks a request of a client denied by ReportAuth unless the
+// whitelist exempts it, only the first one goes through AttackCheck and is
+// logged
+func deniedCheck(client string) {
+	value, ok := deniedClients.Get(client)
+	if !ok {
+		return
+	}
+	d := value.(*denial)
+	if !time.Now().Before(d.until) {
+		return
+	}
+	bruteForceParam := &BruteForceParam{Client: client, Scope: "denied"}
+	if !atomic.CompareAndSwapInt32(&d.reported, 0, 1) {
+		if !openrasp.WhitelistOption(bruteForceParam) {
+			openrasp.BlockRequest()
+		}
+		return
+	}
+	if openrasp.AttackCheck(bruteForceParam, openrasp.WhitelistOption) {
+		openrasp.BlockRequest()
+	}
+}
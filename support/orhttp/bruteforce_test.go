@@ -0,0 +1,97 @@
+package orhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+// loginHandler accepts the password "right" of any user
+var loginHandler = Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+	user := req.URL.Query().Get("user")
+	success := req.URL.Query().Get("password") == "right"
+	ReportAuth(req.Context(), user, success)
+	if !success {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+}))
+
+func login(client, user, password string) int {
+	req := httptest.NewRequest("POST", "/login?user="+user+"&password="+password, nil)
+	req.RemoteAddr = client + ":40000"
+	rec := httptest.NewRecorder()
+	loginHandler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func resetBruteForce() {
+	clientFailures.keys.Purge()
+	userFailures.keys.Purge()
+	deniedClients.Purge()
+}
+
+func TestReportAuth(t *testing.T) {
+	resetBruteForce()
+	ft := &fakeTracer{}
+	openrasp.SetTracer(ft)
+	defer openrasp.SetTracer(nil)
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"brute_force.max_failures_per_ip": 3, "brute_force.max_failures_per_user": 2}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"brute_force.max_failures_per_ip": 20, "brute_force.max_failures_per_user": 10}))
+	}()
+
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.20", "alice", "wrong"))
+	assert.Equal(t, http.StatusOK, login("192.0.2.20", "alice", "right"))
+	assert.Empty(t, ft.recorded)
+
+	// the success of alice forgot her failures, not the ones of the client
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.21", "bob", "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.22", "bob", "wrong"))
+	if assert.Len(t, ft.recorded, 1) {
+		assert.Equal(t, "brute_force", ft.recorded[0].attackLog.AttackType)
+		assert.Equal(t, "Brute force login - user bob failed 2 logins within 300 seconds, the last one from client 192.0.2.22", ft.recorded[0].attackLog.PluginMessage)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.20", "carol", "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.20", "dave", "wrong"))
+	if assert.Len(t, ft.recorded, 2) {
+		assert.Equal(t, "Brute force login - client 192.0.2.20 failed 3 logins within 300 seconds", ft.recorded[1].attackLog.PluginMessage)
+		assert.True(t, openrasp.GetSampler().Flagged("192.0.2.20", time.Now()))
+	}
+}
+
+func TestReportAuthDeny(t *testing.T) {
+	resetBruteForce()
+	ft := &fakeTracer{}
+	openrasp.SetTracer(ft)
+	defer openrasp.SetTracer(nil)
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"brute_force.max_failures_per_ip": 2, "brute_force.action": "block", "brute_force.deny_ttl": 60}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"brute_force.max_failures_per_ip": 20, "brute_force.action": "log", "brute_force.deny_ttl": 0}))
+	}()
+	blocked := openrasp.GetGeneral().GetInt("block.status_code")
+
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.30", "erin", "wrong"))
+	assert.Equal(t, blocked, login("192.0.2.30", "frank", "wrong"))
+	// the right password does not let a denied client in
+	assert.Equal(t, blocked, login("192.0.2.30", "erin", "right"))
+	assert.Equal(t, blocked, login("192.0.2.30", "erin", "right"))
+	assert.Equal(t, http.StatusOK, login("192.0.2.31", "erin", "right"))
+	// the denial is logged once
+	if assert.Len(t, ft.recorded, 2) {
+		assert.True(t, ft.recorded[0].blocked)
+		assert.Equal(t, "Brute force login - client 192.0.2.30 is denied after failing too many logins", ft.recorded[1].attackLog.PluginMessage)
+	}
+
+	// a client caught under the log action is not denied
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"brute_force.action": "log"}))
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.32", "erin", "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, login("192.0.2.32", "frank", "wrong"))
+	assert.Equal(t, http.StatusOK, login("192.0.2.32", "erin", "right"))
+	assert.Equal(t, http.StatusOK, login("192.0.2.32", "erin", "right"))
+	assert.Len(t, ft.recorded, 3)
+}
@@ -0,0 +1,131 @@
+package orhttp
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// CookieParam is a session cookie set without some of the attributes which
+// keep it from scripts, plain http and other sites
+type CookieParam struct {
+	Name    string   `json:"name"`
+	Missing []string `json:"missing"`
+}
+
+// reportedCookies keeps the policy log to one per cookie name
+var reportedCookies = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+const maxReportedCookies = 1024
+
+var sessionCookieRegex = struct {
+	sync.Mutex
+	source string
+	regex  *regexp.Regexp
+}{}
+
+// isSessionCookie matches name against security.cookie.session_names
+func isSessionCookie(name string) bool {
+	source := openrasp.GetGeneral().GetString("security.cookie.session_names")
+	sessionCookieRegex.Lock()
+	defer sessionCookieRegex.Unlock()
+	if sessionCookieRegex.regex == nil || sessionCookieRegex.source != source {
+		regex, err := regexp.Compile(source)
+		if err != nil {
+			return false
+		}
+		sessionCookieRegex.source, sessionCookieRegex.regex = source, regex
+	}
+	return sessionCookieRegex.regex.MatchString(name)
+}
+
+// missingCookieAttributes returns the name of a Set-Cookie header value and
+// the security attributes it lacks, Secure only matters over https
+func missingCookieAttributes(setCookie string, https bool) (string, []string) {
+	parts := strings.Split(setCookie, ";")
+	name := strings.TrimSpace(parts[0])
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	present := make(map[string]bool)
+	for _, part := range parts[1:] {
+		attribute := strings.TrimSpace(part)
+		if i := strings.Index(attribute, "="); i >= 0 {
+			attribute = attribute[:i]
+		}
+		present[strings.ToLower(attribute)] = true
+	}
+	var missing []string
+	if https && !present["secure"] {
+		missing = append(missing, "Secure")
+	}
+	if !present["httponly"] {
+		missing = append(missing, "HttpOnly")
+	}
+	if !present["samesite"] {
+		missing = append(missing, "SameSite")
+	}
+	return name, missing
+}
+
+func isHTTPS(req *http.Request) bool {
+	return req.TLS != nil || strings.EqualFold(req.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// PolicyCheck reports a cookie name once
+func (cp *CookieParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	reportedCookies.Lock()
+	defer reportedCookies.Unlock()
+	if reportedCookies.m[cp.Name] {
+		return model.Ignore, nil
+	}
+	if len(reportedCookies.m) >= maxReportedCookies {
+		reportedCookies.m = make(map[string]bool)
+	}
+	reportedCookies.m[cp.Name] = true
+	return model.Log, model.NewPolicyResult("Cookie security - The session cookie "+cp.Name+" is set without "+strings.Join(cp.Missing, ", "), 3018)
+}
+
+// cookiePolicyCheck inspects the session cookies of header before it is
+// sent, the missing attributes are appended by security.cookie.fix
+func cookiePolicyCheck(header http.Header, req *http.Request) {
+	setCookies := header["Set-Cookie"]
+	if len(setCookies) == 0 {
+		return
+	}
+	https := isHTTPS(req)
+	fix := openrasp.GetGeneral().GetBool("security.cookie.fix")
+	for i, setCookie := range setCookies {
+		name, missing := missingCookieAttributes(setCookie, https)
+		if len(missing) == 0 || !isSessionCookie(name) {
+			continue
+		}
+		cookieParam := &CookieParam{Name: name, Missing: missing}
+		interceptCode, policyResult := cookieParam.PolicyCheck()
+		if openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult) != model.Ignore {
+			if policyLogString := openrasp.NewPolicyLog(policyResult, cookieParam).String(); len(policyLogString) > 0 {
+				openrasp.GetLog().PolicyInfo(policyLogString)
+			}
+		}
+		if fix {
+			setCookies[i] = fixCookie(setCookie, missing)
+		}
+	}
+}
+
+func fixCookie(setCookie string, missing []string) string {
+	for _, attribute := range missing {
+		if attribute == "SameSite" {
+			attribute = "SameSite=Lax"
+		}
+		setCookie += "; " + attribute
+	}
+	return setCookie
+}
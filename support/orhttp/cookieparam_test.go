@@ -0,0 +1,48 @@
+package orhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingCookieAttributes(t *testing.T) {
+	name, missing := missingCookieAttributes("SESSIONID=abc; Path=/", true)
+	assert.Equal(t, "SESSIONID", name)
+	assert.Equal(t, []string{"Secure", "HttpOnly", "SameSite"}, missing)
+
+	_, missing = missingCookieAttributes("sid=abc; Path=/; httponly; SameSite=Strict", false)
+	assert.Empty(t, missing)
+
+	_, missing = missingCookieAttributes("sid=abc; HttpOnly; SameSite=Lax", true)
+	assert.Equal(t, []string{"Secure"}, missing)
+	assert.Equal(t, "sid=abc; HttpOnly; SameSite=Lax; Secure", fixCookie("sid=abc; HttpOnly; SameSite=Lax", missing))
+	assert.Equal(t, "sid=abc; HttpOnly; SameSite=Lax", fixCookie("sid=abc", []string{"HttpOnly", "SameSite"}))
+}
+
+func TestWrapSessionCookie(t *testing.T) {
+	h := Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "SESSIONID", Value: "abc", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+		w.Write([]byte("welcome"))
+	}))
+	serve := func() []string {
+		req := httptest.NewRequest("GET", "/login", nil)
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, "welcome", rec.Body.String())
+		return rec.Header()["Set-Cookie"]
+	}
+
+	assert.Equal(t, []string{"SESSIONID=abc; Path=/", "theme=dark"}, serve())
+
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"security.cookie.fix": true}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"security.cookie.fix": false}))
+	}()
+	assert.Equal(t, []string{"SESSIONID=abc; Path=/; Secure; HttpOnly; SameSite=Lax", "theme=dark"}, serve())
+}
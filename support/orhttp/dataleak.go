@@ -0,0 +1,184 @@
+package orhttp
+
+import (
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// DataLeakParam is the personal data found in a response body, a sign that
+// an injection went through rather than was merely tried. Samples are masked
+// down to their last four characters.
+type DataLeakParam struct {
+	Path        string   `json:"path"`
+	CreditCards int      `json:"credit_cards,omitempty"`
+	NationalIds int      `json:"national_ids,omitempty"`
+	Samples     []string `json:"samples"`
+	Scanned     int      `json:"scanned"`
+}
+
+const maxDataLeakSamples = 3
+
+var (
+	cardRegex  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	cnIdRegex  = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	ssnRegex   = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	cnIdWeight = []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+)
+
+// NewDataLeakParam scans body, the response of path, and returns nil when
+// it carries no personal data
+func NewDataLeakParam(path string, body []byte) *DataLeakParam {
+	dp := &DataLeakParam{Path: path, Scanned: len(body)}
+	ids := make(map[string]bool)
+	for _, id := range cnIdRegex.FindAll(body, -1) {
+		if validCnId(string(id)) {
+			ids[string(id)] = true
+			dp.add(&dp.NationalIds, string(id))
+		}
+	}
+	for _, ssn := range ssnRegex.FindAll(body, -1) {
+		if validSsn(string(ssn)) {
+			dp.add(&dp.NationalIds, string(ssn))
+		}
+	}
+	for _, card := range cardRegex.FindAll(body, -1) {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(string(card))
+		if !ids[digits] && plausibleCard(digits) && luhn(digits) {
+			dp.add(&dp.CreditCards, digits)
+		}
+	}
+	if dp.items() == 0 {
+		return nil
+	}
+	return dp
+}
+
+func (dp *DataLeakParam) add(count *int, value string) {
+	*count++
+	if len(dp.Samples) < maxDataLeakSamples {
+		dp.Samples = append(dp.Samples, strings.Repeat("*", len(value)-4)+value[len(value)-4:])
+	}
+}
+
+func (dp *DataLeakParam) items() int {
+	return dp.CreditCards + dp.NationalIds
+}
+
+func (dp *DataLeakParam) GetType() common.CheckType {
+	return common.DataLeak
+}
+
+func (dp *DataLeakParam) GetTypeString() string {
+	return common.CheckTypeToString(dp.GetType())
+}
+
+// AttackCheck reports the data found, a response of data_leak.dump_items or
+// more is a dump. The response is gone by the time it is scanned, so the
+// result is only logged.
+func (dp *DataLeakParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(dp) {
+			return results
+		}
+	}
+	var found []string
+	if dp.CreditCards > 0 {
+		found = append(found, strconv.Itoa(dp.CreditCards)+" credit card numbers")
+	}
+	if dp.NationalIds > 0 {
+		found = append(found, strconv.Itoa(dp.NationalIds)+" national id numbers")
+	}
+	if len(found) == 0 {
+		return results
+	}
+	message, confidence := "Data leak - response of "+dp.Path+" carries "+strings.Join(found, ", "), uint64(60)
+	if dumpItems := openrasp.AlgorithmInt(common.DataLeak, "dump_items", "data_leak.dump_items"); dumpItems > 0 && dp.items() >= dumpItems {
+		message, confidence = "Data leak - response of "+dp.Path+" dumps "+strings.Join(found, ", "), 90
+	}
+	results = append(results, model.NewAttackResult(model.InterceptCodeToString(model.Log), message, "go_builtin_plugin", dp.GetTypeString(), confidence))
+	return results
+}
+
+// scannableBody reports whether a response with header is text the scan can
+// read, compressed and binary bodies are skipped
+func scannableBody(header http.Header) bool {
+	if encoding := header.Get("Content-Encoding"); len(encoding) > 0 && encoding != "identity" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if len(contentType) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") ||
+		mediaType == "application/javascript" || mediaType == "application/x-www-form-urlencoded"
+}
+
+// luhn reports whether the check digit of digits is right
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// plausibleCard reports whether digits has the prefix and length of a card
+// of a major network, most ids and timestamps which pass luhn do not
+func plausibleCard(digits string) bool {
+	n := len(digits)
+	prefix := func(length int) int {
+		p, _ := strconv.Atoi(digits[:length])
+		return p
+	}
+	switch {
+	case digits[0] == '4':
+		return n == 13 || n == 16 || n == 19
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return n == 16
+	case prefix(2) == 34 || prefix(2) == 37:
+		return n == 15
+	case prefix(4) == 6011, prefix(2) == 65, prefix(3) >= 644 && prefix(3) <= 649, prefix(2) == 62, prefix(2) == 35:
+		return n >= 16 && n <= 19
+	case prefix(3) >= 300 && prefix(3) <= 305, prefix(2) == 36, prefix(2) == 38:
+		return n == 14
+	}
+	return false
+}
+
+// validCnId reports whether the check character of an 18 character resident
+// id of mainland China is right
+func validCnId(id string) bool {
+	sum := 0
+	for i, w := range cnIdWeight {
+		sum += int(id[i]-'0') * w
+	}
+	return strings.EqualFold(string("10X98765432"[sum%11]), id[17:])
+}
+
+// validSsn reports whether ssn is a social security number the US could
+// have issued
+func validSsn(ssn string) bool {
+	area, group, serial := ssn[:3], ssn[4:6], ssn[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
@@ -0,0 +1,70 @@
+package orhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDataLeakParam(t *testing.T) {
+	body := `[{"card":"4111 1111 1111 1111","id":"11010519491231002X"},{"card":"378282246310005","ssn":"078-05-1120"},` +
+		`{"order":"4111111111111112","trace":"1690000000000000","ssn":"000-12-3456"}]`
+	dp := NewDataLeakParam("/api/users", []byte(body))
+	if assert.NotNil(t, dp) {
+		assert.Equal(t, 2, dp.CreditCards)
+		assert.Equal(t, 2, dp.NationalIds)
+		assert.Equal(t, []string{"**************002X", "*******1120", "************1111"}, dp.Samples)
+		assert.Equal(t, len(body), dp.Scanned)
+	}
+	assert.Nil(t, NewDataLeakParam("/api/users", []byte(`{"id":"1690000000000000","phone":"+1 415 555 0132"}`)))
+}
+
+func TestCardChecks(t *testing.T) {
+	assert.True(t, luhn("5500000000000004"))
+	assert.False(t, luhn("5500000000000005"))
+	assert.True(t, plausibleCard("5500000000000004"))
+	assert.True(t, plausibleCard("6011111111111117"))
+	assert.False(t, plausibleCard("1234567812345670"))
+	assert.False(t, plausibleCard("411111111111111"))
+	assert.True(t, validCnId("11010519491231002x"))
+	assert.False(t, validCnId("110105194912310021"))
+	assert.False(t, validSsn("666-12-3456"))
+}
+
+func TestDataLeakCheck(t *testing.T) {
+	ft := &fakeTracer{}
+	openrasp.SetTracer(ft)
+	defer openrasp.SetTracer(nil)
+	dump := strings.Repeat(`{"card":"4111111111111111"},`, 25)
+	serve := func(contentEncoding string) {
+		h := Wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", contentEncoding)
+			w.Write([]byte("[" + dump))
+			w.Write([]byte("{}]"))
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/orders", nil))
+		assert.Equal(t, "["+dump+"{}]", rec.Body.String())
+	}
+
+	serve("")
+	assert.Empty(t, ft.recorded)
+
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"data_leak.enable": true}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"data_leak.enable": false}))
+	}()
+	serve("")
+	if assert.Len(t, ft.recorded, 1) {
+		assert.Equal(t, "data_leak", ft.recorded[0].attackLog.AttackType)
+		assert.Equal(t, "Data leak - response of /api/orders dumps 25 credit card numbers", ft.recorded[0].attackLog.PluginMessage)
+		assert.Equal(t, "log", ft.recorded[0].attackLog.InterceptState)
+	}
+	serve("gzip")
+	assert.Len(t, ft.recorded, 1)
+}
@@ -0,0 +1,83 @@
+package orhttp
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// DebugEndpointParam is a request to the handlers net/http/pprof and expvar
+// register, they leak memory contents and let anyone profile the process
+type DebugEndpointParam struct {
+	Endpoint string `json:"endpoint"`
+	Path     string `json:"path"`
+	Listener string `json:"listener"`
+	Client   string `json:"client"`
+}
+
+// reportedEndpoints keeps the policy log to one per endpoint and listener
+var reportedEndpoints = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+// NewDebugEndpointParam returns nil when req does not address a debug
+// endpoint or is received on a loopback or unix listener
+func NewDebugEndpointParam(req *http.Request) *DebugEndpointParam {
+	var endpoint string
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/debug/pprof"):
+		endpoint = "pprof"
+	case req.URL.Path == "/debug/vars":
+		endpoint = "expvar"
+	default:
+		return nil
+	}
+	addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok || !isExternal(addr.Network(), addr.String()) {
+		return nil
+	}
+	return &DebugEndpointParam{
+		Endpoint: endpoint,
+		Path:     req.URL.Path,
+		Listener: addr.String(),
+		Client:   req.RemoteAddr,
+	}
+}
+
+// PolicyCheck reports the exposure once per listener, a request of a client
+// which is not local is blocked on every call by
+// security.debug_endpoints.block_external
+func (dp *DebugEndpointParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	block := openrasp.GetGeneral().GetBool("security.debug_endpoints.block_external") && isExternal("tcp", dp.Client)
+	reportedEndpoints.Lock()
+	key := dp.Endpoint + "@" + dp.Listener
+	reported := reportedEndpoints.m[key]
+	reportedEndpoints.m[key] = true
+	reportedEndpoints.Unlock()
+	if reported && !block {
+		return model.Ignore, nil
+	}
+	pr := model.NewPolicyResult("Debug endpoint exposure - "+dp.Endpoint+" is served on the externally reachable listener "+dp.Listener, 3016)
+	if block {
+		return model.Block, pr
+	}
+	return model.Log, pr
+}
+
+// isExternal reports whether a tcp address is reachable from other hosts
+func isExternal(network, address string) bool {
+	if !strings.HasPrefix(network, "tcp") {
+		return false
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
+}
@@ -0,0 +1,36 @@
+package orhttp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDebugEndpointParam(t *testing.T) {
+	request := func(path string, local net.Addr) *http.Request {
+		req := httptest.NewRequest("GET", path, nil)
+		return req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, local))
+	}
+	external := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 8080}
+	loopback := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 6060}
+
+	dp := NewDebugEndpointParam(request("/debug/pprof/heap", external))
+	assert.NotNil(t, dp)
+	assert.Equal(t, "pprof", dp.Endpoint)
+	assert.Equal(t, "10.0.0.5:8080", dp.Listener)
+	assert.Equal(t, "expvar", NewDebugEndpointParam(request("/debug/vars", external)).Endpoint)
+	assert.Nil(t, NewDebugEndpointParam(request("/debug/pprof/", loopback)))
+	assert.Nil(t, NewDebugEndpointParam(request("/api/users", external)))
+	assert.Nil(t, NewDebugEndpointParam(request("/debug/vars", &net.UnixAddr{Name: "/run/app.sock", Net: "unix"})))
+}
+
+func TestIsExternal(t *testing.T) {
+	assert.True(t, isExternal("tcp", "[::]:8080"))
+	assert.True(t, isExternal("tcp", "192.0.2.1:1234"))
+	assert.False(t, isExternal("tcp", "[::1]:8080"))
+	assert.False(t, isExternal("unix", "/run/app.sock"))
+}
@@ -0,0 +1,51 @@
+package orhttp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// maxTrackedClients bounds the clients or users whose failures are counted,
+// the least recently failing ones are forgotten
+const maxTrackedClients = 10000
+
+// failureCounter counts the failures of clients or users in fixed windows
+type failureCounter struct {
+	keys *utils.LRU
+	mu   sync.Mutex
+}
+
+type failureWindow struct {
+	start time.Time
+	count int
+}
+
+func newFailureCounter(size int) *failureCounter {
+	return &failureCounter{keys: utils.NewLRU(size)}
+}
+
+// Add counts a failure of key at now and returns the count of the window of
+// that long it falls in
+func (fc *failureCounter) Add(key string, now time.Time, window time.Duration) int {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	value, _ := fc.keys.Get(key)
+	fw, ok := value.(*failureWindow)
+	if !ok || now.Sub(fw.start) >= window {
+		fw = &failureWindow{start: now}
+		fc.keys.Add(key, fw)
+	}
+	fw.count++
+	return fw.count
+}
+
+// Reset forgets the failures of key
+func (fc *failureCounter) Reset(key string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if _, ok := fc.keys.Get(key); ok {
+		fc.keys.Add(key, nil)
+	}
+}
@@ -1,7 +1,9 @@
 package orhttp
 
 import (
+	"net"
 	"net/http"
+	"time"
 
 	openrasp "github.com/baidu-security/openrasp-golang"
 	"github.com/baidu-security/openrasp-golang/gls"
@@ -26,8 +28,10 @@ type handler struct {
 
 // ServeHTTP delegates to h.Handler
 func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if openrasp.IsComplete() {
+	if openrasp.HookActive(openrasp.HookHttp) && sampleRequest(req) {
 		gls.Initialize()
+		openrasp.GetStatistics().AddRequest()
+		openrasp.BindApp(req.Host, req.URL.Path)
 		defer func() {
 			gls.Clear()
 		}()
@@ -37,11 +41,32 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 		clientIpHeader := openrasp.GetGeneral().GetString("clientip.header")
 		bodyMaxByte := openrasp.GetGeneral().GetInt("body.maxbytes")
+		if !openrasp.GetHookSwitch().HookEnabled(openrasp.HookHttpBody) {
+			bodyMaxByte = -1
+		}
 		requestInfo := model.NewRequestInfo(req, clientIpHeader, bodyMaxByte)
 		gls.Set("requestInfo", requestInfo)
+		if cookie, err := req.Cookie(openrasp.GetGeneral().GetString("challenge.cookie_name")); err == nil &&
+			openrasp.VerifyChallengeToken(requestInfo, cookie.Value, time.Now()) {
+			openrasp.PassChallenge()
+		}
 
-		w, resp := WrapResponseWriter(w, req)
+		// the handler writes through the wrapped writer, which the checks of
+		// the response watch
+		var resp *Response
+		w, resp = WrapResponseWriter(w, req)
 		gls.Set("responseWriter", w)
+		defer func() {
+			if sniffer, ok := w.(interface{ flushSniff() error }); ok {
+				sniffer.flushSniff()
+			}
+			if scanner, ok := w.(interface{ dataLeakCheck() }); ok {
+				scanner.dataLeakCheck()
+			}
+		}()
+		blocker, _ := w.(openrasp.Blocker)
+		req = req.WithContext(openrasp.NewContext(req.Context(), requestInfo, blocker))
+		gls.Set("traceContext", req.Context())
 		defer func() {
 			if v := recover(); v != nil {
 				if resp.StatusCode == 0 {
@@ -49,6 +74,42 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				}
 			}
 		}()
+		deniedCheck(requestInfo.Client())
+		if openrasp.AttackCheck(NewRequestParam(), openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+		if addr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && req.TLS != nil {
+			tlsPolicyCheck(newNegotiatedTLSParam(addr.String(), req.TLS))
+		}
+		if debugParam := NewDebugEndpointParam(req); debugParam != nil {
+			debugEndpointCheck(debugParam)
+		}
+		if jwtParam := NewJwtParam(req, requestInfo.Client()); jwtParam != nil && openrasp.AttackCheck(jwtParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
 	}
 	h.handler.ServeHTTP(w, req)
 }
+
+// sampleRequest reports whether req goes through detection, see Sampler
+func sampleRequest(req *http.Request) bool {
+	sampler := openrasp.GetSampler()
+	if !sampler.Sampling() {
+		return true
+	}
+	return sampler.Sample(model.RequestClient(req, openrasp.GetGeneral().GetString("clientip.header")))
+}
+
+func debugEndpointCheck(debugParam *DebugEndpointParam) {
+	interceptCode, policyResult := debugParam.PolicyCheck()
+	interceptCode = openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult)
+	if interceptCode == model.Ignore {
+		return
+	}
+	if policyLogString := openrasp.NewPolicyLog(policyResult, debugParam).String(); len(policyLogString) > 0 {
+		openrasp.GetLog().PolicyInfo(policyLogString)
+	}
+	if interceptCode == model.Block {
+		openrasp.BlockRequest()
+	}
+}
@@ -0,0 +1,171 @@
+package orhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopHandler struct{}
+
+func (nopHandler) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func disableHook(tb testing.TB, key string) func() {
+	assert.Nil(tb, openrasp.GetGeneral().Update(map[string]interface{}{key: false}))
+	return func() {
+		assert.Nil(tb, openrasp.GetGeneral().Update(map[string]interface{}{key: true}))
+	}
+}
+
+func TestServeHTTPHookDisabled(t *testing.T) {
+	defer disableHook(t, "hook.http.enable")()
+	assert.False(t, openrasp.HookActive(openrasp.HookHttp))
+	h := Wrap(nopHandler{})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		h.ServeHTTP(w, req)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkServeHTTPHookDisabled(b *testing.B) {
+	defer disableHook(b, "hook.http.enable")()
+	h := Wrap(nopHandler{})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(w, req)
+	}
+}
+
+func countSampled(req *http.Request, n int) int {
+	sampled := 0
+	for i := 0; i < n; i++ {
+		if sampleRequest(req) {
+			sampled++
+		}
+	}
+	return sampled
+}
+
+func TestSampleRequest(t *testing.T) {
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"sampling.percent": 10}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"sampling.percent": 100}))
+	}()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.10:40000"
+	assert.Equal(t, 10, countSampled(req, 100))
+
+	openrasp.GetSampler().Flag("192.0.2.10")
+	assert.Equal(t, 100, countSampled(req, 100))
+	req.RemoteAddr = "192.0.2.11:40000"
+	assert.Equal(t, 10, countSampled(req, 100))
+}
+
+type traceChecker struct{}
+
+func (traceChecker) CheckType() common.CheckType {
+	return common.Request
+}
+
+func (traceChecker) Check(params common.AttackChecker) *model.AttackResult {
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return nil
+	}
+	switch requestInfo.Header["X-Trace-Test"] {
+	case "attack":
+		return model.NewAttackResult("log", "trace test", "trace_test", "request", 90)
+	case "block":
+		return model.NewAttackResult("block", "trace test", "trace_test", "request", 90)
+	}
+	return nil
+}
+
+func init() {
+	openrasp.RegisterChecker(traceChecker{})
+}
+
+// recordedAttack keeps the attack log itself, which a tracer may retain
+type recordedAttack struct {
+	ctx       context.Context
+	attackLog *model.AttackLog
+	blocked   bool
+}
+
+type fakeTracer struct {
+	spanIds  bool
+	recorded []recordedAttack
+}
+
+func (ft *fakeTracer) SpanIds(ctx context.Context) (string, string, bool) {
+	return "0af7651916cd43dd8448eb211c80319c", "b7ad6b7169203331", ft.spanIds
+}
+
+func (ft *fakeTracer) RecordAttack(ctx context.Context, attackLog *model.AttackLog, blocked bool) {
+	ft.recorded = append(ft.recorded, recordedAttack{ctx: ctx, attackLog: attackLog, blocked: blocked})
+}
+
+func TestTracer(t *testing.T) {
+	ft := &fakeTracer{}
+	openrasp.SetTracer(ft)
+	defer openrasp.SetTracer(nil)
+	h := Wrap(nopHandler{})
+	serve := func() {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Trace-Test", "attack")
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve()
+	if assert.Len(t, ft.recorded, 1) {
+		recorded := ft.recorded[0]
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", recorded.attackLog.TraceId)
+		assert.Equal(t, "00f067aa0ba902b7", recorded.attackLog.SpanId)
+		assert.False(t, recorded.blocked)
+		_, ok := openrasp.FromContext(recorded.ctx)
+		assert.True(t, ok)
+	}
+
+	ft.spanIds = true
+	serve()
+	if assert.Len(t, ft.recorded, 2) {
+		assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", ft.recorded[1].attackLog.TraceId)
+		assert.Equal(t, "b7ad6b7169203331", ft.recorded[1].attackLog.SpanId)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ft := &fakeTracer{}
+	openrasp.SetTracer(ft)
+	defer openrasp.SetTracer(nil)
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"detect.dry_run": true}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"detect.dry_run": false}))
+	}()
+	called := false
+	h := Wrap(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Trace-Test", "block")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, ft.recorded, 1) {
+		assert.Equal(t, "log", ft.recorded[0].attackLog.InterceptState)
+		assert.True(t, ft.recorded[0].attackLog.WouldBlock)
+		assert.False(t, ft.recorded[0].blocked)
+	}
+}
@@ -0,0 +1,22 @@
+package orhttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+)
+
+// HealthHandler serves openrasp.HealthCheck as JSON, with the status 503
+// when the agent is down so that it can back a readiness probe
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		health := openrasp.HealthCheck()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if health.Status == openrasp.HealthDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	})
+}
@@ -0,0 +1,57 @@
+package orhttp
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveHealth(t *testing.T) (int, *openrasp.Health) {
+	rec := httptest.NewRecorder()
+	HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	health := &openrasp.Health{}
+	assert.Nil(t, json.NewDecoder(rec.Body).Decode(health))
+	return rec.Code, health
+}
+
+func hookHealth(health *openrasp.Health, name string) openrasp.HookHealth {
+	for _, hook := range health.Hooks {
+		if hook.Name == name {
+			return hook
+		}
+	}
+	return openrasp.HookHealth{}
+}
+
+func TestHealthHandler(t *testing.T) {
+	Wrap(nopHandler{}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	code, health := serveHealth(t)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, openrasp.HealthOK, health.Status, "%v", health.Problems)
+	assert.True(t, health.Initialized)
+	assert.True(t, health.Config.Loaded)
+	assert.NotEmpty(t, health.Plugins)
+	assert.Nil(t, health.Cloud)
+	assert.Equal(t, openrasp.HookHealth{Name: "http", Enabled: true, Called: true}, hookHealth(health, "http"))
+	assert.Equal(t, openrasp.HookHealth{Name: "ldap", Enabled: true, Called: false}, hookHealth(health, "ldap"))
+
+	hooks := map[string]interface{}{}
+	for _, hook := range health.Hooks {
+		hooks["hook."+hook.Name+".enable"] = false
+	}
+	assert.Nil(t, openrasp.GetGeneral().Update(hooks))
+	defer func() {
+		for key := range hooks {
+			hooks[key] = true
+		}
+		assert.Nil(t, openrasp.GetGeneral().Update(hooks))
+	}()
+	code, health = serveHealth(t)
+	assert.Equal(t, 503, code)
+	assert.Equal(t, openrasp.HealthDown, health.Status)
+	assert.Equal(t, []string{"every hook is disabled"}, health.Problems)
+}
@@ -0,0 +1,196 @@
+package orhttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// JwtParam is the JSON web token a request carries as its bearer token. It
+// is checked for a missing or guessable signature, and for a client sending
+// expired or malformed tokens over and over, which the application rejects
+// while someone forges or replays them.
+type JwtParam struct {
+	Algorithm string `json:"algorithm"`
+	Subject   string `json:"subject,omitempty"`
+	Expired   bool   `json:"expired,omitempty"`
+	Malformed bool   `json:"malformed,omitempty"`
+	Client    string `json:"client"`
+	Failures  int    `json:"failures,omitempty"`
+	parts     []string
+	now       time.Time
+}
+
+// NewJwtParam returns nil when req carries no bearer token shaped like a JWT,
+// opaque tokens are left alone
+func NewJwtParam(req *http.Request, client string) *JwtParam {
+	authorization := req.Header.Get("Authorization")
+	if len(authorization) < len("Bearer ") || !strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return nil
+	}
+	parts := strings.Split(strings.TrimSpace(authorization[len("Bearer "):]), ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	jp := &JwtParam{
+		Client: client,
+		parts:  parts,
+		now:    time.Now(),
+	}
+	var header, claims map[string]interface{}
+	if decodeSegment(parts[0], &header) != nil || decodeSegment(parts[1], &claims) != nil {
+		jp.Malformed = true
+		return jp
+	}
+	jp.Algorithm, _ = header["alg"].(string)
+	jp.Subject, _ = claims["sub"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		jp.Expired = time.Unix(int64(exp), 0).Before(jp.now)
+	}
+	return jp
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (jp *JwtParam) GetType() common.CheckType {
+	return common.Authentication
+}
+
+func (jp *JwtParam) GetTypeString() string {
+	return common.CheckTypeToString(jp.GetType())
+}
+
+func (jp *JwtParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("jwt.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", jp.GetTypeString(), confidence)
+}
+
+func (jp *JwtParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(jp) {
+			return results
+		}
+	}
+	if jp.Expired || jp.Malformed {
+		threshold := openrasp.AlgorithmInt(common.Authentication, "retry_threshold", "jwt.retry_threshold")
+		window := openrasp.AlgorithmInt(common.Authentication, "retry_window", "jwt.retry_window")
+		if failures := tokenFailures.Add(jp.Client, jp.now, time.Duration(window)*time.Second); threshold > 0 && failures >= threshold {
+			jp.Failures = failures
+			results = append(results, jp.newAttackResult("JWT tampering - client "+jp.Client+" sent "+strconv.Itoa(failures)+
+				" expired or malformed tokens within "+strconv.Itoa(window)+" seconds", 80))
+		}
+	}
+	if jp.Malformed {
+		return results
+	}
+	if strings.EqualFold(jp.Algorithm, "none") {
+		results = append(results, jp.newAttackResult("JWT tampering - "+jp.token()+" is unsigned, its algorithm is "+jp.Algorithm, 90))
+	} else if weakSecrets.Signs(jp.Algorithm, jp.parts) {
+		results = append(results, jp.newAttackResult("JWT weak secret - "+jp.token()+" is signed with "+jp.Algorithm+
+			" and a secret listed in jwt.weak_secrets", 100))
+	}
+	return results
+}
+
+// token names the token in messages, by its subject when it has one
+func (jp *JwtParam) token() string {
+	if len(jp.Subject) > 0 {
+		return "the token of subject " + jp.Subject
+	}
+	return "the token"
+}
+
+// tokenFailures counts the expired or malformed tokens of the clients in
+// fixed windows of jwt.retry_window seconds
+var tokenFailures = newFailureCounter(maxTrackedClients)
+
+// weakSecrets tries the HMAC signatures against jwt.weak_secrets
+var weakSecrets = &weakSecretMatcher{checked: utils.NewLRU(maxCheckedSignatures)}
+
+// maxCheckedSignatures bounds the tokens whose verdict is remembered, a
+// client sends the same token many times and it is tried only once
+const maxCheckedSignatures = 4096
+
+type weakSecretMatcher struct {
+	secrets [][]byte
+	checked *utils.LRU
+	mu      sync.RWMutex
+}
+
+func init() {
+	if openrasp.IsComplete() {
+		weakSecrets.OnConfigUpdate()
+		openrasp.GetGeneral().AttachListener(weakSecrets)
+	}
+}
+
+func (wm *weakSecretMatcher) OnConfigUpdate() {
+	var secrets [][]byte
+	for _, secret := range openrasp.AlgorithmStringSlice(common.Authentication, "weak_secrets", "jwt.weak_secrets") {
+		secrets = append(secrets, []byte(secret))
+	}
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.secrets = secrets
+	wm.checked.Purge()
+}
+
+// Signs reports whether one of the secrets signs the token split into parts
+// with the HMAC algorithm alg, other algorithms are not checked
+func (wm *weakSecretMatcher) Signs(alg string, parts []string) bool {
+	var newHash func() hash.Hash
+	switch alg {
+	case "HS256":
+		newHash = sha256.New
+	case "HS384":
+		newHash = sha512.New384
+	case "HS512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	// the verdict holds for the signature of this signing input only
+	sum := sha256.Sum256([]byte(alg + "." + parts[0] + "." + parts[1] + "." + parts[2]))
+	key := string(sum[:])
+	if weak, ok := wm.checked.Get(key); ok {
+		return weak.(bool)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return false
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+	weak := false
+	for _, secret := range wm.secrets {
+		mac := hmac.New(newHash, secret)
+		mac.Write(signingInput)
+		if hmac.Equal(mac.Sum(nil), signature) {
+			weak = true
+			break
+		}
+	}
+	wm.checked.Add(key, weak)
+	return weak
+}
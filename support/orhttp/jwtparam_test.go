@@ -0,0 +1,104 @@
+package orhttp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+// newToken signs the token of header and claims with HS256 and secret, an
+// empty secret leaves it unsigned
+func newToken(header, claims, secret string) string {
+	signingInput := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+	if len(secret) == 0 {
+		return signingInput + "."
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newJwtParam(authorization, client string) *JwtParam {
+	req := httptest.NewRequest("GET", "/api/orders", nil)
+	req.Header.Set("Authorization", authorization)
+	return NewJwtParam(req, client)
+}
+
+func TestNewJwtParam(t *testing.T) {
+	assert.Nil(t, newJwtParam("", "192.0.2.1"))
+	assert.Nil(t, newJwtParam("Basic YWRtaW46YWRtaW4=", "192.0.2.1"))
+	assert.Nil(t, newJwtParam("Bearer 2YotnFZFEjr1zCsicMWpAA", "192.0.2.1"))
+
+	jp := newJwtParam("bearer "+newToken(`{"alg":"HS256","typ":"JWT"}`, `{"sub":"alice","exp":1000000000}`, "k"), "192.0.2.1")
+	if assert.NotNil(t, jp) {
+		assert.Equal(t, "HS256", jp.Algorithm)
+		assert.Equal(t, "alice", jp.Subject)
+		assert.True(t, jp.Expired)
+		assert.False(t, jp.Malformed)
+	}
+	jp = newJwtParam("Bearer eyJhbGciOi.garbled.token", "192.0.2.1")
+	if assert.NotNil(t, jp) {
+		assert.True(t, jp.Malformed)
+	}
+}
+
+func TestJwtParamSignature(t *testing.T) {
+	jp := newJwtParam("Bearer "+newToken(`{"alg":"none"}`, `{"sub":"admin"}`, ""), "192.0.2.2")
+	if results := jp.AttackCheck(); assert.Len(t, results, 1) {
+		assert.Equal(t, "JWT tampering - the token of subject admin is unsigned, its algorithm is none", results[0].PluginMessage)
+		assert.Equal(t, "authentication", results[0].PluginName)
+	}
+
+	jp = newJwtParam("Bearer "+newToken(`{"alg":"HS256"}`, `{"sub":"bob"}`, "secret"), "192.0.2.2")
+	if results := jp.AttackCheck(); assert.Len(t, results, 1) {
+		assert.Contains(t, results[0].PluginMessage, "JWT weak secret - the token of subject bob is signed with HS256")
+	}
+	// the verdict of a signature is remembered
+	assert.Len(t, jp.AttackCheck(), 1)
+
+	jp = newJwtParam("Bearer "+newToken(`{"alg":"HS256"}`, `{"sub":"bob"}`, "7c1f0a4e9d2b86f35e0c4a1b"), "192.0.2.2")
+	assert.Empty(t, jp.AttackCheck())
+
+	weakSecretList := openrasp.GetGeneral().GetStringSlice("jwt.weak_secrets")
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"jwt.weak_secrets": []string{"7c1f0a4e9d2b86f35e0c4a1b"}}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"jwt.weak_secrets": weakSecretList}))
+	}()
+	assert.Len(t, jp.AttackCheck(), 1)
+}
+
+func TestJwtParamForgedSignature(t *testing.T) {
+	weak := newToken(`{"alg":"HS256"}`, `{"sub":"dave"}`, "secret")
+	forged := newToken(`{"alg":"HS256"}`, `{"sub":"admin"}`, "")
+	forged += weak[strings.LastIndex(weak, ".")+1:]
+	// the forged token carrying the real signature is sent first
+	assert.Empty(t, newJwtParam("Bearer "+forged, "192.0.2.5").AttackCheck())
+	assert.Len(t, newJwtParam("Bearer "+weak, "192.0.2.5").AttackCheck(), 1)
+	assert.Empty(t, newJwtParam("Bearer "+forged, "192.0.2.5").AttackCheck())
+}
+
+func TestJwtParamRetries(t *testing.T) {
+	tokenFailures.keys.Purge()
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"jwt.retry_threshold": 3}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"jwt.retry_threshold": 20}))
+	}()
+	expired := "Bearer " + newToken(`{"alg":"HS256"}`, `{"sub":"carol","exp":1000000000}`, "7c1f0a4e9d2b86f35e0c4a1b")
+	assert.Empty(t, newJwtParam(expired, "198.51.100.3").AttackCheck())
+	assert.Empty(t, newJwtParam("Bearer a.b.c", "198.51.100.3").AttackCheck())
+	assert.Empty(t, newJwtParam(expired, "198.51.100.4").AttackCheck())
+	jp := newJwtParam(expired, "198.51.100.3")
+	if results := jp.AttackCheck(); assert.Len(t, results, 1) {
+		assert.Equal(t, "JWT tampering - client 198.51.100.3 sent 3 expired or malformed tokens within 60 seconds", results[0].PluginMessage)
+		assert.Equal(t, 3, jp.Failures)
+	}
+
+	valid := "Bearer " + newToken(`{"alg":"HS256"}`, `{"sub":"carol","exp":4000000000}`, "7c1f0a4e9d2b86f35e0c4a1b")
+	assert.Empty(t, newJwtParam(valid, "198.51.100.3").AttackCheck())
+}
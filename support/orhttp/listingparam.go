@@ -0,0 +1,84 @@
+package orhttp
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// listingPrefixes start the directory indexes of http.FileServer, a listing
+// of Go 1.21 and later is headed by a doctype and a viewport
+var listingPrefixes = [][]byte{
+	[]byte("<pre>\n<a href=\""),
+	[]byte("<pre>\n</pre>\n"),
+	[]byte("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n"),
+}
+
+type sniffResult int
+
+const (
+	sniffNeedMore sniffResult = iota
+	sniffIsListing
+	sniffNotListing
+)
+
+// sniffListing tells whether the start of a body is a directory index, or
+// whether more of it is needed to tell
+func sniffListing(body []byte) sniffResult {
+	result := sniffNotListing
+	for _, prefix := range listingPrefixes {
+		if bytes.HasPrefix(body, prefix) {
+			return sniffIsListing
+		}
+		if bytes.HasPrefix(prefix, body) {
+			result = sniffNeedMore
+		}
+	}
+	return result
+}
+
+// DirectoryListingParam is a response rendering the index of a directory,
+// which discloses every file under it
+type DirectoryListingParam struct {
+	Path string `json:"path"`
+	Url  string `json:"url"`
+}
+
+// reportedListings keeps the policy log to one per path
+var reportedListings = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+const maxReportedListings = 1024
+
+func NewDirectoryListingParam(req *http.Request) *DirectoryListingParam {
+	return &DirectoryListingParam{
+		Path: req.URL.Path,
+		Url:  req.URL.String(),
+	}
+}
+
+// PolicyCheck reports a directory once, the listing is replaced by a 403 on
+// every call by security.directory_listing.forbid
+func (dp *DirectoryListingParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	forbid := openrasp.GetGeneral().GetBool("security.directory_listing.forbid")
+	reportedListings.Lock()
+	reported := reportedListings.m[dp.Path]
+	if len(reportedListings.m) >= maxReportedListings {
+		reportedListings.m = make(map[string]bool)
+	}
+	reportedListings.m[dp.Path] = true
+	reportedListings.Unlock()
+	if reported && !forbid {
+		return model.Ignore, nil
+	}
+	pr := model.NewPolicyResult("Directory listing - The index of "+dp.Path+" is rendered, every file under it is disclosed", 3017)
+	if forbid {
+		return model.Block, pr
+	}
+	return model.Log, pr
+}
@@ -0,0 +1,67 @@
+package orhttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniffListing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listing")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("s"), 0644))
+
+	recorder := httptest.NewRecorder()
+	http.FileServer(http.Dir(dir)).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, sniffIsListing, sniffListing(recorder.Body.Bytes()))
+
+	assert.Equal(t, sniffNeedMore, sniffListing([]byte("<pre>\n")))
+	assert.Equal(t, sniffNeedMore, sniffListing([]byte("<!doctype html>\n")))
+	assert.Equal(t, sniffIsListing, sniffListing([]byte("<pre>\n</pre>\n")))
+	assert.Equal(t, sniffNotListing, sniffListing([]byte("<!doctype html>\n<html>")))
+	assert.Equal(t, sniffNotListing, sniffListing([]byte("<pre>code</pre>")))
+}
+
+func TestWrapFileServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listing")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644))
+	server := httptest.NewServer(Wrap(http.FileServer(http.Dir(dir))))
+	defer server.Close()
+	get := func(path string) (*http.Response, string) {
+		resp, err := http.Get(server.URL + path)
+		if !assert.Nil(t, err) {
+			return nil, ""
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		return resp, string(body)
+	}
+
+	resp, body := get("/secret.txt")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "secret", body)
+	assert.Equal(t, "OpenRASP", resp.Header.Get("X-Protected-By"))
+	resp, body = get("/")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "secret.txt")
+
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"security.directory_listing.forbid": true}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"security.directory_listing.forbid": false}))
+	}()
+	resp, body = get("/")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.NotContains(t, body, "secret.txt")
+	resp, body = get("/secret.txt")
+	assert.Equal(t, "secret", body)
+}
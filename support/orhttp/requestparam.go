@@ -0,0 +1,45 @@
+package orhttp
+
+import (
+	"encoding/json"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// RequestParam lets the plugins check the request before it is handled, the
+// request itself is read from the context
+type RequestParam struct{}
+
+func NewRequestParam() *RequestParam {
+	return &RequestParam{}
+}
+
+func (rp *RequestParam) GetType() common.CheckType {
+	return common.Request
+}
+
+func (rp *RequestParam) GetTypeString() string {
+	return common.CheckTypeToString(rp.GetType())
+}
+
+func (rp *RequestParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var ars []*model.AttackResult
+	for _, opt := range opts {
+		if opt(rp) {
+			return ars
+		}
+	}
+	if openrasp.RequestInfoAvailable() {
+		resultBytes := openrasp.PluginCheck(rp.GetTypeString(), []byte("{}"))
+		var ms []map[string]interface{}
+		err := json.Unmarshal(resultBytes, &ms)
+		if err == nil {
+			for _, m := range ms {
+				ars = append(ars, model.NewAttackResultFromMap(m))
+			}
+		}
+	}
+	return ars
+}
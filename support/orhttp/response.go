@@ -1,8 +1,12 @@
 package orhttp
 
 import (
+	"bytes"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	openrasp "github.com/baidu-security/openrasp-golang"
 	"github.com/baidu-security/openrasp-golang/gls"
@@ -21,6 +25,10 @@ func WrapResponseWriter(w http.ResponseWriter, req *http.Request) (http.Response
 			req:     req,
 		},
 	}
+	if openrasp.GetGeneral().GetBool("data_leak.enable") {
+		rw.leak = &bytes.Buffer{}
+		rw.leakMaxBytes = openrasp.GetGeneral().GetInt("data_leak.max_bytes")
+	}
 	h, _ := w.(http.Hijacker)
 	p, _ := w.(http.Pusher)
 	switch {
@@ -57,8 +65,27 @@ type Response struct {
 type ResponseWriter struct {
 	http.ResponseWriter
 	resp Response
+	// listing is the state of the directory index detection, the start of
+	// the body is held in sniff until the detection is done
+	listing listingState
+	sniff   []byte
+	discard bool
+	// headersChecked is set once the headers about to be sent are checked
+	headersChecked bool
+	// leak holds a copy of the start of the body for dataLeakCheck, it is
+	// nil unless data_leak.enable is set and the body is text
+	leak         *bytes.Buffer
+	leakMaxBytes int
 }
 
+type listingState int
+
+const (
+	listingUnknown listingState = iota
+	listingSniffing
+	listingDone
+)
+
 func (res *Response) detectContentType() string {
 	ct := res.Headers.Get("Content-Type")
 	if len(ct) > 0 {
@@ -78,19 +105,151 @@ func (w *ResponseWriter) WriteHeader(statusCode int) {
 		w.ResponseWriter.Header().Set("X-Request-ID", requestInfo.GetRequestId())
 	}
 	w.ResponseWriter.Header().Set("X-Protected-By", "OpenRASP")
+	w.checkHeaders()
 	w.ResponseWriter.WriteHeader(statusCode)
 	w.resp.StatusCode = statusCode
 	w.resp.Sent = true
 }
 
 func (w *ResponseWriter) Write(data []byte) (int, error) {
+	if w.discard {
+		return len(data), nil
+	}
+	w.startListing()
+	if w.listing != listingSniffing {
+		return w.write(data)
+	}
+	w.sniff = append(w.sniff, data...)
+	switch sniffListing(w.sniff) {
+	case sniffNeedMore:
+		return len(data), nil
+	case sniffIsListing:
+		if w.directoryListingCheck() {
+			w.listing, w.sniff, w.discard = listingDone, nil, true
+			return len(data), nil
+		}
+	}
+	if err := w.flushSniff(); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// startListing holds the body for the directory index detection when it
+// is an html page written without an explicit status
+func (w *ResponseWriter) startListing() {
+	if w.listing != listingUnknown {
+		return
+	}
+	w.listing = listingDone
+	if w.resp.StatusCode == 0 && strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+		w.listing = listingSniffing
+	}
+}
+
+// ReadFrom keeps the io.ReaderFrom of the underlying writer, the sendfile
+// path of http.ServeContent, for the bodies no check has to read
+func (w *ResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.startListing()
+	rf, ok := w.ResponseWriter.(io.ReaderFrom)
+	if !ok || w.discard || w.listing == listingSniffing || w.leak != nil {
+		return io.Copy(writerOnly{w}, src)
+	}
+	if w.resp.StatusCode == 0 {
+		w.checkHeaders()
+	}
+	n, err := rf.ReadFrom(src)
+	if w.resp.StatusCode == 0 {
+		w.resp.StatusCode = http.StatusOK
+	}
+	return n, err
+}
+
+// writerOnly hides ReadFrom from io.Copy
+type writerOnly struct {
+	io.Writer
+}
+
+// flushSniff writes the held start of the body and ends the detection
+func (w *ResponseWriter) flushSniff() error {
+	held := w.sniff
+	w.listing, w.sniff = listingDone, nil
+	if len(held) == 0 {
+		return nil
+	}
+	_, err := w.write(held)
+	return err
+}
+
+// directoryListingCheck reports whether the listing is replaced by a 403
+func (w *ResponseWriter) directoryListingCheck() bool {
+	listingParam := NewDirectoryListingParam(w.resp.req)
+	interceptCode, policyResult := listingParam.PolicyCheck()
+	interceptCode = openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult)
+	if interceptCode == model.Ignore {
+		return false
+	}
+	if policyLogString := openrasp.NewPolicyLog(policyResult, listingParam).String(); len(policyLogString) > 0 {
+		openrasp.GetLog().PolicyInfo(policyLogString)
+	}
+	if interceptCode != model.Block {
+		return false
+	}
+	w.ResponseWriter.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusForbidden)
+	return true
+}
+
+// checkHeaders runs the policy checks of the headers before they are sent
+func (w *ResponseWriter) checkHeaders() {
+	if w.headersChecked {
+		return
+	}
+	w.headersChecked = true
+	cookiePolicyCheck(w.ResponseWriter.Header(), w.resp.req)
+}
+
+func (w *ResponseWriter) write(data []byte) (int, error) {
+	if w.resp.StatusCode == 0 {
+		w.checkHeaders()
+	}
 	n, err := w.ResponseWriter.Write(data)
 	if w.resp.StatusCode == 0 {
 		w.resp.StatusCode = http.StatusOK
 	}
+	w.captureLeak(data[:n])
 	return n, err
 }
 
+// captureLeak copies up to data_leak.max_bytes of the body written
+func (w *ResponseWriter) captureLeak(data []byte) {
+	if w.leak == nil {
+		return
+	}
+	if w.leak.Len() == 0 && !scannableBody(w.Header()) {
+		w.leak = nil
+		return
+	}
+	if room := w.leakMaxBytes - w.leak.Len(); room > 0 {
+		if len(data) > room {
+			data = data[:room]
+		}
+		w.leak.Write(data)
+	}
+}
+
+// dataLeakCheck scans the copy of the body once the handler is done
+func (w *ResponseWriter) dataLeakCheck() {
+	if w.leak == nil || w.leak.Len() == 0 {
+		return
+	}
+	dataLeakParam := NewDataLeakParam(w.resp.req.URL.Path, w.leak.Bytes())
+	w.leak = nil
+	if dataLeakParam != nil {
+		openrasp.AttackCheck(dataLeakParam, openrasp.WhitelistOption)
+	}
+}
+
 func (w *ResponseWriter) CloseNotify() <-chan bool {
 	if closeNotifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
 		return closeNotifier.CloseNotify()
@@ -99,6 +258,7 @@ func (w *ResponseWriter) CloseNotify() <-chan bool {
 }
 
 func (w *ResponseWriter) Flush() {
+	w.flushSniff()
 	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
 		flusher.Flush()
 	}
@@ -143,6 +303,28 @@ func (w *ResponseWriter) BlockByOpenRASP() {
 	panic(openrasp.ErrBlock)
 }
 
+// ChallengeByOpenRASP sends the challenge page which sets the token cookie
+// and reloads, a response already on its way can only be blocked
+func (w *ResponseWriter) ChallengeByOpenRASP() {
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok || w.resp.Sent {
+		w.BlockByOpenRASP()
+		return
+	}
+	general := openrasp.GetGeneral()
+	content := strings.NewReplacer(
+		"%cookie_name%", general.GetString("challenge.cookie_name"),
+		"%token%", openrasp.NewChallengeToken(requestInfo, time.Now()),
+		"%ttl%", strconv.FormatInt(general.GetInt64("challenge.ttl"), 10),
+		"%request_id%", requestInfo.GetRequestId(),
+	).Replace(general.GetString("challenge.content_html"))
+	w.ResponseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.ResponseWriter.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(general.GetInt("challenge.status_code"))
+	w.ResponseWriter.Write([]byte(content))
+	panic(openrasp.ErrBlock)
+}
+
 type responseWriterHijacker struct {
 	ResponseWriter
 	http.Hijacker
@@ -0,0 +1,121 @@
+package orhttp
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+var tlsVersionNames = map[uint16]string{
+	tls.VersionSSL30: "SSLv3",
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+}
+
+// weakCipherSuites are broken by RC4, by 3DES or by the timing of CBC
+// with SHA-256
+var weakCipherSuites = map[uint16]string{
+	tls.TLS_RSA_WITH_RC4_128_SHA:                "TLS_RSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:        "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA:          "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:           "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:     "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA256:         "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:   "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+}
+
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+	return "0x" + strconv.FormatUint(uint64(version), 16)
+}
+
+// TLSParam is the tls configuration of a listener, or the connection state
+// negotiated by a request
+type TLSParam struct {
+	Listener         string   `json:"listener"`
+	MinVersion       string   `json:"min_version,omitempty"`
+	WeakCipherSuites []string `json:"weak_cipher_suites,omitempty"`
+}
+
+// NewTLSParam collects the deprecated protocol version and the weak cipher
+// suites config enables, a zero MinVersion is left to the defaults of Go
+func NewTLSParam(listener string, config *tls.Config) *TLSParam {
+	tp := &TLSParam{Listener: listener}
+	if config == nil {
+		return tp
+	}
+	if config.MinVersion != 0 && config.MinVersion < tls.VersionTLS12 {
+		tp.MinVersion = tlsVersionName(config.MinVersion)
+	}
+	for _, suite := range config.CipherSuites {
+		if name, ok := weakCipherSuites[suite]; ok {
+			tp.WeakCipherSuites = append(tp.WeakCipherSuites, name)
+		}
+	}
+	return tp
+}
+
+// newNegotiatedTLSParam is NewTLSParam for the state negotiated by a client
+func newNegotiatedTLSParam(listener string, state *tls.ConnectionState) *TLSParam {
+	return NewTLSParam(listener, &tls.Config{
+		MinVersion:   state.Version,
+		CipherSuites: []uint16{state.CipherSuite},
+	})
+}
+
+func (tp *TLSParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	var problems []string
+	if len(tp.MinVersion) > 0 {
+		problems = append(problems, "the deprecated protocol "+tp.MinVersion)
+	}
+	if len(tp.WeakCipherSuites) > 0 {
+		problems = append(problems, "the weak cipher suites "+strings.Join(tp.WeakCipherSuites, ", "))
+	}
+	if len(problems) == 0 {
+		return model.Ignore, nil
+	}
+	return model.Log, model.NewPolicyResult("TLS security - The listener "+tp.Listener+" accepts "+strings.Join(problems, " and "), 3019)
+}
+
+// reportedTLS keeps the policy log to one per listener and problem
+var reportedTLS = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+func tlsPolicyCheck(tlsParam *TLSParam) {
+	interceptCode, policyResult := tlsParam.PolicyCheck()
+	if openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult) == model.Ignore {
+		return
+	}
+	key := tlsParam.Listener + "|" + tlsParam.MinVersion + "|" + strings.Join(tlsParam.WeakCipherSuites, ",")
+	reportedTLS.Lock()
+	reported := reportedTLS.m[key]
+	reportedTLS.m[key] = true
+	reportedTLS.Unlock()
+	if reported {
+		return
+	}
+	if policyLogString := openrasp.NewPolicyLog(policyResult, tlsParam).String(); len(policyLogString) > 0 {
+		openrasp.GetLog().PolicyInfo(policyLogString)
+	}
+}
+
+// CheckServer reports the deprecated protocol versions and the weak cipher
+// suites enabled by the tls config of srv, it is meant to be called before
+// srv starts listening
+func CheckServer(srv *http.Server) {
+	if srv == nil || srv.TLSConfig == nil || !openrasp.IsComplete() {
+		return
+	}
+	tlsPolicyCheck(NewTLSParam(srv.Addr, srv.TLSConfig))
+}
@@ -0,0 +1,30 @@
+package orhttp
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSParam(t *testing.T) {
+	tp := NewTLSParam(":443", &tls.Config{
+		MinVersion:   tls.VersionTLS10,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA},
+	})
+	assert.Equal(t, "TLS 1.0", tp.MinVersion)
+	assert.Equal(t, []string{"TLS_RSA_WITH_3DES_EDE_CBC_SHA"}, tp.WeakCipherSuites)
+	interceptCode, pr := tp.PolicyCheck()
+	assert.Equal(t, model.Log, interceptCode)
+	assert.Equal(t, "TLS security - The listener :443 accepts the deprecated protocol TLS 1.0 and the weak cipher suites TLS_RSA_WITH_3DES_EDE_CBC_SHA", pr.Message)
+
+	interceptCode, _ = NewTLSParam(":443", &tls.Config{MinVersion: tls.VersionTLS12}).PolicyCheck()
+	assert.Equal(t, model.Ignore, interceptCode)
+	interceptCode, _ = NewTLSParam(":443", &tls.Config{}).PolicyCheck()
+	assert.Equal(t, model.Ignore, interceptCode)
+
+	tp = newNegotiatedTLSParam("example.com", &tls.ConnectionState{Version: tls.VersionTLS11, CipherSuite: tls.TLS_AES_128_GCM_SHA256})
+	assert.Equal(t, "TLS 1.1", tp.MinVersion)
+	assert.Empty(t, tp.WeakCipherSuites)
+}
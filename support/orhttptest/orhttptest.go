@@ -0,0 +1,197 @@
+// Package orhttptest serves requests through a handler instrumented by
+// orhttp and captures the logs openrasp emits for them, so that applications
+// can assert in their tests that an attack is blocked:
+//
+//	result := orhttptest.Serve(mux, httptest.NewRequest("GET", "/?id=1' or '1'='1", nil))
+//	orhttptest.AssertBlocked(t, result, "sql")
+package orhttptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/support/orhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// requestHeader carries the id of a served request, the attacks are matched
+// with the request by it
+const requestHeader = "X-Openrasp-Test"
+
+// Result is a served request with the logs emitted while it was served
+type Result struct {
+	Response *httptest.ResponseRecorder
+	// Attacks are the attacks detected in the request, including the ones
+	// whose log is filtered out or deduplicated
+	Attacks []*model.AttackLog
+	// Policies are the policy logs emitted while the request was served,
+	// they are not tied to a request and include the ones of requests
+	// served concurrently
+	Policies []*model.PolicyLog
+}
+
+// Blocked reports whether an attack blocked the request
+func (r *Result) Blocked() bool {
+	for _, attack := range r.Attacks {
+		if attack.AttackResult != nil && attack.InterceptState == "block" {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedBy reports whether an attack of attackType blocked the request
+func (r *Result) BlockedBy(attackType string) bool {
+	for _, attack := range r.Attacks {
+		if attack.AttackResult != nil && attack.InterceptState == "block" && attack.AttackType == attackType {
+			return true
+		}
+	}
+	return false
+}
+
+// Serve serves req with h wrapped by orhttp
+func Serve(h http.Handler, req *http.Request) *Result {
+	return ServeWrapped(orhttp.Wrap(h), req)
+}
+
+// ServeWrapped serves req with h, which is already wrapped by orhttp or a
+// framework integration like orbeego
+func ServeWrapped(h http.Handler, req *http.Request) *Result {
+	installHooks()
+	id := strconv.FormatUint(atomic.AddUint64(&requestId, 1), 10)
+	req.Header.Set(requestHeader, id)
+	rec := &recorder{}
+	recordersMu.Lock()
+	recorders[id] = rec
+	recordersMu.Unlock()
+	defer func() {
+		recordersMu.Lock()
+		delete(recorders, id)
+		recordersMu.Unlock()
+	}()
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, req)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return &Result{
+		Response: response,
+		Attacks:  rec.attacks,
+		Policies: rec.policies,
+	}
+}
+
+// TestingT is the part of *testing.T the assertions use
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertBlocked checks that an attack of attackType blocked the request
+func AssertBlocked(t TestingT, result *Result, attackType string) bool {
+	if result.BlockedBy(attackType) {
+		return true
+	}
+	t.Errorf("request was not blocked by %s, attacks: %s", attackType, describe(result.Attacks))
+	return false
+}
+
+// AssertNotBlocked checks that no attack blocked the request
+func AssertNotBlocked(t TestingT, result *Result) bool {
+	if !result.Blocked() {
+		return true
+	}
+	t.Errorf("request was blocked, attacks: %s", describe(result.Attacks))
+	return false
+}
+
+func describe(attacks []*model.AttackLog) string {
+	if len(attacks) == 0 {
+		return "none"
+	}
+	described := make([]string, 0, len(attacks))
+	for _, attack := range attacks {
+		if attack.AttackResult != nil {
+			described = append(described, attack.AttackType+"/"+attack.PluginAlgorithm+" "+attack.InterceptState)
+		}
+	}
+	return strings.Join(described, ", ")
+}
+
+var (
+	requestId uint64
+	hooksOnce sync.Once
+	// recorders holds the recorders of the requests being served by id
+	recorders   = make(map[string]*recorder)
+	recordersMu sync.Mutex
+)
+
+// recorder collects the logs of a request being served
+type recorder struct {
+	attacks  []*model.AttackLog
+	policies []*model.PolicyLog
+	mu       sync.Mutex
+}
+
+// installHooks attaches the callback and the log hook dispatching the
+// attacks and the policy logs to the recorders, once since they cannot be
+// removed
+func installHooks() {
+	hooksOnce.Do(func() {
+		openrasp.OnDetect(recordAttack)
+		if lm := openrasp.GetLog(); lm != nil {
+			lm.AddPolicyHook(logHook(recordPolicy))
+		}
+	})
+}
+
+// logHook passes the message of the entries to f
+type logHook func(message string)
+
+func (logHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h logHook) Fire(entry *logrus.Entry) error {
+	h(entry.Message)
+	return nil
+}
+
+var _ orlog.Hook = logHook(nil)
+
+// recordAttack keeps a copy of attackLog for the request it belongs to
+func recordAttack(attackLog *model.AttackLog) {
+	if attackLog.RequestInfo == nil {
+		return
+	}
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+	if rec, ok := recorders[attackLog.RequestInfo.Header[requestHeader]]; ok {
+		copied := *attackLog
+		rec.mu.Lock()
+		rec.attacks = append(rec.attacks, &copied)
+		rec.mu.Unlock()
+	}
+}
+
+// recordPolicy decodes a policy log for every request being served
+func recordPolicy(message string) {
+	recordersMu.Lock()
+	defer recordersMu.Unlock()
+	for _, rec := range recorders {
+		policyLog := &model.PolicyLog{}
+		if err := json.Unmarshal([]byte(message), policyLog); err != nil {
+			return
+		}
+		rec.mu.Lock()
+		rec.policies = append(rec.policies, policyLog)
+		rec.mu.Unlock()
+	}
+}
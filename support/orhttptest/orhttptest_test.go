@@ -0,0 +1,78 @@
+package orhttptest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type headerChecker struct{}
+
+func (headerChecker) CheckType() common.CheckType {
+	return common.Request
+}
+
+func (headerChecker) Check(params common.AttackChecker) *model.AttackResult {
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok || len(requestInfo.Header["X-Attack"]) == 0 {
+		return nil
+	}
+	return model.NewAttackResult(requestInfo.Header["X-Attack"], "header test", "header_test", "orhttptest", 90)
+}
+
+func init() {
+	openrasp.RegisterChecker(headerChecker{})
+}
+
+type fakeT struct {
+	errors []string
+}
+
+func (ft *fakeT) Errorf(format string, args ...interface{}) {
+	ft.errors = append(ft.errors, fmt.Sprintf(format, args...))
+}
+
+func TestServe(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })
+	serve := func(action string) *Result {
+		called = false
+		req := httptest.NewRequest("GET", "/", nil)
+		if len(action) > 0 {
+			req.Header.Set("X-Attack", action)
+		}
+		return Serve(handler, req)
+	}
+
+	result := serve("")
+	assert.True(t, called)
+	assert.Empty(t, result.Attacks)
+	assert.True(t, AssertNotBlocked(t, result))
+
+	result = serve("log")
+	assert.True(t, called)
+	if assert.Len(t, result.Attacks, 1) {
+		assert.Equal(t, "header_test", result.Attacks[0].PluginAlgorithm)
+		assert.Equal(t, "log", result.Attacks[0].InterceptState)
+	}
+	assert.False(t, result.Blocked())
+	ft := &fakeT{}
+	assert.False(t, AssertBlocked(ft, result, "request"))
+	assert.Equal(t, []string{"request was not blocked by request, attacks: request/header_test log"}, ft.errors)
+
+	result = serve("block")
+	assert.False(t, called)
+	assert.True(t, AssertBlocked(t, result, "request"))
+	assert.False(t, result.BlockedBy("sql"))
+	assert.Equal(t, openrasp.GetGeneral().GetInt("block.status_code"), result.Response.Code)
+	ft = &fakeT{}
+	assert.False(t, AssertNotBlocked(ft, result))
+	assert.Len(t, ft.errors, 1)
+}
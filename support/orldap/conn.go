@@ -0,0 +1,53 @@
+package orldap
+
+import (
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// Conn wraps ldap.Conn, checking filters and DNs before they are sent
+type Conn struct {
+	*ldap.Conn
+}
+
+func Wrap(conn *ldap.Conn) *Conn {
+	return &Conn{Conn: conn}
+}
+
+// DialURL is the wrapped version of ldap.DialURL
+func DialURL(addr string, opts ...ldap.DialOpt) (*Conn, error) {
+	conn, err := ldap.DialURL(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(conn), nil
+}
+
+func ldapAttackCheck(ldapParam *LdapParam) {
+	if openrasp.HookActive(openrasp.HookLdap) && gls.Activated() {
+		if openrasp.AttackCheck(ldapParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+func (c *Conn) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	ldapAttackCheck(NewSearchParam(searchRequest.BaseDN, searchRequest.Filter))
+	return c.Conn.Search(searchRequest)
+}
+
+func (c *Conn) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	ldapAttackCheck(NewSearchParam(searchRequest.BaseDN, searchRequest.Filter))
+	return c.Conn.SearchWithPaging(searchRequest, pagingSize)
+}
+
+func (c *Conn) Bind(username, password string) error {
+	ldapAttackCheck(NewBindParam(username))
+	return c.Conn.Bind(username, password)
+}
+
+func (c *Conn) SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error) {
+	ldapAttackCheck(NewBindParam(simpleBindRequest.Username))
+	return c.Conn.SimpleBind(simpleBindRequest)
+}
@@ -0,0 +1,82 @@
+package orldap
+
+import (
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+const (
+	filterMetaChars = "()*\\\x00"
+	dnMetaChars     = ",=+<>#;\"\\"
+)
+
+type LdapParam struct {
+	Operation string `json:"operation"`
+	BaseDN    string `json:"base_dn,omitempty"`
+	Filter    string `json:"filter,omitempty"`
+	DN        string `json:"dn,omitempty"`
+}
+
+func NewSearchParam(baseDN, filter string) *LdapParam {
+	lp := &LdapParam{
+		Operation: "search",
+		BaseDN:    baseDN,
+		Filter:    filter,
+	}
+	return lp
+}
+
+func NewBindParam(dn string) *LdapParam {
+	lp := &LdapParam{
+		Operation: "bind",
+		DN:        dn,
+	}
+	return lp
+}
+
+func (lp *LdapParam) GetType() common.CheckType {
+	return common.Ldap
+}
+
+func (lp *LdapParam) GetTypeString() string {
+	return common.CheckTypeToString(lp.GetType())
+}
+
+func (lp *LdapParam) newAttackResult(message string) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("ldap.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", lp.GetTypeString(), 90)
+}
+
+func (lp *LdapParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(lp) {
+			return results
+		}
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return results
+	}
+	for _, value := range requestInfo.Parameters() {
+		if unescapedIn(lp.Filter, value, filterMetaChars) {
+			results = append(results, lp.newAttackResult("LDAP injection - unescaped user input in search filter: "+value))
+			break
+		}
+		if unescapedIn(lp.BaseDN, value, dnMetaChars) || unescapedIn(lp.DN, value, dnMetaChars) {
+			results = append(results, lp.newAttackResult("LDAP injection - unescaped user input in DN: "+value))
+			break
+		}
+	}
+	return results
+}
+
+// unescapedIn reports whether value carries metacharacters and shows up
+// verbatim in statement, escaped input never does
+func unescapedIn(statement, value, metaChars string) bool {
+	return len(statement) > 0 && len(value) > 0 && strings.ContainsAny(value, metaChars) && strings.Contains(statement, value)
+}
@@ -0,0 +1,50 @@
+package orldap
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/baidu-security/openrasp-golang/support/orhttptest"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnescapedIn(t *testing.T) {
+	assert.True(t, unescapedIn("(uid=*)(uid=*)", "*)(uid=*", filterMetaChars))
+	assert.False(t, unescapedIn("(uid=bob)", "bob", filterMetaChars))
+	assert.False(t, unescapedIn("(uid=\\2a\\29)", "*)", filterMetaChars))
+	assert.False(t, unescapedIn("", "*)", filterMetaChars))
+	assert.True(t, unescapedIn("cn=bob,dc=admin,ou=users,dc=example", "bob,dc=admin", dnMetaChars))
+	assert.False(t, unescapedIn("cn=bob\\,dc\\=admin,ou=users,dc=example", "bob,dc=admin", dnMetaChars))
+}
+
+func lookup(w http.ResponseWriter, req *http.Request) {
+	user := req.URL.Query().Get("user")
+	switch req.URL.Path {
+	case "/search":
+		ldapAttackCheck(NewSearchParam("ou=users,dc=example", "(&(objectClass=person)(uid="+user+"))"))
+	case "/escaped":
+		ldapAttackCheck(NewSearchParam("ou=users,dc=example", "(&(objectClass=person)(uid="+ldap.EscapeFilter(user)+"))"))
+	case "/bind":
+		ldapAttackCheck(NewBindParam("cn=" + user + ",ou=users,dc=example"))
+	}
+	w.Write([]byte("Hello " + user))
+}
+
+func serve(path, user string) *orhttptest.Result {
+	return orhttptest.Serve(http.HandlerFunc(lookup), httptest.NewRequest("GET", path+"?user="+url.QueryEscape(user), nil))
+}
+
+func TestLdapInjection(t *testing.T) {
+	orhttptest.AssertBlocked(t, serve("/search", "*)(uid=*"), "ldap")
+	orhttptest.AssertBlocked(t, serve("/search", "admin)(|(password=*"), "ldap")
+	orhttptest.AssertBlocked(t, serve("/bind", "bob,dc=admin"), "ldap")
+	orhttptest.AssertNotBlocked(t, serve("/escaped", "*)(uid=*"))
+	for _, path := range []string{"/search", "/bind"} {
+		result := serve(path, "bob")
+		orhttptest.AssertNotBlocked(t, result)
+		assert.Contains(t, result.Response.Body.String(), "Hello bob")
+	}
+}
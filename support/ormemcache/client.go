@@ -0,0 +1,116 @@
+package ormemcache
+
+import (
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Client wraps memcache.Client, checking keys and values before they are sent
+type Client struct {
+	*memcache.Client
+}
+
+func Wrap(client *memcache.Client) *Client {
+	return &Client{Client: client}
+}
+
+// New is the wrapped version of memcache.New, servers are checked against
+// the connection policy
+func New(server ...string) *Client {
+	if openrasp.HookActive(openrasp.HookMemcache) {
+		serverParam := NewServerParam(server)
+		interceptCode, policyResult := serverParam.PolicyCheck()
+		interceptCode = openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult)
+		if interceptCode != model.Ignore {
+			if policyLogString := openrasp.NewPolicyLog(policyResult, serverParam).String(); len(policyLogString) > 0 {
+				openrasp.GetLog().PolicyInfo(policyLogString)
+			}
+			if interceptCode == model.Block {
+				openrasp.BlockRequest()
+			}
+		}
+	}
+	return Wrap(memcache.New(server...))
+}
+
+func memcacheAttackCheck(memcacheParam *MemcacheParam) bool {
+	if openrasp.HookActive(openrasp.HookMemcache) && gls.Activated() {
+		if openrasp.AttackCheck(memcacheParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) Get(key string) (*memcache.Item, error) {
+	if memcacheAttackCheck(NewMemcacheParam("get", []string{key}, nil)) {
+		return nil, openrasp.ErrBlock
+	}
+	return c.Client.Get(key)
+}
+
+func (c *Client) GetMulti(keys []string) (map[string]*memcache.Item, error) {
+	if memcacheAttackCheck(NewMemcacheParam("get", keys, nil)) {
+		return nil, openrasp.ErrBlock
+	}
+	return c.Client.GetMulti(keys)
+}
+
+func (c *Client) Set(item *memcache.Item) error {
+	if memcacheAttackCheck(NewMemcacheParam("set", []string{item.Key}, item.Value)) {
+		return openrasp.ErrBlock
+	}
+	return c.Client.Set(item)
+}
+
+func (c *Client) Add(item *memcache.Item) error {
+	if memcacheAttackCheck(NewMemcacheParam("add", []string{item.Key}, item.Value)) {
+		return openrasp.ErrBlock
+	}
+	return c.Client.Add(item)
+}
+
+func (c *Client) Replace(item *memcache.Item) error {
+	if memcacheAttackCheck(NewMemcacheParam("replace", []string{item.Key}, item.Value)) {
+		return openrasp.ErrBlock
+	}
+	return c.Client.Replace(item)
+}
+
+func (c *Client) CompareAndSwap(item *memcache.Item) error {
+	if memcacheAttackCheck(NewMemcacheParam("cas", []string{item.Key}, item.Value)) {
+		return openrasp.ErrBlock
+	}
+	return c.Client.CompareAndSwap(item)
+}
+
+func (c *Client) Delete(key string) error {
+	if memcacheAttackCheck(NewMemcacheParam("delete", []string{key}, nil)) {
+		return openrasp.ErrBlock
+	}
+	return c.Client.Delete(key)
+}
+
+func (c *Client) Increment(key string, delta uint64) (uint64, error) {
+	if memcacheAttackCheck(NewMemcacheParam("incr", []string{key}, nil)) {
+		return 0, openrasp.ErrBlock
+	}
+	return c.Client.Increment(key, delta)
+}
+
+func (c *Client) Decrement(key string, delta uint64) (uint64, error) {
+	if memcacheAttackCheck(NewMemcacheParam("decr", []string{key}, nil)) {
+		return 0, openrasp.ErrBlock
+	}
+	return c.Client.Decrement(key, delta)
+}
+
+func (c *Client) Touch(key string, seconds int32) error {
+	if memcacheAttackCheck(NewMemcacheParam("touch", []string{key}, nil)) {
+		return openrasp.ErrBlock
+	}
+	return c.Client.Touch(key, seconds)
+}
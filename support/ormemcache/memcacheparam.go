@@ -0,0 +1,118 @@
+package ormemcache
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/support/ornet"
+)
+
+// commandRegex matches a text protocol command smuggled after a line break
+var commandRegex = regexp.MustCompile(`(?i)[\r\n]+\s*(set|add|replace|append|prepend|cas|get|gets|gat|gats|delete|incr|decr|touch|flush_all|stats|shutdown|verbosity)\b`)
+
+type MemcacheParam struct {
+	Command string   `json:"command"`
+	Keys    []string `json:"keys"`
+	Value   string   `json:"value,omitempty"`
+}
+
+func NewMemcacheParam(command string, keys []string, value []byte) *MemcacheParam {
+	mp := &MemcacheParam{
+		Command: command,
+		Keys:    keys,
+		Value:   string(value),
+	}
+	return mp
+}
+
+func (mp *MemcacheParam) GetType() common.CheckType {
+	return common.MemcacheInjection
+}
+
+func (mp *MemcacheParam) GetTypeString() string {
+	return common.CheckTypeToString(mp.GetType())
+}
+
+func (mp *MemcacheParam) newAttackResult(message string) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("memcache.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", mp.GetTypeString(), 90)
+}
+
+func (mp *MemcacheParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(mp) {
+			return results
+		}
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return results
+	}
+	for _, value := range requestInfo.Parameters() {
+		if len(value) == 0 {
+			continue
+		}
+		for _, key := range mp.Keys {
+			if strings.IndexFunc(value, isIllegalKeyRune) >= 0 && strings.Contains(key, value) {
+				results = append(results, mp.newAttackResult("Memcache injection - user input with whitespace or control characters in key: "+value))
+				return results
+			}
+		}
+		if commandRegex.MatchString(value) && strings.Contains(mp.Value, value) {
+			results = append(results, mp.newAttackResult("Memcache injection - user input with protocol commands in value: "+value))
+			return results
+		}
+	}
+	return results
+}
+
+func isIllegalKeyRune(r rune) bool {
+	return r <= ' ' || r == 0x7f
+}
+
+type ServerParam struct {
+	Servers []string `json:"servers"`
+}
+
+func NewServerParam(servers []string) *ServerParam {
+	sp := &ServerParam{
+		Servers: servers,
+	}
+	return sp
+}
+
+// PolicyCheck flags servers addressed by a public IP, memcached has no
+// authentication on the text protocol so such instances are open to anyone
+func (sp *ServerParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	for _, server := range sp.Servers {
+		if !isPublic(server) {
+			continue
+		}
+		pr := model.NewPolicyResult("Memcache security - Connecting to the unauthenticated memcached instance "+server+" on a public address", 3011)
+		is := model.Log
+		if openrasp.GetGeneral().GetBool("security.enforce_policy") {
+			is = model.Block
+		}
+		return is, pr
+	}
+	return model.Ignore, nil
+}
+
+// isPublic only looks at literal IPs, host names are not resolved here
+func isPublic(server string) bool {
+	if strings.Contains(server, "/") {
+		return false
+	}
+	host, _, err := net.SplitHostPort(server)
+	if err != nil {
+		host = server
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && !ip.IsUnspecified() && !ornet.IsInternal(ip)
+}
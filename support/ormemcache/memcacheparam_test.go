@@ -0,0 +1,28 @@
+package ormemcache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublic(t *testing.T) {
+	assert.True(t, isPublic("8.8.8.8:11211"))
+	assert.True(t, isPublic("[2001:4860:4860::8888]:11211"))
+	assert.False(t, isPublic("127.0.0.1:11211"))
+	assert.False(t, isPublic("10.0.0.5:11211"))
+	assert.False(t, isPublic("cache.internal:11211"))
+	assert.False(t, isPublic("/var/run/memcached.sock"))
+}
+
+func TestCommandRegex(t *testing.T) {
+	assert.True(t, commandRegex.MatchString("x\r\nflush_all"))
+	assert.True(t, commandRegex.MatchString("x\nSET k 0 0 1"))
+	assert.False(t, commandRegex.MatchString("set the table"))
+}
+
+func TestIsIllegalKeyRune(t *testing.T) {
+	assert.True(t, isIllegalKeyRune(' '))
+	assert.True(t, isIllegalKeyRune('\n'))
+	assert.False(t, isIllegalKeyRune('a'))
+}
@@ -0,0 +1,62 @@
+package ornet
+
+import (
+	"context"
+	"net"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+)
+
+type DialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+var defaultDialer = &net.Dialer{}
+
+// WrapDialContext returns a DialContextFunc applying the egress policy before
+// delegating to dial, suitable for http.Transport.DialContext and drivers
+// accepting a custom dialer. Host names are resolved once and the checked
+// address is the one dialed, so the answer cannot change in between.
+func WrapDialContext(dial DialContextFunc) DialContextFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if !openrasp.IsComplete() || !openrasp.GetHookSwitch().HookEnabled(openrasp.HookDial) {
+			return dial(ctx, network, address)
+		}
+		leave, ok := openrasp.EnterContext(ctx)
+		defer leave()
+		if !ok {
+			return dial(ctx, network, address)
+		}
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return dial(ctx, network, address)
+		}
+		ssrfParam := NewSsrfParam(network, host, port, nil)
+		if ip := net.ParseIP(host); ip != nil {
+			ssrfParam = NewSsrfParam(network, host, port, []net.IP{ip})
+		} else {
+			_, resolved, err := defaultResolver.lookup(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			ssrfParam = NewSsrfParam(network, host, port, resolved.ips)
+			ssrfParam.setPrevious(resolved.previous)
+			if len(resolved.ips) > 0 {
+				address = net.JoinHostPort(resolved.ips[0].String(), port)
+			}
+		}
+		if openrasp.AttackCheck(ssrfParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+			return nil, openrasp.ErrBlock
+		}
+		return dial(ctx, network, address)
+	}
+}
+
+// DialContext is the wrapped version of net.Dialer.DialContext with zero options
+func DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return WrapDialContext(defaultDialer.DialContext)(ctx, network, address)
+}
+
+// Dial is the wrapped version of net.Dial
+func Dial(network, address string) (net.Conn, error) {
+	return DialContext(context.Background(), network, address)
+}
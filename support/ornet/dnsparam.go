@@ -0,0 +1,115 @@
+package ornet
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+const (
+	encodedLabelMinLength = 20
+	encodedLabelEntropy   = 3.5
+)
+
+type DnsParam struct {
+	Hostname string `json:"hostname"`
+}
+
+func NewDnsParam(hostname string) *DnsParam {
+	dp := &DnsParam{
+		Hostname: strings.TrimSuffix(hostname, "."),
+	}
+	return dp
+}
+
+func (dp *DnsParam) GetType() common.CheckType {
+	return common.DnsExfiltration
+}
+
+func (dp *DnsParam) GetTypeString() string {
+	return common.CheckTypeToString(dp.GetType())
+}
+
+func (dp *DnsParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("dns.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", dp.GetTypeString(), confidence)
+}
+
+func (dp *DnsParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(dp) {
+			return results
+		}
+	}
+	if maxNameLength := openrasp.AlgorithmInt(common.DnsExfiltration, "max_name_length", "dns.max_name_length"); maxNameLength > 0 && len(dp.Hostname) > maxNameLength {
+		results = append(results, dp.newAttackResult("DNS exfiltration - querying name of "+strconv.Itoa(len(dp.Hostname))+" characters: "+dp.Hostname, 70))
+		return results
+	}
+	maxLabelLength := openrasp.AlgorithmInt(common.DnsExfiltration, "max_label_length", "dns.max_label_length")
+	for _, label := range strings.Split(dp.Hostname, ".") {
+		if maxLabelLength > 0 && len(label) > maxLabelLength {
+			results = append(results, dp.newAttackResult("DNS exfiltration - querying label of "+strconv.Itoa(len(label))+" characters: "+dp.Hostname, 70))
+			break
+		}
+		if looksEncoded(label) {
+			results = append(results, dp.newAttackResult("DNS exfiltration - querying encoded label: "+dp.Hostname, 80))
+			break
+		}
+	}
+	return results
+}
+
+// looksEncoded detects hex, base32 and base64url chunks: long labels made of
+// hex digits only, or with high entropy and few vowels or many digits unlike
+// words
+func looksEncoded(label string) bool {
+	if len(label) < encodedLabelMinLength {
+		return false
+	}
+	label = strings.ToLower(label)
+	hex, digits, vowels := 0, 0, 0
+	for _, r := range label {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+			hex++
+		case strings.ContainsRune("aeiou", r):
+			vowels++
+			if r <= 'f' {
+				hex++
+			}
+		case r >= 'a' && r <= 'f':
+			hex++
+		case r >= 'g' && r <= 'z', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	if hex == len(label) && digits > 0 && digits < len(label) {
+		return true
+	}
+	if shannonEntropy(label) < encodedLabelEntropy {
+		return false
+	}
+	length := float64(len(label))
+	return float64(digits)/length >= 0.15 || float64(vowels)/length < 0.25
+}
+
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	var entropy float64
+	length := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
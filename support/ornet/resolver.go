@@ -0,0 +1,112 @@
+package ornet
+
+import (
+	"context"
+	"net"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+var defaultResolver = WrapResolver(net.DefaultResolver)
+
+type dnsLookups map[string][]net.IP
+
+// Resolver wraps net.Resolver, the names queried in a request are recorded
+// in gls and their answers are checked against the egress policy
+type Resolver struct {
+	*net.Resolver
+}
+
+func WrapResolver(r *net.Resolver) *Resolver {
+	return &Resolver{Resolver: r}
+}
+
+func currentLookups() dnsLookups {
+	lookups, ok := gls.Get("dnsLookups").(dnsLookups)
+	if !ok {
+		lookups = make(dnsLookups)
+		gls.Set("dnsLookups", lookups)
+	}
+	return lookups
+}
+
+// lookup resolves host and runs the dns checks, the returned param is the
+// one to feed into the egress policy
+func (r *Resolver) lookup(ctx context.Context, host string) ([]net.IPAddr, *SsrfParam, error) {
+	if !openrasp.IsComplete() || !openrasp.GetHookSwitch().HookEnabled(openrasp.HookDns) {
+		addrs, err := r.Resolver.LookupIPAddr(ctx, host)
+		return addrs, nil, err
+	}
+	leave, ok := openrasp.EnterContext(ctx)
+	defer leave()
+	if !ok {
+		addrs, err := r.Resolver.LookupIPAddr(ctx, host)
+		return addrs, nil, err
+	}
+	if openrasp.AttackCheck(NewDnsParam(host), openrasp.WhitelistOption) {
+		openrasp.BlockRequest()
+		return nil, nil, openrasp.ErrBlock
+	}
+	addrs, err := r.Resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return addrs, nil, err
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	ssrfParam := NewSsrfParam("dns", host, "", ips)
+	lookups := currentLookups()
+	ssrfParam.setPrevious(lookups[host])
+	lookups[host] = ips
+	return addrs, ssrfParam, nil
+}
+
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ssrfParam, err := r.lookup(ctx, host)
+	if ssrfParam == nil {
+		return addrs, err
+	}
+	leave, _ := openrasp.EnterContext(ctx)
+	defer leave()
+	if openrasp.AttackCheck(ssrfParam, openrasp.WhitelistOption) {
+		openrasp.BlockRequest()
+		return nil, openrasp.ErrBlock
+	}
+	return addrs, err
+}
+
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	hosts := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		hosts = append(hosts, addr.String())
+	}
+	return hosts, nil
+}
+
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if openrasp.HookActive(openrasp.HookDns) {
+		leave, ok := openrasp.EnterContext(ctx)
+		defer leave()
+		if ok && openrasp.AttackCheck(NewDnsParam(name), openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+			return nil, openrasp.ErrBlock
+		}
+	}
+	return r.Resolver.LookupTXT(ctx, name)
+}
+
+// LookupIPAddr is the wrapped version of net.DefaultResolver.LookupIPAddr
+func LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return defaultResolver.LookupIPAddr(ctx, host)
+}
+
+// LookupHost is the wrapped version of net.LookupHost
+func LookupHost(host string) ([]string, error) {
+	return defaultResolver.LookupHost(context.Background(), host)
+}
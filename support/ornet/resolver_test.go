@@ -0,0 +1,34 @@
+package ornet
+
+import (
+	"context"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupRemembersAnswers(t *testing.T) {
+	gls.Initialize()
+	defer gls.Clear()
+	requestInfo := &model.RequestInfo{UrlFull: "http://example.com/fetch"}
+	gls.Set("requestInfo", requestInfo)
+	ctx := openrasp.NewContext(context.Background(), requestInfo, nil)
+	lookup := func() *SsrfParam {
+		_, ssrfParam, err := defaultResolver.lookup(ctx, "localhost")
+		assert.Nil(t, err)
+		return ssrfParam
+	}
+
+	first := lookup()
+	if assert.NotNil(t, first) {
+		assert.Empty(t, first.Previous)
+		second := lookup()
+		if assert.NotNil(t, second) {
+			assert.Equal(t, first.Ip, second.Previous)
+		}
+	}
+	assert.Equal(t, requestInfo, gls.Get("requestInfo"))
+}
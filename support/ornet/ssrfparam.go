@@ -0,0 +1,163 @@
+package ornet
+
+import (
+	"net"
+	"strconv"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+var internalNets = parseCIDRs([]string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+})
+
+type SsrfParam struct {
+	Network  string   `json:"network"`
+	Hostname string   `json:"hostname"`
+	Ip       []string `json:"ip"`
+	Port     string   `json:"port"`
+	Previous []string `json:"previous_ip,omitempty"`
+	ips      []net.IP
+	previous []net.IP
+}
+
+// a host given by the user which resolves to an internal address is a ssrf
+func init() {
+	openrasp.RegisterSelfTest(openrasp.SelfTestCase{
+		Hook:  openrasp.HookDial,
+		Name:  "ssrf",
+		Input: "169.254.169.254",
+		Benign: func() common.AttackChecker {
+			return NewSsrfParam("tcp", "example.com", "80", []net.IP{net.ParseIP("93.184.216.34")})
+		},
+		Malicious: func() common.AttackChecker {
+			return NewSsrfParam("tcp", "169.254.169.254", "80", []net.IP{net.ParseIP("169.254.169.254")})
+		},
+	})
+}
+
+func NewSsrfParam(network, hostname, port string, ips []net.IP) *SsrfParam {
+	sp := &SsrfParam{
+		Network:  network,
+		Hostname: hostname,
+		Port:     port,
+		ips:      ips,
+	}
+	for _, ip := range ips {
+		sp.Ip = append(sp.Ip, ip.String())
+	}
+	return sp
+}
+
+func (sp *SsrfParam) GetType() common.CheckType {
+	return common.Ssrf
+}
+
+func (sp *SsrfParam) GetTypeString() string {
+	return common.CheckTypeToString(sp.GetType())
+}
+
+func (sp *SsrfParam) newAttackResult(message string, confidence uint64) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("egress.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", sp.GetTypeString(), confidence)
+}
+
+func (sp *SsrfParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(sp) {
+			return results
+		}
+	}
+	if sp.rebound() {
+		results = append(results, sp.newAttackResult("SSRF - DNS rebinding, "+sp.Hostname+" resolved to external address before and internal address now", 90))
+	}
+	if allowedPorts := openrasp.AlgorithmIntSlice(common.Ssrf, "allowed_ports", "egress.allowed_ports"); len(sp.Port) > 0 && len(allowedPorts) > 0 && !portAllowed(sp.Port, allowedPorts) {
+		results = append(results, sp.newAttackResult("SSRF - outbound connection to "+sp.Hostname+" on disallowed port "+sp.Port, 80))
+	}
+	deniedNets := parseCIDRs(openrasp.AlgorithmStringSlice(common.Ssrf, "denied_cidrs", "egress.denied_cidrs"))
+	if openrasp.AlgorithmBool(common.Ssrf, "deny_internal", "egress.deny_internal") {
+		deniedNets = append(deniedNets, internalNets...)
+	}
+	for _, ip := range sp.ips {
+		if ipNet := containedBy(ip, deniedNets); ipNet != nil {
+			results = append(results, sp.newAttackResult("SSRF - outbound connection to "+sp.Hostname+" resolved to denied address "+ip.String()+" in "+ipNet.String(), 90))
+			break
+		}
+	}
+	return results
+}
+
+func (sp *SsrfParam) setPrevious(previous []net.IP) {
+	sp.previous = previous
+	for _, ip := range previous {
+		sp.Previous = append(sp.Previous, ip.String())
+	}
+}
+
+// rebound reports whether every previous answer was external while the
+// current one points inside
+func (sp *SsrfParam) rebound() bool {
+	if len(sp.previous) == 0 {
+		return false
+	}
+	for _, ip := range sp.previous {
+		if IsInternal(ip) {
+			return false
+		}
+	}
+	for _, ip := range sp.ips {
+		if IsInternal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func portAllowed(port string, allowedPorts []int) bool {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range allowedPorts {
+		if p == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func containedBy(ip net.IP, nets []*net.IPNet) *net.IPNet {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return ipNet
+		}
+	}
+	return nil
+}
+
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+// IsInternal reports whether ip belongs to loopback, private or link-local ranges
+func IsInternal(ip net.IP) bool {
+	return containedBy(ip, internalNets) != nil
+}
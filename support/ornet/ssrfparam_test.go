@@ -0,0 +1,40 @@
+package ornet
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInternal(t *testing.T) {
+	assert.True(t, IsInternal(net.ParseIP("127.0.0.1")))
+	assert.True(t, IsInternal(net.ParseIP("169.254.169.254")))
+	assert.True(t, IsInternal(net.ParseIP("::1")))
+	assert.False(t, IsInternal(net.ParseIP("8.8.8.8")))
+}
+
+func TestPortAllowed(t *testing.T) {
+	assert.True(t, portAllowed("443", []int{80, 443}))
+	assert.False(t, portAllowed("6379", []int{80, 443}))
+	assert.False(t, portAllowed("http", []int{80}))
+}
+
+func TestLooksEncoded(t *testing.T) {
+	assert.True(t, looksEncoded("4a6f686e446f653a50617373773072643132333435"))
+	assert.True(t, looksEncoded("mzxw6ytboi2dkmzwgqytsnrx"))
+	// base64url with '-' and '_'
+	assert.True(t, looksEncoded("dXNlcj1hZG1pbiZwYXNzPX5-aHVudGVyMj4-Pw"))
+	assert.True(t, looksEncoded("c2Vzc2lvbl90b2tlbl9zZWNyZXQ_YWJj"))
+	assert.False(t, looksEncoded("www"))
+	assert.False(t, looksEncoded("aaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	assert.False(t, looksEncoded("thisisaverylongsubdomainname"))
+	assert.False(t, looksEncoded("cdn-images-production-eu"))
+}
+
+func TestRebound(t *testing.T) {
+	sp := NewSsrfParam("dns", "rebind.example", "", []net.IP{net.ParseIP("127.0.0.1")})
+	sp.setPrevious([]net.IP{net.ParseIP("93.184.216.34")})
+	assert.True(t, sp.rebound())
+	assert.False(t, NewSsrfParam("dns", "a.example", "", []net.IP{net.ParseIP("127.0.0.1")}).rebound())
+}
@@ -0,0 +1,27 @@
+package orplugin
+
+import (
+	"plugin"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+// Open wraps plugin.Open, the load is refused with openrasp.ErrBlock when
+// security.enforce_policy is on and the policy check fails
+func Open(path string) (*plugin.Plugin, error) {
+	if openrasp.HookActive(openrasp.HookPlugin) {
+		pluginParam := NewPluginParam(path)
+		interceptCode, policyResult := pluginParam.PolicyCheck()
+		interceptCode = openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult)
+		if interceptCode != model.Ignore {
+			if policyLogString := openrasp.NewPolicyLog(policyResult, pluginParam).String(); len(policyLogString) > 0 {
+				openrasp.GetLog().PolicyInfo(policyLogString)
+			}
+			if interceptCode == model.Block {
+				return nil, openrasp.ErrBlock
+			}
+		}
+	}
+	return plugin.Open(path)
+}
@@ -0,0 +1,76 @@
+package orplugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+type PluginParam struct {
+	Path     string `json:"path"`
+	RealPath string `json:"realpath"`
+}
+
+func NewPluginParam(path string) *PluginParam {
+	pp := &PluginParam{
+		Path:     path,
+		RealPath: realPath(path),
+	}
+	return pp
+}
+
+func realPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		return resolved
+	}
+	return absPath
+}
+
+// PolicyCheck flags shared objects loaded from outside plugin.allowed_dirs,
+// or from world writable directories when no whitelist is configured
+func (pp *PluginParam) PolicyCheck() (model.InterceptCode, *model.PolicyResult) {
+	dir := filepath.Dir(pp.RealPath)
+	allowedDirs := openrasp.GetGeneral().GetStringSlice("plugin.allowed_dirs")
+	var msg string
+	if len(allowedDirs) > 0 {
+		if !isInside(dir, allowedDirs) {
+			msg = "Plugin loading - Loading shared object " + pp.RealPath + " from a directory outside of plugin.allowed_dirs"
+		}
+	} else if worldWritable(dir) {
+		msg = "Plugin loading - Loading shared object " + pp.RealPath + " from the world writable directory " + dir
+	}
+	if len(msg) == 0 {
+		return model.Ignore, nil
+	}
+	pr := model.NewPolicyResult(msg, 3010)
+	is := model.Log
+	if openrasp.GetGeneral().GetBool("security.enforce_policy") {
+		is = model.Block
+	}
+	return is, pr
+}
+
+func isInside(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(realPath(dir), path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func worldWritable(dir string) bool {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+	return fi.Mode().Perm()&0002 != 0
+}
@@ -0,0 +1,27 @@
+package orplugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInside(t *testing.T) {
+	assert.True(t, isInside("/opt/app/plugins", []string{"/opt/app/plugins"}))
+	assert.True(t, isInside("/opt/app/plugins/v1", []string{"/opt/app"}))
+	assert.False(t, isInside("/opt/app-evil", []string{"/opt/app"}))
+}
+
+func TestWorldWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orplugin")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, os.Chmod(dir, 0755))
+	assert.False(t, worldWritable(dir))
+	assert.Nil(t, os.Chmod(dir, 0777))
+	assert.True(t, worldWritable(dir))
+	assert.False(t, worldWritable(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,94 @@
+package orprometheus
+
+import (
+	"net/http"
+	"time"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	requestsDesc = prometheus.NewDesc(
+		"openrasp_requests_inspected_total",
+		"Requests which went through detection.",
+		nil, nil,
+	)
+	attacksDesc = prometheus.NewDesc(
+		"openrasp_attacks_total",
+		"Attacks detected by check type and the action taken.",
+		[]string{"check_type", "action"}, nil,
+	)
+	checkDurationDesc = prometheus.NewDesc(
+		"openrasp_check_duration_seconds",
+		"Time spent in the checks by check type.",
+		[]string{"check_type"}, nil,
+	)
+	queueDepthDesc = prometheus.NewDesc(
+		"openrasp_log_queue_depth",
+		"Log entries waiting in the async queue of a destination.",
+		[]string{"destination"}, nil,
+	)
+	droppedDesc = prometheus.NewDesc(
+		"openrasp_log_dropped_total",
+		"Log entries dropped by channel and reason.",
+		[]string{"channel", "reason"}, nil,
+	)
+)
+
+// Collector exposes the statistics of the agent and of its log manager, it
+// reads them at every scrape and keeps no state of its own
+type Collector struct{}
+
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- requestsDesc
+	ch <- attacksDesc
+	ch <- checkDurationDesc
+	ch <- queueDepthDesc
+	ch <- droppedDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	snapshot := openrasp.GetStatistics().Snapshot()
+	ch <- prometheus.MustNewConstMetric(requestsDesc, prometheus.CounterValue, float64(snapshot.Requests))
+	for key, count := range snapshot.Attacks {
+		ch <- prometheus.MustNewConstMetric(attacksDesc, prometheus.CounterValue, float64(count), key.CheckType, key.Action)
+	}
+	for checkType, h := range snapshot.Latency {
+		buckets := make(map[float64]uint64)
+		for _, bucket := range h.Buckets() {
+			buckets[bucket.UpperBound.Seconds()] = uint64(bucket.Count)
+		}
+		ch <- prometheus.MustNewConstHistogram(checkDurationDesc, uint64(h.Count()), h.Sum().Seconds(), buckets, checkType)
+	}
+	lm := openrasp.GetLog()
+	if lm == nil {
+		return
+	}
+	for destination, depth := range lm.QueueDepth() {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth), destination)
+	}
+	for channel, totals := range lm.Dropped() {
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(totals.RateLimited), channel, "rate_limit")
+		ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(totals.Overflowed), channel, "queue_overflow")
+	}
+}
+
+// Handler serves the metrics of a registry holding only the Collector, for
+// applications which do not expose prometheus metrics themselves. The others
+// register NewCollector with their own registry.
+func Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector())
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+		Timeout:       10 * time.Second,
+	})
+}
@@ -0,0 +1,89 @@
+package orprometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func gather(t *testing.T) map[string]*dto.MetricFamily {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector())
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	byName := make(map[string]*dto.MetricFamily)
+	for _, family := range families {
+		byName[family.GetName()] = family
+	}
+	return byName
+}
+
+func labelValue(m *dto.Metric, name string) string {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == name {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
+func TestCollect(t *testing.T) {
+	before := gather(t)
+	openrasp.GetStatistics().AddRequest()
+	openrasp.GetStatistics().AddAttack("xss_userinput", "block")
+	openrasp.GetStatistics().AddCheck("xss_userinput", 300*time.Microsecond, 1, true)
+	after := gather(t)
+
+	requests := func(families map[string]*dto.MetricFamily) float64 {
+		return families["openrasp_requests_inspected_total"].GetMetric()[0].GetCounter().GetValue()
+	}
+	assert.Equal(t, requests(before)+1, requests(after))
+
+	var attacks float64
+	for _, m := range after["openrasp_attacks_total"].GetMetric() {
+		if labelValue(m, "check_type") == "xss_userinput" && labelValue(m, "action") == "block" {
+			attacks = m.GetCounter().GetValue()
+		}
+	}
+	assert.True(t, attacks >= 1)
+
+	var histogram *dto.Histogram
+	for _, m := range after["openrasp_check_duration_seconds"].GetMetric() {
+		if labelValue(m, "check_type") == "xss_userinput" {
+			histogram = m.GetHistogram()
+		}
+	}
+	if assert.NotNil(t, histogram) {
+		assert.True(t, histogram.GetSampleCount() >= 1)
+		assert.True(t, histogram.GetSampleSum() >= 300e-6)
+		for _, bucket := range histogram.GetBucket() {
+			if bucket.GetUpperBound() < 256e-6 {
+				assert.Equal(t, uint64(0), bucket.GetCumulativeCount())
+			}
+		}
+	}
+
+	dropped := after["openrasp_log_dropped_total"]
+	if assert.NotNil(t, dropped) {
+		reasons := make(map[string]bool)
+		for _, m := range dropped.GetMetric() {
+			reasons[labelValue(m, "reason")] = true
+		}
+		assert.Equal(t, map[string]bool{"rate_limit": true, "queue_overflow": true}, reasons)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rec.Code)
+	body, _ := ioutil.ReadAll(rec.Body)
+	assert.Contains(t, string(body), "openrasp_requests_inspected_total")
+	assert.Contains(t, string(body), "openrasp_log_dropped_total")
+}
@@ -0,0 +1,135 @@
+package orreplay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Record is a request recovered from the attack logs, with the attacks
+// logged for it. The body is the one of the log, truncated to body.maxbytes.
+type Record struct {
+	RequestId string
+	Method    string
+	Url       string
+	Header    map[string]string
+	Body      string
+	Form      url.Values
+	Attacks   []Attack
+}
+
+// Attack is a detection logged or replayed for a request
+type Attack struct {
+	AttackType      string `json:"attack_type"`
+	PluginName      string `json:"plugin_name"`
+	PluginAlgorithm string `json:"plugin_algorithm"`
+	PluginMessage   string `json:"plugin_message"`
+	InterceptState  string `json:"intercept_state"`
+}
+
+// Blocked reports whether any of the attacks blocks the request
+func (r *Record) Blocked() bool {
+	return blocked(r.Attacks)
+}
+
+func blocked(attacks []Attack) bool {
+	for _, attack := range attacks {
+		if attack.InterceptState == "block" {
+			return true
+		}
+	}
+	return false
+}
+
+// logEntry holds the fields of an AttackLog, of any schema version, needed
+// to replay its request
+type logEntry struct {
+	Attack
+	RequestId string            `json:"request_id"`
+	Method    string            `json:"request_method"`
+	Url       string            `json:"url"`
+	Header    map[string]string `json:"header"`
+	Body      string            `json:"body"`
+	Form      url.Values        `json:"form"`
+}
+
+// ReadRecords reads the attack logs of r, one JSON per line like alarm.log,
+// into the requests they were logged for, in the order of their first log.
+// The logs of a request share its request_id, the lines which are not the
+// log of a request, such as policy logs, are skipped.
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+	byId := make(map[string]*Record)
+	reader := bufio.NewReader(r)
+	for lineNum := 1; ; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var entry logEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNum, jsonErr)
+			}
+			if len(entry.Method) > 0 {
+				record, ok := byId[entry.RequestId]
+				if !ok || len(entry.RequestId) == 0 {
+					record = &Record{
+						RequestId: entry.RequestId,
+						Method:    entry.Method,
+						Url:       entry.Url,
+						Header:    entry.Header,
+						Body:      entry.Body,
+						Form:      entry.Form,
+					}
+					byId[entry.RequestId] = record
+					records = append(records, record)
+				}
+				record.Attacks = append(record.Attacks, entry.Attack)
+			}
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+// NewRequest builds the request of r sent to target, the scheme and host of
+// a base url like http://staging:8080, or to the logged url without target
+func (r *Record) NewRequest(target string) (*http.Request, error) {
+	u, err := url.Parse(r.Url)
+	if err != nil {
+		return nil, err
+	}
+	if len(target) > 0 {
+		base, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		u.Scheme, u.Host = base.Scheme, base.Host
+	}
+	body := r.Body
+	if len(body) == 0 && len(r.Form) > 0 {
+		body = r.Form.Encode()
+	}
+	req, err := http.NewRequest(r.Method, u.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range r.Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Host", "Content-Length", "Connection", "Transfer-Encoding":
+		default:
+			req.Header.Set(key, value)
+		}
+	}
+	if len(r.Body) == 0 && len(r.Form) > 0 && len(req.Header.Get("Content-Type")) == 0 {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	return req, nil
+}
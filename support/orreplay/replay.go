@@ -0,0 +1,173 @@
+package orreplay
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/support/orhttp"
+)
+
+// Result is the outcome of a replayed record
+type Result struct {
+	Record     *Record
+	StatusCode int
+	// Blocked tells whether the replayed request was blocked
+	Blocked bool
+	// Attacks are the detections of the replay, only known offline
+	Attacks []Attack
+	Err     error
+}
+
+// Changed reports whether the replay blocks the request while the logs did
+// not, or the other way round
+func (r *Result) Changed() bool {
+	return r.Err == nil && r.Blocked != r.Record.Blocked()
+}
+
+// Client replays records against a running instance protected by openrasp,
+// typically a staging one, a response with BlockStatusCode tells the request
+// was blocked. Redirects are not followed since openrasp blocks with one by
+// default.
+type Client struct {
+	// Target is the base url of the instance, e.g. http://staging:8080
+	Target          string
+	BlockStatusCode int
+	HTTPClient      *http.Client
+}
+
+// NewClient returns a client for target which recognizes blocks by the
+// block.status_code of the local config
+func NewClient(target string) *Client {
+	return &Client{
+		Target:          target,
+		BlockStatusCode: openrasp.GetGeneral().GetInt("block.status_code"),
+		HTTPClient: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Replay sends the records one after the other
+func (c *Client) Replay(records []*Record) []*Result {
+	results := make([]*Result, 0, len(records))
+	for _, record := range records {
+		results = append(results, c.replay(record))
+	}
+	return results
+}
+
+func (c *Client) replay(record *Record) *Result {
+	result := &Result{Record: record}
+	req, err := record.NewRequest(c.Target)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+	result.Blocked = resp.StatusCode == c.BlockStatusCode
+	return result
+}
+
+// replayHeader carries the id of an offline replay, to route its detections
+// back to the replay
+const replayHeader = "X-Openrasp-Replay"
+
+var (
+	replayId     uint64
+	onDetectOnce sync.Once
+	// replaying holds the results of the running replays by id
+	replaying = make(map[string]*Result)
+	resultsMu sync.Mutex
+)
+
+// Engine re-runs records through the detection engine of this process, with
+// its plugins and config, so that rule changes can be validated without a
+// running instance. The requests go to Handler wrapped by orhttp, which only
+// triggers the checks of the request itself, a handler reading the body by
+// default. The hooks of the application such as sql are not reached unless
+// Handler calls them, and sampling and the whitelist still apply.
+type Engine struct {
+	Handler http.Handler
+}
+
+// NewEngine returns an engine serving the records with a handler reading
+// their body
+func NewEngine() *Engine {
+	return &Engine{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			io.Copy(ioutil.Discard, req.Body)
+		}),
+	}
+}
+
+// Replay serves the records one after the other and collects the attacks
+// detected for each of them
+func (e *Engine) Replay(records []*Record) []*Result {
+	onDetectOnce.Do(func() {
+		openrasp.OnDetect(collectAttack)
+	})
+	handler := orhttp.Wrap(e.Handler)
+	results := make([]*Result, 0, len(records))
+	for _, record := range records {
+		results = append(results, replayOffline(handler, record))
+	}
+	return results
+}
+
+func replayOffline(handler http.Handler, record *Record) *Result {
+	result := &Result{Record: record}
+	req, err := record.NewRequest("")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	id := strconv.FormatUint(atomic.AddUint64(&replayId, 1), 10)
+	req.Header.Set(replayHeader, id)
+	resultsMu.Lock()
+	replaying[id] = result
+	resultsMu.Unlock()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	delete(replaying, id)
+	result.StatusCode = rec.Code
+	result.Blocked = blocked(result.Attacks)
+	return result
+}
+
+// collectAttack adds attackLog to the result of the replay it belongs to
+func collectAttack(attackLog *model.AttackLog) {
+	if attackLog.RequestInfo == nil || attackLog.AttackResult == nil {
+		return
+	}
+	resultsMu.Lock()
+	defer resultsMu.Unlock()
+	result, ok := replaying[attackLog.RequestInfo.Header[replayHeader]]
+	if !ok {
+		return
+	}
+	result.Attacks = append(result.Attacks, Attack{
+		AttackType:      attackLog.AttackType,
+		PluginName:      attackLog.PluginName,
+		PluginAlgorithm: attackLog.PluginAlgorithm,
+		PluginMessage:   attackLog.PluginMessage,
+		InterceptState:  attackLog.InterceptState,
+	})
+}
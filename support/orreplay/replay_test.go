@@ -0,0 +1,109 @@
+package orreplay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const logs = `{"request_id":"r1","request_method":"POST","url":"/login?next=%2F","header":{"X-Replay-Test":"attack","Content-Length":"9"},"body":"","form":{"user":["admin'--"]},"attack_type":"request","plugin_name":"replay_test","plugin_algorithm":"replay_test","plugin_message":"replay test","intercept_state":"block","schema_version":2}
+{"request_id":"r1","request_method":"POST","url":"/login?next=%2F","header":{"X-Replay-Test":"attack"},"body":"","form":{"user":["admin'--"]},"attack_type":"sql","plugin_name":"official","plugin_algorithm":"sqli_userinput","plugin_message":"sql injection","intercept_state":"log"}
+
+{"policy_id":"3006","message":"weak password"}
+{"request_id":"r2","request_method":"GET","url":"/search?q=x","header":{"User-Agent":"curl"},"body":"","form":null,"attack_type":"xss_userinput","plugin_name":"official","plugin_algorithm":"xss_userinput","plugin_message":"xss","intercept_state":"log"}
+`
+
+func TestReadRecords(t *testing.T) {
+	records, err := ReadRecords(strings.NewReader(logs))
+	assert.Nil(t, err)
+	if !assert.Len(t, records, 2) {
+		return
+	}
+	assert.Equal(t, "r1", records[0].RequestId)
+	assert.Equal(t, "POST", records[0].Method)
+	assert.Len(t, records[0].Attacks, 2)
+	assert.Equal(t, "sqli_userinput", records[0].Attacks[1].PluginAlgorithm)
+	assert.True(t, records[0].Blocked())
+	assert.Equal(t, "r2", records[1].RequestId)
+	assert.False(t, records[1].Blocked())
+
+	_, err = ReadRecords(strings.NewReader("{\"request_id\":\"r1\"}\nnot json\n"))
+	assert.EqualError(t, err, "line 2: invalid character 'o' in literal null (expecting 'u')")
+}
+
+func TestNewRequest(t *testing.T) {
+	records, _ := ReadRecords(strings.NewReader(logs))
+	req, err := records[0].NewRequest("http://staging:8080")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "http://staging:8080/login?next=%2F", req.URL.String())
+	assert.Equal(t, "attack", req.Header.Get("X-Replay-Test"))
+	assert.Empty(t, req.Header.Get("Content-Length"))
+	assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, "user=admin%27--", string(body))
+}
+
+func TestClientReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Replay-Test") == "attack" {
+			http.Redirect(w, req, "/blocked", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	records, _ := ReadRecords(strings.NewReader(logs))
+	client := NewClient(server.URL)
+	assert.Equal(t, http.StatusFound, client.BlockStatusCode)
+	results := client.Replay(records)
+	if assert.Len(t, results, 2) {
+		assert.Nil(t, results[0].Err)
+		assert.True(t, results[0].Blocked)
+		assert.False(t, results[0].Changed())
+		assert.Equal(t, http.StatusOK, results[1].StatusCode)
+		assert.False(t, results[1].Blocked)
+	}
+}
+
+type replayChecker struct{}
+
+func (replayChecker) CheckType() common.CheckType {
+	return common.Request
+}
+
+func (replayChecker) Check(params common.AttackChecker) *model.AttackResult {
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok || requestInfo.Header["X-Replay-Test"] != "attack" {
+		return nil
+	}
+	return model.NewAttackResult("log", "replay test", "replay_test", "replay_test", 90)
+}
+
+func init() {
+	openrasp.RegisterChecker(replayChecker{})
+}
+
+func TestEngineReplay(t *testing.T) {
+	records, _ := ReadRecords(strings.NewReader(logs))
+	results := NewEngine().Replay(records)
+	if !assert.Len(t, results, 2) {
+		return
+	}
+	assert.Nil(t, results[0].Err)
+	if assert.Len(t, results[0].Attacks, 1) {
+		assert.Equal(t, "replay_test", results[0].Attacks[0].PluginAlgorithm)
+		assert.Equal(t, "log", results[0].Attacks[0].InterceptState)
+	}
+	assert.False(t, results[0].Blocked)
+	assert.True(t, results[0].Changed())
+	assert.Empty(t, results[1].Attacks)
+	assert.False(t, results[1].Changed())
+}
@@ -0,0 +1,78 @@
+package orsmtp
+
+import (
+	"bytes"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+type MailParam struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject,omitempty"`
+	Header  string   `json:"header,omitempty"`
+}
+
+func NewMailParam(from string, to []string, subject string, msg []byte) *MailParam {
+	mp := &MailParam{
+		From:    from,
+		To:      to,
+		Subject: subject,
+		Header:  string(headerSection(msg)),
+	}
+	return mp
+}
+
+// headerSection returns the part of msg before the first empty line
+func headerSection(msg []byte) []byte {
+	if i := bytes.Index(msg, []byte("\r\n\r\n")); i >= 0 {
+		return msg[:i]
+	}
+	if i := bytes.Index(msg, []byte("\n\n")); i >= 0 {
+		return msg[:i]
+	}
+	return msg
+}
+
+func (mp *MailParam) GetType() common.CheckType {
+	return common.MailHeaderInjection
+}
+
+func (mp *MailParam) GetTypeString() string {
+	return common.CheckTypeToString(mp.GetType())
+}
+
+func (mp *MailParam) newAttackResult(message string) *model.AttackResult {
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("mail.action"))
+	return model.NewAttackResult(model.InterceptCodeToString(ic), message, "go_builtin_plugin", mp.GetTypeString(), 90)
+}
+
+func (mp *MailParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(mp) {
+			return results
+		}
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return results
+	}
+	fields := append([]string{mp.From, mp.Subject, mp.Header}, mp.To...)
+	for _, value := range requestInfo.Parameters() {
+		if !strings.ContainsAny(value, "\r\n") {
+			continue
+		}
+		for _, field := range fields {
+			if len(field) > 0 && strings.Contains(field, value) {
+				results = append(results, mp.newAttackResult("Mail header injection - user input with line breaks in mail headers: "+value))
+				return results
+			}
+		}
+	}
+	return results
+}
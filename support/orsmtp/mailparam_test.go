@@ -0,0 +1,19 @@
+package orsmtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderSection(t *testing.T) {
+	assert.Equal(t, "Subject: hi", string(headerSection([]byte("Subject: hi\r\n\r\nbody"))))
+	assert.Equal(t, "Subject: hi", string(headerSection([]byte("Subject: hi\n\nbody"))))
+	assert.Equal(t, "Subject: hi", string(headerSection([]byte("Subject: hi"))))
+}
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage("a@example.com", []string{"b@example.com"}, "hi\r\nBcc: c@example.com", "body")
+	assert.Nil(t, err)
+	assert.Equal(t, "From: a@example.com\r\nTo: b@example.com\r\nSubject: hiBcc: c@example.com\r\n\r\nbody", string(msg))
+}
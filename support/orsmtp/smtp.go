@@ -0,0 +1,47 @@
+package orsmtp
+
+import (
+	"net/smtp"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+func mailAttackCheck(mailParam *MailParam) bool {
+	if openrasp.HookActive(openrasp.HookMail) && gls.Activated() {
+		if openrasp.AttackCheck(mailParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+			return true
+		}
+	}
+	return false
+}
+
+// SendMail is the wrapped version of smtp.SendMail, the envelope and the
+// header section of msg are checked before sending
+func SendMail(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	if mailAttackCheck(NewMailParam(from, to, "", msg)) {
+		return openrasp.ErrBlock
+	}
+	return smtp.SendMail(addr, a, from, to, msg)
+}
+
+// NewMessage builds a plain text message for SendMail, it returns
+// openrasp.ErrBlock when a header field carries injected line breaks
+func NewMessage(from string, to []string, subject, body string) ([]byte, error) {
+	if mailAttackCheck(NewMailParam(from, to, subject, nil)) {
+		return nil, openrasp.ErrBlock
+	}
+	var b strings.Builder
+	b.WriteString("From: " + stripLineBreaks(from) + "\r\n")
+	b.WriteString("To: " + stripLineBreaks(strings.Join(to, ", ")) + "\r\n")
+	b.WriteString("Subject: " + stripLineBreaks(subject) + "\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(body)
+	return []byte(b.String()), nil
+}
+
+func stripLineBreaks(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -4,14 +4,8 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
-	"strings"
 
 	openrasp "github.com/baidu-security/openrasp-golang"
-	"github.com/baidu-security/openrasp-golang/gls"
-	"github.com/baidu-security/openrasp-golang/model"
-	"github.com/baidu-security/openrasp-golang/stacktrace"
-	"github.com/baidu-security/openrasp-golang/support/orhttp"
-	"github.com/baidu-security/openrasp-golang/utils"
 )
 
 func newConn(in driver.Conn, d *wrapDriver, dsnInfo DSNInfo) driver.Conn {
@@ -55,45 +49,18 @@ func (c *conn) interceptError(param string, resultError *error) {
 	c.driver.interceptError(param, resultError)
 }
 
-func (c *conn) queryAttackCheck(query string) {
-	sqlParam := NewSqlParam(c.driver.driverName, query)
-	shouldBlock := false
-	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
-	if ok {
-		attackResults := sqlParam.AttackCheck(openrasp.WhitelistOption)
-		for _, attackResult := range attackResults {
-			if interceptCode := attackResult.GetInterceptState(); interceptCode != model.Ignore {
-				attackLog := model.AttackLog{
-					AttackResult: attackResult,
-					Server:       openrasp.GetGlobals().Server,
-					System:       openrasp.GetGlobals().System,
-					RequestInfo:  requestInfo,
-					AttackParams: sqlParam,
-					SourceCode:   []string{},
-					StackTrace:   strings.Join(stacktrace.LogFormat(stacktrace.AppendStacktrace(nil, 1, openrasp.GetGeneral().GetInt("log.maxstack"))), "\n"),
-					RaspId:       openrasp.GetGlobals().RaspId,
-					AppId:        openrasp.GetBasic().GetString("cloud.app_id"),
-					ServerIp:     openrasp.GetGlobals().HttpAddr,
-					EventTime:    utils.CurrentISO8601Time(),
-					EventType:    "attack",
-					AttackType:   sqlParam.GetTypeString(),
-				}
-				attackLogString := attackLog.String()
-				if len(attackLogString) > 0 {
-					openrasp.GetLog().AlarmInfo(attackLogString)
-				}
-				if interceptCode == model.Block {
-					shouldBlock = true
-				}
-			}
-		}
+// queryAttackCheck returns ErrBlock when the query is blocked on a goroutine
+// which must not be interrupted by a panic
+func (c *conn) queryAttackCheck(query string) error {
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return nil
 	}
-	if shouldBlock {
-		blocker, ok := gls.Get("responseWriter").(orhttp.OpenRASPBlocker)
-		if ok {
-			blocker.BlockByOpenRASP()
-		}
+	sqlParam := NewSqlParam(c.driver.driverName, query)
+	defer sqlParam.Release()
+	if openrasp.AttackCheck(sqlParam, openrasp.WhitelistOption) {
+		return openrasp.Block()
 	}
+	return nil
 }
 
 func (c *conn) Ping(ctx context.Context) (resultError error) {
@@ -103,13 +70,24 @@ func (c *conn) Ping(ctx context.Context) (resultError error) {
 	return c.pinger.Ping(ctx)
 }
 
-func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Rows, resultError error) {
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	if c.queryerContext == nil && c.queryer == nil {
 		return nil, driver.ErrSkip
 	}
-	c.queryAttackCheck(query)
-	defer c.interceptError(query, &resultError)
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return c.queryContext(ctx, query, args)
+	}
+	leave, _ := openrasp.EnterContext(ctx)
+	defer leave()
+	if err := c.queryAttackCheck(query); err != nil {
+		return nil, err
+	}
+	rows, err := c.queryContext(ctx, query, args)
+	c.interceptError(query, &err)
+	return rows, err
+}
 
+func (c *conn) queryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	if c.queryerContext != nil {
 		return c.queryerContext.QueryContext(ctx, query, args)
 	}
@@ -129,9 +107,21 @@ func (*conn) Query(query string, args []driver.Value) (driver.Rows, error) {
 	return nil, errors.New("Query should never be called")
 }
 
-func (c *conn) PrepareContext(ctx context.Context, query string) (_ driver.Stmt, resultError error) {
-	c.queryAttackCheck(query)
-	defer c.interceptError(query, &resultError)
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return c.prepareContext(ctx, query)
+	}
+	leave, _ := openrasp.EnterContext(ctx)
+	defer leave()
+	if err := c.queryAttackCheck(query); err != nil {
+		return nil, err
+	}
+	stmt, err := c.prepareContext(ctx, query)
+	c.interceptError(query, &err)
+	return stmt, err
+}
+
+func (c *conn) prepareContext(ctx context.Context, query string) (driver.Stmt, error) {
 	var stmt driver.Stmt
 	var err error
 	if c.connPrepareContext != nil {
@@ -153,13 +143,24 @@ func (c *conn) PrepareContext(ctx context.Context, query string) (_ driver.Stmt,
 	return stmt, err
 }
 
-func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Result, resultError error) {
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	if c.execerContext == nil && c.execer == nil {
 		return nil, driver.ErrSkip
 	}
-	c.queryAttackCheck(query)
-	defer c.interceptError(query, &resultError)
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return c.execContext(ctx, query, args)
+	}
+	leave, _ := openrasp.EnterContext(ctx)
+	defer leave()
+	if err := c.queryAttackCheck(query); err != nil {
+		return nil, err
+	}
+	result, err := c.execContext(ctx, query, args)
+	c.interceptError(query, &err)
+	return result, err
+}
 
+func (c *conn) execContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	if c.execerContext != nil {
 		return c.execerContext.ExecContext(ctx, query, args)
 	}
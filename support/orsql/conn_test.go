@@ -0,0 +1,135 @@
+package orsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{}, nil
+}
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return nil, nil
+}
+
+func disableSqlHook(tb testing.TB) func() {
+	assert.Nil(tb, openrasp.GetGeneral().Update(map[string]interface{}{"hook.sql.enable": false}))
+	return func() {
+		assert.Nil(tb, openrasp.GetGeneral().Update(map[string]interface{}{"hook.sql.enable": true}))
+	}
+}
+
+func openFakeConn(tb testing.TB) driver.QueryerContext {
+	c, err := newWrapDriver(fakeDriver{}).Open("")
+	assert.Nil(tb, err)
+	return c.(driver.QueryerContext)
+}
+
+func TestQueryContextHookDisabled(t *testing.T) {
+	defer disableSqlHook(t)()
+	assert.False(t, openrasp.HookActive(openrasp.HookSql))
+	c := openFakeConn(t)
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		c.QueryContext(ctx, "SELECT 1", nil)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func BenchmarkQueryContextHookDisabled(b *testing.B) {
+	defer disableSqlHook(b)()
+	c := openFakeConn(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.QueryContext(ctx, "SELECT 1", nil)
+	}
+}
+
+func TestSqlParamRelease(t *testing.T) {
+	sp := NewSqlParam("mysql", "SELECT 1")
+	sp.Retain()
+	sp.Release()
+	assert.Equal(t, "SELECT 1", sp.Query)
+
+	sp = NewSqlParam("mysql", "SELECT 2")
+	sp.Release()
+	assert.Empty(t, sp.Query)
+	assert.Equal(t, `{"query":"SELECT 3","server":"mysql"}`, string(NewSqlParam("mysql", "SELECT 3").Bytes()))
+}
+
+func TestRegister(t *testing.T) {
+	_, ok := loadDriver("fake")
+	assert.False(t, ok)
+	Register("fake", fakeDriver{}, DriverNameWrap("fake"))
+	d, ok := loadDriver("fake")
+	assert.True(t, ok)
+	assert.Equal(t, "fake", d.driverName)
+	assert.Equal(t, DSNInfo{}, DriverDSNParser("fake")("dsn"))
+}
+
+func BenchmarkDriverDSNParser(b *testing.B) {
+	if _, ok := loadDriver("fake-bench"); !ok {
+		Register("fake-bench", fakeDriver{})
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			DriverDSNParser("fake-bench")
+		}
+	})
+}
+
+func TestAsyncDetection(t *testing.T) {
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"detect.async.types": []string{"sql"}}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"detect.async.types": []string{}}))
+	}()
+	assert.True(t, openrasp.GetAsyncDetector().Async(common.Sql))
+	assert.False(t, openrasp.GetAsyncDetector().Async(common.ReadFile))
+
+	gls.Initialize()
+	defer gls.Clear()
+	gls.Set("requestInfo", &model.RequestInfo{UrlFull: "/"})
+	sp := NewSqlParam("mysql", "SELECT 1")
+	assert.False(t, openrasp.AttackCheck(sp))
+	// the param stays with the worker
+	sp.Release()
+	assert.Equal(t, "SELECT 1", sp.Query)
+}
+
+func TestLatencyBudget(t *testing.T) {
+	assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"latency.budget_micros": 1000, "latency.budget_window": 2}))
+	defer func() {
+		assert.Nil(t, openrasp.GetGeneral().Update(map[string]interface{}{"latency.budget_micros": 0, "latency.budget_window": 1000}))
+	}()
+	openrasp.GetStatistics().AddCheck("ldap", 100*time.Microsecond, 0, false)
+	openrasp.GetStatistics().AddCheck("ldap", 200*time.Microsecond, 0, false)
+	assert.False(t, openrasp.GetAsyncDetector().Async(common.Ldap), "the 90th percentile of the first window is within budget")
+	openrasp.GetStatistics().AddCheck("ldap", 5*time.Millisecond, 0, false)
+	openrasp.GetStatistics().AddCheck("ldap", 5*time.Millisecond, 0, false)
+	assert.True(t, openrasp.GetAsyncDetector().Async(common.Ldap))
+
+	latency := openrasp.GetStatistics().Latency()["ldap"]
+	assert.Equal(t, int64(4), latency.Count)
+	assert.Equal(t, 8192*time.Microsecond, latency.P90)
+	assert.True(t, latency.Downgraded)
+}
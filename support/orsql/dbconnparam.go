@@ -32,5 +32,20 @@ func (dcp *DbConnectionParam) PolicyCheck() (model.InterceptCode, *model.PolicyR
 		}
 		return is, pr
 	}
+	// a DSN the driver can't parse has no user, and the socket of a local
+	// instance is often authenticated without password
+	if dcp.hasPassword() && openrasp.GetWeakPasswords().IsWeak(dcp.DSNInfo.User, dcp.DSNInfo.Password) {
+		msg := "Database security - Connecting to a " + dcp.Server + " instance with a weak password, account: " + dcp.DSNInfo.User
+		pr := model.NewPolicyResult(msg, 3003)
+		is := model.Log
+		if enforcePolicy {
+			is = model.Block
+		}
+		return is, pr
+	}
 	return model.Ignore, nil
 }
+
+func (dcp *DbConnectionParam) hasPassword() bool {
+	return len(dcp.DSNInfo.User) > 0 && (len(dcp.DSNInfo.Password) > 0 || len(dcp.DSNInfo.Socket) == 0)
+}
@@ -4,22 +4,28 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"errors"
-	"strings"
 	"sync"
+	"sync/atomic"
 
 	openrasp "github.com/baidu-security/openrasp-golang"
 	"github.com/baidu-security/openrasp-golang/gls"
 	"github.com/baidu-security/openrasp-golang/model"
-	"github.com/baidu-security/openrasp-golang/stacktrace"
-	"github.com/baidu-security/openrasp-golang/support/orhttp"
-	"github.com/baidu-security/openrasp-golang/utils"
 )
 
 var (
-	driversMu sync.RWMutex
-	drivers   = make(map[string]*wrapDriver)
+	// registerMu serializes Register, lookups load drivers without locking
+	registerMu sync.Mutex
+	// drivers holds a map[string]*wrapDriver which is never modified once
+	// stored, Register stores a copy with the new driver
+	drivers atomic.Value
 )
 
+func loadDriver(name string) (*wrapDriver, bool) {
+	registered, _ := drivers.Load().(map[string]*wrapDriver)
+	d, ok := registered[name]
+	return d, ok
+}
+
 type DSNParserFunc func(dsn string) DSNInfo
 type ErrorInterceptorFunc func(err *error) (bool, string, string)
 
@@ -32,43 +38,47 @@ func genericErrorInterceptor(err *error) (bool, string, string) {
 }
 
 func Register(name string, driver driver.Driver, opts ...WrapOption) {
-	driversMu.Lock()
-	defer driversMu.Unlock()
+	registerMu.Lock()
+	defer registerMu.Unlock()
 
 	wrapped := newWrapDriver(driver, opts...)
 	sql.Register(wrapDriverName(name), wrapped)
-	drivers[name] = wrapped
+	registered, _ := drivers.Load().(map[string]*wrapDriver)
+	next := make(map[string]*wrapDriver, len(registered)+1)
+	for k, v := range registered {
+		next[k] = v
+	}
+	next[name] = wrapped
+	drivers.Store(next)
 }
 
 func wrapDriverName(origin string) string {
 	return "openrasp/" + origin
 }
 
+// WrappedName returns the name of the driver registered by Register as name
+// in database/sql, for sql.Open to use the wrapped driver outside of a
+// request
+func WrappedName(name string) string {
+	return wrapDriverName(name)
+}
+
 func sqlConnectionPolicyCheck(d *wrapDriver, name string) (model.InterceptCode, string) {
 	dsnInfo := d.dsnParser(name)
 	dbConnParam := NewDbConnectionParam(&dsnInfo, d.driverName)
 	interceptCode, policyResult := dbConnParam.PolicyCheck()
+	interceptCode = openrasp.GetHookSwitch().FilterPolicy(interceptCode, policyResult)
 	var policyLogString string
 	if interceptCode != model.Ignore {
-		policyLog := model.PolicyLog{
-			PolicyResult: policyResult,
-			Server:       openrasp.GetGlobals().Server,
-			System:       openrasp.GetGlobals().System,
-			PolicyParams: dbConnParam,
-			SourceCode:   []string{},
-			StackTrace:   strings.Join(stacktrace.LogFormat(stacktrace.AppendStacktrace(nil, 1, openrasp.GetGeneral().GetInt("log.maxstack"))), "\n"),
-			RaspId:       openrasp.GetGlobals().RaspId,
-			AppId:        openrasp.GetBasic().GetString("cloud.app_id"),
-			EventTime:    utils.CurrentISO8601Time(),
-		}
+		policyLog := openrasp.NewPolicyLog(policyResult, dbConnParam)
 		policyLogString = policyLog.String()
 	}
 	return interceptCode, policyLogString
 }
 
 func Open(driverName, dataSourceName string) (*sql.DB, error) {
-	if openrasp.IsComplete() && gls.Activated() {
-		d, ok := drivers[driverName]
+	if openrasp.HookActive(openrasp.HookSql) && gls.Activated() {
+		d, ok := loadDriver(driverName)
 		var interceptCode model.InterceptCode = model.Ignore
 		var policyLogString string
 		if ok {
@@ -77,10 +87,7 @@ func Open(driverName, dataSourceName string) (*sql.DB, error) {
 				if len(policyLogString) > 0 {
 					openrasp.GetLog().PolicyInfo(policyLogString)
 				}
-				blocker, ok := gls.Get("responseWriter").(orhttp.OpenRASPBlocker)
-				if ok {
-					blocker.BlockByOpenRASP()
-				}
+				openrasp.BlockRequest()
 			}
 		}
 		db, err := sql.Open(wrapDriverName(driverName), dataSourceName)
@@ -121,9 +128,7 @@ func newWrapDriver(driver driver.Driver, opts ...WrapOption) *wrapDriver {
 }
 
 func DriverDSNParser(driverName string) DSNParserFunc {
-	driversMu.RLock()
-	driver := drivers[driverName]
-	defer driversMu.RUnlock()
+	driver, _ := loadDriver(driverName)
 	return driver.dsnParser
 }
 
@@ -155,57 +160,30 @@ type wrapDriver struct {
 }
 
 func (d *wrapDriver) interceptError(param string, err *error) {
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return
+	}
 	hit, errCode, errMsg := d.errorInterceptor(err)
 	if hit {
 		sqlErrorParam := NewSqlErrorParam(d.driverName, param, errCode, errMsg)
-		shouldBlock := false
-		requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
-		if ok {
-			attackResults := sqlErrorParam.AttackCheck(openrasp.IgnoreActionOption, openrasp.WhitelistOption)
-			for _, attackResult := range attackResults {
-				if interceptCode := attackResult.GetInterceptState(); interceptCode != model.Ignore {
-					attackLog := model.AttackLog{
-						AttackResult: attackResult,
-						Server:       openrasp.GetGlobals().Server,
-						System:       openrasp.GetGlobals().System,
-						RequestInfo:  requestInfo,
-						AttackParams: sqlErrorParam,
-						SourceCode:   []string{},
-						StackTrace:   strings.Join(stacktrace.LogFormat(stacktrace.AppendStacktrace(nil, 1, openrasp.GetGeneral().GetInt("log.maxstack"))), "\n"),
-						RaspId:       openrasp.GetGlobals().RaspId,
-						AppId:        openrasp.GetBasic().GetString("cloud.app_id"),
-						ServerIp:     openrasp.GetGlobals().HttpAddr,
-						EventTime:    utils.CurrentISO8601Time(),
-						EventType:    "attack",
-						AttackType:   sqlErrorParam.GetTypeString(),
-					}
-					attackLogString := attackLog.String()
-					if len(attackLogString) > 0 {
-						openrasp.GetLog().AlarmInfo(attackLogString)
-					}
-					if interceptCode == model.Block {
-						shouldBlock = true
-					}
-				}
-			}
-		}
-		if shouldBlock {
-			blocker, ok := gls.Get("responseWriter").(orhttp.OpenRASPBlocker)
-			if ok {
-				blocker.BlockByOpenRASP()
-			}
+		if openrasp.AttackCheck(sqlErrorParam, openrasp.IgnoreActionOption, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
 		}
 	}
 }
 
 func (d *wrapDriver) Open(name string) (driver.Conn, error) {
 	dsnInfo := d.dsnParser(name)
-	interceptCode, policyLogString := sqlConnectionPolicyCheck(d, name)
+	interceptCode, policyLogString := model.Ignore, ""
+	if openrasp.HookActive(openrasp.HookSql) {
+		interceptCode, policyLogString = sqlConnectionPolicyCheck(d, name)
+	}
 	if interceptCode == model.Block {
 		if len(policyLogString) > 0 {
 			openrasp.GetLog().PolicyInfo(policyLogString)
 		}
-		panic(openrasp.ErrBlock)
+		// outside of a request the connection is refused without a panic
+		return nil, openrasp.Block()
 	}
 	conn, err := d.Driver.Open(name)
 	if err != nil {
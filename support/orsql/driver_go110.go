@@ -5,6 +5,8 @@ package orsql
 import (
 	"context"
 	"database/sql/driver"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
 )
 
 func (d *wrapDriver) OpenConnector(name string) (driver.Connector, error) {
@@ -28,6 +30,10 @@ type wrapConnector struct {
 }
 
 func (d *wrapConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if openrasp.HookActive(openrasp.HookSql) {
+		leave, _ := openrasp.EnterContext(ctx)
+		defer leave()
+	}
 	dsnInfo := d.driver.dsnParser(d.name)
 	conn, err := d.connect(ctx)
 	if err != nil {
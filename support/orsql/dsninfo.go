@@ -4,6 +4,7 @@ type DSNInfo struct {
 	Database         string `json:"-"`
 	Hostname         string `json:"hostname"`
 	User             string `json:"username"`
+	Password         string `json:"-"`
 	Socket           string `json:"socket"`
 	Port             string `json:"port"`
 	ConnectionString string `json:"connectionString"`
@@ -24,6 +24,7 @@ func MysqlParseDSN(name string) orsql.DSNInfo {
 	}
 	dsnInfo.Database = cfg.DBName
 	dsnInfo.User = cfg.User
+	dsnInfo.Password = cfg.Passwd
 
 	if cfg.Net == "" {
 		cfg.Net = "tcp"
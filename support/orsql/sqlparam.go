@@ -2,26 +2,64 @@ package orsql
 
 import (
 	"encoding/json"
+	"sync"
 
 	openrasp "github.com/baidu-security/openrasp-golang"
 	"github.com/baidu-security/openrasp-golang/common"
 	"github.com/baidu-security/openrasp-golang/model"
-	v8 "github.com/baidu-security/openrasp-v8/go"
 )
 
 type SqlParam struct {
-	Query  string `json:"query"`
-	Server string `json:"server"`
+	Query    string `json:"query"`
+	Server   string `json:"server"`
+	retained bool
+}
+
+// sqlParamPool spares an allocation for each of the queries, which are the
+// most frequent checks
+var sqlParamPool = sync.Pool{
+	New: func() interface{} {
+		return new(SqlParam)
+	},
+}
+
+// a query built from user input which spans several tokens is an injection
+func init() {
+	openrasp.RegisterSelfTest(openrasp.SelfTestCase{
+		Hook:  openrasp.HookSql,
+		Name:  "sql injection",
+		Input: "1 or 1=1",
+		Benign: func() common.AttackChecker {
+			return NewSqlParam("mysql", "SELECT * FROM users WHERE id = 1")
+		},
+		Malicious: func() common.AttackChecker {
+			return NewSqlParam("mysql", "SELECT * FROM users WHERE id = 1 or 1=1")
+		},
+	})
 }
 
 func NewSqlParam(server, query string) *SqlParam {
-	sp := &SqlParam{
-		Server: server,
-		Query:  query,
-	}
+	sp := sqlParamPool.Get().(*SqlParam)
+	sp.Server = server
+	sp.Query = query
 	return sp
 }
 
+// Retain keeps sp out of the pool, a log references it
+func (sp *SqlParam) Retain() {
+	sp.retained = true
+}
+
+// Release puts sp back into the pool unless a log retained it, sp must not
+// be used afterwards
+func (sp *SqlParam) Release() {
+	if sp.retained {
+		return
+	}
+	*sp = SqlParam{}
+	sqlParamPool.Put(sp)
+}
+
 func (sp *SqlParam) Bytes() []byte {
 	b, _ := json.Marshal(sp)
 	return b
@@ -43,7 +81,7 @@ func (sp *SqlParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResu
 		}
 	}
 	if openrasp.RequestInfoAvailable() {
-		resultBytes := v8.Check(sp.GetTypeString(), sp.Bytes(), openrasp.DefaultContextGetters(), openrasp.GetGeneral().GetInt("plugin.timeout.millis"))
+		resultBytes := openrasp.PluginCheck(sp.GetTypeString(), sp.Bytes())
 		var ms []map[string]interface{}
 		err := json.Unmarshal(resultBytes, &ms)
 		if err == nil {
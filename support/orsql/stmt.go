@@ -3,6 +3,8 @@ package orsql
 import (
 	"context"
 	"database/sql/driver"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
 )
 
 func newStmt(in driver.Stmt, conn *conn, query string) driver.Stmt {
@@ -32,8 +34,8 @@ type stmt struct {
 	stmtQueryContext  driver.StmtQueryContext
 }
 
-func (s *stmt) queryAttackCheck() {
-	s.conn.queryAttackCheck(s.query)
+func (s *stmt) queryAttackCheck() error {
+	return s.conn.queryAttackCheck(s.query)
 }
 
 func (s *stmt) interceptError(resultError *error) {
@@ -47,9 +49,21 @@ func (s *stmt) ColumnConverter(idx int) driver.ValueConverter {
 	return driver.DefaultParameterConverter
 }
 
-func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (_ driver.Result, resultError error) {
-	s.queryAttackCheck()
-	defer s.interceptError(&resultError)
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return s.execContext(ctx, args)
+	}
+	leave, _ := openrasp.EnterContext(ctx)
+	defer leave()
+	if err := s.queryAttackCheck(); err != nil {
+		return nil, err
+	}
+	result, err := s.execContext(ctx, args)
+	s.interceptError(&err)
+	return result, err
+}
+
+func (s *stmt) execContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	if s.stmtExecContext != nil {
 		return s.stmtExecContext.ExecContext(ctx, args)
 	}
@@ -65,9 +79,21 @@ func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (_ dri
 	return s.Exec(dargs)
 }
 
-func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (_ driver.Rows, resultError error) {
-	s.queryAttackCheck()
-	defer s.interceptError(&resultError)
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if !openrasp.HookActive(openrasp.HookSql) {
+		return s.queryContext(ctx, args)
+	}
+	leave, _ := openrasp.EnterContext(ctx)
+	defer leave()
+	if err := s.queryAttackCheck(); err != nil {
+		return nil, err
+	}
+	rows, err := s.queryContext(ctx, args)
+	s.interceptError(&err)
+	return rows, err
+}
+
+func (s *stmt) queryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	if s.stmtQueryContext != nil {
 		return s.stmtQueryContext.QueryContext(ctx, args)
 	}
@@ -0,0 +1,156 @@
+// Package orsqltest provides an in-memory database/sql driver wrapped by
+// orsql, so that applications can test that their queries are checked
+// without a database. Queries are served by a handler run through
+// orhttptest since the sql checks need the request:
+//
+//	d := orsqltest.Register("app")
+//	db, _ := d.OpenDB()
+//	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+//		db.Query("SELECT * FROM users WHERE id = " + req.FormValue("id"))
+//	})
+//	result := orhttptest.Serve(handler, httptest.NewRequest("GET", "/?id=1%20or%201=1", nil))
+//	orhttptest.AssertBlocked(t, result, "sql")
+package orsqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/support/orsql"
+)
+
+// Driver is an in-memory driver which records the queries it receives and
+// answers them with no rows and no affected rows
+type Driver struct {
+	name string
+	// Error, when set before the driver is used, returns the error of a
+	// query, e.g. a syntax error for the sql error checks of an
+	// orsql.ErrorInterceptorWrap
+	Error   func(query string) error
+	queries []string
+	mu      sync.Mutex
+}
+
+// Register registers a new driver as name with database/sql and, wrapped,
+// with orsql. The driver presents itself as mysql to the plugins unless opts
+// set orsql.DriverNameWrap. Like sql.Register, it panics when name is taken.
+func Register(name string, opts ...orsql.WrapOption) *Driver {
+	d := &Driver{name: name}
+	sql.Register(name, d)
+	orsql.Register(name, d, append([]orsql.WrapOption{orsql.DriverNameWrap("mysql")}, opts...)...)
+	return d
+}
+
+// OpenDB opens a database on the wrapped driver, which is checked in and out
+// of a request unlike one of orsql.Open outside of a request
+func (d *Driver) OpenDB() (*sql.DB, error) {
+	return sql.Open(orsql.WrappedName(d.name), "")
+}
+
+// Queries returns the queries the driver received, the blocked ones never
+// reach it
+func (d *Driver) Queries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+// Reset forgets the queries received so far
+func (d *Driver) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = nil
+}
+
+func (d *Driver) Open(string) (driver.Conn, error) {
+	return &conn{driver: d}, nil
+}
+
+func (d *Driver) receive(query string) error {
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	d.mu.Unlock()
+	if d.Error != nil {
+		return d.Error(query)
+	}
+	return nil
+}
+
+type conn struct {
+	driver *Driver
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.driver.receive(query); err != nil {
+		return nil, err
+	}
+	return rows{}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.driver.receive(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput lets database/sql pass any number of arguments
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+type rows struct{}
+
+func (rows) Columns() []string {
+	return nil
+}
+
+func (rows) Close() error {
+	return nil
+}
+
+func (rows) Next([]driver.Value) error {
+	return io.EOF
+}
@@ -0,0 +1,60 @@
+package orsqltest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/support/orhttptest"
+	"github.com/baidu-security/openrasp-golang/support/orsql"
+	"github.com/stretchr/testify/assert"
+)
+
+type tautologyChecker struct{}
+
+func (tautologyChecker) CheckType() common.CheckType {
+	return common.Sql
+}
+
+func (tautologyChecker) Check(params common.AttackChecker) *model.AttackResult {
+	sqlParam, ok := params.(*orsql.SqlParam)
+	if !ok || !strings.Contains(sqlParam.Query, "or 1=1") {
+		return nil
+	}
+	return model.NewAttackResult("block", "tautology", "tautology_test", "orsqltest", 90)
+}
+
+func init() {
+	openrasp.RegisterChecker(tautologyChecker{})
+}
+
+func TestDriver(t *testing.T) {
+	d := Register("orsqltest")
+	db, err := d.OpenDB()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rows, err := db.Query("SELECT * FROM users WHERE id = " + req.FormValue("id"))
+		if err == nil {
+			rows.Close()
+		}
+	})
+
+	result := orhttptest.Serve(handler, httptest.NewRequest("GET", "/?id=1", nil))
+	orhttptest.AssertNotBlocked(t, result)
+	assert.Equal(t, []string{"SELECT * FROM users WHERE id = 1"}, d.Queries())
+
+	d.Reset()
+	result = orhttptest.Serve(handler, httptest.NewRequest("GET", "/?id=1%20or%201=1", nil))
+	orhttptest.AssertBlocked(t, result, "sql")
+	if assert.Len(t, result.Attacks, 1) {
+		assert.Equal(t, "tautology_test", result.Attacks[0].PluginAlgorithm)
+	}
+	assert.Empty(t, d.Queries())
+}
@@ -0,0 +1,112 @@
+package ortemplate
+
+import (
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+const (
+	leftDelim  = "{{"
+	rightDelim = "}}"
+)
+
+type SstiParam struct {
+	Engine    string `json:"engine"`
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	UserInput string `json:"user_input"`
+}
+
+func NewSstiParam(engine, name, source string) *SstiParam {
+	sp := &SstiParam{
+		Engine: engine,
+		Name:   name,
+		Source: source,
+	}
+	return sp
+}
+
+func (sp *SstiParam) GetType() common.CheckType {
+	return common.Ssti
+}
+
+func (sp *SstiParam) GetTypeString() string {
+	return common.CheckTypeToString(sp.GetType())
+}
+
+func (sp *SstiParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(sp) {
+			return results
+		}
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return results
+	}
+	for _, value := range requestInfo.Parameters() {
+		if hasTemplateAction(value) && (strings.Contains(sp.Source, value) || containsActions(sp.Source, value)) {
+			sp.UserInput = value
+			ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("template.action"))
+			msg := "SSTI - " + sp.Engine + " template " + sp.Name + " is built from user input: " + value
+			ar := model.NewAttackResult(model.InterceptCodeToString(ic), msg, "go_builtin_plugin", sp.GetTypeString(), 95)
+			results = append(results, ar)
+			break
+		}
+	}
+	return results
+}
+
+func hasTemplateAction(value string) bool {
+	left := strings.Index(value, leftDelim)
+	return left >= 0 && strings.Contains(value[left+len(leftDelim):], rightDelim)
+}
+
+// containsActions reports whether every action of value is one of source,
+// the source rebuilt from a parse tree drops the spacing and trim markers of
+// the actions, and html/template appends its escapers to them
+func containsActions(source, value string) bool {
+	inputs := actions(value)
+	if len(inputs) == 0 {
+		return false
+	}
+	sourceActions := actions(source)
+	for _, input := range inputs {
+		found := false
+		for _, action := range sourceActions {
+			if action == input || strings.HasPrefix(action, input+"|") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// actions returns the content of the actions of s without trim markers and
+// spaces
+func actions(s string) []string {
+	var result []string
+	for {
+		left := strings.Index(s, leftDelim)
+		if left < 0 {
+			return result
+		}
+		s = s[left+len(leftDelim):]
+		right := strings.Index(s, rightDelim)
+		if right < 0 {
+			return result
+		}
+		action := strings.TrimSuffix(strings.TrimPrefix(s[:right], "- "), " -")
+		result = append(result, strings.Join(strings.Fields(action), ""))
+		s = s[right+len(rightDelim):]
+	}
+}
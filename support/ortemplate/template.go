@@ -0,0 +1,108 @@
+package ortemplate
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"sync"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+// Check reports an ssti attack when the template source embeds request input
+// containing template actions
+func Check(engine, name, source string) {
+	if openrasp.HookActive(openrasp.HookTemplate) && gls.Activated() {
+		sstiParam := NewSstiParam(engine, name, source)
+		if openrasp.AttackCheck(sstiParam, openrasp.WhitelistOption) {
+			openrasp.BlockRequest()
+		}
+	}
+}
+
+// ParseText is the wrapped version of text/template Template.Parse
+func ParseText(t *texttemplate.Template, text string) (*texttemplate.Template, error) {
+	Check("text/template", t.Name(), text)
+	return t.Parse(text)
+}
+
+// ParseHTML is the wrapped version of html/template Template.Parse
+func ParseHTML(t *htmltemplate.Template, text string) (*htmltemplate.Template, error) {
+	Check("html/template", t.Name(), text)
+	return t.Parse(text)
+}
+
+// NewText is a shortcut of ParseText(texttemplate.New(name), text)
+func NewText(name, text string) (*texttemplate.Template, error) {
+	return ParseText(texttemplate.New(name), text)
+}
+
+// NewHTML is a shortcut of ParseHTML(htmltemplate.New(name), text)
+func NewHTML(name, text string) (*htmltemplate.Template, error) {
+	return ParseHTML(htmltemplate.New(name), text)
+}
+
+// ExecuteText is the wrapped version of text/template Template.Execute, it
+// catches the templates parsed without ParseText
+func ExecuteText(t *texttemplate.Template, w io.Writer, data interface{}) error {
+	checkTree("text/template", t.Name(), t.Tree)
+	return t.Execute(w, data)
+}
+
+// ExecuteTextTemplate is the wrapped version of text/template
+// Template.ExecuteTemplate
+func ExecuteTextTemplate(t *texttemplate.Template, w io.Writer, name string, data interface{}) error {
+	if tmpl := t.Lookup(name); tmpl != nil {
+		checkTree("text/template", name, tmpl.Tree)
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// ExecuteHTML is the wrapped version of html/template Template.Execute, it
+// catches the templates parsed without ParseHTML
+func ExecuteHTML(t *htmltemplate.Template, w io.Writer, data interface{}) error {
+	checkTree("html/template", t.Name(), t.Tree)
+	return t.Execute(w, data)
+}
+
+// ExecuteHTMLTemplate is the wrapped version of html/template
+// Template.ExecuteTemplate
+func ExecuteHTMLTemplate(t *htmltemplate.Template, w io.Writer, name string, data interface{}) error {
+	if tmpl := t.Lookup(name); tmpl != nil {
+		checkTree("html/template", name, tmpl.Tree)
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// maxCheckedTrees bounds checkedTrees, which starts over once full
+const maxCheckedTrees = 4096
+
+// checkedTrees are the parse trees checked at execution, a template is
+// only checked the first time it runs, so input which happens to match an
+// action of a template parsed on start is not taken for an attack later on
+var (
+	checkedTrees   = make(map[*parse.Tree]struct{})
+	checkedTreesMu sync.Mutex
+)
+
+// checkTree checks the source of a template rebuilt from its parse tree the
+// first time tree runs
+func checkTree(engine, name string, tree *parse.Tree) {
+	if tree == nil || tree.Root == nil {
+		return
+	}
+	checkedTreesMu.Lock()
+	_, checked := checkedTrees[tree]
+	if !checked {
+		if len(checkedTrees) >= maxCheckedTrees {
+			checkedTrees = make(map[*parse.Tree]struct{})
+		}
+		checkedTrees[tree] = struct{}{}
+	}
+	checkedTreesMu.Unlock()
+	if !checked {
+		Check(engine, name, tree.Root.String())
+	}
+}
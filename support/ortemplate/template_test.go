@@ -0,0 +1,66 @@
+package ortemplate
+
+import (
+	htmltemplate "html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	texttemplate "text/template"
+
+	"github.com/baidu-security/openrasp-golang/support/orhttptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActions(t *testing.T) {
+	assert.Equal(t, []string{".Name", ".Secret|printf\"%s\""}, actions(`Hi {{- .Name -}}, {{ .Secret | printf "%s" }}`))
+	assert.True(t, containsActions(`Hi {{.Name | _html_template_htmlescaper}}`, "{{ .Name }}"))
+	assert.False(t, containsActions(`Hi {{.Names}}`, "{{.Name}}"))
+	assert.False(t, containsActions(`Hi {{.Name}}`, "{{.Name}} and {{.Env}}"))
+}
+
+func greet(w http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get("name")
+	switch req.URL.Path {
+	case "/parse":
+		tmpl, err := NewText("greeting", "Hello "+name)
+		if err == nil {
+			tmpl.Execute(w, nil)
+		}
+	case "/text":
+		// parsed without ParseText, caught at execution
+		tmpl, err := texttemplate.New("greeting").Parse("Hello " + name)
+		if err == nil {
+			ExecuteText(tmpl, w, map[string]string{"Secret": "s3cret"})
+		}
+	case "/html":
+		tmpl, err := htmltemplate.New("page").Parse("<p>Hello " + name + "</p>")
+		if err == nil {
+			ExecuteHTMLTemplate(tmpl, w, "page", map[string]string{"Secret": "s3cret"})
+		}
+	}
+}
+
+func serve(path, name string) *orhttptest.Result {
+	return orhttptest.Serve(http.HandlerFunc(greet), httptest.NewRequest("GET", path+"?name="+url.QueryEscape(name), nil))
+}
+
+func TestSsti(t *testing.T) {
+	for _, path := range []string{"/parse", "/text", "/html"} {
+		orhttptest.AssertBlocked(t, serve(path, "{{ .Secret }}"), "ssti")
+		result := serve(path, "bob")
+		orhttptest.AssertNotBlocked(t, result)
+		assert.Contains(t, result.Response.Body.String(), "Hello bob")
+	}
+}
+
+func TestStaticTemplate(t *testing.T) {
+	static := texttemplate.Must(texttemplate.New("static").Parse("Hello {{.}}"))
+	h := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ExecuteText(static, w, req.URL.Query().Get("name"))
+	})
+	// the first run outside a request marks the template as checked
+	assert.Nil(t, ExecuteText(static, httptest.NewRecorder(), "start"))
+	result := orhttptest.Serve(h, httptest.NewRequest("GET", "/?name="+url.QueryEscape("{{.}}"), nil))
+	orhttptest.AssertNotBlocked(t, result)
+}
@@ -0,0 +1,119 @@
+package orxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+)
+
+var ErrExternalEntity = errors.New("external entity is refused by OpenRASP")
+
+// Decoder wraps xml.Decoder and inspects the DOCTYPE declarations met before
+// the root element
+type Decoder struct {
+	*xml.Decoder
+	inspected bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		Decoder: xml.NewDecoder(r),
+	}
+}
+
+// Decode works like xml.Decoder.Decode
+func (d *Decoder) Decode(v interface{}) error {
+	return d.DecodeElement(v, nil)
+}
+
+// DecodeElement works like xml.Decoder.DecodeElement
+func (d *Decoder) DecodeElement(v interface{}, start *xml.StartElement) error {
+	if start == nil && !d.inspected {
+		for {
+			tok, err := d.Decoder.Token()
+			if err != nil {
+				return err
+			}
+			if directive, ok := tok.(xml.Directive); ok {
+				if err := inspectDirective(string(directive)); err != nil {
+					return err
+				}
+			}
+			if se, ok := tok.(xml.StartElement); ok {
+				start = &se
+				break
+			}
+		}
+		d.inspected = true
+	}
+	return d.Decoder.DecodeElement(v, start)
+}
+
+// Token works like xml.Decoder.Token
+func (d *Decoder) Token() (xml.Token, error) {
+	tok, err := d.Decoder.Token()
+	if err != nil {
+		return tok, err
+	}
+	if directive, ok := tok.(xml.Directive); ok {
+		if err := inspectDirective(string(directive)); err != nil {
+			return nil, err
+		}
+	}
+	return tok, err
+}
+
+// Unmarshal is the wrapped version of xml.Unmarshal
+func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Inspect checks a document before it is handed to another parser, such as a
+// libxml binding that may resolve external entities
+func Inspect(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.Directive:
+			if err := inspectDirective(string(t)); err != nil {
+				return err
+			}
+		case xml.StartElement:
+			return nil
+		}
+	}
+}
+
+func inspectDirective(directive string) error {
+	if !openrasp.IsComplete() || !openrasp.GetHookSwitch().HookEnabled(openrasp.HookXml) {
+		return nil
+	}
+	entities := externalEntities("<!" + directive + ">")
+	if len(entities) == 0 {
+		return nil
+	}
+	if gls.Activated() {
+		for _, entity := range entities {
+			if openrasp.AttackCheck(NewXxeParam(entity), openrasp.WhitelistOption) {
+				openrasp.BlockRequest()
+			}
+		}
+	}
+	if openrasp.AlgorithmBool(common.Xxe, "refuse_external_entity", "xml.refuse_external_entity") {
+		return ErrExternalEntity
+	}
+	return nil
+}
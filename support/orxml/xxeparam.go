@@ -0,0 +1,80 @@
+package orxml
+
+import (
+	"regexp"
+	"strings"
+
+	openrasp "github.com/baidu-security/openrasp-golang"
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/model"
+)
+
+var (
+	entityDeclRegex = regexp.MustCompile(`(?i)<!ENTITY\s+(?:%\s*)?[^\s]+\s+(?:SYSTEM|PUBLIC)((?:\s*(?:"[^"]*"|'[^']*'))+)`)
+	literalRegex    = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+)
+
+var dangerousProtocols = []string{"file:", "gopher:", "expect:", "php:", "jar:", "netdoc:", "dict:", "ftp:", "data:"}
+
+type XxeParam struct {
+	Entity string `json:"entity"`
+}
+
+func NewXxeParam(entity string) *XxeParam {
+	xp := &XxeParam{
+		Entity: entity,
+	}
+	return xp
+}
+
+func (xp *XxeParam) GetType() common.CheckType {
+	return common.Xxe
+}
+
+func (xp *XxeParam) GetTypeString() string {
+	return common.CheckTypeToString(xp.GetType())
+}
+
+func (xp *XxeParam) AttackCheck(opts ...common.AttackOption) []*model.AttackResult {
+	var results []*model.AttackResult
+	for _, opt := range opts {
+		if opt(xp) {
+			return results
+		}
+	}
+	ic := model.InterceptStringToCode(openrasp.GetGeneral().GetString("xml.action"))
+	msg := "XXE - Using external entity: " + xp.Entity
+	var confidence uint64 = 90
+	if hasDangerousProtocol(xp.Entity) {
+		msg = "XXE - Using dangerous protocol in external entity: " + xp.Entity
+		confidence = 100
+	}
+	ar := model.NewAttackResult(model.InterceptCodeToString(ic), msg, "go_builtin_plugin", xp.GetTypeString(), confidence)
+	results = append(results, ar)
+	return results
+}
+
+func hasDangerousProtocol(entity string) bool {
+	lower := strings.ToLower(strings.TrimSpace(entity))
+	for _, protocol := range dangerousProtocols {
+		if strings.HasPrefix(lower, protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+// externalEntities extracts the system identifiers of every external entity
+// declared in a DOCTYPE directive
+func externalEntities(directive string) []string {
+	var entities []string
+	for _, decl := range entityDeclRegex.FindAllStringSubmatch(directive, -1) {
+		literals := literalRegex.FindAllString(decl[1], -1)
+		if len(literals) == 0 {
+			continue
+		}
+		systemId := literals[len(literals)-1]
+		entities = append(entities, systemId[1:len(systemId)-1])
+	}
+	return entities
+}
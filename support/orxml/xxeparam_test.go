@@ -0,0 +1,19 @@
+package orxml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalEntities(t *testing.T) {
+	directive := `<!DOCTYPE foo [<!ENTITY xxe SYSTEM "file:///etc/passwd"><!ENTITY % dtd PUBLIC "-//X//EN" 'http://evil.com/x.dtd'><!ENTITY internal "text">]>`
+	assert.Equal(t, []string{"file:///etc/passwd", "http://evil.com/x.dtd"}, externalEntities(directive))
+	assert.Empty(t, externalEntities(`<!DOCTYPE html>`))
+}
+
+func TestHasDangerousProtocol(t *testing.T) {
+	assert.True(t, hasDangerousProtocol("file:///etc/passwd"))
+	assert.True(t, hasDangerousProtocol(" Gopher://127.0.0.1:6379/_"))
+	assert.False(t, hasDangerousProtocol("http://example.com/a.dtd"))
+}
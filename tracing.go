@@ -0,0 +1,84 @@
+package openrasp
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+)
+
+// Tracer connects the agent to a distributed tracing library such as
+// OpenTelemetry, which the agent does not depend on. The context handed to
+// it is the one of the request, or the one passed to a hook such as
+// QueryContext, and holds the span active there.
+type Tracer interface {
+	// SpanIds returns the hex ids of the span active in ctx
+	SpanIds(ctx context.Context) (traceId, spanId string, ok bool)
+	// RecordAttack adds an event for attackLog to the span active in ctx,
+	// blocked tells whether the attack interrupts the request. attackLog is
+	// not reused by the agent and may be kept after RecordAttack returns.
+	RecordAttack(ctx context.Context, attackLog *model.AttackLog, blocked bool)
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var tracer atomic.Value
+
+// SetTracer makes the attack logs carry the ids of the active span and
+// records every attack on it, nil stops it. Without a tracer the logs carry
+// the ids of the traceparent header of the request.
+func SetTracer(t Tracer) {
+	tracer.Store(tracerHolder{tracer: t})
+}
+
+func currentTracer() Tracer {
+	holder, _ := tracer.Load().(tracerHolder)
+	return holder.tracer
+}
+
+// traceContext returns the context of the current request, nil outside one
+func traceContext() context.Context {
+	ctx, _ := gls.Get("traceContext").(context.Context)
+	return ctx
+}
+
+// traceIds returns the ids of the span the current check runs in
+func traceIds(requestInfo *model.RequestInfo) (traceId, spanId string) {
+	if t := currentTracer(); t != nil {
+		if ctx := traceContext(); ctx != nil {
+			if traceId, spanId, ok := t.SpanIds(ctx); ok {
+				return traceId, spanId
+			}
+		}
+	}
+	if requestInfo == nil {
+		return "", ""
+	}
+	return requestInfo.TraceId, requestInfo.SpanId
+}
+
+// traceAttack records attackLog on the active span and reports whether the
+// tracer received it, a panic of the tracer is reported and does not affect
+// the check
+func traceAttack(attackLog *model.AttackLog, interceptCode model.InterceptCode) bool {
+	t := currentTracer()
+	if t == nil {
+		return false
+	}
+	ctx := traceContext()
+	if ctx == nil {
+		return false
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ReportError(fmt.Errorf("Recovered from panic of tracer, %v", r), orlog.Panic)
+		}
+	}()
+	t.RecordAttack(ctx, attackLog, interceptCode == model.Block)
+	return true
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramBuckets covers latencies up to 2^31 microseconds
+const histogramBuckets = 32
+
+// Histogram counts durations in buckets doubling from one microsecond, a
+// quantile is estimated by the upper bound of the bucket it falls into,
+// which is at most twice the actual value. It is not safe for concurrent
+// use.
+type Histogram struct {
+	counts [histogramBuckets]int64
+	count  int64
+	sum    time.Duration
+}
+
+// HistogramBucket counts the durations up to UpperBound
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// Observe counts d
+func (h *Histogram) Observe(d time.Duration) {
+	micros := d / time.Microsecond
+	bucket := 0
+	if micros > 0 {
+		bucket = bits.Len64(uint64(micros))
+	}
+	if bucket >= histogramBuckets {
+		bucket = histogramBuckets - 1
+	}
+	h.counts[bucket]++
+	h.count++
+	h.sum += d
+}
+
+// Count returns the number of durations observed
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+// Sum returns the total of the durations observed
+func (h *Histogram) Sum() time.Duration {
+	return h.sum
+}
+
+// Buckets returns the cumulative counts of every bucket, the last one holds
+// the durations beyond its bound as well
+func (h *Histogram) Buckets() []HistogramBucket {
+	buckets := make([]HistogramBucket, histogramBuckets)
+	var count int64
+	for bucket := range h.counts {
+		count += h.counts[bucket]
+		buckets[bucket] = HistogramBucket{
+			UpperBound: bucketBound(bucket),
+			Count:      count,
+		}
+	}
+	return buckets
+}
+
+func bucketBound(bucket int) time.Duration {
+	return time.Duration(uint64(1)<<uint(bucket)) * time.Microsecond
+}
+
+// Quantile returns the estimated q quantile with q between 0 and 1, 0 when
+// nothing was observed
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(h.count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for bucket, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			return bucketBound(bucket)
+		}
+	}
+	return bucketBound(histogramBuckets - 1)
+}
+
+// Merge adds the durations observed by other
+func (h *Histogram) Merge(other *Histogram) {
+	for bucket, count := range other.counts {
+		h.counts[bucket] += count
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// Reset forgets every duration
+func (h *Histogram) Reset() {
+	*h = Histogram{}
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	assert.Equal(t, time.Duration(0), h.Quantile(0.5))
+	for i := 0; i < 90; i++ {
+		h.Observe(100 * time.Microsecond)
+	}
+	for i := 0; i < 10; i++ {
+		h.Observe(5 * time.Millisecond)
+	}
+	assert.Equal(t, int64(100), h.Count())
+	assert.Equal(t, 128*time.Microsecond, h.Quantile(0.5))
+	assert.Equal(t, 128*time.Microsecond, h.Quantile(0.9))
+	assert.Equal(t, 8192*time.Microsecond, h.Quantile(0.99))
+	assert.Equal(t, 90*100*time.Microsecond+10*5*time.Millisecond, h.Sum())
+	buckets := h.Buckets()
+	assert.Equal(t, 128*time.Microsecond, buckets[7].UpperBound)
+	assert.Equal(t, int64(90), buckets[7].Count)
+	assert.Equal(t, int64(90), buckets[12].Count)
+	assert.Equal(t, int64(100), buckets[13].Count)
+	h.Observe(time.Hour)
+	assert.Equal(t, time.Duration(1<<31)*time.Microsecond, h.Quantile(1))
+
+	var other Histogram
+	other.Observe(0)
+	h.Merge(&other)
+	assert.Equal(t, int64(102), h.Count())
+	assert.Equal(t, time.Microsecond, h.Quantile(0))
+	h.Reset()
+	assert.Equal(t, int64(0), h.Count())
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	c := NewLRU(2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	c.Add("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok, "b is the least recently used")
+	assert.Equal(t, 2, c.Len())
+
+	c.Add("a", 4)
+	v, _ = c.Get("a")
+	assert.Equal(t, 4, v)
+
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+	_, ok = c.Get("c")
+	assert.False(t, ok)
+
+	disabled := NewLRU(0)
+	disabled.Add("a", 1)
+	assert.Equal(t, 0, disabled.Len())
+}
@@ -31,3 +31,17 @@ func GetMacAddrs() (macAddrs []string) {
 	}
 	return macAddrs
 }
+
+// AnonymizeIP zeroes the host part of ip, the last octet of an IPv4 address
+// and all but the first 48 bits of an IPv6 one, a value which is not an ip
+// is returned empty
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
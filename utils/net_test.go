@@ -0,0 +1,13 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	assert.Equal(t, "192.168.1.0", AnonymizeIP("192.168.1.77"))
+	assert.Equal(t, "2001:db8:85a3::", AnonymizeIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	assert.Equal(t, "", AnonymizeIP("example.com"))
+}
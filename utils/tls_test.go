@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKeyPair(t *testing.T, dir, commonName string, modTime time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	assert.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	assert.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, der []byte) string {
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert.Subject.CommonName
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	certFile, keyFile := writeKeyPair(t, dir, "first", now.Add(-time.Minute))
+
+	_, err = NewTLSConfig("", certFile, "", false)
+	assert.Error(t, err)
+	_, err = NewTLSConfig(filepath.Join(dir, "none.crt"), "", "", false)
+	assert.Error(t, err)
+
+	tlsConfig, err := NewTLSConfig("", certFile, keyFile, false)
+	assert.NoError(t, err)
+	cert, err := tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, cert.Certificate[0]))
+
+	writeKeyPair(t, dir, "second", now)
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", commonName(t, cert.Certificate[0]))
+
+	assert.NoError(t, ioutil.WriteFile(keyFile, []byte("broken"), 0600))
+	assert.NoError(t, os.Chtimes(keyFile, now.Add(time.Minute), now.Add(time.Minute)))
+	cert, err = tlsConfig.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", commonName(t, cert.Certificate[0]))
+}
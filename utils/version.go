@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two module versions such as v1.2.3, v0.0.0-2019
+// pre-releases or v2.0.0+incompatible by semantic versioning precedence, it
+// returns -1, 0 or 1
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	for i := 0; i < 3; i++ {
+		if c := compareNumeric(aCore[i], bCore[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case len(aPre) == 0:
+		return 1
+	case len(bPre) == 0:
+		return -1
+	}
+	aIds, bIds := strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := 0; i < len(aIds) && i < len(bIds); i++ {
+		if c := comparePrerelease(aIds[i], bIds[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(aIds), len(bIds))
+}
+
+func splitVersion(v string) ([3]string, string) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	var pre string
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, pre = v[:i], v[i+1:]
+	}
+	core := [3]string{"0", "0", "0"}
+	for i, part := range strings.SplitN(v, ".", 3) {
+		core[i] = part
+	}
+	return core, pre
+}
+
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if c := compareInt(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// comparePrerelease orders numeric identifiers numerically and before the
+// alphanumeric ones
+func comparePrerelease(a, b string) int {
+	_, aErr := strconv.ParseUint(a, 10, 64)
+	_, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareNumeric(a, b)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
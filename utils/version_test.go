@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, CompareVersions("v1.2.3", "1.2.3"))
+	assert.Equal(t, -1, CompareVersions("v0.9.0", "v0.17.0"))
+	assert.Equal(t, 1, CompareVersions("v2.0.0+incompatible", "v1.9.9"))
+	assert.Equal(t, -1, CompareVersions("v1.0.0-rc.1", "v1.0.0"))
+	assert.Equal(t, -1, CompareVersions("v1.0.0-alpha.2", "v1.0.0-alpha.10"))
+	assert.Equal(t, -1, CompareVersions("v1.0.0-1", "v1.0.0-alpha"))
+	assert.Equal(t, -1, CompareVersions("v0.0.0-20190308221718-c2843e01d9a2", "v0.0.0-20201021035429-f5854403a974"))
+	assert.Equal(t, -1, CompareVersions("v1.4", "v1.4.1"))
+}
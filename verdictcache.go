@@ -0,0 +1,125 @@
+package openrasp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/utils"
+)
+
+// VerdictCache memoizes the results of the plugins for identical checks of
+// up to lru.max_size keys. Plugins look at the request as well as the params,
+// so a key covers the check type, the compacted params, the input of the
+// current request and the plugin version, a verdict is never shared between
+// requests with different input. Loading plugins drops every verdict.
+type VerdictCache struct {
+	lru     *utils.LRU
+	version uint64
+	mu      sync.RWMutex
+}
+
+func NewVerdictCache() *VerdictCache {
+	vc := &VerdictCache{}
+	vc.OnConfigUpdate()
+	return vc
+}
+
+func (vc *VerdictCache) OnConfigUpdate() {
+	size := GetGeneral().GetInt("lru.max_size")
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.lru = utils.NewLRU(size)
+}
+
+// Invalidate drops the verdicts of the previous plugins
+func (vc *VerdictCache) Invalidate() {
+	if vc == nil {
+		return
+	}
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.version++
+	vc.lru.Purge()
+}
+
+// Key returns the key of a check of checkType with params in the current
+// request, false when the check is outside of a request
+func (vc *VerdictCache) Key(checkType string, params []byte) (string, bool) {
+	if vc == nil {
+		return "", false
+	}
+	requestKey, ok := currentRequestKey()
+	if !ok {
+		return "", false
+	}
+	vc.mu.RLock()
+	version := vc.version
+	vc.mu.RUnlock()
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, params); err != nil {
+		return "", false
+	}
+	h := sha1.New()
+	h.Write([]byte(checkType))
+	h.Write([]byte{0})
+	h.Write(compacted.Bytes())
+	h.Write([]byte{0})
+	h.Write([]byte(requestKey))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatUint(version, 10)))
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func (vc *VerdictCache) Get(key string) (interface{}, bool) {
+	if vc == nil {
+		return nil, false
+	}
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	return vc.lru.Get(key)
+}
+
+func (vc *VerdictCache) Add(key string, verdict interface{}) {
+	if vc == nil {
+		return
+	}
+	vc.mu.RLock()
+	defer vc.mu.RUnlock()
+	vc.lru.Add(key, verdict)
+}
+
+// currentRequestKey hashes what the plugins may read of the current request,
+// it is computed once per request
+func currentRequestKey() (string, bool) {
+	if key, ok := gls.Get("verdictRequestKey").(string); ok {
+		return key, true
+	}
+	requestInfo, ok := gls.Get("requestInfo").(*model.RequestInfo)
+	if !ok {
+		return "", false
+	}
+	h := sha1.New()
+	for _, field := range []string{
+		requestInfo.Method,
+		requestInfo.UrlFull,
+		requestInfo.RemoteAddr,
+		requestInfo.AppBasePath,
+		string(requestInfo.HeaderBytes),
+		string(requestInfo.GetBytes),
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	if requestInfo.RequestBody != nil {
+		h.Write([]byte(requestInfo.RequestBody.Raw))
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	gls.Set("verdictRequestKey", key)
+	return key, true
+}
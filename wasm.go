@@ -0,0 +1,114 @@
+package openrasp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"github.com/baidu-security/openrasp-golang/common"
+	"github.com/baidu-security/openrasp-golang/gls"
+	"github.com/baidu-security/openrasp-golang/model"
+	"github.com/baidu-security/openrasp-golang/orlog"
+	"github.com/baidu-security/openrasp-golang/wasmengine"
+)
+
+// wasmEngine runs the .wasm files of the plugin directory when
+// plugin.wasm.enable is on
+var wasmEngine *wasmengine.Engine
+
+func initWasmEngine() {
+	if !GetBasic().GetBool("plugin.wasm.enable") {
+		return
+	}
+	engine, err := wasmengine.NewEngine(uint32(GetBasic().GetInt64("plugin.wasm.max_memory_pages")), goruntime.NumCPU(), GetLog().PluginInfo)
+	if err != nil {
+		GetLog().RaspWarn("Unable to initialize wasm engine, cuz of "+err.Error(), orlog.Plugin)
+		return
+	}
+	wasmEngine = engine
+	RegisterChecker(wasmChecker{})
+}
+
+func newWasmPlugin(path string) (*wasmengine.Plugin, error) {
+	binary, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &wasmengine.Plugin{
+		Name:   strings.TrimSuffix(filepath.Base(path), ".wasm"),
+		Binary: binary,
+	}, nil
+}
+
+func loadWasmPlugins(plugins []wasmengine.Plugin) {
+	if wasmEngine == nil {
+		return
+	}
+	if err := wasmEngine.Load(plugins); err != nil {
+		GetLog().RaspWarn("Unable to load wasm plugins, cuz of "+err.Error(), orlog.Plugin)
+		return
+	}
+	GetVerdictCache().Invalidate()
+}
+
+// wasmChecker hands every hook point over to the wasm plugins
+type wasmChecker struct{}
+
+func (wasmChecker) CheckType() common.CheckType {
+	return common.AllType
+}
+
+func (wasmChecker) Check(params common.AttackChecker) *model.AttackResult {
+	if !wasmEngine.Loaded() {
+		return nil
+	}
+	input, err := json.Marshal(map[string]interface{}{
+		"type":    params.GetTypeString(),
+		"params":  params,
+		"context": gls.Get("requestInfo"),
+	})
+	if err != nil {
+		return nil
+	}
+	key, cacheable := GetVerdictCache().Key("wasm", input)
+	if cacheable {
+		if cached, ok := GetVerdictCache().Get(key); ok {
+			return copyAttackResult(cached.(*model.AttackResult))
+		}
+	}
+	timeout := time.Duration(GetGeneral().GetInt("plugin.timeout.millis")) * time.Millisecond
+	ms, err := wasmEngine.Check(input, timeout)
+	if err != nil {
+		GetLog().RaspDebug("Unable to run wasm plugins, cuz of "+err.Error(), orlog.Plugin)
+		ms = append(ms, failureResults(err)...)
+		cacheable = false
+	}
+	// report the most severe result
+	var result *model.AttackResult
+	for _, m := range ms {
+		ar := model.NewAttackResultFromMap(m)
+		if result == nil || ar.GetInterceptState() < result.GetInterceptState() {
+			result = ar
+		}
+	}
+	if result != nil && result.GetInterceptState() == model.Ignore {
+		result = nil
+	}
+	if cacheable {
+		GetVerdictCache().Add(key, copyAttackResult(result))
+	}
+	return result
+}
+
+// copyAttackResult keeps a cached result apart from the one algorithm.config
+// overrides
+func copyAttackResult(ar *model.AttackResult) *model.AttackResult {
+	if ar == nil {
+		return nil
+	}
+	copied := *ar
+	return &copied
+}
@@ -0,0 +1,237 @@
+// +build openrasp_wasm
+
+package wasmengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Supported reports whether the build includes a WebAssembly runtime
+const Supported = true
+
+// Engine runs every loaded plugin on each check. An instance is not safe for
+// concurrent use, so each plugin keeps a pool of instances which is refilled
+// on demand, an instance which trapped or timed out is closed.
+type Engine struct {
+	runtime  wazero.Runtime
+	logger   func(string)
+	poolSize int
+	mu       sync.RWMutex
+	plugins  []*plugin
+}
+
+type plugin struct {
+	name     string
+	compiled wazero.CompiledModule
+	pool     chan api.Module
+}
+
+func NewEngine(maxMemoryPages uint32, poolSize int, logger func(string)) (*Engine, error) {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	if logger == nil {
+		logger = func(string) {}
+	}
+	ctx := context.Background()
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if maxMemoryPages > 0 {
+		config = config.WithMemoryLimitPages(maxMemoryPages)
+	}
+	e := &Engine{
+		runtime:  wazero.NewRuntimeWithConfig(ctx, config),
+		logger:   logger,
+		poolSize: poolSize,
+	}
+	_, err := e.runtime.NewHostModuleBuilder("openrasp").
+		NewFunctionBuilder().WithFunc(e.hostLog).Export("log").
+		Instantiate(ctx)
+	if err != nil {
+		e.runtime.Close(ctx)
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) hostLog(ctx context.Context, m api.Module, ptr, size uint32) {
+	if b, ok := m.Memory().Read(ptr, size); ok {
+		e.logger(string(b))
+	}
+}
+
+// Load compiles the plugins and replaces the running ones, the running ones
+// are kept when a plugin fails to compile or misses an export
+func (e *Engine) Load(plugins []Plugin) error {
+	ctx := context.Background()
+	loaded := make([]*plugin, 0, len(plugins))
+	closeAll := func() {
+		for _, p := range loaded {
+			p.compiled.Close(ctx)
+		}
+	}
+	for _, p := range plugins {
+		compiled, err := e.runtime.CompileModule(ctx, p.Binary)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("unable to compile plugin %s, %v", p.Name, err)
+		}
+		loaded = append(loaded, &plugin{
+			name:     p.Name,
+			compiled: compiled,
+			pool:     make(chan api.Module, e.poolSize),
+		})
+		if err := checkExports(compiled); err != nil {
+			closeAll()
+			return fmt.Errorf("invalid plugin %s, %v", p.Name, err)
+		}
+	}
+	e.mu.Lock()
+	previous := e.plugins
+	e.plugins = loaded
+	e.mu.Unlock()
+	for _, p := range previous {
+		p.close(ctx)
+	}
+	return nil
+}
+
+func checkExports(compiled wazero.CompiledModule) error {
+	functions := compiled.ExportedFunctions()
+	for _, name := range []string{"alloc", "check"} {
+		if _, ok := functions[name]; !ok {
+			return fmt.Errorf("function %s is not exported", name)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		return fmt.Errorf("memory is not exported")
+	}
+	return nil
+}
+
+func (e *Engine) Loaded() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.plugins) > 0
+}
+
+// Check passes input to every plugin and returns all their results, each
+// plugin is given timeout
+func (e *Engine) Check(input []byte, timeout time.Duration) ([]map[string]interface{}, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	var results []map[string]interface{}
+	for _, p := range e.plugins {
+		output, err := e.call(p, input, timeout)
+		if err != nil {
+			return results, fmt.Errorf("plugin %s, %v", p.name, err)
+		}
+		var ms []map[string]interface{}
+		if err := json.Unmarshal(output, &ms); err != nil {
+			return results, fmt.Errorf("plugin %s returned invalid results, %v", p.name, err)
+		}
+		for _, m := range ms {
+			if _, ok := m["name"]; !ok {
+				m["name"] = p.name
+			}
+			results = append(results, m)
+		}
+	}
+	return results, nil
+}
+
+func (e *Engine) call(p *plugin, input []byte, timeout time.Duration) ([]byte, error) {
+	mod, err := p.get(e.runtime)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	output, err := invoke(ctx, mod, input)
+	if err != nil {
+		mod.Close(context.Background())
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrTimeout
+		}
+		return nil, err
+	}
+	p.put(mod)
+	return output, nil
+}
+
+func invoke(ctx context.Context, mod api.Module, input []byte) ([]byte, error) {
+	results, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+	ptr := uint32(results[0])
+	if !mod.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("alloc returned %d out of memory", ptr)
+	}
+	results, err = mod.ExportedFunction("check").Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, err
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	view, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fmt.Errorf("check returned %d+%d out of memory", outPtr, outLen)
+	}
+	// the view is only valid until the next call into the module
+	output := make([]byte, len(view))
+	copy(output, view)
+	if reset := mod.ExportedFunction("reset"); reset != nil {
+		if _, err := reset.Call(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return output, nil
+}
+
+func (p *plugin) get(r wazero.Runtime) (api.Module, error) {
+	select {
+	case mod := <-p.pool:
+		return mod, nil
+	default:
+		// anonymous modules can be instantiated more than once
+		return r.InstantiateModule(context.Background(), p.compiled, wazero.NewModuleConfig().WithName(""))
+	}
+}
+
+func (p *plugin) put(mod api.Module) {
+	select {
+	case p.pool <- mod:
+	default:
+		mod.Close(context.Background())
+	}
+}
+
+func (p *plugin) close(ctx context.Context) {
+	for {
+		select {
+		case mod := <-p.pool:
+			mod.Close(ctx)
+		default:
+			p.compiled.Close(ctx)
+			return
+		}
+	}
+}
+
+// Close releases the runtime and all the plugins
+func (e *Engine) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.plugins = nil
+	return e.runtime.Close(context.Background())
+}
@@ -0,0 +1,32 @@
+// +build !openrasp_wasm
+
+package wasmengine
+
+import (
+	"time"
+)
+
+// Supported reports whether the build includes a WebAssembly runtime
+const Supported = false
+
+type Engine struct{}
+
+func NewEngine(maxMemoryPages uint32, poolSize int, logger func(string)) (*Engine, error) {
+	return nil, ErrNotSupported
+}
+
+func (e *Engine) Load(plugins []Plugin) error {
+	return ErrNotSupported
+}
+
+func (e *Engine) Loaded() bool {
+	return false
+}
+
+func (e *Engine) Check(input []byte, timeout time.Duration) ([]map[string]interface{}, error) {
+	return nil, ErrNotSupported
+}
+
+func (e *Engine) Close() error {
+	return nil
+}
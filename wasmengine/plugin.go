@@ -0,0 +1,30 @@
+// Package wasmengine runs detection plugins compiled to WebAssembly.
+//
+// A plugin exports its memory and the two functions
+//
+//	alloc(size i32) i32
+//	check(ptr i32, len i32) i64
+//
+// check receives a JSON document {"type", "params", "context"} written by
+// the host at the address returned by alloc and returns the address and the
+// length of a JSON array of results packed as ptr<<32|len, an empty array
+// when there is nothing to report. When the plugin also exports reset() it is
+// called after each check, so that a bump allocator can start over.
+//
+// The only host function is openrasp.log(ptr i32, len i32), the plugins have
+// no access to the file system, the network or the clock.
+package wasmengine
+
+import (
+	"errors"
+)
+
+var ErrNotSupported = errors.New("wasm plugins are not supported by this build, rebuild with -tags openrasp_wasm")
+
+var ErrTimeout = errors.New("wasm plugin check timeout")
+
+// Plugin is a compiled WebAssembly module
+type Plugin struct {
+	Name   string
+	Binary []byte
+}
@@ -0,0 +1,46 @@
+package openrasp
+
+import (
+	"strings"
+	"sync"
+)
+
+// WeakPasswords is the dictionary of the connection policy checks, built
+// from security.weak_passwords and the corporate specific ones appended by
+// security.weak_passwords_append
+type WeakPasswords struct {
+	mu        sync.RWMutex
+	passwords map[string]bool
+}
+
+func NewWeakPasswords() *WeakPasswords {
+	wp := &WeakPasswords{}
+	wp.OnConfigUpdate()
+	return wp
+}
+
+func (wp *WeakPasswords) OnConfigUpdate() {
+	passwords := make(map[string]bool)
+	for _, key := range []string{"security.weak_passwords", "security.weak_passwords_append"} {
+		for _, password := range GetGeneral().GetStringSlice(key) {
+			passwords[strings.ToLower(password)] = true
+		}
+	}
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	wp.passwords = passwords
+}
+
+// IsWeak reports whether password is in the dictionary, regardless of case,
+// or repeats the user name
+func (wp *WeakPasswords) IsWeak(user, password string) bool {
+	if wp == nil {
+		return false
+	}
+	if len(user) > 0 && password == user {
+		return true
+	}
+	wp.mu.RLock()
+	defer wp.mu.RUnlock()
+	return wp.passwords[strings.ToLower(password)]
+}
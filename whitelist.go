@@ -10,8 +10,9 @@ import (
 )
 
 type WhiteList struct {
-	dat *common.DoubleArrayTrie
-	mu  sync.RWMutex
+	dat     *common.DoubleArrayTrie
+	entries map[string]int
+	mu      sync.RWMutex
 }
 
 func NewWhiteList() *WhiteList {
@@ -30,6 +31,35 @@ func (wl *WhiteList) build(urls []string, bits []int) {
 	defer wl.mu.Unlock()
 	wl.dat.Clear()
 	wl.dat.Build(urls, nil, bits, len(urls))
+	wl.entries = make(map[string]int, len(urls))
+	for i, url := range urls {
+		wl.entries[url] = bits[i]
+	}
+}
+
+// Entries returns the check types exempted under every url prefix, * is
+// the prefix matching every url
+func (wl *WhiteList) Entries() map[string][]string {
+	wl.mu.RLock()
+	defer wl.mu.RUnlock()
+	entries := make(map[string][]string, len(wl.entries))
+	for url, mask := range wl.entries {
+		if url == "" {
+			url = "*"
+		}
+		if mask&common.AllType == common.AllType {
+			entries[url] = []string{"all"}
+			continue
+		}
+		var types []string
+		for ct := common.CheckType(1); ct&common.AllType != 0; ct <<= 1 {
+			if mask&int(ct) != 0 {
+				types = append(types, common.CheckTypeToString(ct))
+			}
+		}
+		entries[url] = types
+	}
+	return entries
 }
 
 func (wl *WhiteList) Build(m map[string]int) {